@@ -0,0 +1,156 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package aggregation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+/*
+	NewAggregationConstraints: the circuit aggregating blocksCount blocks of txsCount txs, whose gas is
+	collected by gasAccountIndex in gasAssetIds
+*/
+func NewAggregationConstraints(blocksCount int, txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*AggregationConstraints, error) {
+	if blocksCount <= 0 {
+		errInfo := fmt.Sprintf("[NewAggregationConstraints] invalid blocks count: %d", blocksCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if txsCount <= 0 {
+		errInfo := fmt.Sprintf("[NewAggregationConstraints] invalid txs count: %d", txsCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if len(gasAssetIds) == 0 {
+		errInfo := "[NewAggregationConstraints] gas asset ids should not be empty"
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	aggregationConstraints := &AggregationConstraints{
+		Blocks:          make([]BlockConstraints, blocksCount),
+		TxsCount:        txsCount,
+		GasAssetIds:     append([]int64{}, gasAssetIds...),
+		GasAccountIndex: gasAccountIndex,
+		Hasher:          hasher.Default,
+	}
+	for i := range aggregationConstraints.Blocks {
+		aggregationConstraints.Blocks[i].Txs = make([]circuit.TxConstraints, txsCount)
+		for j := 0; j < txsCount; j++ {
+			aggregationConstraints.Blocks[i].Txs[j] = circuit.GetZeroTxConstraint()
+		}
+		aggregationConstraints.Blocks[i].Gas = circuit.GetZeroGasConstraints(gasAssetIds)
+	}
+	return aggregationConstraints, nil
+}
+
+/*
+	SetAggregationWitness: the witness of the aggregation of blocks, which have to be chained
+*/
+func SetAggregationWitness(blocks []*circuit.Block) (witness AggregationConstraints, err error) {
+	aggregatedCommitment, err := ComputeAggregatedCommitment(blocks)
+	if err != nil {
+		return witness, err
+	}
+	witness.AggregatedCommitment = aggregatedCommitment
+	witness.Blocks = make([]BlockConstraints, len(blocks))
+	for i, oBlock := range blocks {
+		witness.Blocks[i], err = setBlockWitness(oBlock)
+		if err != nil {
+			errInfo := fmt.Sprintf("[SetAggregationWitness] invalid block %d: %s", i, err.Error())
+			log.Println(errInfo)
+			return witness, errors.New(errInfo)
+		}
+	}
+	return witness, nil
+}
+
+func setBlockWitness(oBlock *circuit.Block) (witness BlockConstraints, err error) {
+	blockWitness, err := circuit.SetBlockWitness(oBlock)
+	if err != nil {
+		return witness, err
+	}
+	return BlockConstraints{
+		BlockNumber:     blockWitness.BlockNumber,
+		CreatedAt:       blockWitness.CreatedAt,
+		OldStateRoot:    blockWitness.OldStateRoot,
+		NewStateRoot:    blockWitness.NewStateRoot,
+		BlockCommitment: blockWitness.BlockCommitment,
+		Txs:             blockWitness.Txs,
+		Gas:             blockWitness.Gas,
+	}, nil
+}
+
+/*
+	ChainBlocks: every block has to follow the previous one and start from its new state root,
+	as the aggregation circuit checks
+*/
+func ChainBlocks(blocks []*circuit.Block) error {
+	if len(blocks) == 0 {
+		log.Println("[ChainBlocks] no block to aggregate")
+		return errors.New("[ChainBlocks] no block to aggregate")
+	}
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].BlockNumber != blocks[i-1].BlockNumber+1 {
+			errInfo := fmt.Sprintf("[ChainBlocks] block %d does not follow block %d", blocks[i].BlockNumber, blocks[i-1].BlockNumber)
+			log.Println(errInfo)
+			return errors.New(errInfo)
+		}
+		if !bytes.Equal(blocks[i-1].NewStateRoot, blocks[i].OldStateRoot) {
+			errInfo := fmt.Sprintf("[ChainBlocks] old state root of block %d is not the new state root of block %d",
+				blocks[i].BlockNumber, blocks[i-1].BlockNumber)
+			log.Println(errInfo)
+			return errors.New(errInfo)
+		}
+	}
+	return nil
+}
+
+/*
+	ComputeAggregatedCommitment: native counterpart of the commitment checked by VerifyAggregation,
+	blocks have to be chained and carry their own commitment. The rollup contract computes it from the
+	commitments it stored when the blocks were committed.
+*/
+func ComputeAggregatedCommitment(blocks []*circuit.Block) ([]byte, error) {
+	if err := ChainBlocks(blocks); err != nil {
+		return nil, err
+	}
+	hFunc := mimc.NewMiMC()
+	for _, oBlock := range blocks {
+		blockCommitment, err := circuit.ComputeBlockCommitment(oBlock)
+		if err != nil {
+			errInfo := fmt.Sprintf("[ComputeAggregatedCommitment] unable to compute commitment of block %d: %s",
+				oBlock.BlockNumber, err.Error())
+			log.Println(errInfo)
+			return nil, errors.New(errInfo)
+		}
+		if !bytes.Equal(blockCommitment, oBlock.BlockCommitment) {
+			errInfo := fmt.Sprintf("[ComputeAggregatedCommitment] invalid commitment of block %d", oBlock.BlockNumber)
+			log.Println(errInfo)
+			return nil, errors.New(errInfo)
+		}
+		hFunc.Write(blockCommitment)
+	}
+	return hFunc.Sum(nil), nil
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+	Package aggregation proves K chained blocks in a single proof over BN254, so that L1 verifies one
+	proof, against a single public input, instead of one per block.
+
+	Every block is checked by circuit.VerifyBlock, as BlockConstraints checks it, but its commitment is
+	not a public input: the aggregation exposes the mimc of the commitments of its blocks, in order.
+	The circuit chains the blocks: every block follows the previous one and starts from its new state root.
+
+	This is not recursive aggregation: the proving cost is that of K blocks. Verifying proofs of
+	BlockConstraints in a circuit needs a block circuit over BLS12-377, or an in-circuit verifier of
+	BN254 proofs, which gnark has none of.
+*/
+package aggregation
+
+import (
+	"errors"
+
+	"github.com/consensys/gnark/frontend"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+)
+
+type (
+	Variable = frontend.Variable
+	API      = frontend.API
+	MiMC     = types.MiMC
+)
+
+/*
+	BlockConstraints: a block of the aggregation, its fields are those of circuit.BlockConstraints,
+	but its commitment is secret
+*/
+type BlockConstraints struct {
+	BlockNumber     Variable
+	CreatedAt       Variable
+	OldStateRoot    Variable
+	NewStateRoot    Variable
+	BlockCommitment Variable
+	Txs             []circuit.TxConstraints
+	Gas             circuit.GasConstraints
+}
+
+/*
+	AggregationConstraints: the blocks share the size and the gas settings of a block circuit,
+	AggregatedCommitment is the only public input
+*/
+type AggregationConstraints struct {
+	AggregatedCommitment Variable `gnark:",public"`
+	Blocks               []BlockConstraints
+	TxsCount             int
+	GasAssetIds          []int64
+	GasAccountIndex      int64
+	// hash function of the trees, mimc by default
+	Hasher hasher.Type `gnark:"-"`
+}
+
+func (circuit AggregationConstraints) Define(api API) error {
+	return VerifyAggregation(api, circuit, types.NewMiMC(api))
+}
+
+/*
+	VerifyAggregation: every block is a valid block, follows the previous one and starts from its new
+	state root, and the aggregated commitment is the mimc of the block commitments, in order
+*/
+func VerifyAggregation(
+	api API,
+	aggregation AggregationConstraints,
+	hFunc MiMC,
+) error {
+	if len(aggregation.Blocks) == 0 {
+		return errors.New("[VerifyAggregation] no block to aggregate")
+	}
+	commitments := make([]Variable, len(aggregation.Blocks))
+	for i, block := range aggregation.Blocks {
+		if i > 0 {
+			previous := aggregation.Blocks[i-1]
+			api.AssertIsEqual(block.BlockNumber, api.Add(previous.BlockNumber, 1))
+			api.AssertIsEqual(block.OldStateRoot, previous.NewStateRoot)
+		}
+		hFunc.Reset()
+		if err := circuit.VerifyBlock(api, aggregation.blockConstraints(block), hFunc); err != nil {
+			return err
+		}
+		commitments[i] = block.BlockCommitment
+	}
+	hFunc.Reset()
+	hFunc.Write(commitments...)
+	api.AssertIsEqual(hFunc.Sum(), aggregation.AggregatedCommitment)
+	return nil
+}
+
+/*
+	blockConstraints: the block circuit checking block
+*/
+func (aggregation AggregationConstraints) blockConstraints(block BlockConstraints) circuit.BlockConstraints {
+	return circuit.BlockConstraints{
+		BlockNumber:     block.BlockNumber,
+		CreatedAt:       block.CreatedAt,
+		OldStateRoot:    block.OldStateRoot,
+		NewStateRoot:    block.NewStateRoot,
+		BlockCommitment: block.BlockCommitment,
+		Txs:             block.Txs,
+		TxsCount:        aggregation.TxsCount,
+		Gas:             block.Gas,
+		GasAssetIds:     aggregation.GasAssetIds,
+		GasAccountIndex: aggregation.GasAccountIndex,
+		Hasher:          aggregation.Hasher,
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+const txsCount = 2
+
+var gasAssetIds = []int64{0}
+
+/*
+	buildBlocks: chained blocks numbered from blockNumbers on a new state, the first one registers
+	the gas account and alice, every other one deposits to alice
+*/
+func buildBlocks(t *testing.T, blockNumbers ...int64) []*circuit.Block {
+	s, err := state.NewState()
+	require.NoError(t, err)
+	blockBuilder, err := witness.NewBlockBuilder(witness.NewBuilder(s), txsCount, testutil.GasAccount, gasAssetIds)
+	require.NoError(t, err)
+	var registerTxs []txtypes.TxInfo
+	for _, accountIndex := range []int64{testutil.GasAccount, testutil.Alice} {
+		txInfo, err := testutil.RegisterTx(accountIndex)
+		require.NoError(t, err)
+		registerTxs = append(registerTxs, txInfo)
+	}
+	var blocks []*circuit.Block
+	for i, blockNumber := range blockNumbers {
+		txInfos := registerTxs
+		if i > 0 {
+			txInfos = []txtypes.TxInfo{testutil.DepositTx(testutil.Alice, 0, 1000)}
+		}
+		oBlock, err := blockBuilder.BuildBlock(blockNumber, 1660000000000+1000*blockNumber, txInfos)
+		require.NoError(t, err)
+		blocks = append(blocks, oBlock)
+	}
+	return blocks
+}
+
+func TestAggregation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("solving the aggregation of two blocks is slow")
+	}
+	aggregationConstraints, err := NewAggregationConstraints(2, txsCount, testutil.GasAccount, gasAssetIds)
+	require.NoError(t, err)
+	blocks := buildBlocks(t, 1, 2)
+
+	assignment, err := SetAggregationWitness(blocks)
+	require.NoError(t, err)
+	assert.NoError(t, test.IsSolved(aggregationConstraints, &assignment, ecc.BN254.ScalarField()))
+
+	// the commitment of the last block is not the aggregated commitment
+	aggregatedCommitment := assignment.AggregatedCommitment
+	assignment.AggregatedCommitment = blocks[1].BlockCommitment
+	assert.Error(t, test.IsSolved(aggregationConstraints, &assignment, ecc.BN254.ScalarField()))
+	assignment.AggregatedCommitment = aggregatedCommitment
+
+	// a valid block 2 which does not start from the new state root of block 1
+	unchained := buildBlocks(t, 2)[0]
+	_, err = SetAggregationWitness([]*circuit.Block{blocks[0], unchained})
+	assert.Error(t, err)
+	assignment.Blocks[1], err = setBlockWitness(unchained)
+	require.NoError(t, err)
+	assert.Error(t, test.IsSolved(aggregationConstraints, &assignment, ecc.BN254.ScalarField()))
+
+	// a valid block 3 which starts from the new state root of block 1
+	skipped := buildBlocks(t, 1, 3)[1]
+	_, err = SetAggregationWitness([]*circuit.Block{blocks[0], skipped})
+	assert.Error(t, err)
+	assignment.Blocks[1], err = setBlockWitness(skipped)
+	require.NoError(t, err)
+	assert.Error(t, test.IsSolved(aggregationConstraints, &assignment, ecc.BN254.ScalarField()))
+}
+
+func TestComputeAggregatedCommitment(t *testing.T) {
+	blocks := buildBlocks(t, 1, 2)
+	aggregatedCommitment, err := ComputeAggregatedCommitment(blocks)
+	require.NoError(t, err)
+	assert.Len(t, aggregatedCommitment, 32)
+	single, err := ComputeAggregatedCommitment(blocks[:1])
+	require.NoError(t, err)
+	assert.NotEqual(t, aggregatedCommitment, single)
+
+	_, err = ComputeAggregatedCommitment(nil)
+	assert.Error(t, err)
+	_, err = ComputeAggregatedCommitment([]*circuit.Block{blocks[1], blocks[0]})
+	assert.Error(t, err)
+	_, err = ComputeAggregatedCommitment([]*circuit.Block{blocks[0], blocks[0]})
+	assert.Error(t, err)
+	tampered := *blocks[1]
+	tampered.BlockCommitment = blocks[0].BlockCommitment
+	_, err = ComputeAggregatedCommitment([]*circuit.Block{blocks[0], &tampered})
+	assert.Error(t, err)
+
+	_, err = NewAggregationConstraints(0, txsCount, testutil.GasAccount, gasAssetIds)
+	assert.Error(t, err)
+	_, err = NewAggregationConstraints(2, 0, testutil.GasAccount, gasAssetIds)
+	assert.Error(t, err)
+	_, err = NewAggregationConstraints(2, txsCount, testutil.GasAccount, nil)
+	assert.Error(t, err)
+}
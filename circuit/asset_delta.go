@@ -493,15 +493,24 @@ func GetAssetDeltasFromFullExit(
 	return deltas
 }
 
-func GetNftDeltaFromFullExitNft() (nftDelta NftDeltaConstraints) {
+/*
+	GetNftDeltaFromFullExitNft: the nft is cleared if the account owns it, a full exit
+	of an nft owned by another account leaves it to its owner
+*/
+func GetNftDeltaFromFullExitNft(
+	api API,
+	txInfo FullExitNftTxConstraints,
+	nftBefore NftConstraints,
+) (nftDelta NftDeltaConstraints) {
+	isOwner := api.IsZero(api.Sub(txInfo.AccountIndex, nftBefore.OwnerAccountIndex))
 	nftDelta = NftDeltaConstraints{
-		CreatorAccountIndex: types.ZeroInt,
-		OwnerAccountIndex:   types.ZeroInt,
-		NftContentHash:      types.ZeroInt,
-		NftL1Address:        types.ZeroInt,
-		NftL1TokenId:        types.ZeroInt,
-		CreatorTreasuryRate: types.ZeroInt,
-		CollectionId:        types.ZeroInt,
+		CreatorAccountIndex: api.Select(isOwner, types.ZeroInt, nftBefore.CreatorAccountIndex),
+		OwnerAccountIndex:   api.Select(isOwner, types.ZeroInt, nftBefore.OwnerAccountIndex),
+		NftContentHash:      api.Select(isOwner, types.ZeroInt, nftBefore.NftContentHash),
+		NftL1Address:        api.Select(isOwner, types.ZeroInt, nftBefore.NftL1Address),
+		NftL1TokenId:        api.Select(isOwner, types.ZeroInt, nftBefore.NftL1TokenId),
+		CreatorTreasuryRate: api.Select(isOwner, types.ZeroInt, nftBefore.CreatorTreasuryRate),
+		CollectionId:        api.Select(isOwner, types.ZeroInt, nftBefore.CollectionId),
 	}
 	return nftDelta
 }
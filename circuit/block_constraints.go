@@ -20,9 +20,8 @@ package circuit
 import (
 	"log"
 
-	"github.com/consensys/gnark/std/hash/mimc"
-
 	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
 )
 
 type BlockConstraints struct {
@@ -36,16 +35,15 @@ type BlockConstraints struct {
 	Gas             GasConstraints
 	GasAssetIds     []int64
 	GasAccountIndex int64
+	// hash function of the trees, mimc by default, signatures are always hashed with mimc
+	Hasher hasher.Type `gnark:"-"`
 }
 
 func (circuit BlockConstraints) Define(api API) error {
 	// mimc
-	hFunc, err := mimc.NewMiMC(api)
-	if err != nil {
-		return err
-	}
+	hFunc := types.NewMiMC(api)
 
-	err = VerifyBlock(api, circuit, hFunc)
+	err := VerifyBlock(api, circuit, hFunc)
 	if err != nil {
 		return err
 	}
@@ -82,8 +80,18 @@ func VerifyBlock(
 		roots[i] = Variable(0)
 	}
 
+	tFunc, err := block.Hasher.NewCircuit(api)
+	if err != nil {
+		return err
+	}
+	txs := make([]TxConstraints, block.TxsCount)
+	for i := 0; i < block.TxsCount; i++ {
+		txs[i] = block.Txs[i]
+		txs[i].Hasher = block.Hasher
+	}
+
 	onChainOpsCount = 0
-	isOnChainOp, pendingPubData, roots, gasDeltas, err := VerifyTransaction(api, block.Txs[0], hFunc, block.CreatedAt, block.GasAssetIds, roots)
+	isOnChainOp, pendingPubData, roots, gasDeltas, err := VerifyTransaction(api, txs[0], hFunc, block.CreatedAt, block.GasAssetIds, roots)
 	if err != nil {
 		log.Println("unable to verify transaction, err:", err)
 		return err
@@ -108,7 +116,7 @@ func VerifyBlock(
 	for i := 1; i < block.TxsCount; i++ {
 		api.AssertIsEqual(block.Txs[i-1].StateRootAfter, block.Txs[i].StateRootBefore)
 		hFunc.Reset()
-		isOnChainOp, pendingPubData, roots, gasDeltas, err = VerifyTransaction(api, block.Txs[i], hFunc, block.CreatedAt, block.GasAssetIds, roots)
+		isOnChainOp, pendingPubData, roots, gasDeltas, err = VerifyTransaction(api, txs[i], hFunc, block.CreatedAt, block.GasAssetIds, roots)
 		if err != nil {
 			log.Println("unable to verify transaction, err:", err)
 			return err
@@ -143,21 +151,33 @@ func VerifyBlock(
 		transferNft := api.IsZero(api.Sub(block.Txs[i].TxType, types.TxTypeTransferNft))
 		txNeedGas := api.Or(api.Or(api.Or(api.Or(api.Or(api.Or(api.Or(transferTx, withdrawTx), createCollectionTx), mintNftTx), cancelOfferTx), atomicMatchTx), withdrawNftTx), transferNft)
 		needGas = api.Or(needGas, txNeedGas)
+		// the gas of a tx goes to the gas account of the block
+		txGasAccountIndex := api.Add(
+			api.Mul(transferTx, block.Txs[i].TransferTxInfo.GasAccountIndex),
+			api.Mul(withdrawTx, block.Txs[i].WithdrawTxInfo.GasAccountIndex),
+			api.Mul(createCollectionTx, block.Txs[i].CreateCollectionTxInfo.GasAccountIndex),
+			api.Mul(mintNftTx, block.Txs[i].MintNftTxInfo.GasAccountIndex),
+			api.Mul(cancelOfferTx, block.Txs[i].CancelOfferTxInfo.GasAccountIndex),
+			api.Mul(atomicMatchTx, block.Txs[i].AtomicMatchTxInfo.GasAccountIndex),
+			api.Mul(withdrawNftTx, block.Txs[i].WithdrawNftTxInfo.GasAccountIndex),
+			api.Mul(transferNft, block.Txs[i].TransferNftTxInfo.GasAccountIndex),
+		)
+		types.IsVariableEqual(api, txNeedGas, txGasAccountIndex, block.GasAccountIndex)
 	}
 
 	types.IsVariableEqual(api, needGas, block.Gas.AccountInfoBefore.AccountIndex, block.GasAccountIndex)
-	roots[0], err = VerifyGas(api, block.Gas, needGas, blockGasDeltas, hFunc, roots[0])
+	roots[0], err = VerifyGas(api, block.Gas, needGas, blockGasDeltas, tFunc, roots[0])
 	if err != nil {
 		log.Println("unable to verify gas, err:", err)
 		return err
 	}
-	hFunc.Reset()
+	tFunc.Reset()
 	for i := 0; i < types.NbRoots; i++ {
-		hFunc.Write(
+		tFunc.Write(
 			roots[i],
 		)
 	}
-	newStateRoot := hFunc.Sum()
+	newStateRoot := tFunc.Sum()
 	types.IsVariableEqual(api, needGas, block.NewStateRoot, newStateRoot)
 
 	notNeedGas := api.Xor(1, needGas)
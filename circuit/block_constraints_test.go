@@ -0,0 +1,142 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+/*
+	TestHasherConstraints: the constraints of a tx and of a block of 1 tx with every tree hash function,
+	and a block built on a poseidon state is only solved by the poseidon circuit
+*/
+func TestHasherConstraints(t *testing.T) {
+	gasAssetIds := []int64{0, 1}
+	counts := make(map[hasher.Type][2]int)
+	for _, h := range []hasher.Type{hasher.MiMC, hasher.Poseidon} {
+		tx := circuit.GetZeroTxConstraint()
+		tx.Hasher = h
+		txCs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &tx, frontend.IgnoreUnconstrainedInputs())
+		require.NoError(t, err, h.String())
+
+		block := circuit.BlockConstraints{
+			TxsCount:        1,
+			Txs:             []circuit.TxConstraints{circuit.GetZeroTxConstraint()},
+			GasAssetIds:     gasAssetIds,
+			GasAccountIndex: 1,
+			Gas:             circuit.GetZeroGasConstraints(gasAssetIds),
+			Hasher:          h,
+		}
+		blockCs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &block, frontend.IgnoreUnconstrainedInputs())
+		require.NoError(t, err, h.String())
+
+		counts[h] = [2]int{txCs.GetNbConstraints(), blockCs.GetNbConstraints()}
+		t.Logf("%s: tx %d constraints, block of 1 tx %d constraints", h, txCs.GetNbConstraints(), blockCs.GetNbConstraints())
+	}
+	// the signature hashes stay on mimc, only the tree hashes change
+	assert.NotEqual(t, counts[hasher.MiMC][0], counts[hasher.Poseidon][0])
+	assert.NotEqual(t, counts[hasher.MiMC][1], counts[hasher.Poseidon][1])
+
+	oBlock := buildPoseidonBlock(t)
+	blockWitness, err := circuit.SetBlockWitness(oBlock)
+	require.NoError(t, err)
+	for _, h := range []hasher.Type{hasher.Poseidon, hasher.MiMC} {
+		block := &circuit.BlockConstraints{
+			TxsCount:        len(oBlock.Txs),
+			Txs:             make([]circuit.TxConstraints, len(oBlock.Txs)),
+			GasAssetIds:     []int64{0},
+			GasAccountIndex: testutil.GasAccount,
+			Gas:             circuit.GetZeroGasConstraints([]int64{0}),
+			Hasher:          h,
+		}
+		for i := range block.Txs {
+			block.Txs[i] = circuit.GetZeroTxConstraint()
+		}
+		err = test.IsSolved(block, &blockWitness, ecc.BN254.ScalarField())
+		if h == hasher.Poseidon {
+			assert.NoError(t, err)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+}
+
+/*
+	buildPoseidonBlock: a deposit to alice and a transfer from alice to bob, on a state hashed
+	with poseidon whose accounts are set directly
+*/
+func buildPoseidonBlock(t *testing.T) *circuit.Block {
+	s, err := state.NewStateWithHasher(hasher.Poseidon)
+	require.NoError(t, err)
+	for _, accountIndex := range []int64{testutil.GasAccount, testutil.Alice, testutil.Bob} {
+		sk, err := testutil.PrivateKey(accountIndex)
+		require.NoError(t, err)
+		require.NoError(t, s.SetAccount(&state.AccountState{
+			AccountIndex:    accountIndex,
+			AccountNameHash: testutil.NameHash(accountIndex),
+			AccountPk:       &sk.PublicKey,
+		}))
+	}
+	require.NoError(t, s.SetAsset(testutil.Alice, &state.AssetState{
+		AssetId:                  0,
+		Balance:                  big.NewInt(100000),
+		OfferCanceledOrFinalized: big.NewInt(0),
+	}))
+
+	sk, err := testutil.PrivateKey(testutil.Alice)
+	require.NoError(t, err)
+	transferInfo, err := txtypes.ConstructTransferTxInfo(sk, testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		AssetId:           0,
+		AssetAmount:       "1000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         time.Now().Add(time.Hour).UnixMilli(),
+		Nonce:             0,
+	}))
+	require.NoError(t, err)
+	blockBuilder, err := witness.NewBlockBuilder(witness.NewBuilder(s), 2, testutil.GasAccount, []int64{0})
+	require.NoError(t, err)
+	oBlock, err := blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		testutil.DepositTx(testutil.Alice, 0, 1000),
+		transferInfo,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, s.StateRoot(), oBlock.NewStateRoot)
+	return oBlock
+}
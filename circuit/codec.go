@@ -0,0 +1,774 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+)
+
+/*
+	Wire formats of Block, Tx and Gas, so that a block witness built by the sequencer can be proven by another process.
+
+	JSON: an object whose first key is "version", followed by the fields of the value.
+	Struct fields are keyed by their snake case name, NftL1TokenId is "nft_l1_token_id", in declaration order:
+	- int64, int and uint8 are numbers, strings are strings
+	- []byte is a hex string, *big.Int a decimal string
+	- *eddsa.PublicKey is the hex of A.X | A.Y, *eddsa.Signature the hex of R.X | R.Y | S, 32 bytes each
+	- fixed arrays, the accounts and merkle proofs, must have exactly the length of their level constant
+	- nil pointers are left out, a Tx only carries the tx info of its TxType
+	Unknown and missing fields are rejected.
+
+	Binary, all integers are big endian:
+	magic "ZKBW" | version uint8 | kind uint8 | NbAccountsPerTx | NbAccountAssetsPerAccount | AssetMerkleLevels |
+	AccountMerkleLevels | NftMerkleLevels, all uint8 | value.
+	kind is 1 for a Block, 2 for a Tx and 3 for Gas. Fields are written in declaration order:
+	int64 and int as 8 bytes, uint8 as 1 byte, []byte, string and *big.Int as uint16 length | bytes,
+	public keys and signatures as in JSON, fixed arrays as their items, other slices as uint32 count | items
+	and pointers as present uint8 | value.
+
+	Decoded values are checked by ValidateBlock, ValidateTx and ValidateGas, as are values before being encoded.
+*/
+const BlockFormatVersion = 1
+
+const (
+	blockKind = 1
+	txKind    = 2
+	gasKind   = 3
+)
+
+var (
+	blockMagic = []byte("ZKBW")
+
+	bigIntType    = reflect.TypeOf(&big.Int{})
+	publicKeyType = reflect.TypeOf(&eddsa.PublicKey{})
+	signatureType = reflect.TypeOf(&eddsa.Signature{})
+	txStructType  = reflect.TypeOf(Tx{})
+)
+
+/*
+	fieldName: snake case name of a struct field
+*/
+func fieldName(name string) string {
+	var buf bytes.Buffer
+	for i, c := range name {
+		if unicode.IsUpper(c) && i > 0 && !unicode.IsUpper(rune(name[i-1])) {
+			buf.WriteByte('_')
+		}
+		buf.WriteRune(unicode.ToLower(c))
+	}
+	return buf.String()
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func pathError(path string, format string, a ...interface{}) error {
+	if path == "" {
+		return fmt.Errorf(format, a...)
+	}
+	return fmt.Errorf("%s: %s", path, fmt.Sprintf(format, a...))
+}
+
+func elementBytes(e *fr.Element) []byte {
+	b := e.Bytes()
+	return b[:]
+}
+
+func setElement(e *fr.Element, b []byte) error {
+	e.SetBytes(b)
+	if !bytes.Equal(elementBytes(e), b) {
+		return errors.New("not a canonical field element")
+	}
+	return nil
+}
+
+func pointBytes(p *twistededwards.PointAffine) []byte {
+	return append(elementBytes(&p.X), elementBytes(&p.Y)...)
+}
+
+func setPoint(p *twistededwards.PointAffine, b []byte) error {
+	if err := setElement(&p.X, b[:fr.Bytes]); err != nil {
+		return err
+	}
+	return setElement(&p.Y, b[fr.Bytes:2*fr.Bytes])
+}
+
+/*
+	leafBytes: fixed size encoding of public keys and signatures
+*/
+func leafBytes(v reflect.Value) []byte {
+	if pk, ok := v.Interface().(*eddsa.PublicKey); ok {
+		return pointBytes(&pk.A)
+	}
+	sig := v.Interface().(*eddsa.Signature)
+	return append(pointBytes(&sig.R), sig.S[:]...)
+}
+
+func setLeaf(v reflect.Value, b []byte) error {
+	if v.Type() == publicKeyType {
+		if len(b) != 2*fr.Bytes {
+			return fmt.Errorf("expected %d bytes, got %d", 2*fr.Bytes, len(b))
+		}
+		pk := new(eddsa.PublicKey)
+		if err := setPoint(&pk.A, b); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(pk))
+		return nil
+	}
+	if len(b) != 3*fr.Bytes {
+		return fmt.Errorf("expected %d bytes, got %d", 3*fr.Bytes, len(b))
+	}
+	sig := new(eddsa.Signature)
+	if err := setPoint(&sig.R, b); err != nil {
+		return err
+	}
+	copy(sig.S[:], b[2*fr.Bytes:])
+	v.Set(reflect.ValueOf(sig))
+	return nil
+}
+
+func isLeaf(t reflect.Type) bool {
+	return t == publicKeyType || t == signatureType
+}
+
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+func encodeJSON(buf *bytes.Buffer, v reflect.Value, path string) error {
+	if v.Kind() == reflect.Ptr {
+		switch {
+		case v.IsNil():
+			buf.WriteString("null")
+		case v.Type() == bigIntType:
+			n := v.Interface().(*big.Int)
+			if n.Sign() < 0 {
+				return pathError(path, "negative integer")
+			}
+			writeJSONString(buf, n.String())
+		case isLeaf(v.Type()):
+			writeJSONString(buf, hex.EncodeToString(leafBytes(v)))
+		default:
+			return encodeJSON(buf, v.Elem(), path)
+		}
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if field.Kind() == reflect.Ptr && field.IsNil() {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			name := fieldName(v.Type().Field(i).Name)
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, field, joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		if isBytes(v.Type()) {
+			writeJSONString(buf, hex.EncodeToString(v.Bytes()))
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, v.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case reflect.Int64, reflect.Int:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint8:
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.String:
+		writeJSONString(buf, v.String())
+	default:
+		return pathError(path, "unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func decodeJSONString(data json.RawMessage, path string) (string, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", pathError(path, "expected a string")
+	}
+	return s, nil
+}
+
+func decodeJSONHex(data json.RawMessage, path string) ([]byte, error) {
+	s, err := decodeJSONString(data, path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, pathError(path, "invalid hex: %s", err)
+	}
+	return b, nil
+}
+
+func decodeJSON(data json.RawMessage, v reflect.Value, path string) error {
+	isNull := bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+	if v.Kind() == reflect.Ptr {
+		switch {
+		case isNull:
+			v.Set(reflect.Zero(v.Type()))
+		case v.Type() == bigIntType:
+			s, err := decodeJSONString(data, path)
+			if err != nil {
+				return err
+			}
+			n, ok := new(big.Int).SetString(s, 10)
+			if !ok || n.Sign() < 0 {
+				return pathError(path, "invalid unsigned integer %q", s)
+			}
+			v.Set(reflect.ValueOf(n))
+		case isLeaf(v.Type()):
+			b, err := decodeJSONHex(data, path)
+			if err != nil {
+				return err
+			}
+			if err = setLeaf(v, b); err != nil {
+				return pathError(path, "%s", err)
+			}
+		default:
+			v.Set(reflect.New(v.Type().Elem()))
+			return decodeJSON(data, v.Elem(), path)
+		}
+		return nil
+	}
+	if isNull {
+		return pathError(path, "unexpected null")
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return pathError(path, "expected an object")
+		}
+		return decodeJSONFields(fields, v, path)
+	case reflect.Slice, reflect.Array:
+		if isBytes(v.Type()) {
+			b, err := decodeJSONHex(data, path)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return pathError(path, "expected an array")
+		}
+		if v.Kind() == reflect.Array && len(items) != v.Len() {
+			return pathError(path, "expected %d items, got %d", v.Len(), len(items))
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		}
+		for i, item := range items {
+			if err := decodeJSON(item, v.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Int64, reflect.Int, reflect.Uint8, reflect.String:
+		if err := json.Unmarshal(data, v.Addr().Interface()); err != nil {
+			return pathError(path, "expected a %s", v.Type())
+		}
+	default:
+		return pathError(path, "unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func decodeJSONFields(fields map[string]json.RawMessage, v reflect.Value, path string) error {
+	for i := 0; i < v.NumField(); i++ {
+		name := fieldName(v.Type().Field(i).Name)
+		data, ok := fields[name]
+		if !ok {
+			if v.Field(i).Kind() == reflect.Ptr {
+				continue
+			}
+			return pathError(joinPath(path, name), "missing")
+		}
+		delete(fields, name)
+		if err := decodeJSON(data, v.Field(i), joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	var unknown []string
+	for name := range fields {
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return pathError(joinPath(path, unknown[0]), "unknown field")
+	}
+	return nil
+}
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"version":` + strconv.Itoa(BlockFormatVersion))
+	var body bytes.Buffer
+	if err := encodeJSON(&body, reflect.ValueOf(v).Elem(), ""); err != nil {
+		return nil, err
+	}
+	if body.Len() > 2 {
+		buf.WriteByte(',')
+		buf.Write(body.Bytes()[1:])
+	} else {
+		buf.WriteByte('}')
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalJSON(data []byte, v interface{}) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return errors.New("expected an object")
+	}
+	var version int
+	if err := json.Unmarshal(fields["version"], &version); err != nil {
+		return errors.New("version: expected a number")
+	}
+	if version != BlockFormatVersion {
+		return fmt.Errorf("unsupported version: %d", version)
+	}
+	delete(fields, "version")
+	return decodeJSONFields(fields, reflect.ValueOf(v).Elem(), "")
+}
+
+func writeBytes16(buf *bytes.Buffer, b []byte, path string) error {
+	if len(b) > 1<<16-1 {
+		return pathError(path, "value too long")
+	}
+	var size [2]byte
+	binary.BigEndian.PutUint16(size[:], uint16(len(b)))
+	buf.Write(size[:])
+	buf.Write(b)
+	return nil
+}
+
+func readBytes16(r *bytes.Reader, path string) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, pathError(path, "%s", io.ErrUnexpectedEOF)
+	}
+	return readN(r, int(binary.BigEndian.Uint16(size[:])), path)
+}
+
+func readN(r *bytes.Reader, n int, path string) ([]byte, error) {
+	if n > r.Len() {
+		return nil, pathError(path, "%s", io.ErrUnexpectedEOF)
+	}
+	b := make([]byte, n)
+	_, _ = io.ReadFull(r, b)
+	return b, nil
+}
+
+func encodeBinary(buf *bytes.Buffer, v reflect.Value, path string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.WriteByte(1)
+		switch {
+		case v.Type() == bigIntType:
+			n := v.Interface().(*big.Int)
+			if n.Sign() < 0 {
+				return pathError(path, "negative integer")
+			}
+			return writeBytes16(buf, n.Bytes(), path)
+		case isLeaf(v.Type()):
+			buf.Write(leafBytes(v))
+			return nil
+		default:
+			return encodeBinary(buf, v.Elem(), path)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			err := encodeBinary(buf, v.Field(i), joinPath(path, fieldName(v.Type().Field(i).Name)))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if isBytes(v.Type()) {
+			return writeBytes16(buf, v.Bytes(), path)
+		}
+		if v.Kind() == reflect.Slice {
+			var count [4]byte
+			binary.BigEndian.PutUint32(count[:], uint32(v.Len()))
+			buf.Write(count[:])
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeBinary(buf, v.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Int64, reflect.Int:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(v.Int()))
+		buf.Write(b[:])
+	case reflect.Uint8:
+		buf.WriteByte(uint8(v.Uint()))
+	case reflect.String:
+		return writeBytes16(buf, []byte(v.String()), path)
+	default:
+		return pathError(path, "unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func decodeBinary(r *bytes.Reader, v reflect.Value, path string) error {
+	if v.Kind() == reflect.Ptr {
+		present, err := readN(r, 1, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case present[0] == 0:
+			v.Set(reflect.Zero(v.Type()))
+		case present[0] != 1:
+			return pathError(path, "invalid presence byte %d", present[0])
+		case v.Type() == bigIntType:
+			b, err := readBytes16(r, path)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(new(big.Int).SetBytes(b)))
+		case isLeaf(v.Type()):
+			size := 2 * fr.Bytes
+			if v.Type() == signatureType {
+				size = 3 * fr.Bytes
+			}
+			b, err := readN(r, size, path)
+			if err != nil {
+				return err
+			}
+			if err = setLeaf(v, b); err != nil {
+				return pathError(path, "%s", err)
+			}
+		default:
+			v.Set(reflect.New(v.Type().Elem()))
+			return decodeBinary(r, v.Elem(), path)
+		}
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			err := decodeBinary(r, v.Field(i), joinPath(path, fieldName(v.Type().Field(i).Name)))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if isBytes(v.Type()) {
+			b, err := readBytes16(r, path)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		if v.Kind() == reflect.Slice {
+			count, err := readN(r, 4, path)
+			if err != nil {
+				return err
+			}
+			// every item takes at least a byte, which bounds the allocation by the input size
+			n := binary.BigEndian.Uint32(count)
+			if int64(n) > int64(r.Len()) {
+				return pathError(path, "%s", io.ErrUnexpectedEOF)
+			}
+			v.Set(reflect.MakeSlice(v.Type(), int(n), int(n)))
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := decodeBinary(r, v.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Int64, reflect.Int:
+		b, err := readN(r, 8, path)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(binary.BigEndian.Uint64(b)))
+	case reflect.Uint8:
+		b, err := readN(r, 1, path)
+		if err != nil {
+			return err
+		}
+		v.SetUint(uint64(b[0]))
+	case reflect.String:
+		b, err := readBytes16(r, path)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	default:
+		return pathError(path, "unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func binaryHeader(kind byte) []byte {
+	return append(append([]byte{}, blockMagic...),
+		BlockFormatVersion,
+		kind,
+		NbAccountsPerTx,
+		NbAccountAssetsPerAccount,
+		AssetMerkleLevels,
+		AccountMerkleLevels,
+		NftMerkleLevels,
+	)
+}
+
+func marshalBinary(kind byte, v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(binaryHeader(kind))
+	if err := encodeBinary(&buf, reflect.ValueOf(v).Elem(), ""); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalBinary(data []byte, kind byte, v interface{}) error {
+	header := binaryHeader(kind)
+	if len(data) < len(header) {
+		return io.ErrUnexpectedEOF
+	}
+	if !bytes.Equal(data[:len(blockMagic)], blockMagic) {
+		return errors.New("invalid magic")
+	}
+	if data[len(blockMagic)] != BlockFormatVersion {
+		return fmt.Errorf("unsupported version: %d", data[len(blockMagic)])
+	}
+	if data[len(blockMagic)+1] != kind {
+		return fmt.Errorf("unexpected kind: %d, expected %d", data[len(blockMagic)+1], kind)
+	}
+	if !bytes.Equal(data[len(blockMagic)+2:len(header)], header[len(blockMagic)+2:]) {
+		return fmt.Errorf("levels %v do not match the circuit levels %v", data[len(blockMagic)+2:len(header)], header[len(blockMagic)+2:])
+	}
+	r := bytes.NewReader(data[len(header):])
+	if err := decodeBinary(r, reflect.ValueOf(v).Elem(), ""); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes", r.Len())
+	}
+	return nil
+}
+
+/*
+	MarshalJSON: the versioned json encoding of a valid block
+*/
+func (b *Block) MarshalJSON() ([]byte, error) {
+	if err := validateBlock(b); err != nil {
+		return nil, codecError("Block.MarshalJSON", err)
+	}
+	data, err := marshalJSON(b)
+	if err != nil {
+		return nil, codecError("Block.MarshalJSON", err)
+	}
+	return data, nil
+}
+
+/*
+	UnmarshalJSON: decode and validate a block, b is left unchanged on error
+*/
+func (b *Block) UnmarshalJSON(data []byte) error {
+	var oBlock Block
+	if err := unmarshalJSON(data, &oBlock); err != nil {
+		return codecError("Block.UnmarshalJSON", err)
+	}
+	if err := validateBlock(&oBlock); err != nil {
+		return codecError("Block.UnmarshalJSON", err)
+	}
+	*b = oBlock
+	return nil
+}
+
+/*
+	MarshalBinary: the versioned binary encoding of a valid block
+*/
+func (b *Block) MarshalBinary() ([]byte, error) {
+	if err := validateBlock(b); err != nil {
+		return nil, codecError("Block.MarshalBinary", err)
+	}
+	data, err := marshalBinary(blockKind, b)
+	if err != nil {
+		return nil, codecError("Block.MarshalBinary", err)
+	}
+	return data, nil
+}
+
+/*
+	UnmarshalBinary: decode and validate a block, b is left unchanged on error
+*/
+func (b *Block) UnmarshalBinary(data []byte) error {
+	var oBlock Block
+	if err := unmarshalBinary(data, blockKind, &oBlock); err != nil {
+		return codecError("Block.UnmarshalBinary", err)
+	}
+	if err := validateBlock(&oBlock); err != nil {
+		return codecError("Block.UnmarshalBinary", err)
+	}
+	*b = oBlock
+	return nil
+}
+
+func (oTx *Tx) MarshalJSON() ([]byte, error) {
+	if err := validateTx(oTx, ""); err != nil {
+		return nil, codecError("Tx.MarshalJSON", err)
+	}
+	data, err := marshalJSON(oTx)
+	if err != nil {
+		return nil, codecError("Tx.MarshalJSON", err)
+	}
+	return data, nil
+}
+
+func (oTx *Tx) UnmarshalJSON(data []byte) error {
+	var tx Tx
+	if err := unmarshalJSON(data, &tx); err != nil {
+		return codecError("Tx.UnmarshalJSON", err)
+	}
+	if err := validateTx(&tx, ""); err != nil {
+		return codecError("Tx.UnmarshalJSON", err)
+	}
+	*oTx = tx
+	return nil
+}
+
+func (oTx *Tx) MarshalBinary() ([]byte, error) {
+	if err := validateTx(oTx, ""); err != nil {
+		return nil, codecError("Tx.MarshalBinary", err)
+	}
+	data, err := marshalBinary(txKind, oTx)
+	if err != nil {
+		return nil, codecError("Tx.MarshalBinary", err)
+	}
+	return data, nil
+}
+
+func (oTx *Tx) UnmarshalBinary(data []byte) error {
+	var tx Tx
+	if err := unmarshalBinary(data, txKind, &tx); err != nil {
+		return codecError("Tx.UnmarshalBinary", err)
+	}
+	if err := validateTx(&tx, ""); err != nil {
+		return codecError("Tx.UnmarshalBinary", err)
+	}
+	*oTx = tx
+	return nil
+}
+
+func (gas *Gas) MarshalJSON() ([]byte, error) {
+	if err := validateGas(gas, ""); err != nil {
+		return nil, codecError("Gas.MarshalJSON", err)
+	}
+	data, err := marshalJSON(gas)
+	if err != nil {
+		return nil, codecError("Gas.MarshalJSON", err)
+	}
+	return data, nil
+}
+
+func (gas *Gas) UnmarshalJSON(data []byte) error {
+	var g Gas
+	if err := unmarshalJSON(data, &g); err != nil {
+		return codecError("Gas.UnmarshalJSON", err)
+	}
+	if err := validateGas(&g, ""); err != nil {
+		return codecError("Gas.UnmarshalJSON", err)
+	}
+	*gas = g
+	return nil
+}
+
+func (gas *Gas) MarshalBinary() ([]byte, error) {
+	if err := validateGas(gas, ""); err != nil {
+		return nil, codecError("Gas.MarshalBinary", err)
+	}
+	data, err := marshalBinary(gasKind, gas)
+	if err != nil {
+		return nil, codecError("Gas.MarshalBinary", err)
+	}
+	return data, nil
+}
+
+func (gas *Gas) UnmarshalBinary(data []byte) error {
+	var g Gas
+	if err := unmarshalBinary(data, gasKind, &g); err != nil {
+		return codecError("Gas.UnmarshalBinary", err)
+	}
+	if err := validateGas(&g, ""); err != nil {
+		return codecError("Gas.UnmarshalBinary", err)
+	}
+	*gas = g
+	return nil
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/difftest"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+/*
+	buildBlocks: a block of 2 txs for every tx type, the second one is an empty tx
+*/
+func buildBlocks(t *testing.T) map[int]*circuit.Block {
+	r := rand.New(rand.NewSource(1))
+	blocks := make(map[int]*circuit.Block)
+	for _, txType := range difftest.TxTypes {
+		world, err := difftest.NewWorld()
+		require.NoError(t, err)
+		txInfo, err := difftest.Generate(r, txType, false).TxInfo(world)
+		require.NoError(t, err)
+		blockBuilder, err := witness.NewBlockBuilder(witness.NewBuilder(world), 2, difftest.GasAccount, difftest.GasAssetIds)
+		require.NoError(t, err)
+		blocks[txType], err = blockBuilder.BuildBlock(1, difftest.BlockCreatedAt, []txtypes.TxInfo{txInfo})
+		require.NoError(t, err, "tx type %d", txType)
+	}
+	return blocks
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	for txType, oBlock := range buildBlocks(t) {
+		jsonBytes, err := json.Marshal(oBlock)
+		require.NoError(t, err)
+		var fromJSON circuit.Block
+		require.NoError(t, json.Unmarshal(jsonBytes, &fromJSON))
+		assert.Equal(t, oBlock, &fromJSON, "tx type %d", txType)
+
+		binaryBytes, err := oBlock.MarshalBinary()
+		require.NoError(t, err)
+		var fromBinary circuit.Block
+		require.NoError(t, fromBinary.UnmarshalBinary(binaryBytes))
+		assert.Equal(t, oBlock, &fromBinary, "tx type %d", txType)
+		assert.Less(t, len(binaryBytes), len(jsonBytes))
+
+		commitment, err := circuit.ComputeBlockCommitment(&fromBinary)
+		require.NoError(t, err)
+		assert.Equal(t, oBlock.BlockCommitment, commitment)
+	}
+}
+
+func TestTxAndGasRoundTrip(t *testing.T) {
+	oBlock := buildBlocks(t)[txtypes.TxTypeAtomicMatch]
+	for _, oTx := range oBlock.Txs {
+		jsonBytes, err := json.Marshal(oTx)
+		require.NoError(t, err)
+		var fromJSON circuit.Tx
+		require.NoError(t, json.Unmarshal(jsonBytes, &fromJSON))
+		assert.Equal(t, oTx, &fromJSON)
+
+		binaryBytes, err := oTx.MarshalBinary()
+		require.NoError(t, err)
+		var fromBinary circuit.Tx
+		require.NoError(t, fromBinary.UnmarshalBinary(binaryBytes))
+		assert.Equal(t, oTx, &fromBinary)
+	}
+
+	jsonBytes, err := json.Marshal(oBlock.Gas)
+	require.NoError(t, err)
+	var fromJSON circuit.Gas
+	require.NoError(t, json.Unmarshal(jsonBytes, &fromJSON))
+	assert.Equal(t, oBlock.Gas, &fromJSON)
+
+	binaryBytes, err := oBlock.Gas.MarshalBinary()
+	require.NoError(t, err)
+	var fromBinary circuit.Gas
+	require.NoError(t, fromBinary.UnmarshalBinary(binaryBytes))
+	assert.Equal(t, oBlock.Gas, &fromBinary)
+
+	// a tx is not a block
+	txBytes, err := oBlock.Txs[0].MarshalBinary()
+	require.NoError(t, err)
+	var b circuit.Block
+	assert.Error(t, b.UnmarshalBinary(txBytes))
+}
+
+/*
+	goldenGas: a gas of one asset whose binary encoding is goldenGasHex
+*/
+func goldenGas() *circuit.Gas {
+	pk := new(eddsa.PublicKey)
+	pk.A.X.SetZero()
+	pk.A.Y.SetOne()
+	gas := &circuit.Gas{
+		GasAssetCount: 1,
+		AccountInfoBefore: &types.GasAccount{
+			AccountIndex:    1,
+			AccountNameHash: bytes.Repeat([]byte{0xaa}, 32),
+			AccountPk:       pk,
+			Nonce:           2,
+			CollectionNonce: 3,
+			AssetRoot:       bytes.Repeat([]byte{0xbb}, 32),
+			AssetsInfo: []*types.AccountAsset{
+				{AssetId: 0, Balance: big.NewInt(100), OfferCanceledOrFinalized: big.NewInt(0)},
+			},
+		},
+		MerkleProofsAccountAssetsBefore: make([][circuit.AssetMerkleLevels][]byte, 1),
+	}
+	for i := range gas.MerkleProofsAccountBefore {
+		gas.MerkleProofsAccountBefore[i] = bytes.Repeat([]byte{0x01}, 32)
+	}
+	for i := range gas.MerkleProofsAccountAssetsBefore[0] {
+		gas.MerkleProofsAccountAssetsBefore[0][i] = bytes.Repeat([]byte{0x02}, 32)
+	}
+	return gas
+}
+
+var goldenGasHex = strings.Join([]string{
+	// magic "ZKBW", version 1, kind gas, 4 accounts per tx, 2 assets per account, 16, 32 and 40 merkle levels
+	"5a4b4257", "01", "03", "04", "02", "10", "20", "28",
+	// gas_asset_count
+	"0000000000000001",
+	// account_info_before: present, account_index, account_name_hash
+	"01", "0000000000000001", "0020" + strings.Repeat("aa", 32),
+	// account_pk: present, A.X = 0, A.Y = 1
+	"01", strings.Repeat("00", 32), strings.Repeat("00", 31) + "01",
+	// nonce, collection_nonce, asset_root
+	"0000000000000002", "0000000000000003", "0020" + strings.Repeat("bb", 32),
+	// assets_info: 1 item, present, asset_id 0, balance 100, offer_canceled_or_finalized 0
+	"00000001", "01", "0000000000000000", "01" + "0001" + "64", "01" + "0000",
+	// merkle_proofs_account_before: 32 nodes
+	strings.Repeat("0020"+strings.Repeat("01", 32), circuit.AccountMerkleLevels),
+	// merkle_proofs_account_assets_before: 1 item of 16 nodes
+	"00000001", strings.Repeat("0020"+strings.Repeat("02", 32), circuit.AssetMerkleLevels),
+}, "")
+
+func TestGasBinaryGolden(t *testing.T) {
+	golden, err := hex.DecodeString(goldenGasHex)
+	require.NoError(t, err)
+
+	binaryBytes, err := goldenGas().MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, goldenGasHex, hex.EncodeToString(binaryBytes))
+
+	var fromBinary circuit.Gas
+	require.NoError(t, fromBinary.UnmarshalBinary(golden))
+	assert.Equal(t, goldenGas(), &fromBinary)
+}
+
+/*
+	editJSON: decode the json of a block into generic values, edit them and encode them back
+*/
+func editJSON(t *testing.T, oBlock *circuit.Block, edit func(block map[string]interface{})) []byte {
+	jsonBytes, err := json.Marshal(oBlock)
+	require.NoError(t, err)
+	var block map[string]interface{}
+	require.NoError(t, json.Unmarshal(jsonBytes, &block))
+	edit(block)
+	jsonBytes, err = json.Marshal(block)
+	require.NoError(t, err)
+	return jsonBytes
+}
+
+func firstTx(block map[string]interface{}) map[string]interface{} {
+	return block["txs"].([]interface{})[0].(map[string]interface{})
+}
+
+func TestBlockValidation(t *testing.T) {
+	oBlock := buildBlocks(t)[txtypes.TxTypeTransfer]
+
+	testCases := []struct {
+		name string
+		edit func(block map[string]interface{})
+		err  string
+	}{
+		{"version", func(block map[string]interface{}) { block["version"] = 2 }, "unsupported version: 2"},
+		{"unknown field", func(block map[string]interface{}) { block["extra"] = 1 }, "extra: unknown field"},
+		{"missing field", func(block map[string]interface{}) { delete(block, "created_at") }, "created_at: missing"},
+		{"missing gas", func(block map[string]interface{}) { delete(block, "gas") }, "gas: missing"},
+		{"no txs", func(block map[string]interface{}) { block["txs"] = []interface{}{} }, "txs: no tx"},
+		{"short account proof", func(block map[string]interface{}) {
+			proofs := firstTx(block)["merkle_proofs_account_before"].([]interface{})
+			proofs[1] = proofs[1].([]interface{})[1:]
+		}, "txs[0].merkle_proofs_account_before[1]: expected 32 items, got 31"},
+		{"long nft proof", func(block map[string]interface{}) {
+			tx := firstTx(block)
+			proof := tx["merkle_proofs_nft_before"].([]interface{})
+			tx["merkle_proofs_nft_before"] = append(proof, proof[0])
+		}, "txs[0].merkle_proofs_nft_before: expected 40 items, got 41"},
+		{"missing account", func(block map[string]interface{}) {
+			firstTx(block)["accounts_info_before"].([]interface{})[2] = nil
+		}, "txs[0].accounts_info_before[2]: missing"},
+		{"short root", func(block map[string]interface{}) { firstTx(block)["state_root_after"] = "00" }, "txs[0].state_root_after: expected 32 bytes, got 1"},
+		{"missing tx info", func(block map[string]interface{}) { delete(firstTx(block), "transfer_tx_info") }, "missing tx info of tx type 4"},
+		{"negative amount", func(block map[string]interface{}) {
+			asset := firstTx(block)["accounts_info_before"].([]interface{})[0].(map[string]interface{})["assets_info"].([]interface{})[0]
+			asset.(map[string]interface{})["balance"] = "-1"
+		}, `invalid unsigned integer "-1"`},
+		{"gas assets", func(block map[string]interface{}) {
+			block["gas"].(map[string]interface{})["gas_asset_count"] = 3
+		}, "gas.account_info_before.assets_info: expected 3 items, got 2"},
+	}
+	for _, testCase := range testCases {
+		var decoded circuit.Block
+		err := json.Unmarshal(editJSON(t, oBlock, testCase.edit), &decoded)
+		if assert.Error(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.err, testCase.name)
+		}
+	}
+
+	binaryBytes, err := oBlock.MarshalBinary()
+	require.NoError(t, err)
+	var decoded circuit.Block
+	levels := append([]byte{}, binaryBytes...)
+	levels[9]++
+	assert.Error(t, decoded.UnmarshalBinary(levels))
+	assert.Error(t, decoded.UnmarshalBinary(binaryBytes[:len(binaryBytes)-1]))
+	assert.Error(t, decoded.UnmarshalBinary(append(binaryBytes, 0)))
+
+	// invalid blocks are not encoded
+	invalid := *oBlock
+	invalid.Gas = nil
+	_, err = json.Marshal(&invalid)
+	assert.Error(t, err)
+	_, err = invalid.MarshalBinary()
+	assert.Error(t, err)
+	assert.Error(t, circuit.ValidateBlock(&invalid))
+	assert.NoError(t, circuit.ValidateBlock(oBlock))
+}
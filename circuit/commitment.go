@@ -0,0 +1,158 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+// size in bytes of a pubdata chunk in the commitment
+const PubDataChunkSize = 32
+
+func IsOnChainOp(txType uint8) bool {
+	switch txType {
+	case types.TxTypeRegisterZns, types.TxTypeDeposit, types.TxTypeDepositNft, types.TxTypeWithdraw,
+		types.TxTypeWithdrawNft, types.TxTypeFullExit, types.TxTypeFullExitNft:
+		return true
+	}
+	return false
+}
+
+/*
+	ComputeTxPubData: native counterpart of the pubdata selected by VerifyTransaction
+*/
+func ComputeTxPubData(oTx *Tx) (pubData [types.PubDataSizePerTx]*big.Int, err error) {
+	switch oTx.TxType {
+	case types.TxTypeEmptyTx:
+		return types.EmptyTxPubData(), nil
+	case types.TxTypeRegisterZns:
+		pubData, err = types.RegisterZnsPubData(oTx.RegisterZnsTxInfo)
+	case types.TxTypeDeposit:
+		pubData, err = types.DepositPubData(oTx.DepositTxInfo)
+	case types.TxTypeDepositNft:
+		pubData, err = types.DepositNftPubData(oTx.DepositNftTxInfo)
+	case types.TxTypeTransfer:
+		pubData, err = types.TransferPubData(oTx.TransferTxInfo)
+	case types.TxTypeWithdraw:
+		pubData, err = types.WithdrawPubData(oTx.WithdrawTxInfo)
+	case types.TxTypeCreateCollection:
+		pubData, err = types.CreateCollectionPubData(oTx.CreateCollectionTxInfo)
+	case types.TxTypeMintNft:
+		pubData, err = types.MintNftPubData(oTx.MintNftTxInfo)
+	case types.TxTypeTransferNft:
+		pubData, err = types.TransferNftPubData(oTx.TransferNftTxInfo)
+	case types.TxTypeAtomicMatch:
+		pubData, err = types.AtomicMatchPubData(oTx.AtomicMatchTxInfo)
+	case types.TxTypeCancelOffer:
+		pubData, err = types.CancelOfferPubData(oTx.CancelOfferTxInfo)
+	case types.TxTypeWithdrawNft:
+		pubData, err = types.WithdrawNftPubData(oTx.WithdrawNftTxInfo)
+	case types.TxTypeFullExit:
+		pubData, err = types.FullExitPubData(oTx.FullExitTxInfo)
+	case types.TxTypeFullExitNft:
+		pubData, err = types.FullExitNftPubData(oTx.FullExitNftTxInfo)
+	default:
+		err = errors.New(fmt.Sprintf("invalid tx type: %d", oTx.TxType))
+	}
+	if err != nil {
+		errInfo := fmt.Sprintf("[ComputeTxPubData] unable to compute pubdata: %s", err.Error())
+		log.Println(errInfo)
+		return pubData, errors.New(errInfo)
+	}
+	return pubData, nil
+}
+
+/*
+	ComputeBlockPubData: pubdata of every tx of the block, PubDataSizePerTx chunks of
+	PubDataChunkSize bytes per tx, and the number of on chain operations
+*/
+func ComputeBlockPubData(oBlock *Block) (pubData []byte, onChainOpsCount int64, err error) {
+	pubData = make([]byte, 0, len(oBlock.Txs)*types.PubDataSizePerTx*PubDataChunkSize)
+	for i, oTx := range oBlock.Txs {
+		txPubData, err := ComputeTxPubData(oTx)
+		if err != nil {
+			errInfo := fmt.Sprintf("[ComputeBlockPubData] tx %d: %s", i, err.Error())
+			log.Println(errInfo)
+			return nil, 0, errors.New(errInfo)
+		}
+		pubData = append(pubData, types.PubDataToBytes(txPubData)...)
+		if IsOnChainOp(oTx.TxType) {
+			onChainOpsCount++
+		}
+	}
+	return pubData, onChainOpsCount, nil
+}
+
+/*
+	ComputeCommitment: keccak256 over [BlockNumber, CreatedAt, OldStateRoot, NewStateRoot,
+	pubData chunks, onChainOpsCount], every item as a 32 bytes big endian word.
+	The result is reduced into the field, which is the value of the public BlockCommitment.
+*/
+func ComputeCommitment(
+	blockNumber int64,
+	createdAt int64,
+	oldStateRoot []byte,
+	newStateRoot []byte,
+	pubData []byte,
+	onChainOpsCount int64,
+) (commitment []byte, err error) {
+	if len(pubData)%(types.PubDataSizePerTx*PubDataChunkSize) != 0 {
+		errInfo := fmt.Sprintf("[ComputeCommitment] invalid pubdata size: %d", len(pubData))
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	var buf bytes.Buffer
+	buf.Write(big.NewInt(blockNumber).FillBytes(make([]byte, PubDataChunkSize)))
+	buf.Write(big.NewInt(createdAt).FillBytes(make([]byte, PubDataChunkSize)))
+	buf.Write(types.BytesToPubData(oldStateRoot).FillBytes(make([]byte, PubDataChunkSize)))
+	buf.Write(types.BytesToPubData(newStateRoot).FillBytes(make([]byte, PubDataChunkSize)))
+	for i := 0; i < len(pubData); i += PubDataChunkSize {
+		buf.Write(types.BytesToPubData(pubData[i : i+PubDataChunkSize]).FillBytes(make([]byte, PubDataChunkSize)))
+	}
+	buf.Write(big.NewInt(onChainOpsCount).FillBytes(make([]byte, PubDataChunkSize)))
+	hashVal := crypto.Keccak256(buf.Bytes())
+	res := new(big.Int).SetBytes(hashVal)
+	return res.Mod(res, fr.Modulus()).FillBytes(make([]byte, PubDataChunkSize)), nil
+}
+
+/*
+	ComputeBlockCommitment: the BlockCommitment VerifyBlock checks for oBlock
+*/
+func ComputeBlockCommitment(oBlock *Block) (commitment []byte, err error) {
+	pubData, onChainOpsCount, err := ComputeBlockPubData(oBlock)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeCommitment(
+		oBlock.BlockNumber,
+		oBlock.CreatedAt,
+		oBlock.OldStateRoot,
+		oBlock.NewStateRoot,
+		pubData,
+		onChainOpsCount,
+	)
+}
@@ -21,9 +21,8 @@ import (
 func TestAbiEncodeTransfer(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -71,13 +70,13 @@ func TestAbiEncodeTransfer(t *testing.T) {
 
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -102,9 +101,8 @@ func DefaultCircuit() (circuit KeccakCircuit) {
 func TestAbiEncodeWithdraw(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -150,13 +148,13 @@ func TestAbiEncodeWithdraw(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -166,9 +164,8 @@ func TestAbiEncodeWithdraw(t *testing.T) {
 func TestAbiEncodeCreateCollection(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -201,13 +198,13 @@ func TestAbiEncodeCreateCollection(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -218,9 +215,8 @@ func TestAbiEncodeCreateCollection(t *testing.T) {
 func TestAbiEncodeWithdrawNft(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -259,13 +255,13 @@ func TestAbiEncodeWithdrawNft(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -276,9 +272,8 @@ func TestAbiEncodeWithdrawNft(t *testing.T) {
 func TestAbiEncodeTransferNft(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -323,13 +318,13 @@ func TestAbiEncodeTransferNft(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -340,9 +335,8 @@ func TestAbiEncodeTransferNft(t *testing.T) {
 func TestAbiEncodeMintNft(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -388,13 +382,13 @@ func TestAbiEncodeMintNft(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -405,9 +399,8 @@ func TestAbiEncodeMintNft(t *testing.T) {
 func TestAbiEncodeCancelOffer(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -441,13 +434,13 @@ func TestAbiEncodeCancelOffer(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
@@ -458,9 +451,8 @@ func TestAbiEncodeCancelOffer(t *testing.T) {
 func TestAbiEncodeAtomicMatch(t *testing.T) {
 	// Compile circuit
 	var circuit KeccakCircuit = DefaultCircuit()
-	_scs, _ := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
-	fmt.Println("Schema:", _scs.GetSchema())
-	fmt.Println("SCs:", len(_scs.GetConstraints()))
+	_scs, _ := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	fmt.Println("SCs:", _scs.GetNbConstraints())
 
 	srs, _ := test.NewKZGSRS(_scs)
 	pk, vk, _ := plonk.Setup(_scs, srs)
@@ -522,13 +514,13 @@ func TestAbiEncodeAtomicMatch(t *testing.T) {
 	}
 	w.Name = 1
 
-	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254.ScalarField())
 	assert.NoError(t, err)
 
 	proof, err := plonk.Prove(_scs, pk, witnessFull)
 	assert.NoError(t, err)
 
-	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254.ScalarField(), frontend.PublicOnly())
 	assert.NoError(t, err)
 
 	err = plonk.Verify(proof, vk, witnessPublic)
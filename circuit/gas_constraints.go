@@ -48,7 +48,7 @@ func VerifyGas(
 	gas GasConstraints,
 	needGas Variable,
 	gasAssetDeltas []Variable,
-	hFunc MiMC,
+	hFunc types.Hasher,
 	accountRoot Variable) (newAccountRoot Variable, err error) {
 	newAccountRoot = accountRoot
 	newAccountAssetsRoot := gas.AccountInfoBefore.AssetRoot
@@ -0,0 +1,677 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+	Package profile reports the constraints of the block circuit by component. Every component is
+	compiled alone, in a circuit holding the inputs of a tx and of the gas account, and counted above
+	the constraints of that circuit left empty.
+*/
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+type (
+	Variable = frontend.Variable
+	API      = frontend.API
+	MiMC     = types.MiMC
+)
+
+const (
+	Groth16 = "groth16"
+	Plonk   = "plonk"
+)
+
+// calls of the selectors in VerifyTransaction, one per tx type they apply to,
+// TestSelectorCalls checks them against the source of VerifyTransaction
+const (
+	selectPubDataCalls     = 13
+	selectAssetDeltasCalls = 10
+	selectGasDeltasCalls   = 8
+	selectNftDeltasCalls   = 6
+)
+
+/*
+	Component: the constraints of a single call of a component. Calls are per tx for the
+	components of a tx, per block for the components of the block.
+*/
+type Component struct {
+	Name string `json:"name"`
+	// the component this one is part of, it is only counted once in the totals
+	Parent      string `json:"parent,omitempty"`
+	Calls       int    `json:"calls"`
+	Constraints int    `json:"constraints"`
+	Total       int    `json:"total"`
+}
+
+/*
+	Report: the components of a tx and of the block. Both lists end with "other", the constraints
+	of the glue between the components, and "total", the constraints of the compiled tx or block.
+*/
+type Report struct {
+	Backend     string      `json:"backend"`
+	TxsCount    int         `json:"txs_count"`
+	GasAssetIds []int64     `json:"gas_asset_ids"`
+	Tx          []Component `json:"tx"`
+	Block       []Component `json:"block"`
+}
+
+type defineFunc func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error
+
+/*
+	componentConstraints: the inputs of every component, define runs one of them
+*/
+type componentConstraints struct {
+	Tx        circuit.TxConstraints
+	Gas       circuit.GasConstraints
+	CreatedAt Variable
+	Data      []Variable
+	define    defineFunc
+}
+
+func (c *componentConstraints) Define(api API) error {
+	hFunc := types.NewMiMC(api)
+	// the flags of VerifyTransaction are checks of the tx type
+	flag := api.IsZero(api.Sub(c.Tx.TxType, types.TxTypeTransfer))
+	if c.define == nil {
+		return nil
+	}
+	return c.define(api, c, flag, hFunc)
+}
+
+type component struct {
+	name   string
+	parent string
+	calls  int
+	define defineFunc
+}
+
+type txType struct {
+	name    string
+	hash    func(api API, tx *circuit.TxConstraints, hFunc MiMC)
+	verify  func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error
+	pubData func(api API, tx *circuit.TxConstraints)
+}
+
+var txTypes = []txType{
+	{
+		name: "RegisterZns",
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			emptyAssetRoot, err := tx.Hasher.EmptyAssetRoot(circuit.AssetMerkleLevels)
+			if err != nil {
+				return err
+			}
+			types.VerifyRegisterZNSTx(api, flag, tx.RegisterZnsTxInfo, tx.AccountsInfoBefore, emptyAssetRoot)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromRegisterZNS(api, tx.RegisterZnsTxInfo)
+		},
+	},
+	{
+		name: "Deposit",
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyDepositTx(api, flag, tx.DepositTxInfo, tx.AccountsInfoBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromDeposit(api, tx.DepositTxInfo)
+		},
+	},
+	{
+		name: "DepositNft",
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyDepositNftTx(api, flag, tx.DepositNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromDepositNft(api, tx.DepositNftTxInfo)
+		},
+	},
+	{
+		name: "Transfer",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromTransferTx(api, tx.TransferTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyTransferTx(api, flag, &tx.TransferTxInfo, tx.AccountsInfoBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromTransfer(api, tx.TransferTxInfo)
+		},
+	},
+	{
+		name: "CreateCollection",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromCreateCollectionTx(api, tx.CreateCollectionTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyCreateCollectionTx(api, flag, &tx.CreateCollectionTxInfo, tx.AccountsInfoBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromCreateCollection(api, tx.CreateCollectionTxInfo)
+		},
+	},
+	{
+		name: "Withdraw",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromWithdrawTx(api, tx.WithdrawTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyWithdrawTx(api, flag, &tx.WithdrawTxInfo, tx.AccountsInfoBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromWithdraw(api, tx.WithdrawTxInfo)
+		},
+	},
+	{
+		name: "MintNft",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromMintNftTx(api, tx.MintNftTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyMintNftTx(api, flag, &tx.MintNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromMintNft(api, tx.MintNftTxInfo)
+		},
+	},
+	{
+		name: "TransferNft",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromTransferNftTx(api, tx.TransferNftTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyTransferNftTx(api, flag, &tx.TransferNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromTransferNft(api, tx.TransferNftTxInfo)
+		},
+	},
+	{
+		name: "AtomicMatch",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromAtomicMatchTx(api, tx.AtomicMatchTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			_, err := types.VerifyAtomicMatchTx(api, flag, &tx.AtomicMatchTxInfo, tx.AccountsInfoBefore, tx.NftBefore, tx.ExpiredAt, hFunc)
+			return err
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromAtomicMatch(api, tx.AtomicMatchTxInfo)
+		},
+	},
+	{
+		name: "CancelOffer",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromCancelOfferTx(api, tx.CancelOfferTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyCancelOfferTx(api, flag, &tx.CancelOfferTxInfo, tx.AccountsInfoBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromCancelOffer(api, tx.CancelOfferTxInfo)
+		},
+	},
+	{
+		name: "WithdrawNft",
+		hash: func(api API, tx *circuit.TxConstraints, hFunc MiMC) {
+			types.ComputeHashFromWithdrawNftTx(api, tx.WithdrawNftTxInfo, tx.Nonce, tx.ExpiredAt, hFunc)
+		},
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyWithdrawNftTx(api, flag, &tx.WithdrawNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromWithdrawNft(api, tx.WithdrawNftTxInfo)
+		},
+	},
+	{
+		name: "FullExit",
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyFullExitTx(api, flag, tx.FullExitTxInfo, tx.AccountsInfoBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromFullExit(api, tx.FullExitTxInfo)
+		},
+	},
+	{
+		name: "FullExitNft",
+		verify: func(api API, flag Variable, tx *circuit.TxConstraints, hFunc MiMC) error {
+			types.VerifyFullExitNftTx(api, flag, tx.FullExitNftTxInfo, tx.AccountsInfoBefore, tx.NftBefore)
+			return nil
+		},
+		pubData: func(api API, tx *circuit.TxConstraints) {
+			types.CollectPubDataFromFullExitNft(api, tx.FullExitNftTxInfo)
+		},
+	},
+}
+
+/*
+	txComponents: the signature hash of every layer 2 tx type, the signature, the verifier and the
+	pubdata of every tx type, the selectors of the tx type branch and the merkle proofs
+*/
+func txComponents() []component {
+	var components []component
+	for i := range txTypes {
+		txType := txTypes[i]
+		if txType.hash == nil {
+			continue
+		}
+		components = append(components, component{
+			name:  "hash/" + txType.name,
+			calls: 1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				txType.hash(api, &c.Tx, hFunc)
+				return nil
+			},
+		})
+	}
+	components = append(components, component{
+		name:  "eddsa",
+		calls: 1,
+		define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+			return types.VerifyEddsaSig(flag, api, hFunc, c.Tx.StateRootBefore, c.Tx.AccountsInfoBefore[0].AccountPk, c.Tx.Signature)
+		},
+	})
+	for i := range txTypes {
+		txType := txTypes[i]
+		components = append(components, component{
+			name:  "verify/" + txType.name,
+			calls: 1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				return txType.verify(api, flag, &c.Tx, hFunc)
+			},
+		}, component{
+			name:   "pubdata/" + txType.name,
+			parent: "verify/" + txType.name,
+			calls:  1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				txType.pubData(api, &c.Tx)
+				return nil
+			},
+		})
+	}
+	return append(components,
+		component{
+			name:  "select/pubdata",
+			calls: selectPubDataCalls,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				var pubData, pubDataCheck [types.PubDataSizePerTx]Variable
+				for i := 0; i < types.PubDataSizePerTx; i++ {
+					pubData[i] = c.Tx.MerkleProofsAccountBefore[0][i]
+					pubDataCheck[i] = c.Tx.MerkleProofsAccountBefore[1][i]
+				}
+				circuit.SelectPubData(api, flag, pubDataCheck, pubData)
+				return nil
+			},
+		},
+		component{
+			name:  "select/asset deltas",
+			calls: selectAssetDeltasCalls,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				circuit.SelectAssetDeltas(api, flag, assetDeltas(c, 1), assetDeltas(c, 0))
+				return nil
+			},
+		},
+		component{
+			name:  "select/gas deltas",
+			calls: selectGasDeltasCalls,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				var deltas, deltasCheck [circuit.NbGasAssetsPerTx]circuit.GasDeltaConstraints
+				for i := 0; i < circuit.NbGasAssetsPerTx; i++ {
+					deltas[i] = circuit.GasDeltaConstraints{
+						AssetId:      c.Tx.AccountsInfoBefore[i].AssetsInfo[0].AssetId,
+						BalanceDelta: c.Tx.AccountsInfoBefore[i].AssetsInfo[0].Balance,
+					}
+					deltasCheck[i] = circuit.GasDeltaConstraints{
+						AssetId:      c.Tx.AccountsInfoBefore[i].AssetsInfo[1].AssetId,
+						BalanceDelta: c.Tx.AccountsInfoBefore[i].AssetsInfo[1].Balance,
+					}
+				}
+				circuit.SelectGasDeltas(api, flag, deltasCheck, deltas)
+				return nil
+			},
+		},
+		component{
+			name:  "select/nft deltas",
+			calls: selectNftDeltasCalls,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				nft := c.Tx.NftBefore
+				delta := circuit.NftDeltaConstraints{
+					CreatorAccountIndex: nft.CreatorAccountIndex,
+					OwnerAccountIndex:   nft.OwnerAccountIndex,
+					NftContentHash:      nft.NftContentHash,
+					NftL1Address:        nft.NftL1Address,
+					NftL1TokenId:        nft.NftL1TokenId,
+					CreatorTreasuryRate: nft.CreatorTreasuryRate,
+					CollectionId:        nft.CollectionId,
+				}
+				account := c.Tx.AccountsInfoBefore[0]
+				deltaCheck := circuit.NftDeltaConstraints{
+					CreatorAccountIndex: account.AccountIndex,
+					OwnerAccountIndex:   account.AccountIndex,
+					NftContentHash:      account.AccountNameHash,
+					NftL1Address:        account.AccountNameHash,
+					NftL1TokenId:        account.Nonce,
+					CreatorTreasuryRate: account.Nonce,
+					CollectionId:        account.CollectionNonce,
+				}
+				circuit.SelectNftDeltas(api, flag, deltaCheck, delta)
+				return nil
+			},
+		},
+		component{
+			name:  "update accounts",
+			calls: 1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				circuit.UpdateAccounts(api, c.Tx.AccountsInfoBefore, assetDeltas(c, 1))
+				return nil
+			},
+		},
+		component{
+			name:  "merkle/asset",
+			calls: circuit.NbAccountsPerTx * circuit.NbAccountAssetsPerAccount,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				helper := circuit.AssetIdToMerkleHelper(api, c.Tx.AccountsInfoBefore[0].AssetsInfo[0].AssetId)
+				merkleProof(api, flag, &hFunc, c.Tx.AccountsInfoBefore[0].AssetRoot, c.Tx.MerkleProofsAccountAssetsBefore[0][0][:], helper)
+				return nil
+			},
+		},
+		component{
+			name:  "merkle/account",
+			calls: circuit.NbAccountsPerTx,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				helper := circuit.AccountIndexToMerkleHelper(api, c.Tx.AccountsInfoBefore[0].AccountIndex)
+				merkleProof(api, flag, &hFunc, c.Tx.AccountRootBefore, c.Tx.MerkleProofsAccountBefore[0][:], helper)
+				return nil
+			},
+		},
+		component{
+			name:  "merkle/nft",
+			calls: 1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				helper := circuit.NftIndexToMerkleHelper(api, c.Tx.NftBefore.NftIndex)
+				merkleProof(api, flag, &hFunc, c.Tx.NftRootBefore, c.Tx.MerkleProofsNftBefore[:], helper)
+				return nil
+			},
+		},
+	)
+}
+
+/*
+	blockComponents: the gas account update and the block commitment
+*/
+func blockComponents(gasAssetCount int) []component {
+	return []component{
+		{
+			name:  "gas",
+			calls: 1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				_, err := circuit.VerifyGas(api, c.Gas, flag, c.Data[:gasAssetCount], &hFunc, c.Tx.AccountRootBefore)
+				return err
+			},
+		},
+		{
+			// the keccak of the pubdata is computed by a hint, only its result is constrained
+			name:  "commitment",
+			calls: 1,
+			define: func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+				commitments, err := api.Compiler().NewHint(types.Keccak256, 1, c.Data...)
+				if err != nil {
+					return err
+				}
+				api.AssertIsEqual(commitments[0], c.Tx.StateRootAfter)
+				return nil
+			},
+		},
+	}
+}
+
+/*
+	verifyTransaction: a whole tx, as VerifyBlock verifies every tx after the first one
+*/
+func verifyTransaction(gasAssetIds []int64) defineFunc {
+	return func(api API, c *componentConstraints, flag Variable, hFunc MiMC) error {
+		roots := [types.NbRoots]Variable{c.Tx.AccountRootBefore, c.Tx.NftRootBefore}
+		_, _, _, _, err := circuit.VerifyTransaction(api, c.Tx, hFunc, c.CreatedAt, gasAssetIds, roots)
+		return err
+	}
+}
+
+func assetDeltas(c *componentConstraints, offset int) (deltas [circuit.NbAccountsPerTx][circuit.NbAccountAssetsPerAccount]circuit.AccountAssetDeltaConstraints) {
+	for i := 0; i < circuit.NbAccountsPerTx; i++ {
+		for j := 0; j < circuit.NbAccountAssetsPerAccount; j++ {
+			asset := c.Tx.AccountsInfoBefore[(i+offset)%circuit.NbAccountsPerTx].AssetsInfo[j]
+			deltas[i][j] = circuit.AccountAssetDeltaConstraints{
+				BalanceDelta:             asset.Balance,
+				OfferCanceledOrFinalized: asset.OfferCanceledOrFinalized,
+			}
+		}
+	}
+	return deltas
+}
+
+/*
+	merkleProof: check a leaf against the root then update the root, as VerifyTransaction does
+*/
+func merkleProof(api API, flag Variable, hFunc *MiMC, root Variable, proofSet []Variable, helper []Variable) {
+	types.VerifyMerkleProof(api, flag, hFunc, root, api.Add(root, 1), proofSet, helper)
+	hFunc.Reset()
+	types.UpdateMerkleProof(api, hFunc, api.Add(root, 2), proofSet, helper)
+}
+
+/*
+	Profile: the constraints of every component of the block circuit of txsCount txs, compiled
+	for backend, Groth16 or Plonk
+*/
+func Profile(backend string, txsCount int, gasAssetIds []int64) (*Report, error) {
+	var newBuilder frontend.NewBuilder
+	switch backend {
+	case Groth16:
+		newBuilder = r1cs.NewBuilder
+	case Plonk:
+		newBuilder = scs.NewBuilder
+	default:
+		errInfo := fmt.Sprintf("[Profile] unknown backend: %s", backend)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if txsCount <= 0 || len(gasAssetIds) == 0 {
+		errInfo := fmt.Sprintf("[Profile] invalid block of %d txs and gas assets %v", txsCount, gasAssetIds)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	p := &profiler{
+		newBuilder:  newBuilder,
+		gasAssetIds: gasAssetIds,
+		dataSize:    types.PubDataSizePerTx*txsCount + 5,
+	}
+	var err error
+	if p.baseline, err = p.count(nil); err != nil {
+		return nil, err
+	}
+
+	txTotal, err := p.count(verifyTransaction(gasAssetIds))
+	if err != nil {
+		return nil, err
+	}
+	txComponents, err := p.components(txComponents())
+	if err != nil {
+		return nil, err
+	}
+
+	blockConstraints := circuit.BlockConstraints{
+		TxsCount:    txsCount,
+		Txs:         make([]circuit.TxConstraints, txsCount),
+		GasAssetIds: gasAssetIds,
+		Gas:         circuit.GetZeroGasConstraints(gasAssetIds),
+	}
+	for i := 0; i < txsCount; i++ {
+		blockConstraints.Txs[i] = circuit.GetZeroTxConstraint()
+	}
+	blockTotal, err := p.compile(&blockConstraints)
+	if err != nil {
+		return nil, err
+	}
+	blockComponents, err := p.components(blockComponents(len(gasAssetIds)))
+	if err != nil {
+		return nil, err
+	}
+	txs := Component{Name: "txs", Calls: txsCount, Constraints: txTotal, Total: txsCount * txTotal}
+	txReport, err := withTotal(txComponents, txTotal)
+	if err != nil {
+		return nil, err
+	}
+	blockReport, err := withTotal(append([]Component{txs}, blockComponents...), blockTotal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{
+		Backend:     backend,
+		TxsCount:    txsCount,
+		GasAssetIds: append([]int64{}, gasAssetIds...),
+		Tx:          txReport,
+		Block:       blockReport,
+	}, nil
+}
+
+type profiler struct {
+	newBuilder  frontend.NewBuilder
+	gasAssetIds []int64
+	dataSize    int
+	// constraints of componentConstraints without component
+	baseline int
+}
+
+func (p *profiler) compile(c frontend.Circuit) (int, error) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), p.newBuilder, c, frontend.IgnoreUnconstrainedInputs())
+	if err != nil {
+		errInfo := fmt.Sprintf("[Profile] unable to compile circuit: %s", err.Error())
+		log.Println(errInfo)
+		return 0, errors.New(errInfo)
+	}
+	return ccs.GetNbConstraints(), nil
+}
+
+/*
+	count: the constraints of define, above the baseline
+*/
+func (p *profiler) count(define defineFunc) (int, error) {
+	nbConstraints, err := p.compile(&componentConstraints{
+		Tx:     circuit.GetZeroTxConstraint(),
+		Gas:    circuit.GetZeroGasConstraints(p.gasAssetIds),
+		Data:   make([]Variable, p.dataSize),
+		define: define,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return nbConstraints - p.baseline, nil
+}
+
+func (p *profiler) components(components []component) ([]Component, error) {
+	counted := make([]Component, len(components))
+	for i, c := range components {
+		nbConstraints, err := p.count(c.define)
+		if err != nil {
+			return nil, err
+		}
+		counted[i] = Component{
+			Name:        c.name,
+			Parent:      c.parent,
+			Calls:       c.calls,
+			Constraints: nbConstraints,
+			Total:       c.calls * nbConstraints,
+		}
+	}
+	return counted, nil
+}
+
+/*
+	withTotal: append what the components leave of total, then total. The components can only
+	exceed total if their calls no longer match the circuit.
+*/
+func withTotal(components []Component, total int) ([]Component, error) {
+	other := total
+	for _, c := range components {
+		if c.Parent == "" {
+			other -= c.Total
+		}
+	}
+	if other < 0 {
+		errInfo := fmt.Sprintf("[Profile] components exceed the total of %d constraints by %d, calls are out of date", total, -other)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return append(components,
+		Component{Name: "other", Calls: 1, Constraints: other, Total: other},
+		Component{Name: "total", Calls: 1, Constraints: total, Total: total},
+	), nil
+}
+
+/*
+	WriteTable: the components and their share of the constraints of the block
+*/
+func (r *Report) WriteTable(w io.Writer) error {
+	blockTotal := r.Block[len(r.Block)-1].Total
+	if _, err := fmt.Fprintf(w, "%s block of %d txs, gas assets %v\n", r.Backend, r.TxsCount, r.GasAssetIds); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, section := range []struct {
+		name       string
+		components []Component
+		// calls of the section per block
+		calls int
+	}{
+		{"tx", r.Tx, r.TxsCount},
+		{"block", r.Block, 1},
+	} {
+		fmt.Fprintf(tw, "\n%s\tcalls\tconstraints\ttotal\tshare of block\n", section.name)
+		for _, c := range section.components {
+			name := c.Name
+			if c.Parent != "" {
+				name = "  " + name
+			}
+			share := float64(c.Total*section.calls) / float64(blockTotal) * 100
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f%%\n", name, c.Calls, c.Constraints, c.Total, share)
+		}
+	}
+	return tw.Flush()
+}
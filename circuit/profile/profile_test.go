@@ -0,0 +1,114 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+)
+
+func TestProfile(t *testing.T) {
+	report, err := Profile(Groth16, 1, []int64{0, 1})
+	require.NoError(t, err)
+
+	components := make(map[string]Component)
+	for _, c := range report.Tx {
+		components[c.Name] = c
+	}
+	for _, txType := range txTypes {
+		verify, pubData := components["verify/"+txType.name], components["pubdata/"+txType.name]
+		assert.True(t, pubData.Constraints > 0, txType.name)
+		assert.True(t, verify.Constraints >= pubData.Constraints, txType.name)
+		_, ok := components["hash/"+txType.name]
+		assert.Equal(t, txType.hash != nil, ok, txType.name)
+	}
+	for _, name := range []string{"eddsa", "merkle/asset", "merkle/account", "merkle/nft"} {
+		assert.True(t, components[name].Constraints > 0, name)
+	}
+	assert.Equal(t, circuit.NbAccountsPerTx*components["merkle/account"].Constraints, components["merkle/account"].Total)
+
+	for _, section := range [][]Component{report.Tx, report.Block} {
+		other := section[len(section)-2]
+		assert.Equal(t, "other", other.Name)
+		assert.True(t, other.Total >= 0)
+	}
+
+	txTotal := report.Tx[len(report.Tx)-1]
+	assert.Equal(t, "total", txTotal.Name)
+	assert.Equal(t, "txs", report.Block[0].Name)
+	assert.Equal(t, txTotal.Total, report.Block[0].Total)
+	blockTotal := report.Block[len(report.Block)-1]
+	assert.Equal(t, "total", blockTotal.Name)
+	sum := 0
+	for _, c := range report.Block[:len(report.Block)-1] {
+		sum += c.Total
+	}
+	assert.Equal(t, blockTotal.Total, sum)
+
+	reportBytes, err := json.Marshal(report)
+	require.NoError(t, err)
+	var readReport Report
+	require.NoError(t, json.Unmarshal(reportBytes, &readReport))
+	assert.Equal(t, report, &readReport)
+
+	var table bytes.Buffer
+	require.NoError(t, report.WriteTable(&table))
+	assert.True(t, strings.HasPrefix(table.String(), "groth16 block of 1 txs"))
+	assert.Contains(t, table.String(), "  pubdata/AtomicMatch")
+
+	_, err = Profile("stark", 1, []int64{0})
+	assert.Error(t, err)
+	_, err = Profile(Plonk, 0, []int64{0})
+	assert.Error(t, err)
+	_, err = Profile(Plonk, 1, nil)
+	assert.Error(t, err)
+}
+
+func TestSelectorCalls(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../tx_constraints.go", nil, 0)
+	require.NoError(t, err)
+	calls := make(map[string]int)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "VerifyTransaction" {
+			continue
+		}
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				if ident, ok := call.Fun.(*ast.Ident); ok {
+					calls[ident.Name]++
+				}
+			}
+			return true
+		})
+	}
+	assert.Equal(t, selectPubDataCalls, calls["SelectPubData"])
+	assert.Equal(t, selectAssetDeltasCalls, calls["SelectAssetDeltas"])
+	assert.Equal(t, selectGasDeltasCalls, calls["SelectGasDeltas"])
+	assert.Equal(t, selectNftDeltasCalls, calls["SelectNftDeltas"])
+}
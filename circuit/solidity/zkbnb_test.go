@@ -18,18 +18,36 @@
 package solidity
 
 import (
+	"flag"
 	"fmt"
-	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/require"
 
 	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/prover"
 )
 
+var (
+	outputDir = flag.String("solidity.dir", "", "directory the keys and verifier contracts are written to")
+	ptauPath  = flag.String("solidity.ptau", "", "powers of tau transcript the plonk srs is taken from")
+)
+
+/*
+	exportDir: the directory given by -solidity.dir, or a temporary directory
+*/
+func exportDir(t *testing.T) string {
+	if *outputDir != "" {
+		return *outputDir
+	}
+	return t.TempDir()
+}
+
 func TestCompileCircuit(t *testing.T) {
 	differentBlockSizes := []int{1, 10}
 	gasAssetIds := []int64{0, 1}
@@ -44,76 +62,91 @@ func TestCompileCircuit(t *testing.T) {
 		blockConstraints.GasAssetIds = gasAssetIds
 		blockConstraints.GasAccountIndex = gasAccountIndex
 		blockConstraints.Gas = circuit.GetZeroGasConstraints(gasAssetIds)
-		oR1cs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
+		oR1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
 		if err != nil {
 			panic(err)
 		}
 		fmt.Printf("Number of constraints: %d\n", oR1cs.GetNbConstraints())
+		if testing.Short() {
+			continue
+		}
+		oScs, err := prover.CompilePlonk(&blockConstraints)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Number of plonk constraints: %d\n", oScs.GetNbConstraints())
 	}
 }
 
 func TestExportSol(t *testing.T) {
 	differentBlockSizes := []int{1, 10}
-	exportSol(differentBlockSizes)
+	exportSol(t, differentBlockSizes)
 }
 
 func TestExportSolSmall(t *testing.T) {
 	differentBlockSizes := []int{1}
-	exportSol(differentBlockSizes)
+	exportSol(t, differentBlockSizes)
 }
 
-func exportSol(differentBlockSizes []int) {
+/*
+	exportSol: write the keys and the verifier contract of every block size to the export directory,
+	prover.LoadCircuit reads them back for the registry of the prover
+*/
+func exportSol(t *testing.T, differentBlockSizes []int) {
+	if testing.Short() {
+		t.Skip("skipping the groth16 setup of the block circuits in short mode")
+	}
+	dir := exportDir(t)
 	gasAssetIds := []int64{0, 1}
 	gasAccountIndex := int64(1)
 	for i := 0; i < len(differentBlockSizes); i++ {
-		var blockConstraints circuit.BlockConstraints
-		blockConstraints.TxsCount = differentBlockSizes[i]
-		blockConstraints.Txs = make([]circuit.TxConstraints, blockConstraints.TxsCount)
-		for i := 0; i < blockConstraints.TxsCount; i++ {
-			blockConstraints.Txs[i] = circuit.GetZeroTxConstraint()
-		}
-		blockConstraints.GasAssetIds = gasAssetIds
-		blockConstraints.GasAccountIndex = gasAccountIndex
-		blockConstraints.Gas = circuit.GetZeroGasConstraints(gasAssetIds)
-		oR1cs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &blockConstraints, frontend.IgnoreUnconstrainedInputs())
-		if err != nil {
-			panic(err)
-		}
-
-		pk, vk, err := groth16.Setup(oR1cs)
-		if err != nil {
-			panic(err)
-		}
-		{
-			f, err := os.Create("zkbnb" + fmt.Sprint(differentBlockSizes[i]) + ".vk")
-			if err != nil {
-				panic(err)
-			}
-			_, err = vk.WriteRawTo(f)
-			if err != nil {
-				panic(err)
-			}
-		}
-		{
-			f, err := os.Create("zkbnb" + fmt.Sprint(differentBlockSizes[i]) + ".pk")
-			if err != nil {
-				panic(err)
-			}
-			_, err = pk.WriteRawTo(f)
-			if err != nil {
-				panic(err)
-			}
-		}
+		c, err := prover.NewCircuit(differentBlockSizes[i], gasAccountIndex, gasAssetIds)
+		require.NoError(t, err)
+		require.NoError(t, prover.WriteCircuit(dir, c))
+		require.NoError(t, prover.WriteSolidity(filepath.Join(dir, "ZkBNBVerifier"+fmt.Sprint(differentBlockSizes[i])+".sol"), c.VK))
+	}
+}
 
-		{
-			f, err := os.Create("ZkBNBVerifier" + fmt.Sprint(differentBlockSizes[i]) + ".sol")
-			if err != nil {
-				panic(err)
-			}
-			err = vk.ExportSolidity(f)
-			if err != nil {
-				panic(err)
-			}
+/*
+	TestExportSolPlonk: write a single srs, sized for the largest block and taken from the transcript
+	of a powers of tau ceremony, and the constraint system, the keys and the verifier contract of every block size
+*/
+func TestExportSolPlonk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the plonk setup of the block circuits in short mode")
+	}
+	if *ptauPath == "" {
+		t.Skip("the plonk srs is taken from a powers of tau transcript, set -solidity.ptau")
+	}
+	dir := exportDir(t)
+	differentBlockSizes := []int{1, 10}
+	gasAssetIds := []int64{0, 1}
+	gasAccountIndex := int64(1)
+	var (
+		scss    []constraint.ConstraintSystem
+		srsSize uint64
+	)
+	for i := 0; i < len(differentBlockSizes); i++ {
+		blockConstraints, err := prover.NewBlockConstraints(differentBlockSizes[i], gasAccountIndex, gasAssetIds)
+		require.NoError(t, err)
+		oScs, err := prover.CompilePlonk(blockConstraints)
+		require.NoError(t, err)
+		if size := prover.SRSSize(oScs); size > srsSize {
+			srsSize = size
 		}
+		scss = append(scss, oScs)
+	}
+	srs, err := prover.ReadPtauSRS(*ptauPath, srsSize)
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteSRS(filepath.Join(dir, "zkbnb.srs_plonk"), srs))
+	for i := 0; i < len(differentBlockSizes); i++ {
+		// the srs of the largest block sets up every block size
+		pk, vk, err := prover.SetupPlonk(scss[i], srs)
+		require.NoError(t, err)
+		name := filepath.Join(dir, "zkbnb"+fmt.Sprint(differentBlockSizes[i]))
+		require.NoError(t, prover.WriteConstraintSystem(name+".scs", scss[i]))
+		require.NoError(t, prover.WritePlonkProvingKey(name+".pk_plonk", pk))
+		require.NoError(t, prover.WritePlonkVerifyingKey(name+".vk_plonk", vk))
+		require.NoError(t, prover.WritePlonkSolidity(filepath.Join(dir, "ZkBNBVerifierPlonk"+fmt.Sprint(differentBlockSizes[i])+".sol"), vk))
 	}
 }
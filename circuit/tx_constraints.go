@@ -21,9 +21,8 @@ import (
 	"errors"
 	"log"
 
-	"github.com/consensys/gnark/std/hash/mimc"
-
 	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
 )
 
 type TxConstraints struct {
@@ -67,16 +66,15 @@ type TxConstraints struct {
 	MerkleProofsAccountBefore [NbAccountsPerTx][AccountMerkleLevels]Variable
 	// state root after
 	StateRootAfter Variable
+	// hash function of the trees, mimc by default
+	Hasher hasher.Type `gnark:"-"`
 }
 
 func (circuit TxConstraints) Define(api API) error {
 	// mimc
-	hFunc, err := mimc.NewMiMC(api)
-	if err != nil {
-		return err
-	}
+	hFunc := types.NewMiMC(api)
 
-	_, _, _, _, err = VerifyTransaction(api, circuit, hFunc, 1633400952228, []int64{0}, [types.NbRoots]Variable{Variable(0), Variable(0)})
+	_, _, _, _, err := VerifyTransaction(api, circuit, hFunc, 1633400952228, []int64{0}, [types.NbRoots]Variable{Variable(0), Variable(0)})
 	if err != nil {
 		return err
 	}
@@ -175,7 +173,12 @@ func VerifyTransaction(
 	for i := 0; i < types.PubDataSizePerTx; i++ {
 		pubData[i] = 0
 	}
-	pubDataCheck := types.VerifyRegisterZNSTx(api, isRegisterZnsTx, tx.RegisterZnsTxInfo, tx.AccountsInfoBefore)
+	// a registered account starts with the empty asset tree of the tree hash function
+	emptyAssetRoot, err := tx.Hasher.EmptyAssetRoot(AssetMerkleLevels)
+	if err != nil {
+		return nil, pubData, roots, gasDeltas, err
+	}
+	pubDataCheck := types.VerifyRegisterZNSTx(api, isRegisterZnsTx, tx.RegisterZnsTxInfo, tx.AccountsInfoBefore, emptyAssetRoot)
 	pubData = SelectPubData(api, isRegisterZnsTx, pubDataCheck, pubData)
 	pubDataCheck = types.VerifyDepositTx(api, isDepositTx, tx.DepositTxInfo, tx.AccountsInfoBefore)
 	pubData = SelectPubData(api, isDepositTx, pubDataCheck, pubData)
@@ -284,7 +287,7 @@ func VerifyTransaction(
 	assetDeltasCheck = GetAssetDeltasFromFullExit(api, tx.FullExitTxInfo)
 	assetDeltas = SelectAssetDeltas(api, isFullExitTx, assetDeltasCheck, assetDeltas)
 	// full exit nft
-	nftDeltaCheck = GetNftDeltaFromFullExitNft()
+	nftDeltaCheck = GetNftDeltaFromFullExitNft(api, tx.FullExitNftTxInfo, tx.NftBefore)
 	nftDelta = SelectNftDeltas(api, isFullExitNftTx, nftDeltaCheck, nftDelta)
 	// update accounts
 	AccountsInfoAfter := UpdateAccounts(api, tx.AccountsInfoBefore, assetDeltas)
@@ -297,13 +300,18 @@ func VerifyTransaction(
 	// update nft
 	NftAfter := UpdateNft(tx.NftBefore, nftDelta)
 
+	// the trees and the state root are hashed with the tree hash function, signatures stay on mimc
+	tFunc, err := tx.Hasher.NewCircuit(api)
+	if err != nil {
+		return nil, pubData, roots, gasDeltas, err
+	}
 	// check old state root
-	hFunc.Reset()
-	hFunc.Write(
+	tFunc.Reset()
+	tFunc.Write(
 		tx.AccountRootBefore,
 		tx.NftRootBefore,
 	)
-	oldStateRoot := hFunc.Sum()
+	oldStateRoot := tFunc.Sum()
 	notEmptyTx := api.IsZero(isEmptyTx)
 	types.IsVariableEqual(api, notEmptyTx, oldStateRoot, tx.StateRootBefore)
 
@@ -316,39 +324,39 @@ func VerifyTransaction(
 		for j := 0; j < NbAccountAssetsPerAccount; j++ {
 			api.AssertIsLessOrEqual(tx.AccountsInfoBefore[i].AssetsInfo[j].AssetId, LastAccountAssetId)
 			assetMerkleHelper := AssetIdToMerkleHelper(api, tx.AccountsInfoBefore[i].AssetsInfo[j].AssetId)
-			hFunc.Reset()
-			hFunc.Write(
+			tFunc.Reset()
+			tFunc.Write(
 				tx.AccountsInfoBefore[i].AssetsInfo[j].Balance,
 				tx.AccountsInfoBefore[i].AssetsInfo[j].OfferCanceledOrFinalized,
 			)
-			assetNodeHash := hFunc.Sum()
+			assetNodeHash := tFunc.Sum()
 			// verify account asset merkle proof
-			hFunc.Reset()
+			tFunc.Reset()
 			types.VerifyMerkleProof(
 				api,
 				notEmptyTx,
-				hFunc,
+				tFunc,
 				NewAccountAssetsRoot,
 				assetNodeHash,
 				tx.MerkleProofsAccountAssetsBefore[i][j][:],
 				assetMerkleHelper,
 			)
-			hFunc.Reset()
-			hFunc.Write(
+			tFunc.Reset()
+			tFunc.Write(
 				AccountsInfoAfter[i].AssetsInfo[j].Balance,
 				AccountsInfoAfter[i].AssetsInfo[j].OfferCanceledOrFinalized,
 			)
-			assetNodeHash = hFunc.Sum()
-			hFunc.Reset()
+			assetNodeHash = tFunc.Sum()
+			tFunc.Reset()
 			// update merkle proof
 			NewAccountAssetsRoot = types.UpdateMerkleProof(
-				api, hFunc, assetNodeHash, tx.MerkleProofsAccountAssetsBefore[i][j][:], assetMerkleHelper)
+				api, tFunc, assetNodeHash, tx.MerkleProofsAccountAssetsBefore[i][j][:], assetMerkleHelper)
 		}
 		// verify account node hash
 		api.AssertIsLessOrEqual(tx.AccountsInfoBefore[i].AccountIndex, LastAccountIndex)
 		accountIndexMerkleHelper := AccountIndexToMerkleHelper(api, tx.AccountsInfoBefore[i].AccountIndex)
-		hFunc.Reset()
-		hFunc.Write(
+		tFunc.Reset()
+		tFunc.Write(
 			tx.AccountsInfoBefore[i].AccountNameHash,
 			tx.AccountsInfoBefore[i].AccountPk.A.X,
 			tx.AccountsInfoBefore[i].AccountPk.A.Y,
@@ -356,20 +364,20 @@ func VerifyTransaction(
 			tx.AccountsInfoBefore[i].CollectionNonce,
 			tx.AccountsInfoBefore[i].AssetRoot,
 		)
-		accountNodeHash := hFunc.Sum()
+		accountNodeHash := tFunc.Sum()
 		// verify account merkle proof
-		hFunc.Reset()
+		tFunc.Reset()
 		types.VerifyMerkleProof(
 			api,
 			notEmptyTx,
-			hFunc,
+			tFunc,
 			newAccountRoot,
 			accountNodeHash,
 			tx.MerkleProofsAccountBefore[i][:],
 			accountIndexMerkleHelper,
 		)
-		hFunc.Reset()
-		hFunc.Write(
+		tFunc.Reset()
+		tFunc.Write(
 			AccountsInfoAfter[i].AccountNameHash,
 			AccountsInfoAfter[i].AccountPk.A.X,
 			AccountsInfoAfter[i].AccountPk.A.Y,
@@ -377,10 +385,10 @@ func VerifyTransaction(
 			AccountsInfoAfter[i].CollectionNonce,
 			NewAccountAssetsRoot,
 		)
-		accountNodeHash = hFunc.Sum()
-		hFunc.Reset()
+		accountNodeHash = tFunc.Sum()
+		tFunc.Reset()
 		// update merkle proof
-		newAccountRoot = types.UpdateMerkleProof(api, hFunc, accountNodeHash, tx.MerkleProofsAccountBefore[i][:], accountIndexMerkleHelper)
+		newAccountRoot = types.UpdateMerkleProof(api, tFunc, accountNodeHash, tx.MerkleProofsAccountBefore[i][:], accountIndexMerkleHelper)
 		oldRoots[0] = api.Select(isEmptyTx, oldRoots[0], newAccountRoot)
 	}
 
@@ -388,8 +396,8 @@ func VerifyTransaction(
 	newNftRoot := tx.NftRootBefore
 	api.AssertIsLessOrEqual(tx.NftBefore.NftIndex, LastNftIndex)
 	nftIndexMerkleHelper := NftIndexToMerkleHelper(api, tx.NftBefore.NftIndex)
-	hFunc.Reset()
-	hFunc.Write(
+	tFunc.Reset()
+	tFunc.Write(
 		tx.NftBefore.CreatorAccountIndex,
 		tx.NftBefore.OwnerAccountIndex,
 		tx.NftBefore.NftContentHash,
@@ -398,20 +406,20 @@ func VerifyTransaction(
 		tx.NftBefore.CreatorTreasuryRate,
 		tx.NftBefore.CollectionId,
 	)
-	nftNodeHash := hFunc.Sum()
+	nftNodeHash := tFunc.Sum()
 	// verify account merkle proof
-	hFunc.Reset()
+	tFunc.Reset()
 	types.VerifyMerkleProof(
 		api,
 		notEmptyTx,
-		hFunc,
+		tFunc,
 		newNftRoot,
 		nftNodeHash,
 		tx.MerkleProofsNftBefore[:],
 		nftIndexMerkleHelper,
 	)
-	hFunc.Reset()
-	hFunc.Write(
+	tFunc.Reset()
+	tFunc.Write(
 		NftAfter.CreatorAccountIndex,
 		NftAfter.OwnerAccountIndex,
 		NftAfter.NftContentHash,
@@ -420,19 +428,19 @@ func VerifyTransaction(
 		NftAfter.CreatorTreasuryRate,
 		NftAfter.CollectionId,
 	)
-	nftNodeHash = hFunc.Sum()
-	hFunc.Reset()
+	nftNodeHash = tFunc.Sum()
+	tFunc.Reset()
 	// update merkle proof
-	newNftRoot = types.UpdateMerkleProof(api, hFunc, nftNodeHash, tx.MerkleProofsNftBefore[:], nftIndexMerkleHelper)
+	newNftRoot = types.UpdateMerkleProof(api, tFunc, nftNodeHash, tx.MerkleProofsNftBefore[:], nftIndexMerkleHelper)
 	oldRoots[1] = api.Select(isEmptyTx, oldRoots[1], newNftRoot)
 
 	// check state root
-	hFunc.Reset()
-	hFunc.Write(
+	tFunc.Reset()
+	tFunc.Write(
 		newAccountRoot,
 		newNftRoot,
 	)
-	newStateRoot := hFunc.Sum()
+	newStateRoot := tFunc.Sum()
 	types.IsVariableEqual(api, notEmptyTx, newStateRoot, tx.StateRootAfter)
 
 	roots[0] = oldRoots[0]
@@ -0,0 +1,136 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+/*
+	TestAtomicMatchRates: the creator and the treasury may not take more than the amount
+	together, the seller would be paid a negative share
+*/
+func TestAtomicMatchRates(t *testing.T) {
+	s, err := state.NewState()
+	require.NoError(t, err)
+	builder := witness.NewBuilder(s)
+	rules := executor.NewExecutor(s, time.Now().UnixMilli(), testutil.GasAccount, []int64{0})
+	for i := int64(testutil.GasAccount); i <= testutil.Carol; i++ {
+		txInfo, err := testutil.RegisterTx(i)
+		require.NoError(t, err)
+		_, err = builder.ConstructTx(rules, txInfo)
+		require.NoError(t, err)
+	}
+	for i := int64(testutil.Alice); i <= testutil.Bob; i++ {
+		_, err = builder.ConstructTx(rules, testutil.DepositTx(i, 0, 100000000))
+		require.NoError(t, err)
+	}
+	// bob owns an nft of carol whose creator rate is 60%
+	_, err = builder.ConstructTx(rules, &txtypes.DepositNftTxInfo{
+		TxType:              txtypes.TxTypeDepositNft,
+		AccountNameHash:     testutil.NameHash(testutil.Bob),
+		CreatorAccountIndex: testutil.Carol,
+		CreatorTreasuryRate: 6000,
+		NftL1Address:        "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		NftL1TokenId:        big.NewInt(7),
+		NftContentHash:      testutil.NameHash(100),
+		NftIndex:            0,
+		AccountIndex:        testutil.Bob,
+	})
+	require.NoError(t, err)
+
+	expiredAt := time.Now().Add(time.Hour).UnixMilli()
+	offer := func(accountIndex int64, offerType int64, treasuryRate int64) string {
+		sk, err := testutil.PrivateKey(accountIndex)
+		require.NoError(t, err)
+		offer, err := txtypes.ConstructOfferTxInfo(sk, testutil.Segment(&txtypes.OfferSegmentFormat{
+			Type:         offerType,
+			OfferId:      1,
+			AccountIndex: accountIndex,
+			NftIndex:     0,
+			AssetId:      0,
+			AssetAmount:  "1000000",
+			ListedAt:     time.Now().UnixMilli(),
+			ExpiredAt:    expiredAt,
+			TreasuryRate: treasuryRate,
+		}))
+		require.NoError(t, err)
+		return testutil.Segment(offer)
+	}
+	// alice buys the nft from bob
+	solve := func(treasuryRate int64) error {
+		sk, err := testutil.PrivateKey(testutil.Alice)
+		require.NoError(t, err)
+		matchInfo, err := txtypes.ConstructAtomicMatchTxInfo(sk, testutil.Segment(&txtypes.AtomicMatchSegmentFormat{
+			AccountIndex:      testutil.Alice,
+			BuyOffer:          offer(testutil.Alice, txtypes.BuyOfferType, treasuryRate),
+			SellOffer:         offer(testutil.Bob, txtypes.SellOfferType, treasuryRate),
+			GasAccountIndex:   testutil.GasAccount,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			Nonce:             0,
+			ExpiredAt:         expiredAt,
+		}))
+		require.NoError(t, err)
+		matchState, err := s.Copy()
+		require.NoError(t, err)
+		// built unchecked, the circuit is what is tested
+		oTx, err := witness.NewBuilder(matchState).ConstructUncheckedTx(matchInfo)
+		require.NoError(t, err)
+		txWitness, err := circuit.SetTxWitness(oTx)
+		require.NoError(t, err)
+		return test.IsSolved(&circuit.TxConstraints{}, &txWitness, ecc.BN254.ScalarField())
+	}
+	assert.NoError(t, solve(4000))
+	// 60% + 40.01%, the seller would lose 100 of the 1000000 paid by alice
+	assert.Error(t, solve(4001))
+}
+
+/*
+	TestRegisterZnsWithHasher: a registered account starts with the empty asset tree of the tree
+	hash function, so a registration on a state of each hash function is solved by its circuit
+*/
+func TestRegisterZnsWithHasher(t *testing.T) {
+	for _, h := range []hasher.Type{hasher.MiMC, hasher.Poseidon} {
+		s, err := state.NewStateWithHasher(h)
+		require.NoError(t, err)
+		txInfo, err := testutil.RegisterTx(testutil.GasAccount)
+		require.NoError(t, err)
+		rules := executor.NewExecutor(s, time.Now().UnixMilli(), testutil.GasAccount, []int64{0})
+		oTx, err := witness.NewBuilder(s).ConstructTx(rules, txInfo)
+		require.NoError(t, err)
+		txWitness, err := circuit.SetTxWitness(oTx)
+		require.NoError(t, err)
+		assert.NoError(t, test.IsSolved(&circuit.TxConstraints{Hasher: h}, &txWitness, ecc.BN254.ScalarField()), h.String())
+	}
+}
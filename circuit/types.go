@@ -20,7 +20,6 @@ package circuit
 import (
 	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/std/hash/mimc"
 	eddsaConstraints "github.com/consensys/gnark/std/signature/eddsa"
 
 	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
@@ -32,7 +31,7 @@ type (
 	Signature            = eddsa.Signature
 	SignatureConstraints = eddsaConstraints.Signature
 	API                  = frontend.API
-	MiMC                 = mimc.MiMC
+	MiMC                 = types.MiMC
 
 	RegisterZnsTx      = types.RegisterZnsTx
 	DepositTx          = types.DepositTx
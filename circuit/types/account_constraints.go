@@ -35,14 +35,18 @@ type AccountConstraints struct {
 	AssetsInfo [NbAccountAssetsPerAccount]AccountAssetConstraints
 }
 
-func CheckEmptyAccountNode(api API, flag Variable, account AccountConstraints) {
+/*
+	CheckEmptyAccountNode: the account is empty, emptyAssetRoot is the root of an empty asset tree
+	under the hash function of the trees
+*/
+func CheckEmptyAccountNode(api API, flag Variable, account AccountConstraints, emptyAssetRoot Variable) {
 	IsVariableEqual(api, flag, account.AccountNameHash, ZeroInt)
 	IsVariableEqual(api, flag, account.AccountPk.A.X, ZeroInt)
 	IsVariableEqual(api, flag, account.AccountPk.A.Y, ZeroInt)
 	IsVariableEqual(api, flag, account.Nonce, ZeroInt)
 	IsVariableEqual(api, flag, account.CollectionNonce, ZeroInt)
 	// empty asset
-	IsVariableEqual(api, flag, account.AssetRoot, EmptyAssetRoot)
+	IsVariableEqual(api, flag, account.AssetRoot, emptyAssetRoot)
 }
 
 func CheckNonEmptyAccountNode(api API, flag Variable, account AccountConstraints) {
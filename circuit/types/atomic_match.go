@@ -125,6 +125,8 @@ func VerifyAtomicMatchTx(
 	IsVariableLessOrEqual(api, flag, blockCreatedAt, tx.SellOffer.ExpiredAt)
 	IsVariableEqual(api, flag, nftBefore.NftIndex, tx.SellOffer.NftIndex)
 	IsVariableEqual(api, flag, tx.BuyOffer.TreasuryRate, tx.SellOffer.TreasuryRate)
+	// the seller gets what the creator and the treasury leave
+	IsVariableLessOrEqual(api, flag, api.Add(nftBefore.CreatorTreasuryRate, tx.BuyOffer.TreasuryRate), RateBase)
 	// verify signature
 	hFunc.Reset()
 	buyOfferHash := ComputeHashFromOfferTx(api, tx.BuyOffer, hFunc)
@@ -153,6 +155,9 @@ func VerifyAtomicMatchTx(
 	IsVariableEqual(api, flag, tx.SellOffer.AccountIndex, accountsBefore[sellAccount].AccountIndex)
 	// creator
 	IsVariableEqual(api, flag, nftBefore.CreatorAccountIndex, accountsBefore[creatorAccount].AccountIndex)
+	// the seller owns the nft, which is not empty
+	IsVariableEqual(api, flag, tx.SellOffer.AccountIndex, nftBefore.OwnerAccountIndex)
+	IsVariableEqual(api, flag, api.IsZero(nftBefore.NftContentHash), 0)
 	// verify buy offer id
 	buyOfferIdBits := api.ToBinary(tx.BuyOffer.OfferId, 24)
 	buyAssetId := api.FromBinary(buyOfferIdBits[7:]...)
@@ -174,6 +179,9 @@ func VerifyAtomicMatchTx(
 	// buyer should have enough balance
 	tx.BuyOffer.AssetAmount = UnpackAmount(api, tx.BuyOffer.AssetAmount)
 	IsVariableLessOrEqual(api, flag, tx.BuyOffer.AssetAmount, accountsBefore[buyAccount].AssetsInfo[0].Balance)
+	// the creator and the treasury amounts of the pubdata are exact shares of the amount
+	IsVariableEqual(api, flag, api.Mul(UnpackAmount(api, tx.CreatorAmount), RateBase), api.Mul(tx.BuyOffer.AssetAmount, nftBefore.CreatorTreasuryRate))
+	IsVariableEqual(api, flag, api.Mul(UnpackAmount(api, tx.TreasuryAmount), RateBase), api.Mul(tx.BuyOffer.AssetAmount, tx.BuyOffer.TreasuryRate))
 	// submitter should have enough balance
 	tx.GasFeeAssetAmount = UnpackFee(api, tx.GasFeeAssetAmount)
 	IsVariableLessOrEqual(api, flag, tx.GasFeeAssetAmount, accountsBefore[fromAccount].AssetsInfo[0].Balance)
@@ -78,6 +78,13 @@ func VerifyCancelOfferTx(
 	offerIdBits := api.ToBinary(tx.OfferId, 24)
 	assetId := api.FromBinary(offerIdBits[7:]...)
 	IsVariableEqual(api, flag, assetId, accountsBefore[fromAccount].AssetsInfo[1].AssetId)
+	// the offer should not be canceled or finalized yet
+	offerIndex := api.Sub(tx.OfferId, api.Mul(assetId, OfferSizePerAsset))
+	offerIndexBits := api.ToBinary(accountsBefore[fromAccount].AssetsInfo[1].OfferCanceledOrFinalized, OfferSizePerAsset)
+	for i := 0; i < OfferSizePerAsset; i++ {
+		isOffer := api.And(flag, api.IsZero(api.Sub(offerIndex, i)))
+		IsVariableEqual(api, isOffer, offerIndexBits[i], 0)
+	}
 	// should have enough balance
 	tx.GasFeeAssetAmount = UnpackFee(api, tx.GasFeeAssetAmount)
 	IsVariableLessOrEqual(api, flag, tx.GasFeeAssetAmount, accountsBefore[fromAccount].AssetsInfo[0].Balance)
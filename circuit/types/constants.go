@@ -22,14 +22,12 @@ import (
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/std/hash/mimc"
 	eddsaConstraints "github.com/consensys/gnark/std/signature/eddsa"
 )
 
 type (
 	Variable             = frontend.Variable
 	API                  = frontend.API
-	MiMC                 = mimc.MiMC
 	PublicKeyConstraints = eddsaConstraints.PublicKey
 	PublicKey            = eddsa.PublicKey
 )
@@ -83,7 +83,6 @@ func VerifyFullExitNftTx(
 	tx FullExitNftTxConstraints,
 	accountsBefore [NbAccountsPerTx]AccountConstraints, nftBefore NftConstraints,
 ) (pubData [PubDataSizePerTx]Variable) {
-	pubData = CollectPubDataFromFullExitNft(api, tx)
 	// verify params
 	IsVariableEqual(api, flag, tx.AccountNameHash, accountsBefore[0].AccountNameHash)
 	IsVariableEqual(api, flag, tx.AccountIndex, accountsBefore[0].AccountIndex)
@@ -99,5 +98,7 @@ func VerifyFullExitNftTx(
 	tx.NftContentHash = api.Select(isOwner, tx.NftContentHash, 0)
 	tx.NftL1Address = api.Select(isOwner, tx.NftL1Address, 0)
 	tx.NftL1TokenId = api.Select(isOwner, tx.NftL1TokenId, 0)
+	// the pubdata of an nft the account does not own is empty
+	pubData = CollectPubDataFromFullExitNft(api, tx)
 	return pubData
 }
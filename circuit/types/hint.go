@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"math/big"
 
-	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/hint"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-func Keccak256(_ ecc.ID, inputs []*big.Int, outputs []*big.Int) error {
+func init() {
+	hint.Register(Keccak256)
+}
+
+func Keccak256(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
 	var buf bytes.Buffer
 	for i := 0; i < len(inputs); i++ {
 		buf.Write(inputs[i].FillBytes(make([]byte, 32)))
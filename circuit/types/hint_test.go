@@ -7,10 +7,11 @@ import (
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/test"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type HintConstraints struct {
@@ -34,14 +35,16 @@ func TestHint(t *testing.T) {
 	buf.Write(new(big.Int).SetInt64(2).FillBytes(make([]byte, 32)))
 	hashVal := crypto.Keccak256Hash(buf.Bytes())
 	log.Println(new(big.Int).SetBytes(hashVal.Bytes()).String())
-	assert := test.NewAssert(t)
 	var circuit, witness HintConstraints
 	witness.A = 1
 	witness.B = 2
 	witness.C = hashVal.Bytes()
-	assert.SolvingSucceeded(
-		&circuit, &witness, test.WithBackends(backend.GROTH16),
-		test.WithProverOpts(backend.WithHints(Keccak256)),
-		test.WithCurves(ecc.BN254),
-		test.WithCompileOpts(frontend.IgnoreUnconstrainedInputs()))
+	// not test.Assert: it compiles twice and compares with reflect.DeepEqual, which tells
+	// apart the hint gnark keeps by pointer when it is used by the last constraint
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit, frontend.IgnoreUnconstrainedInputs())
+	require.NoError(t, err)
+	fullWitness, err := frontend.NewWitness(&witness, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	// Keccak256 is registered, the solver finds it without backend.WithHints
+	assert.NoError(t, ccs.IsSolved(fullWitness))
 }
@@ -24,7 +24,7 @@ VerifyMerkleProof: takes a Merkle root, a proofSet, and a proofIndex and returns
 	root. False is returned if the proof set or Merkle root is nil, and if
 	'numLeaves' equals 0.
 */
-func VerifyMerkleProof(api API, isEnabled Variable, h MiMC, merkleRoot Variable, node Variable, proofSet, helper []Variable) {
+func VerifyMerkleProof(api API, isEnabled Variable, h Hasher, merkleRoot Variable, node Variable, proofSet, helper []Variable) {
 	for i := 0; i < len(proofSet); i++ {
 		api.AssertIsBoolean(helper[i])
 		d1 := api.Select(helper[i], proofSet[i], node)
@@ -35,7 +35,7 @@ func VerifyMerkleProof(api API, isEnabled Variable, h MiMC, merkleRoot Variable,
 	IsVariableEqual(api, isEnabled, merkleRoot, node)
 }
 
-func UpdateMerkleProof(api API, h MiMC, node Variable, proofSet, helper []Variable) (root Variable) {
+func UpdateMerkleProof(api API, h Hasher, node Variable, proofSet, helper []Variable) (root Variable) {
 	for i := 0; i < len(proofSet); i++ {
 		api.AssertIsBoolean(helper[i])
 		d1 := api.Select(helper[i], proofSet[i], node)
@@ -48,7 +48,8 @@ func UpdateMerkleProof(api API, h MiMC, node Variable, proofSet, helper []Variab
 
 // nodeSum returns the hash created from data inserted to form a leaf.
 // Without domain separation.
-func nodeSum(h MiMC, a, b Variable) Variable {
+func nodeSum(h Hasher, a, b Variable) Variable {
+	h.Reset()
 	h.Write(a)
 	h.Write(b)
 	res := h.Sum()
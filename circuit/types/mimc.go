@@ -0,0 +1,76 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+/*
+	MiMC: in-circuit version of hasher/mimc, over field elements. It is not the mimc of
+	gnark, which has more rounds since gnark v0.8.0.
+*/
+type MiMC struct {
+	api    API
+	params []big.Int
+	h      Variable
+	data   []Variable
+}
+
+func NewMiMC(api API) MiMC {
+	return MiMC{
+		api:    api,
+		params: mimc.GetConstants(),
+		h:      0,
+	}
+}
+
+func (h *MiMC) Write(data ...Variable) {
+	h.data = append(h.data, data...)
+}
+
+func (h *MiMC) Reset() {
+	h.data = nil
+	h.h = 0
+}
+
+/*
+	Sum: hash the written data and flush it, the state is kept like the native Sum
+*/
+func (h *MiMC) Sum() Variable {
+	for _, x := range h.data {
+		r := h.encrypt(x)
+		h.h = h.api.Add(h.h, r, x)
+	}
+	h.data = nil
+	return h.h
+}
+
+func (h *MiMC) encrypt(m Variable) Variable {
+	api := h.api
+	for i := range h.params {
+		// m = (m+k+c)^5
+		tmp := api.Add(m, h.h, h.params[i])
+		m = api.Mul(tmp, tmp)
+		m = api.Mul(m, m)
+		m = api.Mul(m, tmp)
+	}
+	return api.Add(m, h.h)
+}
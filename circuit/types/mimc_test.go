@@ -0,0 +1,44 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/test"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+type MiMCConstraints struct {
+	Inputs [3]Variable
+	Output Variable
+}
+
+func (circuit MiMCConstraints) Define(api API) error {
+	h := NewMiMC(api)
+	h.Write(circuit.Inputs[:2]...)
+	h.Sum()
+	h.Write(circuit.Inputs[2])
+	api.AssertIsEqual(h.Sum(), circuit.Output)
+	return nil
+}
+
+func TestMiMCCircuit(t *testing.T) {
+	var circuit, witness MiMCConstraints
+	h := mimc.NewMiMC()
+	for i := range witness.Inputs {
+		witness.Inputs[i] = i + 1
+		block := make([]byte, mimc.BlockSize)
+		block[mimc.BlockSize-1] = byte(i + 1)
+		h.Write(block)
+		if i == 1 {
+			h.Sum(nil)
+		}
+	}
+	witness.Output = h.Sum(nil)
+	assert := test.NewAssert(t)
+	assert.SolvingSucceeded(
+		&circuit, &witness, test.WithBackends(backend.GROTH16),
+		test.WithCurves(ecc.BN254))
+}
@@ -0,0 +1,405 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+/*
+	pubDataChunk: native counterpart of the api.ToBinary / api.FromBinary packing used by
+	CollectPubDataFrom*, fields are written from the most significant bits down
+*/
+type pubDataChunk struct {
+	value *big.Int
+	size  int
+	err   error
+}
+
+func newPubDataChunk() *pubDataChunk {
+	return &pubDataChunk{value: big.NewInt(0)}
+}
+
+func (c *pubDataChunk) writeBig(v *big.Int, bitsSize int) *pubDataChunk {
+	if c.err != nil {
+		return c
+	}
+	if v == nil || v.Sign() < 0 || v.BitLen() > bitsSize {
+		c.err = errors.New(fmt.Sprintf("value %v does not fit in %d bits", v, bitsSize))
+		return c
+	}
+	c.value.Lsh(c.value, uint(bitsSize))
+	c.value.Or(c.value, v)
+	c.size += bitsSize
+	return c
+}
+
+func (c *pubDataChunk) write(v int64, bitsSize int) *pubDataChunk {
+	return c.writeBig(big.NewInt(v), bitsSize)
+}
+
+func (c *pubDataChunk) pad(bitsSize int) *pubDataChunk {
+	return c.writeBig(big.NewInt(0), bitsSize)
+}
+
+func (c *pubDataChunk) sum() (*big.Int, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.size > 256 {
+		return nil, errors.New(fmt.Sprintf("chunk of %d bits exceeds 256 bits", c.size))
+	}
+	return c.value, nil
+}
+
+/*
+	BytesToPubData: a witness given as bytes, reduced into the field as the circuit does
+*/
+func BytesToPubData(b []byte) *big.Int {
+	return new(big.Int).Mod(new(big.Int).SetBytes(b), fr.Modulus())
+}
+
+/*
+	StringToPubData: a witness given as a decimal or 0x prefixed string
+*/
+func StringToPubData(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("invalid number: %s", s))
+	}
+	return v.Mod(v, fr.Modulus()), nil
+}
+
+func bigIntToPubData(v *big.Int) (*big.Int, error) {
+	if v == nil {
+		return nil, errors.New("nil value")
+	}
+	return new(big.Int).Mod(v, fr.Modulus()), nil
+}
+
+func emptyPubData() (pubData [PubDataSizePerTx]*big.Int) {
+	for i := 0; i < PubDataSizePerTx; i++ {
+		pubData[i] = big.NewInt(0)
+	}
+	return pubData
+}
+
+/*
+	PubDataToBytes: every chunk as a 32 bytes big endian word
+*/
+func PubDataToBytes(pubData [PubDataSizePerTx]*big.Int) []byte {
+	buf := make([]byte, 0, PubDataSizePerTx*32)
+	for i := 0; i < PubDataSizePerTx; i++ {
+		buf = append(buf, pubData[i].FillBytes(make([]byte, 32))...)
+	}
+	return buf
+}
+
+func EmptyTxPubData() [PubDataSizePerTx]*big.Int {
+	return emptyPubData()
+}
+
+func RegisterZnsPubData(tx *RegisterZnsTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeRegisterZns, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		pad(216).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToPubData(tx.AccountName)
+	pubData[2] = BytesToPubData(tx.AccountNameHash)
+	pubData[3] = tx.PubKey.A.X.ToBigIntRegular(new(big.Int))
+	pubData[4] = tx.PubKey.A.Y.ToBigIntRegular(new(big.Int))
+	return pubData, nil
+}
+
+func DepositPubData(tx *DepositTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeDeposit, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.AssetId, AssetIdBitsSize).
+		writeBig(tx.AssetAmount, StateAmountBitsSize).
+		pad(72).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToPubData(tx.AccountNameHash)
+	return pubData, nil
+}
+
+func DepositNftPubData(tx *DepositNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	nftL1Address, err := StringToPubData(tx.NftL1Address)
+	if err != nil {
+		return pubData, err
+	}
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeDepositNft, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.NftIndex, NftIndexBitsSize).
+		writeBig(nftL1Address, AddressBitsSize).
+		pad(16).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1], err = newPubDataChunk().
+		write(tx.CreatorAccountIndex, AccountIndexBitsSize).
+		write(tx.CreatorTreasuryRate, CreatorTreasuryRateBitsSize).
+		write(tx.CollectionId, CollectionIdBitsSize).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[2] = BytesToPubData(tx.NftContentHash)
+	if pubData[3], err = bigIntToPubData(tx.NftL1TokenId); err != nil {
+		return pubData, err
+	}
+	pubData[4] = BytesToPubData(tx.AccountNameHash)
+	return pubData, nil
+}
+
+func TransferPubData(tx *TransferTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeTransfer, TxTypeBitsSize).
+		write(tx.FromAccountIndex, AccountIndexBitsSize).
+		write(tx.ToAccountIndex, AccountIndexBitsSize).
+		write(tx.AssetId, AssetIdBitsSize).
+		write(tx.AssetAmount, PackedAmountBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		pad(64).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToPubData(tx.CallDataHash)
+	return pubData, nil
+}
+
+func WithdrawPubData(tx *WithdrawTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeWithdraw, TxTypeBitsSize).
+		write(tx.FromAccountIndex, AccountIndexBitsSize).
+		writeBig(tx.ToAddress, AddressBitsSize).
+		write(tx.AssetId, AssetIdBitsSize).
+		pad(40).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1], err = newPubDataChunk().
+		writeBig(tx.AssetAmount, StateAmountBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		sum()
+	return pubData, err
+}
+
+func CreateCollectionPubData(tx *CreateCollectionTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeCreateCollection, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.CollectionId, CollectionIdBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		pad(136).
+		sum()
+	return pubData, err
+}
+
+func MintNftPubData(tx *MintNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeMintNft, TxTypeBitsSize).
+		write(tx.CreatorAccountIndex, AccountIndexBitsSize).
+		write(tx.ToAccountIndex, AccountIndexBitsSize).
+		write(tx.NftIndex, NftIndexBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		write(tx.CreatorTreasuryRate, CreatorTreasuryRateBitsSize).
+		write(tx.CollectionId, CollectionIdBitsSize).
+		pad(48).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToPubData(tx.NftContentHash)
+	return pubData, nil
+}
+
+func TransferNftPubData(tx *TransferNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeTransferNft, TxTypeBitsSize).
+		write(tx.FromAccountIndex, AccountIndexBitsSize).
+		write(tx.ToAccountIndex, AccountIndexBitsSize).
+		write(tx.NftIndex, NftIndexBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		pad(80).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToPubData(tx.CallDataHash)
+	return pubData, nil
+}
+
+func AtomicMatchPubData(tx *AtomicMatchTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	if tx.BuyOffer == nil || tx.SellOffer == nil {
+		return pubData, errors.New("offers should not be nil")
+	}
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeAtomicMatch, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.BuyOffer.AccountIndex, AccountIndexBitsSize).
+		write(tx.BuyOffer.OfferId, OfferIdBitsSize).
+		write(tx.SellOffer.AccountIndex, AccountIndexBitsSize).
+		write(tx.SellOffer.OfferId, OfferIdBitsSize).
+		write(tx.BuyOffer.NftIndex, NftIndexBitsSize).
+		write(tx.SellOffer.AssetId, AssetIdBitsSize).
+		pad(48).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1], err = newPubDataChunk().
+		write(tx.SellOffer.AssetAmount, PackedAmountBitsSize).
+		write(tx.CreatorAmount, PackedAmountBitsSize).
+		write(tx.TreasuryAmount, PackedAmountBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		sum()
+	return pubData, err
+}
+
+func CancelOfferPubData(tx *CancelOfferTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeCancelOffer, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.OfferId, OfferIdBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		pad(128).
+		sum()
+	return pubData, err
+}
+
+func WithdrawNftPubData(tx *WithdrawNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	nftL1Address, err := StringToPubData(tx.NftL1Address)
+	if err != nil {
+		return pubData, err
+	}
+	toAddress, err := StringToPubData(tx.ToAddress)
+	if err != nil {
+		return pubData, err
+	}
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeWithdrawNft, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.CreatorAccountIndex, AccountIndexBitsSize).
+		write(tx.CreatorTreasuryRate, FeeRateBitsSize).
+		write(tx.NftIndex, NftIndexBitsSize).
+		write(tx.CollectionId, CollectionIdBitsSize).
+		pad(112).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = nftL1Address
+	pubData[2], err = newPubDataChunk().
+		writeBig(toAddress, AddressBitsSize).
+		write(tx.GasAccountIndex, AccountIndexBitsSize).
+		write(tx.GasFeeAssetId, AssetIdBitsSize).
+		write(tx.GasFeeAssetAmount, PackedFeeBitsSize).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[3] = BytesToPubData(tx.NftContentHash)
+	if pubData[4], err = bigIntToPubData(tx.NftL1TokenId); err != nil {
+		return pubData, err
+	}
+	pubData[5] = BytesToPubData(tx.CreatorAccountNameHash)
+	return pubData, nil
+}
+
+func FullExitPubData(tx *FullExitTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeFullExit, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.AssetId, AssetIdBitsSize).
+		writeBig(tx.AssetAmount, StateAmountBitsSize).
+		pad(72).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = BytesToPubData(tx.AccountNameHash)
+	return pubData, nil
+}
+
+func FullExitNftPubData(tx *FullExitNftTx) (pubData [PubDataSizePerTx]*big.Int, err error) {
+	pubData = emptyPubData()
+	nftL1Address, err := StringToPubData(tx.NftL1Address)
+	if err != nil {
+		return pubData, err
+	}
+	pubData[0], err = newPubDataChunk().
+		write(TxTypeFullExitNft, TxTypeBitsSize).
+		write(tx.AccountIndex, AccountIndexBitsSize).
+		write(tx.CreatorAccountIndex, AccountIndexBitsSize).
+		write(tx.CreatorTreasuryRate, FeeRateBitsSize).
+		write(tx.NftIndex, NftIndexBitsSize).
+		write(tx.CollectionId, CollectionIdBitsSize).
+		pad(112).
+		sum()
+	if err != nil {
+		return pubData, err
+	}
+	pubData[1] = nftL1Address
+	pubData[2] = BytesToPubData(tx.AccountNameHash)
+	pubData[3] = BytesToPubData(tx.CreatorAccountNameHash)
+	pubData[4] = BytesToPubData(tx.NftContentHash)
+	pubData[5], err = bigIntToPubData(tx.NftL1TokenId)
+	return pubData, err
+}
@@ -130,6 +130,8 @@ func VerifyWithdrawNftTx(
 	IsVariableEqual(api, flag, tx.NftContentHash, nftBefore.NftContentHash)
 	IsVariableEqual(api, flag, tx.NftL1TokenId, nftBefore.NftL1TokenId)
 	IsVariableEqual(api, flag, tx.NftL1Address, nftBefore.NftL1Address)
+	// an empty nft has no content hash
+	IsVariableEqual(api, flag, api.IsZero(nftBefore.NftContentHash), 0)
 	// have enough assets
 	tx.GasFeeAssetAmount = UnpackFee(api, tx.GasFeeAssetAmount)
 	IsVariableLessOrEqual(api, flag, tx.GasFeeAssetAmount, accountsBefore[fromAccount].AssetsInfo[0].Balance)
@@ -0,0 +1,239 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+// roots, merkle proof nodes and the block commitment are 32 bytes hashes
+const hashLen = 32
+
+func codecError(funcName string, err error) error {
+	errInfo := fmt.Sprintf("[%s] %s", funcName, err.Error())
+	log.Println(errInfo)
+	return errors.New(errInfo)
+}
+
+func checkHash(path string, hash []byte) error {
+	if len(hash) != hashLen {
+		return pathError(path, "expected %d bytes, got %d", hashLen, len(hash))
+	}
+	return nil
+}
+
+/*
+	checkPointers: every pointer reachable from v is set, except the tx infos of a tx which are checked by validateTx
+*/
+func checkPointers(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return pathError(path, "missing")
+		}
+		if v.Type() == bigIntType || isLeaf(v.Type()) {
+			return nil
+		}
+		return checkPointers(v.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			name := v.Type().Field(i).Name
+			if v.Type() == txStructType && strings.HasSuffix(name, "TxInfo") && field.IsNil() {
+				continue
+			}
+			if err := checkPointers(field, joinPath(path, fieldName(name))); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkPointers(v.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateTx(oTx *Tx, path string) error {
+	if oTx == nil {
+		return pathError(path, "missing")
+	}
+	if oTx.TxType > types.TxTypeFullExitNft {
+		return pathError(joinPath(path, "tx_type"), "invalid tx type %d", oTx.TxType)
+	}
+	txInfos := map[int]bool{
+		types.TxTypeRegisterZns:      oTx.RegisterZnsTxInfo != nil,
+		types.TxTypeDeposit:          oTx.DepositTxInfo != nil,
+		types.TxTypeDepositNft:       oTx.DepositNftTxInfo != nil,
+		types.TxTypeTransfer:         oTx.TransferTxInfo != nil,
+		types.TxTypeWithdraw:         oTx.WithdrawTxInfo != nil,
+		types.TxTypeCreateCollection: oTx.CreateCollectionTxInfo != nil,
+		types.TxTypeMintNft:          oTx.MintNftTxInfo != nil,
+		types.TxTypeTransferNft:      oTx.TransferNftTxInfo != nil,
+		types.TxTypeAtomicMatch:      oTx.AtomicMatchTxInfo != nil,
+		types.TxTypeCancelOffer:      oTx.CancelOfferTxInfo != nil,
+		types.TxTypeWithdrawNft:      oTx.WithdrawNftTxInfo != nil,
+		types.TxTypeFullExit:         oTx.FullExitTxInfo != nil,
+		types.TxTypeFullExitNft:      oTx.FullExitNftTxInfo != nil,
+	}
+	for txType := types.TxTypeRegisterZns; txType <= types.TxTypeFullExitNft; txType++ {
+		isSet := txInfos[txType]
+		if txType == int(oTx.TxType) && !isSet {
+			return pathError(path, "missing tx info of tx type %d", oTx.TxType)
+		}
+		if txType != int(oTx.TxType) && isSet {
+			return pathError(path, "tx info of tx type %d set in a tx of type %d", txType, oTx.TxType)
+		}
+	}
+	if err := checkPointers(reflect.ValueOf(oTx).Elem(), path); err != nil {
+		return err
+	}
+	roots := []struct {
+		name string
+		hash []byte
+	}{
+		{"account_root_before", oTx.AccountRootBefore},
+		{"nft_root_before", oTx.NftRootBefore},
+		{"state_root_before", oTx.StateRootBefore},
+		{"state_root_after", oTx.StateRootAfter},
+	}
+	for _, root := range roots {
+		if err := checkHash(joinPath(path, root.name), root.hash); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < NbAccountsPerTx; i++ {
+		for j := 0; j < NbAccountAssetsPerAccount; j++ {
+			for k := 0; k < AssetMerkleLevels; k++ {
+				nodePath := fmt.Sprintf("%s[%d][%d][%d]", joinPath(path, "merkle_proofs_account_assets_before"), i, j, k)
+				if err := checkHash(nodePath, oTx.MerkleProofsAccountAssetsBefore[i][j][k]); err != nil {
+					return err
+				}
+			}
+		}
+		for j := 0; j < AccountMerkleLevels; j++ {
+			nodePath := fmt.Sprintf("%s[%d][%d]", joinPath(path, "merkle_proofs_account_before"), i, j)
+			if err := checkHash(nodePath, oTx.MerkleProofsAccountBefore[i][j]); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; i < NftMerkleLevels; i++ {
+		if err := checkHash(indexPath(joinPath(path, "merkle_proofs_nft_before"), i), oTx.MerkleProofsNftBefore[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateGas(gas *Gas, path string) error {
+	if gas == nil {
+		return pathError(path, "missing")
+	}
+	if err := checkPointers(reflect.ValueOf(gas).Elem(), path); err != nil {
+		return err
+	}
+	if gas.GasAssetCount <= 0 {
+		return pathError(joinPath(path, "gas_asset_count"), "expected a positive count, got %d", gas.GasAssetCount)
+	}
+	if len(gas.AccountInfoBefore.AssetsInfo) != gas.GasAssetCount {
+		return pathError(joinPath(path, "account_info_before.assets_info"),
+			"expected %d items, got %d", gas.GasAssetCount, len(gas.AccountInfoBefore.AssetsInfo))
+	}
+	if len(gas.MerkleProofsAccountAssetsBefore) != gas.GasAssetCount {
+		return pathError(joinPath(path, "merkle_proofs_account_assets_before"),
+			"expected %d items, got %d", gas.GasAssetCount, len(gas.MerkleProofsAccountAssetsBefore))
+	}
+	for i := 0; i < gas.GasAssetCount; i++ {
+		for j := 0; j < AssetMerkleLevels; j++ {
+			nodePath := fmt.Sprintf("%s[%d][%d]", joinPath(path, "merkle_proofs_account_assets_before"), i, j)
+			if err := checkHash(nodePath, gas.MerkleProofsAccountAssetsBefore[i][j]); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; i < AccountMerkleLevels; i++ {
+		if err := checkHash(indexPath(joinPath(path, "merkle_proofs_account_before"), i), gas.MerkleProofsAccountBefore[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateBlock(oBlock *Block) error {
+	if oBlock == nil {
+		return errors.New("missing block")
+	}
+	if err := checkHash("old_state_root", oBlock.OldStateRoot); err != nil {
+		return err
+	}
+	if err := checkHash("new_state_root", oBlock.NewStateRoot); err != nil {
+		return err
+	}
+	if err := checkHash("block_commitment", oBlock.BlockCommitment); err != nil {
+		return err
+	}
+	if len(oBlock.Txs) == 0 {
+		return pathError("txs", "no tx")
+	}
+	for i, oTx := range oBlock.Txs {
+		if err := validateTx(oTx, indexPath("txs", i)); err != nil {
+			return err
+		}
+	}
+	return validateGas(oBlock.Gas, "gas")
+}
+
+/*
+	ValidateBlock: check that a block has every field the block witness needs and that its arrays
+	match the level constants, the proof itself is only checked by the circuit
+*/
+func ValidateBlock(oBlock *Block) error {
+	if err := validateBlock(oBlock); err != nil {
+		return codecError("ValidateBlock", err)
+	}
+	return nil
+}
+
+/*
+	ValidateTx: check a tx like ValidateBlock does
+*/
+func ValidateTx(oTx *Tx) error {
+	if err := validateTx(oTx, ""); err != nil {
+		return codecError("ValidateTx", err)
+	}
+	return nil
+}
+
+/*
+	ValidateGas: check the gas of a block like ValidateBlock does
+*/
+func ValidateGas(gas *Gas) error {
+	if err := validateGas(gas, ""); err != nil {
+		return codecError("ValidateGas", err)
+	}
+	return nil
+}
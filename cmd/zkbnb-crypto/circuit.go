@@ -0,0 +1,379 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/profile"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/prover"
+)
+
+var circuitCommands = []*command{
+	compileCommand,
+	setupCommand,
+	proveCommand,
+	verifyProofCommand,
+	exportSolidityCommand,
+	serveCommand,
+	profileCommand,
+}
+
+var circuitCommand = &command{
+	name:  "circuit",
+	usage: "compile, setup, prove and verify the block circuit with groth16",
+	run: func(args []string, out io.Writer) error {
+		if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			circuitUsage(out)
+			return nil
+		}
+		for _, cmd := range circuitCommands {
+			if cmd.name == args[0] {
+				return cmd.run(args[1:], out)
+			}
+		}
+		circuitUsage(os.Stderr)
+		return fmt.Errorf("unknown circuit command: %s", args[0])
+	},
+}
+
+func circuitUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: zkbnb-crypto circuit <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range circuitCommands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func requireFlags(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if fs.Lookup(name).Value.String() == "" {
+			return fmt.Errorf("-%s is required", name)
+		}
+	}
+	return nil
+}
+
+func parseInts(s string) ([]int64, error) {
+	var values []int64
+	for _, field := range strings.Split(s, ",") {
+		value, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", field, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+var compileCommand = &command{
+	name:  "compile",
+	usage: "compile the block circuit of a block size and gas assets",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit compile")
+		txsCount := fs.Int("txs", 0, "txs per block")
+		gasAccountIndex := fs.Int64("gas-account", 1, "index of the gas account")
+		gasAssets := fs.String("gas-assets", "0,1", "comma separated ids of the gas assets")
+		hasherName := fs.String("hasher", hasher.Default.String(), "hash function of the trees, mimc or poseidon")
+		output := fs.String("out", "", "path of the compiled constraint system, its metadata is written next to it")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if err := requireFlags(fs, "out"); err != nil {
+			return err
+		}
+		gasAssetIds, err := parseInts(*gasAssets)
+		if err != nil {
+			return err
+		}
+		h, err := hasher.FromString(*hasherName)
+		if err != nil {
+			return err
+		}
+		blockConstraints, err := prover.NewBlockConstraintsWithHasher(h, *txsCount, *gasAccountIndex, gasAssetIds)
+		if err != nil {
+			return err
+		}
+		ccs, err := prover.Compile(blockConstraints)
+		if err != nil {
+			return err
+		}
+		if err = prover.WriteConstraintSystem(*output, ccs); err != nil {
+			return err
+		}
+		err = prover.WriteCircuitMetadata(prover.MetadataPath(*output), &prover.CircuitMetadata{
+			TxsCount:        *txsCount,
+			GasAccountIndex: *gasAccountIndex,
+			GasAssetIds:     blockConstraints.GasAssetIds,
+			Hasher:          h.String(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "constraints: %d\n", ccs.GetNbConstraints())
+		return err
+	},
+}
+
+var setupCommand = &command{
+	name:  "setup",
+	usage: "run the groth16 setup of a compiled circuit, for tests only",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit setup")
+		csPath := fs.String("r1cs", "", "path of the compiled constraint system")
+		pkPath := fs.String("pk", "", "path of the proving key to write")
+		vkPath := fs.String("vk", "", "path of the verifying key to write")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if err := requireFlags(fs, "r1cs", "pk", "vk"); err != nil {
+			return err
+		}
+		ccs, err := prover.ReadConstraintSystem(*csPath)
+		if err != nil {
+			return err
+		}
+		pk, vk, err := prover.Setup(ccs)
+		if err != nil {
+			return err
+		}
+		if err = prover.WriteProvingKey(*pkPath, pk); err != nil {
+			return err
+		}
+		return prover.WriteVerifyingKey(*vkPath, vk)
+	},
+}
+
+var proveCommand = &command{
+	name:  "prove",
+	usage: "prove a block witness, print its commitment",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit prove")
+		csPath := fs.String("r1cs", "", "path of the compiled constraint system")
+		pkPath := fs.String("pk", "", "path of the proving key")
+		blockPath := fs.String("block", "", "path of the block witness, json or binary")
+		proofPath := fs.String("proof", "", "path of the proof to write")
+		publicPath := fs.String("public", "", "path to write the block commitment to, optional")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if err := requireFlags(fs, "r1cs", "pk", "block", "proof"); err != nil {
+			return err
+		}
+		oBlock, err := prover.ReadBlock(*blockPath)
+		if err != nil {
+			return err
+		}
+		ccs, err := prover.ReadConstraintSystem(*csPath)
+		if err != nil {
+			return err
+		}
+		pk, err := prover.ReadProvingKey(*pkPath)
+		if err != nil {
+			return err
+		}
+		proof, err := prover.ProveBlock(ccs, pk, oBlock)
+		if err != nil {
+			return err
+		}
+		if err = prover.WriteProof(*proofPath, proof); err != nil {
+			return err
+		}
+		commitment := hex.EncodeToString(oBlock.BlockCommitment)
+		if *publicPath != "" {
+			if err = ioutil.WriteFile(*publicPath, []byte(commitment+"\n"), 0644); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(out, commitment)
+		return err
+	},
+}
+
+var verifyProofCommand = &command{
+	name:  "verify",
+	usage: "verify the proof of a block against its commitment",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit verify")
+		vkPath := fs.String("vk", "", "path of the verifying key")
+		proofPath := fs.String("proof", "", "path of the proof")
+		commitment := fs.String("commitment", "", "block commitment in hex, @file or - for stdin")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if err := requireFlags(fs, "vk", "proof"); err != nil {
+			return err
+		}
+		commitmentStr, err := readInput("commitment", *commitment)
+		if err != nil {
+			return err
+		}
+		blockCommitment, err := hex.DecodeString(strings.TrimPrefix(commitmentStr, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid -commitment: %w", err)
+		}
+		if len(blockCommitment) != 32 {
+			return errors.New("invalid -commitment: expected 32 bytes")
+		}
+		vk, err := prover.ReadVerifyingKey(*vkPath)
+		if err != nil {
+			return err
+		}
+		proof, err := prover.ReadProof(*proofPath)
+		if err != nil {
+			return err
+		}
+		if err = prover.VerifyBlock(vk, proof, blockCommitment); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, "valid")
+		return err
+	},
+}
+
+var exportSolidityCommand = &command{
+	name:  "export-solidity",
+	usage: "write the verifier contract of a verifying key",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit export-solidity")
+		vkPath := fs.String("vk", "", "path of the verifying key")
+		output := fs.String("out", "", "path of the contract to write")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if err := requireFlags(fs, "vk", "out"); err != nil {
+			return err
+		}
+		vk, err := prover.ReadVerifyingKey(*vkPath)
+		if err != nil {
+			return err
+		}
+		return prover.WriteSolidity(*output, vk)
+	},
+}
+
+var serveCommand = &command{
+	name:  "serve",
+	usage: "serve block proving jobs over http, blocks are padded to the smallest loaded circuit",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit serve")
+		dir := fs.String("dir", "", "directory of the zkbnb<N>.r1cs, zkbnb<N>.json, zkbnb<N>.pk and zkbnb<N>.vk files")
+		blockSizes := fs.String("txs", "", "comma separated block sizes to load")
+		gasAccountIndex := fs.Int64("gas-account", 1, "index of the gas account the circuits are compiled for")
+		gasAssets := fs.String("gas-assets", "0,1", "comma separated ids of the gas assets the circuits are compiled for")
+		hasherName := fs.String("hasher", hasher.Default.String(), "hash function of the trees the circuits are compiled for")
+		addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+		workers := fs.Int("workers", 1, "proofs computed at a time")
+		queueSize := fs.Int("queue", 16, "jobs waiting to be proven")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if err := requireFlags(fs, "dir", "txs"); err != nil {
+			return err
+		}
+		txsCounts, err := parseInts(*blockSizes)
+		if err != nil {
+			return err
+		}
+		gasAssetIds, err := parseInts(*gasAssets)
+		if err != nil {
+			return err
+		}
+		h, err := hasher.FromString(*hasherName)
+		if err != nil {
+			return err
+		}
+		registry := prover.NewRegistryWithHasher(h)
+		for _, txsCount := range txsCounts {
+			c, err := prover.LoadCircuitWithHasher(*dir, h, int(txsCount), *gasAccountIndex, gasAssetIds)
+			if err != nil {
+				return err
+			}
+			if err = registry.Register(c); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "loaded the circuit of %d txs\n", txsCount)
+		}
+		s, err := prover.NewService(registry, *workers, *queueSize)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{Addr: *addr, Handler: s.Handler()}
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-stop
+			log.Println("shutting down, waiting for the queued jobs")
+			_ = server.Close()
+		}()
+		fmt.Fprintf(out, "listening on %s\n", *addr)
+		if err = server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		s.Close()
+		return nil
+	},
+}
+
+var profileCommand = &command{
+	name:  "profile",
+	usage: "print the constraints of every component of the block circuit",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("circuit profile")
+		txsCount := fs.Int("txs", 1, "txs per block")
+		gasAssets := fs.String("gas-assets", "0,1", "comma separated ids of the gas assets")
+		backend := fs.String("backend", profile.Groth16, "groth16 or plonk")
+		jsonPath := fs.String("json", "", "path to write the report in json to, for diffs between commits")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		gasAssetIds, err := parseInts(*gasAssets)
+		if err != nil {
+			return err
+		}
+		report, err := profile.Profile(*backend, *txsCount, gasAssetIds)
+		if err != nil {
+			return err
+		}
+		if *jsonPath != "" {
+			reportBytes, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(*jsonPath, append(reportBytes, '\n'), 0644); err != nil {
+				return err
+			}
+		}
+		return report.WriteTable(out)
+	},
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+)
+
+var keyCommand = &command{
+	name:  "key",
+	usage: "generate the eddsa private key of a seed",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("key")
+		seed := seedFlag(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		s, err := readSeed(*seed)
+		if err != nil {
+			return err
+		}
+		sk, err := curve.GenerateEddsaPrivateKey(s)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, hex.EncodeToString(sk.Bytes()))
+		return err
+	},
+}
+
+var pubKeyCommand = &command{
+	name:  "pubkey",
+	usage: "print the public key of a seed, compressed unless -raw is set",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("pubkey")
+		seed := seedFlag(fs)
+		raw := fs.Bool("raw", false, "print the uncompressed point, x and y")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		s, err := readSeed(*seed)
+		if err != nil {
+			return err
+		}
+		sk, err := curve.GenerateEddsaPrivateKey(s)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if *raw {
+			buf.Write(sk.PublicKey.A.X.Marshal())
+			buf.Write(sk.PublicKey.A.Y.Marshal())
+		} else {
+			buf.Write(sk.PublicKey.Bytes())
+		}
+		_, err = fmt.Fprintln(out, hex.EncodeToString(buf.Bytes()))
+		return err
+	},
+}
+
+var nameHashCommand = &command{
+	name:  "name-hash",
+	usage: "compute the hash of an account name, e.g. alice.legend",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("name-hash")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("usage: name-hash <account name>")
+		}
+		nameHash, err := util.ComputeAccountNameHash(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, nameHash)
+		return err
+	},
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+/*
+	zkbnb-crypto: command line access to the keys, hashes and tx signing of the wasm library
+*/
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, out io.Writer) error
+}
+
+var commands = []*command{
+	keyCommand,
+	pubKeyCommand,
+	nameHashCommand,
+	signCommand,
+	verifyCommand,
+	circuitCommand,
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: zkbnb-crypto <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run zkbnb-crypto <command> -h for the flags of a command")
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(out)
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], out)
+		}
+	}
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// the seed may be passed in the environment, to keep it out of the process list
+const seedEnv = "ZKBNB_SEED"
+
+func seedFlag(fs *flag.FlagSet) *string {
+	return fs.String("seed", "", "seed of the eddsa key, $"+seedEnv+" if not set")
+}
+
+func readSeed(seed string) (string, error) {
+	if seed == "" {
+		seed = os.Getenv(seedEnv)
+	}
+	if seed == "" {
+		return "", errors.New("seed is required, set -seed or $" + seedEnv)
+	}
+	return seed, nil
+}
+
+/*
+	readInput: a json argument given inline, as @file or as - for stdin
+*/
+func readInput(name string, value string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case value == "":
+		return "", fmt.Errorf("-%s is required", name)
+	case value == "-":
+		b, err = ioutil.ReadAll(os.Stdin)
+	case strings.HasPrefix(value, "@"):
+		b, err = ioutil.ReadFile(value[1:])
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read -%s: %w", name, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,291 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/prover"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+func runCommand(t *testing.T, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := run(args, &out)
+	return strings.TrimSpace(out.String()), err
+}
+
+func segment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestKeys(t *testing.T) {
+	sk, err := curve.GenerateEddsaPrivateKey("alice")
+	require.NoError(t, err)
+
+	out, err := runCommand(t, "key", "-seed", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sk.Bytes()), out)
+
+	out, err = runCommand(t, "pubkey", "-seed", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sk.PublicKey.Bytes()), out)
+
+	out, err = runCommand(t, "pubkey", "-seed", "alice", "-raw")
+	require.NoError(t, err)
+	assert.Equal(t, 128, len(out))
+	x := sk.PublicKey.A.X.Bytes()
+	assert.Equal(t, hex.EncodeToString(x[:]), out[:64])
+
+	require.NoError(t, os.Setenv(seedEnv, "alice"))
+	out, err = runCommand(t, "pubkey")
+	os.Unsetenv(seedEnv)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sk.PublicKey.Bytes()), out)
+	_, err = runCommand(t, "key")
+	assert.Error(t, err)
+
+	out, err = runCommand(t, "name-hash", "alice.legend")
+	require.NoError(t, err)
+	nameHash, err := util.ComputeAccountNameHash("alice.legend")
+	require.NoError(t, err)
+	assert.Equal(t, nameHash, out)
+	_, err = runCommand(t, "name-hash", "alice")
+	assert.Error(t, err)
+	_, err = runCommand(t, "name-hash")
+	assert.Error(t, err)
+
+	_, err = runCommand(t, "unknown")
+	assert.Error(t, err)
+	out, err = runCommand(t)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "name-hash")
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pubKey, err := runCommand(t, "pubkey", "-seed", "alice")
+	require.NoError(t, err)
+	otherPubKey, err := runCommand(t, "pubkey", "-seed", "bob")
+	require.NoError(t, err)
+
+	nameHash, err := util.ComputeAccountNameHash("bob.legend")
+	require.NoError(t, err)
+	offer := &txtypes.OfferSegmentFormat{
+		Type:         txtypes.SellOfferType,
+		OfferId:      1,
+		AccountIndex: 1,
+		NftIndex:     2,
+		AssetId:      0,
+		AssetAmount:  "10000",
+		ListedAt:     1660000000000,
+		ExpiredAt:    1660003600000,
+		TreasuryRate: 200,
+	}
+	signedOffer, err := runCommand(t, "sign", "-seed", "alice", "-type", "offer", "-segment", segment(t, offer))
+	require.NoError(t, err)
+	segments := map[string]interface{}{
+		"transfer": &txtypes.TransferSegmentFormat{
+			FromAccountIndex:  1,
+			ToAccountIndex:    2,
+			ToAccountNameHash: nameHash,
+			AssetId:           0,
+			AssetAmount:       "100000",
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			ExpiredAt:         1660003600000,
+			Nonce:             1,
+		},
+		"withdraw": &txtypes.WithdrawSegmentFormat{
+			FromAccountIndex:  1,
+			AssetId:           0,
+			AssetAmount:       "100000",
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+			ExpiredAt:         1660003600000,
+			Nonce:             1,
+		},
+		"offer": offer,
+		"atomic-match": &txtypes.AtomicMatchSegmentFormat{
+			AccountIndex:      1,
+			BuyOffer:          signedOffer,
+			SellOffer:         signedOffer,
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			Nonce:             1,
+			ExpiredAt:         1660003600000,
+		},
+		"cancel-offer": &txtypes.CancelOfferSegmentFormat{
+			AccountIndex:      1,
+			OfferId:           1,
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			ExpiredAt:         1660003600000,
+			Nonce:             1,
+		},
+		"create-collection": &txtypes.CreateCollectionSegmentFormat{
+			AccountIndex:      1,
+			Name:              "collection",
+			Introduction:      "collection",
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			ExpiredAt:         1660003600000,
+			Nonce:             1,
+		},
+		"mint-nft": &txtypes.MintNftSegmentFormat{
+			CreatorAccountIndex: 1,
+			ToAccountIndex:      2,
+			ToAccountNameHash:   nameHash,
+			NftContentHash:      nameHash,
+			NftCollectionId:     0,
+			CreatorTreasuryRate: 100,
+			GasAccountIndex:     0,
+			GasFeeAssetId:       0,
+			GasFeeAssetAmount:   "1000",
+			ExpiredAt:           1660003600000,
+			Nonce:               1,
+		},
+		"transfer-nft": &txtypes.TransferNftSegmentFormat{
+			FromAccountIndex:  1,
+			ToAccountIndex:    2,
+			ToAccountNameHash: nameHash,
+			NftIndex:          2,
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			ExpiredAt:         1660003600000,
+			Nonce:             1,
+		},
+		"withdraw-nft": &txtypes.WithdrawNftSegmentFormat{
+			AccountIndex:      1,
+			NftIndex:          2,
+			ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+			GasAccountIndex:   0,
+			GasFeeAssetId:     0,
+			GasFeeAssetAmount: "1000",
+			ExpiredAt:         1660003600000,
+			Nonce:             1,
+		},
+	}
+
+	for name := range txTypes {
+		format, ok := segments[name]
+		require.True(t, ok, name)
+		signed, err := runCommand(t, "sign", "-seed", "alice", "-type", name, "-segment", segment(t, format))
+		require.NoError(t, err, name)
+
+		out, err := runCommand(t, "verify", "-pubkey", pubKey, "-type", name, "-tx", signed)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "valid", out, name)
+		_, err = runCommand(t, "verify", "-pubkey", otherPubKey, "-type", name, "-tx", signed)
+		assert.Error(t, err, name)
+	}
+
+	// segments from files
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transfer.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(segment(t, segments["transfer"])+"\n"), 0644))
+	signed, err := runCommand(t, "sign", "-seed", "alice", "-type", "transfer", "-segment", "@"+path)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, []byte(signed), 0644))
+	_, err = runCommand(t, "verify", "-pubkey", pubKey, "-type", "transfer", "-tx", "@"+path)
+	assert.NoError(t, err)
+
+	// tampered tx
+	var transfer txtypes.TransferTxInfo
+	require.NoError(t, json.Unmarshal([]byte(signed), &transfer))
+	transfer.Nonce++
+	tampered, err := json.Marshal(&transfer)
+	require.NoError(t, err)
+	_, err = runCommand(t, "verify", "-pubkey", pubKey, "-type", "transfer", "-tx", string(tampered))
+	assert.Error(t, err)
+
+	_, err = runCommand(t, "sign", "-seed", "alice", "-type", "unknown", "-segment", "{}")
+	assert.Error(t, err)
+	_, err = runCommand(t, "sign", "-seed", "alice", "-type", "transfer")
+	assert.Error(t, err)
+	_, err = runCommand(t, "verify", "-pubkey", "00", "-type", "transfer", "-tx", signed)
+	assert.Error(t, err)
+
+	// messages
+	sig, err := runCommand(t, "sign", "-seed", "alice", "-msg", "hello")
+	require.NoError(t, err)
+	out, err := runCommand(t, "verify", "-pubkey", pubKey, "-msg", "hello", "-sig", sig)
+	assert.NoError(t, err)
+	assert.Equal(t, "valid", out)
+	_, err = runCommand(t, "verify", "-pubkey", pubKey, "-msg", "hello!", "-sig", sig)
+	assert.Error(t, err)
+	_, err = runCommand(t, "verify", "-pubkey", otherPubKey, "-msg", "hello", "-sig", sig)
+	assert.Error(t, err)
+}
+
+func TestCircuitCommands(t *testing.T) {
+	dir := t.TempDir()
+	csPath := filepath.Join(dir, "zkbnb1.r1cs")
+
+	out, err := runCommand(t, "circuit", "compile", "-txs", "1", "-gas-account", "1", "-gas-assets", "0,1", "-out", csPath)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "constraints: "))
+	_, err = os.Stat(csPath)
+	assert.NoError(t, err)
+	metadata, err := prover.ReadCircuitMetadata(filepath.Join(dir, "zkbnb1.json"))
+	require.NoError(t, err)
+	assert.Equal(t, &prover.CircuitMetadata{TxsCount: 1, GasAccountIndex: 1, GasAssetIds: []int64{0, 1}, Hasher: "mimc"}, metadata)
+
+	for _, args := range [][]string{
+		{"circuit", "unknown"},
+		{"circuit", "compile", "-txs", "1"},
+		{"circuit", "compile", "-txs", "0", "-out", csPath},
+		{"circuit", "compile", "-txs", "1", "-gas-assets", "0,x", "-out", csPath},
+		{"circuit", "compile", "-txs", "1", "-hasher", "sha256", "-out", csPath},
+		{"circuit", "setup", "-r1cs", csPath, "-pk", filepath.Join(dir, "zkbnb1.pk")},
+		{"circuit", "setup", "-r1cs", filepath.Join(dir, "missing.r1cs"), "-pk", "pk", "-vk", "vk"},
+		{"circuit", "prove", "-r1cs", csPath, "-pk", "pk", "-proof", "proof"},
+		{"circuit", "prove", "-r1cs", csPath, "-pk", "pk", "-block", filepath.Join(dir, "missing.json"), "-proof", "proof"},
+		{"circuit", "verify", "-vk", "vk", "-proof", "proof"},
+		{"circuit", "verify", "-vk", "vk", "-proof", "proof", "-commitment", "zz"},
+		{"circuit", "verify", "-vk", "vk", "-proof", "proof", "-commitment", "0x0102"},
+		{"circuit", "export-solidity", "-vk", filepath.Join(dir, "missing.vk"), "-out", filepath.Join(dir, "ZkBNBVerifier1.sol")},
+		{"circuit", "serve", "-txs", "1"},
+		{"circuit", "serve", "-dir", dir, "-txs", "1"},
+		{"circuit", "profile", "-backend", "stark"},
+		{"circuit", "profile", "-txs", "0"},
+	} {
+		_, err = runCommand(t, args...)
+		assert.Error(t, err, strings.Join(args, " "))
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+type signedTx interface {
+	VerifySignature(pubKey string) error
+}
+
+/*
+	txType: how a segment of the type is signed and how its signed form is read back
+*/
+type txType struct {
+	construct func(sk *txtypes.PrivateKey, segment string) (interface{}, error)
+	newTxInfo func() signedTx
+}
+
+// the txs signed by wasm/main.go, named after their sign functions
+var txTypes = map[string]txType{
+	"transfer": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructTransferTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.TransferTxInfo) },
+	},
+	"withdraw": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructWithdrawTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.WithdrawTxInfo) },
+	},
+	"offer": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructOfferTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.OfferTxInfo) },
+	},
+	"atomic-match": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructAtomicMatchTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.AtomicMatchTxInfo) },
+	},
+	"cancel-offer": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructCancelOfferTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.CancelOfferTxInfo) },
+	},
+	"create-collection": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructCreateCollectionTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.CreateCollectionTxInfo) },
+	},
+	"mint-nft": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructMintNftTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.MintNftTxInfo) },
+	},
+	"transfer-nft": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructTransferNftTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.TransferNftTxInfo) },
+	},
+	"withdraw-nft": {
+		construct: func(sk *txtypes.PrivateKey, segment string) (interface{}, error) {
+			return txtypes.ConstructWithdrawNftTxInfo(sk, segment)
+		},
+		newTxInfo: func() signedTx { return new(txtypes.WithdrawNftTxInfo) },
+	},
+}
+
+func txTypeNames() string {
+	names := make([]string, 0, len(txTypes))
+	for name := range txTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func lookupTxType(name string) (txType, error) {
+	t, ok := txTypes[name]
+	if !ok {
+		return txType{}, fmt.Errorf("unknown tx type: %s, expected one of: %s", name, txTypeNames())
+	}
+	return t, nil
+}
+
+var signCommand = &command{
+	name:  "sign",
+	usage: "sign a tx segment with -type and -segment, or a message with -msg",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("sign")
+		seed := seedFlag(fs)
+		typ := fs.String("type", "", "tx type: "+txTypeNames())
+		segment := fs.String("segment", "", "segment json, @file or - for stdin")
+		msg := fs.String("msg", "", "message to sign instead of a tx")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		s, err := readSeed(*seed)
+		if err != nil {
+			return err
+		}
+		sk, err := curve.GenerateEddsaPrivateKey(s)
+		if err != nil {
+			return err
+		}
+		if *msg != "" {
+			if *typ != "" {
+				return errors.New("-msg and -type are exclusive")
+			}
+			signature, err := sk.Sign([]byte(*msg), mimc.NewMiMC())
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(out, hex.EncodeToString(signature))
+			return err
+		}
+		t, err := lookupTxType(*typ)
+		if err != nil {
+			return err
+		}
+		segmentStr, err := readInput("segment", *segment)
+		if err != nil {
+			return err
+		}
+		txInfo, err := t.construct(sk, segmentStr)
+		if err != nil {
+			return fmt.Errorf("unable to sign %s: %w", *typ, err)
+		}
+		txInfoBytes, err := json.Marshal(txInfo)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(txInfoBytes))
+		return err
+	},
+}
+
+var verifyCommand = &command{
+	name:  "verify",
+	usage: "verify a signed tx with -type and -tx, or a message with -msg and -sig",
+	run: func(args []string, out io.Writer) error {
+		fs := newFlagSet("verify")
+		pubKey := fs.String("pubkey", "", "compressed public key of the signer")
+		typ := fs.String("type", "", "tx type: "+txTypeNames())
+		tx := fs.String("tx", "", "signed tx json as printed by sign, @file or - for stdin")
+		msg := fs.String("msg", "", "signed message instead of a tx")
+		sig := fs.String("sig", "", "signature of -msg")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		pk, err := txtypes.ParsePublicKey(*pubKey)
+		if err != nil {
+			return fmt.Errorf("invalid -pubkey: %w", err)
+		}
+		if *msg != "" {
+			if *typ != "" {
+				return errors.New("-msg and -type are exclusive")
+			}
+			signature, err := hex.DecodeString(*sig)
+			if err != nil {
+				return fmt.Errorf("invalid -sig: %w", err)
+			}
+			isValid, err := pk.Verify(signature, []byte(*msg), mimc.NewMiMC())
+			if err != nil {
+				return err
+			}
+			if !isValid {
+				return errors.New("invalid signature")
+			}
+			_, err = fmt.Fprintln(out, "valid")
+			return err
+		}
+		t, err := lookupTxType(*typ)
+		if err != nil {
+			return err
+		}
+		txStr, err := readInput("tx", *tx)
+		if err != nil {
+			return err
+		}
+		txInfo := t.newTxInfo()
+		if err = json.Unmarshal([]byte(txStr), txInfo); err != nil {
+			return fmt.Errorf("invalid -tx: %w", err)
+		}
+		if err = txInfo.VerifySignature(*pubKey); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, "valid")
+		return err
+	},
+}
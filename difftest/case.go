@@ -0,0 +1,590 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package difftest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	Fault: the rule a case breaks, a case without a fault is valid
+*/
+type Fault string
+
+const (
+	NoFault             Fault = ""
+	FaultNonce          Fault = "nonce"
+	FaultSignature      Fault = "signature"
+	FaultExpired        Fault = "expired"
+	FaultGasAccount     Fault = "gas_account"
+	FaultGasAsset       Fault = "gas_asset"
+	FaultBalance        Fault = "balance"
+	FaultNameHash       Fault = "name_hash"
+	FaultRegistered     Fault = "registered"
+	FaultNftExists      Fault = "nft_exists"
+	FaultNftOwner       Fault = "nft_owner"
+	FaultCollection     Fault = "collection"
+	FaultOfferExpired   Fault = "offer_expired"
+	FaultOfferSignature Fault = "offer_signature"
+	FaultOfferFinalized Fault = "offer_finalized"
+	FaultOfferMismatch  Fault = "offer_mismatch"
+	FaultTreasuryRate   Fault = "treasury_rate"
+	FaultRoyaltyAmount  Fault = "royalty_amount"
+	FaultPackedAmount   Fault = "packed_amount"
+	FaultPackedFee      Fault = "packed_fee"
+	FaultFullExitAmount Fault = "full_exit_amount"
+	FaultNftContentHash Fault = "nft_content_hash"
+)
+
+// TxTypes are the tx types cases are generated for
+var TxTypes = []int{
+	txtypes.TxTypeRegisterZns,
+	txtypes.TxTypeDeposit,
+	txtypes.TxTypeDepositNft,
+	txtypes.TxTypeTransfer,
+	txtypes.TxTypeWithdraw,
+	txtypes.TxTypeCreateCollection,
+	txtypes.TxTypeMintNft,
+	txtypes.TxTypeTransferNft,
+	txtypes.TxTypeAtomicMatch,
+	txtypes.TxTypeCancelOffer,
+	txtypes.TxTypeWithdrawNft,
+	txtypes.TxTypeFullExit,
+	txtypes.TxTypeFullExitNft,
+}
+
+var layer2Faults = []Fault{FaultNonce, FaultSignature, FaultExpired, FaultGasAccount, FaultGasAsset, FaultPackedFee}
+
+// faults that apply to each tx type
+var faults = map[int][]Fault{
+	txtypes.TxTypeRegisterZns:      {FaultRegistered},
+	txtypes.TxTypeDeposit:          {FaultNameHash},
+	txtypes.TxTypeDepositNft:       {FaultNameHash, FaultNftExists},
+	txtypes.TxTypeTransfer:         append([]Fault{FaultBalance, FaultNameHash, FaultPackedAmount}, layer2Faults...),
+	txtypes.TxTypeWithdraw:         append([]Fault{FaultBalance}, layer2Faults...),
+	txtypes.TxTypeCreateCollection: append([]Fault{FaultCollection}, layer2Faults...),
+	txtypes.TxTypeMintNft:          append([]Fault{FaultNameHash, FaultNftExists, FaultCollection, FaultNftContentHash}, layer2Faults...),
+	txtypes.TxTypeTransferNft:      append([]Fault{FaultNameHash, FaultNftOwner}, layer2Faults...),
+	txtypes.TxTypeAtomicMatch: append([]Fault{FaultBalance, FaultNftOwner, FaultOfferExpired, FaultOfferSignature, FaultOfferFinalized,
+		FaultOfferMismatch, FaultTreasuryRate, FaultRoyaltyAmount, FaultPackedAmount}, layer2Faults...),
+	txtypes.TxTypeCancelOffer: append([]Fault{FaultOfferFinalized}, layer2Faults...),
+	txtypes.TxTypeWithdrawNft: append([]Fault{FaultNftOwner}, layer2Faults...),
+	txtypes.TxTypeFullExit:    {FaultNameHash, FaultFullExitAmount},
+	txtypes.TxTypeFullExitNft: {FaultNameHash},
+}
+
+/*
+	Faults: the faults a case of txType may carry
+*/
+func Faults(txType int) []Fault {
+	return faults[txType]
+}
+
+/*
+	Case: a transaction against the world of NewWorld. Nonces, signatures and the
+	fields that follow from the state are filled in by TxInfo, so that a case stays
+	meaningful while it is minimized.
+
+	Account is the account the tx is sent from, the submitter of atomic matches and the
+	index registered by RegisterZns. To is the receiver, the creator of deposited nfts
+	and the buyer of atomic matches, the seller is the owner of the nft. Rate is the
+	creator treasury rate of new nfts and the treasury rate of offers.
+*/
+type Case struct {
+	TxType      int   `json:"tx_type"`
+	Fault       Fault `json:"fault,omitempty"`
+	Account     int64 `json:"account"`
+	To          int64 `json:"to"`
+	AssetId     int64 `json:"asset_id"`
+	Amount      int64 `json:"amount"`
+	GasAssetId  int64 `json:"gas_asset_id"`
+	GasFee      int64 `json:"gas_fee"`
+	NftIndex    int64 `json:"nft_index"`
+	Rate        int64 `json:"rate"`
+	OfferId     int64 `json:"offer_id"`
+	SellOfferId int64 `json:"sell_offer_id"`
+}
+
+func (c Case) String() string {
+	return testutil.Segment(c)
+}
+
+func pow10(n int) int64 {
+	res := int64(1)
+	for i := 0; i < n; i++ {
+		res *= 10
+	}
+	return res
+}
+
+func randAccount(r *rand.Rand) int64 {
+	return Alice + r.Int63n(3)
+}
+
+func randOfferId(r *rand.Rand, accountIndex int64) int64 {
+	offerId := r.Int63n(256)
+	if accountIndex == Carol && offerId == SetupCanceledOffer {
+		offerId++
+	}
+	return offerId
+}
+
+/*
+	Generate: a random valid case of txType, or one breaking a random rule of txType if invalid is set
+*/
+func Generate(r *rand.Rand, txType int, invalid bool) Case {
+	c := Case{
+		TxType:     txType,
+		Account:    randAccount(r),
+		To:         randAccount(r),
+		AssetId:    r.Int63n(int64(len(GasAssetIds))),
+		Amount:     (1 + r.Int63n(999)) * pow10(r.Intn(6)),
+		GasAssetId: GasAssetIds[r.Intn(len(GasAssetIds))],
+		GasFee:     r.Int63n(2048) * pow10(r.Intn(3)),
+		NftIndex:   1 + r.Int63n(64),
+		Rate:       r.Int63n(types.RateBase + 1),
+	}
+	switch txType {
+	case txtypes.TxTypeRegisterZns:
+		c.Account = Carol + 1 + r.Int63n(64)
+	case txtypes.TxTypeMintNft:
+		// carol owns the only collection
+		c.Account = Carol
+	case txtypes.TxTypeTransferNft, txtypes.TxTypeWithdrawNft:
+		c.Account, c.NftIndex = Bob, SetupNftIndex
+	case txtypes.TxTypeFullExitNft:
+		// the nft of bob, or an empty one
+		if r.Intn(2) == 0 {
+			c.Account, c.NftIndex = Bob, SetupNftIndex
+		}
+	case txtypes.TxTypeAtomicMatch:
+		// bob sells nft 0, whose creator rate is 100, to alice or carol
+		c.To = []int64{Alice, Carol}[r.Intn(2)]
+		c.NftIndex = SetupNftIndex
+		c.Amount = (1 + r.Int63n(1000)) * types.RateBase
+		c.Rate = r.Int63n(types.RateBase - 100 + 1)
+		c.OfferId = randOfferId(r, c.To)
+		c.SellOfferId = randOfferId(r, Bob)
+	case txtypes.TxTypeCancelOffer:
+		c.OfferId = randOfferId(r, c.Account)
+	}
+	if invalid {
+		faults := Faults(txType)
+		c.Fault = faults[r.Intn(len(faults))]
+	}
+	// the amounts above always pack, these are raw
+	switch c.Fault {
+	case FaultPackedAmount:
+		c.Amount = unpackedAmount(r.Int63n(InitialBalance / 2))
+	case FaultPackedFee:
+		c.GasFee = unpackedFee(r.Int63n(1000000))
+	}
+	return c
+}
+
+/*
+	unpackedAmount: an amount close to amount that pubdata.PackAmount refuses,
+	it is above the largest mantissa and not a multiple of 10
+*/
+func unpackedAmount(amount int64) int64 {
+	if maxMantissa := util.PackedAmountMaxMantissa.Int64(); amount <= maxMantissa {
+		amount += maxMantissa + 1
+	}
+	if amount%10 == 0 {
+		amount++
+	}
+	return amount
+}
+
+/*
+	unpackedFee: a fee close to fee that pubdata.PackFee refuses
+*/
+func unpackedFee(fee int64) int64 {
+	if maxMantissa := util.PackedFeeMaxMantissa.Int64(); fee <= maxMantissa {
+		fee += maxMantissa + 1
+	}
+	if fee%10 == 0 {
+		fee++
+	}
+	return fee
+}
+
+// another existing account, for wrong keys and name hashes
+func other(accountIndex int64) int64 {
+	return Alice + (accountIndex-Alice+1)%3
+}
+
+/*
+	TxInfo: the tx of the case against s, which is not modified. The txtypes constructors
+	round amounts and fees down to what packs, the tx carries the raw ones of the case instead.
+	Its signature stays valid, the signed hash is over the rounded ones.
+*/
+func (c Case) TxInfo(s *state.State) (txInfo txtypes.TxInfo, err error) {
+	txInfo, err = c.txInfo(s)
+	if err != nil {
+		errInfo := fmt.Sprintf("[TxInfo] unable to construct %s: %s", c.String(), err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	gasFee := c.GasFee
+	if c.Fault == FaultPackedFee {
+		gasFee = unpackedFee(gasFee)
+	}
+	setGasFee(txInfo, big.NewInt(gasFee))
+	return txInfo, nil
+}
+
+func setGasFee(txInfo txtypes.TxInfo, gasFee *big.Int) {
+	switch txInfo := txInfo.(type) {
+	case *txtypes.TransferTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.WithdrawTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.CreateCollectionTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.MintNftTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.TransferNftTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.AtomicMatchTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.CancelOfferTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	case *txtypes.WithdrawNftTxInfo:
+		txInfo.GasFeeAssetAmount = gasFee
+	}
+}
+
+func (c Case) txInfo(s *state.State) (txtypes.TxInfo, error) {
+	from, to := c.Account, c.To
+	nftIndex, amount := c.NftIndex, c.Amount
+	toNameHash := testutil.NameHash(to)
+	gasAccount, gasAssetId, expiredAt := int64(GasAccount), c.GasAssetId, ExpiredAt
+	switch c.Fault {
+	case FaultExpired:
+		expiredAt = BlockCreatedAt - 1
+	case FaultGasAccount:
+		gasAccount = from
+	case FaultGasAsset:
+		gasAssetId = nonGasAssetId
+	case FaultBalance:
+		amount = 2 * InitialBalance
+	case FaultPackedAmount:
+		amount = unpackedAmount(amount)
+	case FaultNameHash:
+		toNameHash = testutil.NameHash(other(to))
+	case FaultRegistered:
+		from = Alice
+	case FaultNftExists:
+		nftIndex = SetupNftIndex
+	case FaultNftOwner:
+		from = Alice
+	}
+	// layer 2 txs are signed by the sender
+	signer := from
+	if c.Fault == FaultSignature {
+		signer = other(from)
+	}
+	sk, err := testutil.PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	nonce := s.GetAccount(from).Nonce
+	if c.Fault == FaultNonce {
+		nonce++
+	}
+	gasFee := big.NewInt(c.GasFee).String()
+
+	switch c.TxType {
+	case txtypes.TxTypeRegisterZns:
+		// FaultRegistered has set from to a registered account
+		return testutil.RegisterTx(from)
+	case txtypes.TxTypeDeposit:
+		txInfo := testutil.DepositTx(from, c.AssetId, amount)
+		if c.Fault == FaultNameHash {
+			txInfo.AccountNameHash = testutil.NameHash(other(from))
+		}
+		return txInfo, nil
+	case txtypes.TxTypeDepositNft:
+		accountNameHash := testutil.NameHash(from)
+		if c.Fault == FaultNameHash {
+			accountNameHash = testutil.NameHash(other(from))
+		}
+		return &txtypes.DepositNftTxInfo{
+			TxType:              txtypes.TxTypeDepositNft,
+			AccountNameHash:     accountNameHash,
+			CreatorAccountIndex: to,
+			CreatorTreasuryRate: c.Rate,
+			NftL1Address:        toAddress,
+			NftL1TokenId:        big.NewInt(nftIndex),
+			NftContentHash:      testutil.NameHash(2000 + nftIndex),
+			NftIndex:            nftIndex,
+			AccountIndex:        from,
+		}, nil
+	case txtypes.TxTypeTransfer:
+		txInfo, err := txtypes.ConstructTransferTxInfo(sk, testutil.Segment(&txtypes.TransferSegmentFormat{
+			FromAccountIndex:  from,
+			ToAccountIndex:    to,
+			ToAccountNameHash: common.Bytes2Hex(toNameHash),
+			AssetId:           c.AssetId,
+			AssetAmount:       big.NewInt(amount).String(),
+			GasAccountIndex:   gasAccount,
+			GasFeeAssetId:     gasAssetId,
+			GasFeeAssetAmount: gasFee,
+			ExpiredAt:         expiredAt,
+			Nonce:             nonce,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		txInfo.AssetAmount = big.NewInt(amount)
+		return txInfo, nil
+	case txtypes.TxTypeWithdraw:
+		return txtypes.ConstructWithdrawTxInfo(sk, testutil.Segment(&txtypes.WithdrawSegmentFormat{
+			FromAccountIndex:  from,
+			AssetId:           c.AssetId,
+			AssetAmount:       big.NewInt(amount).String(),
+			GasAccountIndex:   gasAccount,
+			GasFeeAssetId:     gasAssetId,
+			GasFeeAssetAmount: gasFee,
+			ToAddress:         toAddress,
+			ExpiredAt:         expiredAt,
+			Nonce:             nonce,
+		}))
+	case txtypes.TxTypeCreateCollection:
+		txInfo, err := txtypes.ConstructCreateCollectionTxInfo(sk, testutil.Segment(&txtypes.CreateCollectionSegmentFormat{
+			AccountIndex:      from,
+			Name:              "collection",
+			Introduction:      "collection",
+			GasAccountIndex:   gasAccount,
+			GasFeeAssetId:     gasAssetId,
+			GasFeeAssetAmount: gasFee,
+			ExpiredAt:         expiredAt,
+			Nonce:             nonce,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		txInfo.CollectionId = s.GetAccount(from).CollectionNonce
+		if c.Fault == FaultCollection {
+			txInfo.CollectionId++
+		}
+		return txInfo, nil
+	case txtypes.TxTypeMintNft:
+		collectionId := int64(0)
+		if c.Fault == FaultCollection {
+			collectionId = s.GetAccount(from).CollectionNonce
+		}
+		// the modulus is zero in the field of the circuit
+		nftContentHash := testutil.NameHash(2000 + nftIndex)
+		if c.Fault == FaultNftContentHash {
+			nftContentHash = fr.Modulus().FillBytes(make([]byte, 32))
+		}
+		txInfo, err := txtypes.ConstructMintNftTxInfo(sk, testutil.Segment(&txtypes.MintNftSegmentFormat{
+			CreatorAccountIndex: from,
+			ToAccountIndex:      to,
+			ToAccountNameHash:   common.Bytes2Hex(toNameHash),
+			NftContentHash:      common.Bytes2Hex(nftContentHash),
+			NftCollectionId:     collectionId,
+			CreatorTreasuryRate: c.Rate,
+			GasAccountIndex:     gasAccount,
+			GasFeeAssetId:       gasAssetId,
+			GasFeeAssetAmount:   gasFee,
+			ExpiredAt:           expiredAt,
+			Nonce:               nonce,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		txInfo.NftIndex = nftIndex
+		return txInfo, nil
+	case txtypes.TxTypeTransferNft:
+		return txtypes.ConstructTransferNftTxInfo(sk, testutil.Segment(&txtypes.TransferNftSegmentFormat{
+			FromAccountIndex:  from,
+			ToAccountIndex:    to,
+			ToAccountNameHash: common.Bytes2Hex(toNameHash),
+			NftIndex:          nftIndex,
+			GasAccountIndex:   gasAccount,
+			GasFeeAssetId:     gasAssetId,
+			GasFeeAssetAmount: gasFee,
+			ExpiredAt:         expiredAt,
+			Nonce:             nonce,
+		}))
+	case txtypes.TxTypeAtomicMatch:
+		return c.atomicMatchTx(s, sk, nonce, gasAccount, gasAssetId, expiredAt)
+	case txtypes.TxTypeCancelOffer:
+		offerId := c.OfferId
+		if c.Fault == FaultOfferFinalized {
+			from, offerId = Carol, SetupCanceledOffer
+			if sk, err = testutil.PrivateKey(Carol); err != nil {
+				return nil, err
+			}
+			nonce = s.GetAccount(Carol).Nonce
+		}
+		return txtypes.ConstructCancelOfferTxInfo(sk, testutil.Segment(&txtypes.CancelOfferSegmentFormat{
+			AccountIndex:      from,
+			OfferId:           offerId,
+			GasAccountIndex:   gasAccount,
+			GasFeeAssetId:     gasAssetId,
+			GasFeeAssetAmount: gasFee,
+			ExpiredAt:         expiredAt,
+			Nonce:             nonce,
+		}))
+	case txtypes.TxTypeWithdrawNft:
+		return txtypes.ConstructWithdrawNftTxInfo(sk, testutil.Segment(&txtypes.WithdrawNftSegmentFormat{
+			AccountIndex:      from,
+			NftIndex:          nftIndex,
+			ToAddress:         toAddress,
+			GasAccountIndex:   gasAccount,
+			GasFeeAssetId:     gasAssetId,
+			GasFeeAssetAmount: gasFee,
+			ExpiredAt:         expiredAt,
+			Nonce:             nonce,
+		}))
+	case txtypes.TxTypeFullExit:
+		accountNameHash := testutil.NameHash(from)
+		if c.Fault == FaultNameHash {
+			accountNameHash = testutil.NameHash(other(from))
+		}
+		assetAmount := s.GetAsset(from, c.AssetId).Balance
+		if c.Fault == FaultFullExitAmount {
+			assetAmount = new(big.Int).Add(assetAmount, big.NewInt(1))
+		}
+		return &txtypes.FullExitTxInfo{
+			TxType:          txtypes.TxTypeFullExit,
+			AccountNameHash: accountNameHash,
+			AssetId:         c.AssetId,
+			AccountIndex:    from,
+			AssetAmount:     assetAmount,
+		}, nil
+	case txtypes.TxTypeFullExitNft:
+		accountNameHash := testutil.NameHash(from)
+		if c.Fault == FaultNameHash {
+			accountNameHash = testutil.NameHash(other(from))
+		}
+		nft := s.GetNft(nftIndex)
+		return &txtypes.FullExitNftTxInfo{
+			TxType:                 txtypes.TxTypeFullExitNft,
+			NftIndex:               nftIndex,
+			AccountNameHash:        accountNameHash,
+			AccountIndex:           from,
+			CreatorAccountIndex:    nft.CreatorAccountIndex,
+			CreatorTreasuryRate:    nft.CreatorTreasuryRate,
+			CreatorAccountNameHash: s.GetAccount(nft.CreatorAccountIndex).AccountNameHash,
+			NftContentHash:         nft.NftContentHash,
+			CollectionId:           nft.CollectionId,
+		}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unsupported tx type %d", c.TxType))
+}
+
+/*
+	atomicMatchTx: To buys the nft from its owner, faults of the offers are put on the sell offer
+*/
+func (c Case) atomicMatchTx(s *state.State, sk *txtypes.PrivateKey, nonce, gasAccount, gasAssetId, expiredAt int64) (txtypes.TxInfo, error) {
+	nft := s.GetNft(c.NftIndex)
+	seller, buyer := nft.OwnerAccountIndex, c.To
+	buyOfferId, amount, rate := c.OfferId, c.Amount, c.Rate
+	switch c.Fault {
+	case FaultNftOwner:
+		seller = other(seller)
+		if seller == buyer {
+			seller = other(seller)
+		}
+	case FaultBalance:
+		amount = 2 * InitialBalance
+	case FaultOfferFinalized:
+		buyer, buyOfferId = Carol, SetupCanceledOffer
+	case FaultTreasuryRate:
+		rate = types.RateBase - nft.CreatorTreasuryRate + 1
+	case FaultRoyaltyAmount:
+		// the creator share is no longer exact
+		amount++
+	case FaultPackedAmount:
+		amount = unpackedAmount(amount)
+	}
+	sellAmount := amount
+	if c.Fault == FaultOfferMismatch {
+		sellAmount += types.RateBase
+	}
+	offer := func(accountIndex int64, offerType int64, offerId int64, amount int64, signer int64, offerExpiredAt int64) (*txtypes.OfferTxInfo, error) {
+		sk, err := testutil.PrivateKey(signer)
+		if err != nil {
+			return nil, err
+		}
+		offer, err := txtypes.ConstructOfferTxInfo(sk, testutil.Segment(&txtypes.OfferSegmentFormat{
+			Type:         offerType,
+			OfferId:      offerId,
+			AccountIndex: accountIndex,
+			NftIndex:     c.NftIndex,
+			AssetId:      c.AssetId,
+			AssetAmount:  big.NewInt(amount).String(),
+			ListedAt:     BlockCreatedAt,
+			ExpiredAt:    offerExpiredAt,
+			TreasuryRate: rate,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		offer.AssetAmount = big.NewInt(amount)
+		return offer, nil
+	}
+	// the offers of the submitter are covered by the signature of the tx, their own is not checked
+	buySigner, sellSigner, sellExpiredAt := buyer, seller, ExpiredAt
+	if c.Fault == FaultOfferSignature {
+		if seller == c.Account {
+			buySigner = other(buyer)
+		} else {
+			sellSigner = other(seller)
+		}
+	}
+	buyOffer, err := offer(buyer, txtypes.BuyOfferType, buyOfferId, amount, buySigner, ExpiredAt)
+	if err != nil {
+		return nil, err
+	}
+	if c.Fault == FaultOfferExpired {
+		sellExpiredAt = BlockCreatedAt - 1
+	}
+	sellOffer, err := offer(seller, txtypes.SellOfferType, c.SellOfferId, sellAmount, sellSigner, sellExpiredAt)
+	if err != nil {
+		return nil, err
+	}
+	return txtypes.ConstructAtomicMatchTxInfo(sk, testutil.Segment(&txtypes.AtomicMatchSegmentFormat{
+		AccountIndex:      c.Account,
+		BuyOffer:          testutil.Segment(buyOffer),
+		SellOffer:         testutil.Segment(sellOffer),
+		GasAccountIndex:   gasAccount,
+		GasFeeAssetId:     gasAssetId,
+		GasFeeAssetAmount: big.NewInt(c.GasFee).String(),
+		Nonce:             nonce,
+		ExpiredAt:         expiredAt,
+	}))
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package difftest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+/*
+	Verdict: what a circuit makes of a case
+*/
+type Verdict int
+
+const (
+	Skipped Verdict = iota
+	// the witness builder can not encode the tx, the circuit never sees it
+	NoWitness
+	Accepted
+	Rejected
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case NoWitness:
+		return "no witness"
+	case Accepted:
+		return "accepted"
+	case Rejected:
+		return "rejected"
+	default:
+		return "skipped"
+	}
+}
+
+/*
+	Result: the verdicts of the executor, of TxConstraints and of a BlockConstraints
+	holding the tx alone. The witnesses are built without the rules of the executor,
+	so that both circuits check every tx.
+*/
+type Result struct {
+	Native   error
+	Tx       Verdict
+	TxErr    error
+	Block    Verdict
+	BlockErr error
+}
+
+/*
+	isBlockRule: rules TxConstraints can not check on its own, it has neither the
+	creation time nor the gas account and assets of the block
+*/
+func isBlockRule(err error) bool {
+	return errors.Is(err, executor.ErrTxExpired) ||
+		errors.Is(err, executor.ErrOfferExpired) ||
+		errors.Is(err, executor.ErrInvalidGasAccount) ||
+		errors.Is(err, executor.ErrInvalidGasAsset)
+}
+
+/*
+	Mismatch: why the executor and the circuits disagree, empty if they agree.
+	A tx accepted by the executor must be proven by both circuits, a tx it rejects
+	must be rejected by the block circuit, and by the tx circuit unless it breaks
+	a rule of the block. A tx without a witness was not checked by the circuit.
+*/
+func (r *Result) Mismatch() string {
+	if r.Tx == NoWitness {
+		return fmt.Sprintf("tx circuit: no witness (%v)", r.TxErr)
+	}
+	if r.Block == NoWitness {
+		return fmt.Sprintf("block circuit: no witness (%v)", r.BlockErr)
+	}
+	if r.Native == nil {
+		if r.Tx != Accepted {
+			return fmt.Sprintf("executor accepts, tx circuit: %s (%v)", r.Tx, r.TxErr)
+		}
+		if r.Block != Accepted {
+			return fmt.Sprintf("executor accepts, block circuit: %s (%v)", r.Block, r.BlockErr)
+		}
+		return ""
+	}
+	if r.Tx == Accepted && !isBlockRule(r.Native) {
+		return fmt.Sprintf("executor rejects (%s), tx circuit accepts", r.Native.Error())
+	}
+	if r.Block != Rejected {
+		return fmt.Sprintf("executor rejects (%s), block circuit accepts", r.Native.Error())
+	}
+	return ""
+}
+
+func (r *Result) String() string {
+	native := "accepted"
+	if r.Native != nil {
+		native = r.Native.Error()
+	}
+	return fmt.Sprintf("executor: %s, tx circuit: %s, block circuit: %s", native, r.Tx, r.Block)
+}
+
+/*
+	Check: run c against a copy of world through the executor and the circuits
+*/
+func Check(world *state.State, c Case) (*Result, error) {
+	s, err := world.Copy()
+	if err != nil {
+		return nil, err
+	}
+	txInfo, err := c.TxInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	blockState, err := s.Copy()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	res.Native = executor.NewExecutor(s, BlockCreatedAt, GasAccount, GasAssetIds).CheckTx(txInfo)
+
+	// built unchecked, the circuits must reject what the executor rejects on their own
+	if oTx, err := witness.NewBuilder(s).ConstructUncheckedTx(txInfo); err != nil {
+		res.Tx, res.TxErr = NoWitness, err
+	} else {
+		res.Tx, res.TxErr = solveTx(oTx)
+	}
+	res.Block, res.BlockErr = solveBlock(blockState, txInfo)
+	return res, nil
+}
+
+func solveTx(oTx *circuit.Tx) (Verdict, error) {
+	txWitness, err := circuit.SetTxWitness(oTx)
+	if err != nil {
+		return NoWitness, err
+	}
+	if err = test.IsSolved(&circuit.TxConstraints{}, &txWitness, ecc.BN254.ScalarField()); err != nil {
+		return Rejected, err
+	}
+	return Accepted, nil
+}
+
+func solveBlock(s *state.State, txInfo txtypes.TxInfo) (Verdict, error) {
+	blockBuilder, err := witness.NewBlockBuilder(witness.NewBuilder(s), 1, GasAccount, GasAssetIds)
+	if err != nil {
+		return NoWitness, err
+	}
+	oBlock, err := blockBuilder.BuildUncheckedBlock(1, BlockCreatedAt, []txtypes.TxInfo{txInfo})
+	if err != nil {
+		return NoWitness, err
+	}
+	blockWitness, err := circuit.SetBlockWitness(oBlock)
+	if err != nil {
+		return NoWitness, err
+	}
+	blockConstraints := &circuit.BlockConstraints{
+		TxsCount:        1,
+		Txs:             []circuit.TxConstraints{circuit.GetZeroTxConstraint()},
+		GasAssetIds:     GasAssetIds,
+		GasAccountIndex: GasAccount,
+		Gas:             circuit.GetZeroGasConstraints(GasAssetIds),
+	}
+	if err = test.IsSolved(blockConstraints, &blockWitness, ecc.BN254.ScalarField()); err != nil {
+		return Rejected, err
+	}
+	return Accepted, nil
+}
+
+// CheckFunc checks a case against world, it is Check outside of tests
+type CheckFunc func(world *state.State, c Case) (*Result, error)
+
+/*
+	Minimize: shrink a mismatching case field by field towards the simplest values, as long
+	as check still finds a mismatch. Cases that agree are returned as is.
+*/
+func Minimize(world *state.State, c Case, check CheckFunc) (Case, error) {
+	res, err := check(world, c)
+	if err != nil {
+		return c, err
+	}
+	if res.Mismatch() == "" {
+		return c, nil
+	}
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for _, candidate := range shrink(c) {
+			res, err = check(world, candidate)
+			if err != nil || res.Mismatch() == "" {
+				continue
+			}
+			log.Printf("[Minimize] %s still mismatches: %s", candidate.String(), res.Mismatch())
+			c, shrunk = candidate, true
+			break
+		}
+	}
+	return c, nil
+}
+
+/*
+	shrink: the cases one step simpler than c
+*/
+func shrink(c Case) []Case {
+	var candidates []Case
+	try := func(f func(c *Case)) {
+		candidate := c
+		f(&candidate)
+		if candidate != c {
+			candidates = append(candidates, candidate)
+		}
+	}
+	try(func(c *Case) { c.Fault = NoFault })
+	try(func(c *Case) { c.GasFee = 0 })
+	try(func(c *Case) { c.Rate = 0 })
+	try(func(c *Case) { c.AssetId = 0 })
+	try(func(c *Case) { c.GasAssetId = GasAssetIds[0] })
+	try(func(c *Case) { c.OfferId = 0 })
+	try(func(c *Case) { c.SellOfferId = 0 })
+	if c.TxType == txtypes.TxTypeAtomicMatch {
+		// the shares have to stay exact
+		try(func(c *Case) { c.Amount = types.RateBase })
+	} else if c.Amount > 1 {
+		try(func(c *Case) { c.Amount = 1 })
+		try(func(c *Case) { c.Amount /= 10 })
+	}
+	try(func(c *Case) { c.Account = Alice })
+	try(func(c *Case) { c.To = Alice })
+	try(func(c *Case) { c.NftIndex = 1 })
+	return candidates
+}
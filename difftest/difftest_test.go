@@ -0,0 +1,209 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package difftest
+
+import (
+	"errors"
+	"flag"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/pubdata"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+var (
+	seed       = flag.Int64("difftest.seed", 1, "seed of the generated cases, 0 for a random one")
+	rounds     = flag.Int("difftest.rounds", 1, "valid and invalid cases generated per tx type")
+	vectorsDir = flag.String("difftest.vectors", "testdata/vectors", "directory of the regression vectors")
+	allowGaps  = flag.Bool("difftest.allowgaps", false, "log the mismatches instead of failing, every one is still minimized and saved")
+)
+
+func TestDifferential(t *testing.T) {
+	world, err := NewWorld()
+	require.NoError(t, err)
+	s := *seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	t.Logf("seed: %d", s)
+	r := rand.New(rand.NewSource(s))
+	// the mismatches are only tolerated when asked for, but never silently
+	tolerated := 0
+	defer func() {
+		if *allowGaps {
+			t.Logf("tolerated mismatches: %d", tolerated)
+		}
+	}()
+	for i := 0; i < *rounds; i++ {
+		for _, txType := range TxTypes {
+			for _, invalid := range []bool{false, true} {
+				c := Generate(r, txType, invalid)
+				res, err := Check(world, c)
+				require.NoError(t, err, c.String())
+				t.Logf("%s: %s", c.String(), res.String())
+				if res.Mismatch() == "" {
+					continue
+				}
+				c, err = Minimize(world, c, Check)
+				require.NoError(t, err)
+				res, err = Check(world, c)
+				require.NoError(t, err)
+				path, err := SaveVector(*vectorsDir, Vector{Case: c, Mismatch: res.Mismatch()})
+				require.NoError(t, err)
+				if *allowGaps {
+					tolerated++
+					t.Logf("%s: tolerated: %s, saved to %s", c.String(), res.Mismatch(), path)
+					continue
+				}
+				t.Errorf("%s: %s, saved to %s", c.String(), res.Mismatch(), path)
+			}
+		}
+	}
+}
+
+func TestEveryFault(t *testing.T) {
+	world, err := NewWorld()
+	require.NoError(t, err)
+	r := rand.New(rand.NewSource(2))
+	for _, txType := range TxTypes {
+		c := Generate(r, txType, false)
+		res, err := Check(world, c)
+		require.NoError(t, err)
+		assert.NoError(t, res.Native, c.String())
+		assert.Empty(t, res.Mismatch(), c.String())
+		for _, fault := range Faults(txType) {
+			c.Fault = fault
+			res, err = Check(world, c)
+			require.NoError(t, err)
+			assert.Error(t, res.Native, c.String())
+			// both circuits see every fault
+			assert.NotEqual(t, NoWitness, res.Tx, c.String())
+			assert.NotEqual(t, NoWitness, res.Block, c.String())
+			assert.Equal(t, Rejected, res.Block, c.String())
+			assert.Empty(t, res.Mismatch(), c.String())
+		}
+	}
+}
+
+func TestFaultErrors(t *testing.T) {
+	world, err := NewWorld()
+	require.NoError(t, err)
+	r := rand.New(rand.NewSource(3))
+	cases := []struct {
+		txType int
+		fault  Fault
+		err    error
+	}{
+		{txtypes.TxTypeTransfer, FaultPackedAmount, executor.ErrInvalidTx},
+		{txtypes.TxTypeTransfer, FaultPackedFee, executor.ErrInvalidTx},
+		{txtypes.TxTypeAtomicMatch, FaultPackedAmount, executor.ErrInvalidTx},
+		{txtypes.TxTypeAtomicMatch, FaultOfferMismatch, executor.ErrOfferMismatch},
+		{txtypes.TxTypeAtomicMatch, FaultTreasuryRate, executor.ErrInvalidTreasuryRate},
+		{txtypes.TxTypeAtomicMatch, FaultRoyaltyAmount, executor.ErrInvalidRoyaltyAmount},
+		{txtypes.TxTypeFullExit, FaultFullExitAmount, executor.ErrInvalidFullExitAmount},
+		{txtypes.TxTypeMintNft, FaultNftContentHash, executor.ErrInvalidNftContentHash},
+	}
+	for _, fc := range cases {
+		assert.Contains(t, Faults(fc.txType), fc.fault)
+		c := Generate(r, fc.txType, false)
+		c.Fault = fc.fault
+		res, err := Check(world, c)
+		require.NoError(t, err)
+		assert.True(t, errors.Is(res.Native, fc.err), "%s: %v", c.String(), res.Native)
+		assert.Empty(t, res.Mismatch(), c.String())
+	}
+	// the generated amounts and fees are raw ones that do not pack
+	seen := make(map[Fault]bool)
+	for i := 0; i < 200; i++ {
+		c := Generate(r, txtypes.TxTypeTransfer, true)
+		switch c.Fault {
+		case FaultPackedAmount:
+			_, err = pubdata.PackAmount(big.NewInt(c.Amount))
+		case FaultPackedFee:
+			_, err = pubdata.PackFee(big.NewInt(c.GasFee))
+		default:
+			continue
+		}
+		assert.Error(t, err, c.String())
+		seen[c.Fault] = true
+	}
+	assert.Len(t, seen, 2)
+}
+
+func TestRegressionVectors(t *testing.T) {
+	world, err := NewWorld()
+	require.NoError(t, err)
+	vectors, err := LoadVectors(*vectorsDir)
+	require.NoError(t, err)
+	for name, v := range vectors {
+		res, err := Check(world, v.Case)
+		require.NoError(t, err, name)
+		assert.Empty(t, res.Mismatch(), "%s, was: %s", name, v.Mismatch)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	world, err := NewWorld()
+	require.NoError(t, err)
+	// carol is registered already, which all of them reject
+	c := Case{TxType: txtypes.TxTypeRegisterZns, Account: Carol, To: Alice, AssetId: 1, Amount: 12300, GasAssetId: 1}
+	minimized, err := Minimize(world, c, Check)
+	require.NoError(t, err)
+	// cases that agree are not touched
+	assert.Equal(t, c, minimized)
+
+	// a circuit that wrongly rejects transfers of 1000 or more
+	forced := func(world *state.State, c Case) (*Result, error) {
+		if c.TxType == txtypes.TxTypeTransfer && c.Amount >= 1000 {
+			return &Result{Tx: Rejected}, nil
+		}
+		return &Result{Tx: Accepted, Block: Accepted}, nil
+	}
+	mismatch := Case{TxType: txtypes.TxTypeTransfer, Fault: FaultNonce, Account: Bob, To: Carol, AssetId: 1, Amount: 123000,
+		GasAssetId: 1, GasFee: 30, NftIndex: 7, Rate: 5, OfferId: 3, SellOfferId: 4}
+	minimized, err = Minimize(world, mismatch, forced)
+	require.NoError(t, err)
+	assert.Equal(t, Case{TxType: txtypes.TxTypeTransfer, Account: Alice, To: Alice, Amount: 1230, GasAssetId: GasAssetIds[0], NftIndex: 1},
+		minimized)
+	assert.NotEmpty(t, shrink(c))
+	for _, candidate := range shrink(c) {
+		assert.NotEqual(t, c, candidate)
+	}
+
+	dir := t.TempDir()
+	path, err := SaveVector(dir, Vector{Case: c, Mismatch: "mismatch"})
+	require.NoError(t, err)
+	vectors, err := LoadVectors(dir)
+	require.NoError(t, err)
+	assert.Len(t, vectors, 1)
+	for name, v := range vectors {
+		assert.Contains(t, path, name)
+		assert.Equal(t, Vector{Case: c, Mismatch: "mismatch"}, v)
+	}
+	vectors, err = LoadVectors(dir + "/missing")
+	assert.NoError(t, err)
+	assert.Empty(t, vectors)
+}
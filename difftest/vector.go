@@ -0,0 +1,91 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package difftest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+	Vector: a minimized case the executor and the circuits disagreed on, kept as a
+	regression test once the disagreement is fixed
+*/
+type Vector struct {
+	Case     Case   `json:"case"`
+	Mismatch string `json:"mismatch"`
+}
+
+/*
+	SaveVector: write v to dir, the file is named after the tx type, the fault and a hash of the case
+*/
+func SaveVector(dir string, v Vector) (path string, err error) {
+	caseBytes, err := json.Marshal(v.Case)
+	if err != nil {
+		return "", err
+	}
+	fault := string(v.Case.Fault)
+	if fault == "" {
+		fault = "valid"
+	}
+	hash := sha256.Sum256(caseBytes)
+	path = filepath.Join(dir, fmt.Sprintf("%02d-%s-%x.json", v.Case.TxType, fault, hash[:4]))
+	vectorBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, append(vectorBytes, '\n'), 0644)
+}
+
+/*
+	LoadVectors: the vectors saved in dir, by file name. A missing dir holds no vectors.
+*/
+func LoadVectors(dir string) (map[string]Vector, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	vectors := make(map[string]Vector, len(files))
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		vectorBytes, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var v Vector
+		if err = json.Unmarshal(vectorBytes, &v); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		vectors[name] = v
+	}
+	return vectors, nil
+}
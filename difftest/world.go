@@ -0,0 +1,145 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package difftest
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+// accounts of the world every case runs against
+const (
+	GasAccount = testutil.GasAccount
+	Alice      = testutil.Alice
+	Bob        = testutil.Bob
+	Carol      = testutil.Carol
+)
+
+const (
+	// BlockCreatedAt is the creation time of the blocks the cases are checked in
+	BlockCreatedAt = int64(1660000000000)
+	// ExpiredAt is the expiry of valid txs and offers
+	ExpiredAt = BlockCreatedAt + 3600000
+	// InitialBalance of alice, bob and carol in assets 0 and 1, it covers amounts that do not pack
+	InitialBalance = int64(100000000000)
+
+	// carol owns collection 0, has minted nft 0 to bob and canceled her offer 5
+	SetupNftIndex      = int64(0)
+	SetupCanceledOffer = int64(5)
+	// alice also holds asset 2, which is not a gas asset
+	nonGasAssetId = int64(2)
+)
+
+var (
+	GasAssetIds = []int64{0, 1}
+	toAddress   = "0x5b38da6a701c568545dcfcb03fcb875f56beddc4"
+)
+
+func setupTxs() ([]txtypes.TxInfo, error) {
+	var txInfos []txtypes.TxInfo
+	for i := int64(GasAccount); i <= Carol; i++ {
+		txInfo, err := testutil.RegisterTx(i)
+		if err != nil {
+			return nil, err
+		}
+		txInfos = append(txInfos, txInfo)
+	}
+	for i := int64(Alice); i <= Carol; i++ {
+		for _, assetId := range GasAssetIds {
+			txInfos = append(txInfos, testutil.DepositTx(i, assetId, InitialBalance))
+		}
+	}
+	txInfos = append(txInfos, testutil.DepositTx(Alice, nonGasAssetId, InitialBalance))
+
+	sk, err := testutil.PrivateKey(Carol)
+	if err != nil {
+		return nil, err
+	}
+	collectionInfo, err := txtypes.ConstructCreateCollectionTxInfo(sk, testutil.Segment(&txtypes.CreateCollectionSegmentFormat{
+		AccountIndex:      Carol,
+		Name:              "collection",
+		Introduction:      "collection",
+		GasAccountIndex:   GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         ExpiredAt,
+		Nonce:             0,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	mintInfo, err := txtypes.ConstructMintNftTxInfo(sk, testutil.Segment(&txtypes.MintNftSegmentFormat{
+		CreatorAccountIndex: Carol,
+		ToAccountIndex:      Bob,
+		ToAccountNameHash:   common.Bytes2Hex(testutil.NameHash(Bob)),
+		NftContentHash:      common.Bytes2Hex(testutil.NameHash(1000)),
+		NftCollectionId:     0,
+		CreatorTreasuryRate: 100,
+		GasAccountIndex:     GasAccount,
+		GasFeeAssetId:       0,
+		GasFeeAssetAmount:   "10",
+		ExpiredAt:           ExpiredAt,
+		Nonce:               1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	mintInfo.NftIndex = SetupNftIndex
+	cancelInfo, err := txtypes.ConstructCancelOfferTxInfo(sk, testutil.Segment(&txtypes.CancelOfferSegmentFormat{
+		AccountIndex:      Carol,
+		OfferId:           SetupCanceledOffer,
+		GasAccountIndex:   GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         ExpiredAt,
+		Nonce:             2,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return append(txInfos, collectionInfo, mintInfo, cancelInfo), nil
+}
+
+/*
+	NewWorld: the state every case starts from. The gas of the setup txs is dropped,
+	the cases are checked in blocks of their own.
+*/
+func NewWorld() (*state.State, error) {
+	s, err := state.NewState()
+	if err != nil {
+		return nil, err
+	}
+	txInfos, err := setupTxs()
+	if err != nil {
+		return nil, err
+	}
+	builder := witness.NewBuilder(s)
+	rules := executor.NewExecutor(s, BlockCreatedAt, GasAccount, GasAssetIds)
+	for _, txInfo := range txInfos {
+		if _, err = builder.ConstructTx(rules, txInfo); err != nil {
+			return nil, err
+		}
+	}
+	builder.ResetPendingGas()
+	return s, nil
+}
@@ -22,7 +22,7 @@ import (
 	"math/big"
 	"testing"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
 )
 
 func TestGenerateEddsaPrivateKey(t *testing.T) {
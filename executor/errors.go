@@ -0,0 +1,81 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// every error of the executor wraps one of these, each mirrors an assertion of the circuit
+var (
+	// range checks of txtypes Validate, and amounts that can not be packed into the pubdata
+	ErrInvalidTx         = errors.New("invalid tx")
+	ErrUnsupportedTxType = errors.New("unsupported tx type")
+
+	// VerifyTransaction: blockCreatedAt <= ExpiredAt, nonce and signature of layer 2 txs
+	ErrTxExpired        = errors.New("tx expired")
+	ErrInvalidNonce     = errors.New("invalid nonce")
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// VerifyBlock: gas is collected by the gas account of the block, in its gas assets
+	ErrInvalidGasAccount       = errors.New("invalid gas account")
+	ErrInvalidGasAsset         = errors.New("invalid gas asset")
+	ErrGasAccountNotRegistered = errors.New("gas account is not registered")
+
+	// CheckEmptyAccountNode, account name hashes and balances
+	ErrAccountNotEmpty         = errors.New("account is already registered")
+	ErrAccountNameHashMismatch = errors.New("account name hash mismatch")
+	ErrInsufficientBalance     = errors.New("insufficient balance")
+	ErrInvalidCollectionId     = errors.New("invalid collection id")
+	ErrInvalidFullExitAmount   = errors.New("full exit amount is not the balance")
+
+	// CheckEmptyNftNode, nft owner and content hash
+	ErrNftNotEmpty           = errors.New("nft already exists")
+	ErrNftNotOwned           = errors.New("nft is not owned by the account")
+	ErrInvalidNftContentHash = errors.New("invalid nft content hash")
+
+	// VerifyAtomicMatchTx
+	ErrInvalidOfferType         = errors.New("invalid offer type")
+	ErrOfferMismatch            = errors.New("offers do not match")
+	ErrOfferExpired             = errors.New("offer expired")
+	ErrInvalidOfferSignature    = errors.New("invalid offer signature")
+	ErrOfferCanceledOrFinalized = errors.New("offer is already canceled or finalized")
+	ErrInvalidTreasuryRate      = errors.New("invalid treasury rate")
+	ErrInvalidRoyaltyAmount     = errors.New("invalid creator or treasury amount")
+)
+
+/*
+	TxError: the rule a tx breaks, Err is one of the Err* values of this package
+*/
+type TxError struct {
+	TxType int
+	Err    error
+	Detail string
+}
+
+func (e *TxError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("tx type %d: %s", e.TxType, e.Err.Error())
+	}
+	return fmt.Sprintf("tx type %d: %s: %s", e.TxType, e.Err.Error(), e.Detail)
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
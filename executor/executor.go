@@ -0,0 +1,625 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/pubdata"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	Executor: applies txtypes transactions to the native state with the rules of the block
+	circuit, so that a tx the circuit would not accept is rejected before any proving work
+*/
+type Executor struct {
+	state           *state.State
+	blockCreatedAt  int64
+	gasAccountIndex int64
+	gasAssetIds     []int64
+	// gas collected since the last SettleGas, asset id -> amount
+	pendingGas map[int64]*big.Int
+}
+
+func NewExecutor(s *state.State, blockCreatedAt int64, gasAccountIndex int64, gasAssetIds []int64) *Executor {
+	return &Executor{
+		state:           s,
+		blockCreatedAt:  blockCreatedAt,
+		gasAccountIndex: gasAccountIndex,
+		gasAssetIds:     gasAssetIds,
+		pendingGas:      make(map[int64]*big.Int),
+	}
+}
+
+func (e *Executor) State() *state.State {
+	return e.state
+}
+
+/*
+	PendingGas: gas collected by the transactions executed since the last SettleGas
+*/
+func (e *Executor) PendingGas() map[int64]*big.Int {
+	res := make(map[int64]*big.Int, len(e.pendingGas))
+	for assetId, amount := range e.pendingGas {
+		res[assetId] = new(big.Int).Set(amount)
+	}
+	return res
+}
+
+/*
+	ExecuteTx: apply txInfo to the state. A *TxError is returned if the circuit would reject
+	the tx, and the state is then left unchanged.
+*/
+func (e *Executor) ExecuteTx(txInfo txtypes.TxInfo) error {
+	x := &txExecution{Executor: e, txType: txInfo.GetTxType()}
+	if err := x.execute(txInfo); err != nil {
+		err = e.revert(&x.undo, err)
+		log.Println("[ExecuteTx]", err.Error())
+		return err
+	}
+	for _, gas := range x.gas {
+		if _, ok := e.pendingGas[gas.assetId]; !ok {
+			e.pendingGas[gas.assetId] = big.NewInt(0)
+		}
+		e.pendingGas[gas.assetId].Add(e.pendingGas[gas.assetId], gas.amount)
+	}
+	return nil
+}
+
+/*
+	CheckTx: the error ExecuteTx would return, the state is never changed
+*/
+func (e *Executor) CheckTx(txInfo txtypes.TxInfo) error {
+	x := &txExecution{Executor: e, txType: txInfo.GetTxType()}
+	return e.revert(&x.undo, x.execute(txInfo))
+}
+
+/*
+	revert: undo the leaves recorded by undo, err is why. The error of the undo is
+	added to err, a state that is not reverted must not go unnoticed.
+*/
+func (e *Executor) revert(undo *state.Journal, err error) error {
+	revertErr := undo.Revert(e.state)
+	if revertErr == nil {
+		return err
+	}
+	if err == nil {
+		return revertErr
+	}
+	return fmt.Errorf("%w, %s", err, revertErr.Error())
+}
+
+/*
+	SettleGas: credit the pending gas to the gas account, as VerifyGas does at the end of a block
+*/
+func (e *Executor) SettleGas() error {
+	var undo state.Journal
+	for _, assetId := range e.gasAssetIds {
+		amount, ok := e.pendingGas[assetId]
+		if !ok || amount.Sign() == 0 {
+			continue
+		}
+		if new(big.Int).SetBytes(e.state.GetAccount(e.gasAccountIndex).AccountNameHash).Sign() == 0 {
+			return e.revert(&undo, fmt.Errorf("[SettleGas] account %d: %w", e.gasAccountIndex, ErrGasAccountNotRegistered))
+		}
+		asset := e.state.GetAsset(e.gasAccountIndex, assetId)
+		undo.RecordAsset(e.gasAccountIndex, asset)
+		asset.Balance.Add(asset.Balance, amount)
+		if err := e.state.SetAsset(e.gasAccountIndex, asset); err != nil {
+			return e.revert(&undo, err)
+		}
+	}
+	e.pendingGas = make(map[int64]*big.Int)
+	return nil
+}
+
+func (e *Executor) isGasAsset(assetId int64) bool {
+	for _, gasAssetId := range e.gasAssetIds {
+		if gasAssetId == assetId {
+			return true
+		}
+	}
+	return false
+}
+
+type gasDelta struct {
+	assetId int64
+	amount  *big.Int
+}
+
+/*
+	txExecution: the leaves touched by a tx and the gas it collects. Updates are applied in
+	the order of the account slots of the circuit, every check sees the previous updates.
+*/
+type txExecution struct {
+	*Executor
+	txType int
+	undo   state.Journal
+	gas    []gasDelta
+}
+
+func (x *txExecution) fail(err error, format string, a ...interface{}) error {
+	return &TxError{TxType: x.txType, Err: err, Detail: fmt.Sprintf(format, a...)}
+}
+
+func (x *txExecution) execute(txInfo txtypes.TxInfo) error {
+	if err := txInfo.Validate(); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	if IsLayer2Tx(x.txType) {
+		if err := x.checkLayer2Tx(txInfo); err != nil {
+			return err
+		}
+	}
+	switch info := txInfo.(type) {
+	case *txtypes.RegisterZnsTxInfo:
+		return x.registerZns(info)
+	case *txtypes.DepositTxInfo:
+		return x.deposit(info)
+	case *txtypes.DepositNftTxInfo:
+		return x.depositNft(info)
+	case *txtypes.TransferTxInfo:
+		return x.transfer(info)
+	case *txtypes.WithdrawTxInfo:
+		return x.withdraw(info)
+	case *txtypes.CreateCollectionTxInfo:
+		return x.createCollection(info)
+	case *txtypes.MintNftTxInfo:
+		return x.mintNft(info)
+	case *txtypes.TransferNftTxInfo:
+		return x.transferNft(info)
+	case *txtypes.AtomicMatchTxInfo:
+		return x.atomicMatch(info)
+	case *txtypes.CancelOfferTxInfo:
+		return x.cancelOffer(info)
+	case *txtypes.WithdrawNftTxInfo:
+		return x.withdrawNft(info)
+	case *txtypes.FullExitTxInfo:
+		return x.fullExit(info)
+	case *txtypes.FullExitNftTxInfo:
+		return x.fullExitNft(info)
+	default:
+		return x.fail(ErrUnsupportedTxType, "%T", txInfo)
+	}
+}
+
+/*
+	checkLayer2Tx: expiry, gas, nonce and signature, checked for every layer 2 tx
+*/
+func (x *txExecution) checkLayer2Tx(txInfo txtypes.TxInfo) error {
+	if txInfo.GetExpiredAt() < x.blockCreatedAt {
+		return x.fail(ErrTxExpired, "expired at %d, block created at %d", txInfo.GetExpiredAt(), x.blockCreatedAt)
+	}
+	gasAccountIndex, gasFeeAssetId, gasFeeAssetAmount := txInfo.GetGas()
+	if gasAccountIndex != x.gasAccountIndex {
+		return x.fail(ErrInvalidGasAccount, "gas account %d, expected: %d", gasAccountIndex, x.gasAccountIndex)
+	}
+	if !x.isGasAsset(gasFeeAssetId) {
+		return x.fail(ErrInvalidGasAsset, "asset %d", gasFeeAssetId)
+	}
+	if _, err := pubdata.PackFee(gasFeeAssetAmount); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	from := x.state.GetAccount(txInfo.GetFromAccountIndex())
+	if txInfo.GetNonce() != from.Nonce {
+		return x.fail(ErrInvalidNonce, "expected: %d, actual: %d", from.Nonce, txInfo.GetNonce())
+	}
+	if err := txInfo.VerifySignature(hex.EncodeToString(from.AccountPk.Bytes())); err != nil {
+		return x.fail(ErrInvalidSignature, "%s", err.Error())
+	}
+	return nil
+}
+
+func (x *txExecution) registerZns(txInfo *txtypes.RegisterZnsTxInfo) error {
+	tx, err := pubdata.ToRegisterZnsTx(txInfo)
+	if err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	if !x.state.IsEmptyAccount(txInfo.AccountIndex) {
+		return x.fail(ErrAccountNotEmpty, "account %d", txInfo.AccountIndex)
+	}
+	account := x.state.GetAccount(txInfo.AccountIndex)
+	account.AccountNameHash = txInfo.AccountNameHash
+	account.AccountPk = &eddsa.PublicKey{A: tx.PubKey.A}
+	return x.setAccount(account)
+}
+
+func (x *txExecution) deposit(txInfo *txtypes.DepositTxInfo) error {
+	if err := x.addBalance(txInfo.AccountIndex, txInfo.AssetId, txInfo.AssetAmount); err != nil {
+		return err
+	}
+	return x.checkNameHash(txInfo.AccountIndex, txInfo.AccountNameHash)
+}
+
+func (x *txExecution) depositNft(txInfo *txtypes.DepositNftTxInfo) error {
+	if _, err := pubdata.ToDepositNftTx(txInfo); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	if err := x.checkNameHash(txInfo.AccountIndex, txInfo.AccountNameHash); err != nil {
+		return err
+	}
+	nft := x.state.GetNft(txInfo.NftIndex)
+	if !isEmptyNft(nft) {
+		return x.fail(ErrNftNotEmpty, "nft %d", txInfo.NftIndex)
+	}
+	nft.CreatorAccountIndex = txInfo.CreatorAccountIndex
+	nft.OwnerAccountIndex = txInfo.AccountIndex
+	nft.NftContentHash = txInfo.NftContentHash
+	nft.NftL1Address = pubdata.AddressToBigInt(txInfo.NftL1Address)
+	nft.NftL1TokenId = txInfo.NftL1TokenId
+	nft.CreatorTreasuryRate = txInfo.CreatorTreasuryRate
+	nft.CollectionId = txInfo.CollectionId
+	return x.setNft(nft)
+}
+
+func (x *txExecution) transfer(txInfo *txtypes.TransferTxInfo) error {
+	if _, err := pubdata.ToTransferTx(txInfo); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	if err := x.subBalance(txInfo.FromAccountIndex, txInfo.AssetId, txInfo.AssetAmount); err != nil {
+		return err
+	}
+	if err := x.payGas(txInfo.FromAccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	if err := x.incNonce(txInfo.FromAccountIndex); err != nil {
+		return err
+	}
+	if err := x.addBalance(txInfo.ToAccountIndex, txInfo.AssetId, txInfo.AssetAmount); err != nil {
+		return err
+	}
+	return x.checkNameHash(txInfo.ToAccountIndex, common.FromHex(txInfo.ToAccountNameHash))
+}
+
+func (x *txExecution) withdraw(txInfo *txtypes.WithdrawTxInfo) error {
+	if err := x.subBalance(txInfo.FromAccountIndex, txInfo.AssetId, txInfo.AssetAmount); err != nil {
+		return err
+	}
+	if err := x.payGas(txInfo.FromAccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	return x.incNonce(txInfo.FromAccountIndex)
+}
+
+func (x *txExecution) createCollection(txInfo *txtypes.CreateCollectionTxInfo) error {
+	if err := x.payGas(txInfo.AccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	account := x.state.GetAccount(txInfo.AccountIndex)
+	if txInfo.CollectionId != account.CollectionNonce {
+		return x.fail(ErrInvalidCollectionId, "collection %d, expected: %d", txInfo.CollectionId, account.CollectionNonce)
+	}
+	account.CollectionNonce++
+	account.Nonce++
+	return x.setAccount(account)
+}
+
+func (x *txExecution) mintNft(txInfo *txtypes.MintNftTxInfo) error {
+	tx, err := pubdata.ToMintNftTx(txInfo)
+	if err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	// the circuit checks the content hash is not zero in its field
+	if new(big.Int).Mod(new(big.Int).SetBytes(tx.NftContentHash), fr.Modulus()).Sign() == 0 {
+		return x.fail(ErrInvalidNftContentHash, "empty content hash")
+	}
+	if err = x.payGas(txInfo.CreatorAccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	creator := x.state.GetAccount(txInfo.CreatorAccountIndex)
+	if txInfo.NftCollectionId >= creator.CollectionNonce {
+		return x.fail(ErrInvalidCollectionId, "collection %d does not exist", txInfo.NftCollectionId)
+	}
+	if err = x.incNonce(txInfo.CreatorAccountIndex); err != nil {
+		return err
+	}
+	if err = x.checkNameHash(txInfo.ToAccountIndex, tx.ToAccountNameHash); err != nil {
+		return err
+	}
+	nft := x.state.GetNft(txInfo.NftIndex)
+	if !isEmptyNft(nft) {
+		return x.fail(ErrNftNotEmpty, "nft %d", txInfo.NftIndex)
+	}
+	nft.CreatorAccountIndex = txInfo.CreatorAccountIndex
+	nft.OwnerAccountIndex = txInfo.ToAccountIndex
+	nft.NftContentHash = tx.NftContentHash
+	nft.CreatorTreasuryRate = txInfo.CreatorTreasuryRate
+	nft.CollectionId = txInfo.NftCollectionId
+	return x.setNft(nft)
+}
+
+func (x *txExecution) transferNft(txInfo *txtypes.TransferNftTxInfo) error {
+	tx, err := pubdata.ToTransferNftTx(txInfo)
+	if err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	if err = x.payGas(txInfo.FromAccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	if err = x.incNonce(txInfo.FromAccountIndex); err != nil {
+		return err
+	}
+	if err = x.checkNameHash(txInfo.ToAccountIndex, tx.ToAccountNameHash); err != nil {
+		return err
+	}
+	nft := x.state.GetNft(txInfo.NftIndex)
+	if isEmptyNft(nft) || nft.OwnerAccountIndex != txInfo.FromAccountIndex {
+		return x.fail(ErrNftNotOwned, "nft %d, account %d", txInfo.NftIndex, txInfo.FromAccountIndex)
+	}
+	nft.OwnerAccountIndex = txInfo.ToAccountIndex
+	return x.setNft(nft)
+}
+
+/*
+	atomicMatch: the buyer pays the offer amount, the creator and the treasury take their
+	share of it and the seller gets the rest. The circuit divides by RateBase in the field,
+	so the shares must be exact, and the rates may not exceed RateBase together.
+*/
+func (x *txExecution) atomicMatch(txInfo *txtypes.AtomicMatchTxInfo) error {
+	buyOffer, sellOffer := txInfo.BuyOffer, txInfo.SellOffer
+	if buyOffer.Type != txtypes.BuyOfferType || sellOffer.Type != txtypes.SellOfferType {
+		return x.fail(ErrInvalidOfferType, "buy offer type %d, sell offer type %d", buyOffer.Type, sellOffer.Type)
+	}
+	if buyOffer.AssetId != sellOffer.AssetId || buyOffer.AssetAmount.Cmp(sellOffer.AssetAmount) != 0 ||
+		buyOffer.NftIndex != sellOffer.NftIndex || buyOffer.TreasuryRate != sellOffer.TreasuryRate {
+		return x.fail(ErrOfferMismatch, "buy offer %d, sell offer %d", buyOffer.OfferId, sellOffer.OfferId)
+	}
+	for _, offer := range []*txtypes.OfferTxInfo{buyOffer, sellOffer} {
+		if _, err := pubdata.ToOfferTx(offer); err != nil {
+			return x.fail(ErrInvalidTx, "offer %d: %s", offer.OfferId, err.Error())
+		}
+		if offer.ExpiredAt < x.blockCreatedAt {
+			return x.fail(ErrOfferExpired, "offer %d expired at %d, block created at %d", offer.OfferId, offer.ExpiredAt, x.blockCreatedAt)
+		}
+		// the offers of the submitter are covered by the signature of the tx
+		if offer.AccountIndex == txInfo.AccountIndex {
+			continue
+		}
+		pk := x.state.GetAccount(offer.AccountIndex).AccountPk
+		if err := offer.VerifySignature(hex.EncodeToString(pk.Bytes())); err != nil {
+			return x.fail(ErrInvalidOfferSignature, "offer %d: %s", offer.OfferId, err.Error())
+		}
+	}
+	// the treasury share is collected as gas, in the asset of the offers
+	if !x.isGasAsset(buyOffer.AssetId) {
+		return x.fail(ErrInvalidGasAsset, "asset %d", buyOffer.AssetId)
+	}
+	nft := x.state.GetNft(sellOffer.NftIndex)
+	if isEmptyNft(nft) || nft.OwnerAccountIndex != sellOffer.AccountIndex {
+		return x.fail(ErrNftNotOwned, "nft %d, account %d", sellOffer.NftIndex, sellOffer.AccountIndex)
+	}
+	if nft.CreatorTreasuryRate+buyOffer.TreasuryRate > types.RateBase {
+		return x.fail(ErrInvalidTreasuryRate, "creator rate %d, treasury rate %d", nft.CreatorTreasuryRate, buyOffer.TreasuryRate)
+	}
+	amount := buyOffer.AssetAmount
+	creatorAmount, err := x.rateShare(amount, nft.CreatorTreasuryRate, txInfo.CreatorAmount)
+	if err != nil {
+		return err
+	}
+	treasuryAmount, err := x.rateShare(amount, buyOffer.TreasuryRate, txInfo.TreasuryAmount)
+	if err != nil {
+		return err
+	}
+	sellerAmount := new(big.Int).Sub(amount, new(big.Int).Add(creatorAmount, treasuryAmount))
+
+	// submitter
+	if err = x.payGas(txInfo.AccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	if err = x.incNonce(txInfo.AccountIndex); err != nil {
+		return err
+	}
+	// buyer
+	if err = x.subBalance(buyOffer.AccountIndex, buyOffer.AssetId, amount); err != nil {
+		return err
+	}
+	if err = x.finalizeOffer(buyOffer.AccountIndex, buyOffer.OfferId); err != nil {
+		return err
+	}
+	// seller
+	if err = x.addBalance(sellOffer.AccountIndex, sellOffer.AssetId, sellerAmount); err != nil {
+		return err
+	}
+	if err = x.finalizeOffer(sellOffer.AccountIndex, sellOffer.OfferId); err != nil {
+		return err
+	}
+	// creator
+	if err = x.addBalance(nft.CreatorAccountIndex, sellOffer.AssetId, creatorAmount); err != nil {
+		return err
+	}
+	x.gas = append(x.gas, gasDelta{assetId: buyOffer.AssetId, amount: treasuryAmount})
+	nft.OwnerAccountIndex = buyOffer.AccountIndex
+	return x.setNft(nft)
+}
+
+func (x *txExecution) cancelOffer(txInfo *txtypes.CancelOfferTxInfo) error {
+	if err := x.payGas(txInfo.AccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	if err := x.finalizeOffer(txInfo.AccountIndex, txInfo.OfferId); err != nil {
+		return err
+	}
+	return x.incNonce(txInfo.AccountIndex)
+}
+
+func (x *txExecution) withdrawNft(txInfo *txtypes.WithdrawNftTxInfo) error {
+	nft := x.state.GetNft(txInfo.NftIndex)
+	if isEmptyNft(nft) || nft.OwnerAccountIndex != txInfo.AccountIndex {
+		return x.fail(ErrNftNotOwned, "nft %d, account %d", txInfo.NftIndex, txInfo.AccountIndex)
+	}
+	if err := x.payGas(txInfo.AccountIndex, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount); err != nil {
+		return err
+	}
+	if err := x.incNonce(txInfo.AccountIndex); err != nil {
+		return err
+	}
+	return x.setNft(state.EmptyNftState(txInfo.NftIndex))
+}
+
+func (x *txExecution) fullExit(txInfo *txtypes.FullExitTxInfo) error {
+	// the whole balance leaves the layer 2
+	balance := x.state.GetAsset(txInfo.AccountIndex, txInfo.AssetId).Balance
+	if txInfo.AssetAmount != nil && txInfo.AssetAmount.Cmp(balance) != 0 {
+		return x.fail(ErrInvalidFullExitAmount, "amount %s, balance: %s", txInfo.AssetAmount.String(), balance.String())
+	}
+	if err := x.subBalance(txInfo.AccountIndex, txInfo.AssetId, balance); err != nil {
+		return err
+	}
+	return x.checkNameHash(txInfo.AccountIndex, txInfo.AccountNameHash)
+}
+
+/*
+	fullExitNft: a full exit is a priority request which can not be refused, the nft is
+	cleared if the account owns it and left to its owner otherwise, like the circuit does
+*/
+func (x *txExecution) fullExitNft(txInfo *txtypes.FullExitNftTxInfo) error {
+	if err := x.checkNameHash(txInfo.AccountIndex, txInfo.AccountNameHash); err != nil {
+		return err
+	}
+	nft := x.state.GetNft(txInfo.NftIndex)
+	if nft.OwnerAccountIndex != txInfo.AccountIndex {
+		return nil
+	}
+	return x.setNft(state.EmptyNftState(txInfo.NftIndex))
+}
+
+/*
+	rateShare: amount * rate / RateBase, which must be exact, packable and equal to expected if set
+*/
+func (x *txExecution) rateShare(amount *big.Int, rate int64, expected *big.Int) (*big.Int, error) {
+	share, mod := new(big.Int).DivMod(new(big.Int).Mul(amount, big.NewInt(rate)), big.NewInt(types.RateBase), new(big.Int))
+	if mod.Sign() != 0 {
+		return nil, x.fail(ErrInvalidRoyaltyAmount, "%s * %d is not a multiple of %d", amount.String(), rate, types.RateBase)
+	}
+	if expected != nil && expected.Cmp(share) != 0 {
+		return nil, x.fail(ErrInvalidRoyaltyAmount, "%s, expected: %s", expected.String(), share.String())
+	}
+	if _, err := pubdata.PackAmount(share); err != nil {
+		return nil, x.fail(ErrInvalidRoyaltyAmount, "%s", err.Error())
+	}
+	return share, nil
+}
+
+func (x *txExecution) checkNameHash(accountIndex int64, nameHash []byte) error {
+	account := x.state.GetAccount(accountIndex)
+	if new(big.Int).SetBytes(nameHash).Cmp(new(big.Int).SetBytes(account.AccountNameHash)) != 0 {
+		return x.fail(ErrAccountNameHashMismatch, "account %d", accountIndex)
+	}
+	return nil
+}
+
+func (x *txExecution) payGas(accountIndex int64, assetId int64, amount *big.Int) error {
+	if err := x.subBalance(accountIndex, assetId, amount); err != nil {
+		return err
+	}
+	x.gas = append(x.gas, gasDelta{assetId: assetId, amount: amount})
+	return nil
+}
+
+func (x *txExecution) subBalance(accountIndex int64, assetId int64, amount *big.Int) error {
+	asset := x.state.GetAsset(accountIndex, assetId)
+	if asset.Balance.Cmp(amount) < 0 {
+		return x.fail(ErrInsufficientBalance, "account %d asset %d: %s < %s",
+			accountIndex, assetId, asset.Balance.String(), amount.String())
+	}
+	asset.Balance.Sub(asset.Balance, amount)
+	return x.setAsset(accountIndex, asset)
+}
+
+func (x *txExecution) addBalance(accountIndex int64, assetId int64, amount *big.Int) error {
+	asset := x.state.GetAsset(accountIndex, assetId)
+	asset.Balance.Add(asset.Balance, amount)
+	return x.setAsset(accountIndex, asset)
+}
+
+/*
+	finalizeOffer: set the bit of the offer in OfferCanceledOrFinalized of the asset it belongs to
+*/
+func (x *txExecution) finalizeOffer(accountIndex int64, offerId int64) error {
+	asset := x.state.GetAsset(accountIndex, offerId/types.OfferSizePerAsset)
+	bit := int(offerId % types.OfferSizePerAsset)
+	if asset.OfferCanceledOrFinalized.Bit(bit) == 1 {
+		return x.fail(ErrOfferCanceledOrFinalized, "account %d offer %d", accountIndex, offerId)
+	}
+	asset.OfferCanceledOrFinalized.SetBit(asset.OfferCanceledOrFinalized, bit, 1)
+	return x.setAsset(accountIndex, asset)
+}
+
+func (x *txExecution) incNonce(accountIndex int64) error {
+	account := x.state.GetAccount(accountIndex)
+	account.Nonce++
+	return x.setAccount(account)
+}
+
+/*
+	setAccount: a leaf is recorded once it is set, a leaf the state refuses is left as it was
+*/
+func (x *txExecution) setAccount(account *state.AccountState) error {
+	before := x.state.GetAccount(account.AccountIndex)
+	if err := x.state.SetAccount(account); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	x.undo.RecordAccount(before)
+	return nil
+}
+
+func (x *txExecution) setAsset(accountIndex int64, asset *state.AssetState) error {
+	before := x.state.GetAsset(accountIndex, asset.AssetId)
+	if err := x.state.SetAsset(accountIndex, asset); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	x.undo.RecordAsset(accountIndex, before)
+	return nil
+}
+
+func (x *txExecution) setNft(nft *state.NftState) error {
+	before := x.state.GetNft(nft.NftIndex)
+	if err := x.state.SetNft(nft); err != nil {
+		return x.fail(ErrInvalidTx, "%s", err.Error())
+	}
+	x.undo.RecordNft(before)
+	return nil
+}
+
+func isEmptyNft(nft *state.NftState) bool {
+	return bytes.Equal(nft.Hash(), state.NilNftNodeHash)
+}
+
+/*
+	IsLayer2Tx: txs signed by an account, which pay gas and carry a nonce and an expiry
+*/
+func IsLayer2Tx(txType int) bool {
+	switch txType {
+	case txtypes.TxTypeTransfer, txtypes.TxTypeWithdraw, txtypes.TxTypeCreateCollection,
+		txtypes.TxTypeMintNft, txtypes.TxTypeTransferNft, txtypes.TxTypeAtomicMatch,
+		txtypes.TxTypeCancelOffer, txtypes.TxTypeWithdrawNft:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,413 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package executor_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+var (
+	createdAt   = time.Now().UnixMilli()
+	expiredAt   = createdAt + time.Hour.Milliseconds()
+	gasAssetIds = []int64{0, 1}
+)
+
+func privateKey(t *testing.T, accountIndex int64) *txtypes.PrivateKey {
+	sk, err := testutil.PrivateKey(accountIndex)
+	assert.NoError(t, err)
+	return sk
+}
+
+func registerTxs(t *testing.T) []txtypes.TxInfo {
+	var txInfos []txtypes.TxInfo
+	for i := int64(testutil.GasAccount); i <= testutil.Carol; i++ {
+		txInfo, err := testutil.RegisterTx(i)
+		assert.NoError(t, err)
+		txInfos = append(txInfos, txInfo)
+	}
+	for i := int64(testutil.Alice); i <= testutil.Carol; i++ {
+		txInfos = append(txInfos, testutil.DepositTx(i, 0, 1000000000))
+	}
+	return txInfos
+}
+
+func newTestExecutor(t *testing.T) *executor.Executor {
+	s, err := state.NewState()
+	assert.NoError(t, err)
+	e := executor.NewExecutor(s, createdAt, testutil.GasAccount, gasAssetIds)
+	for _, txInfo := range registerTxs(t) {
+		assert.NoError(t, e.ExecuteTx(txInfo))
+	}
+	return e
+}
+
+func transferTx(t *testing.T, sk *txtypes.PrivateKey, format txtypes.TransferSegmentFormat) txtypes.TxInfo {
+	txInfo, err := txtypes.ConstructTransferTxInfo(sk, testutil.Segment(&format))
+	assert.NoError(t, err)
+	return txInfo
+}
+
+func aliceToBob() txtypes.TransferSegmentFormat {
+	return txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		AssetId:           0,
+		AssetAmount:       "100000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}
+}
+
+func offer(t *testing.T, accountIndex int64, format txtypes.OfferSegmentFormat) *txtypes.OfferTxInfo {
+	format.AccountIndex = accountIndex
+	if format.ListedAt == 0 {
+		format.ListedAt = createdAt
+	}
+	if format.ExpiredAt == 0 {
+		format.ExpiredAt = expiredAt
+	}
+	res, err := txtypes.ConstructOfferTxInfo(privateKey(t, accountIndex), testutil.Segment(&format))
+	assert.NoError(t, err)
+	return res
+}
+
+func matchTx(t *testing.T, submitter int64, nonce int64, buyOffer, sellOffer *txtypes.OfferTxInfo) txtypes.TxInfo {
+	txInfo, err := txtypes.ConstructAtomicMatchTxInfo(privateKey(t, submitter), testutil.Segment(&txtypes.AtomicMatchSegmentFormat{
+		AccountIndex:      submitter,
+		BuyOffer:          testutil.Segment(buyOffer),
+		SellOffer:         testutil.Segment(sellOffer),
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		Nonce:             nonce,
+		ExpiredAt:         expiredAt,
+	}))
+	assert.NoError(t, err)
+	return txInfo
+}
+
+/*
+	nftTxs: carol creates a collection and mints nft 0 to bob, alice buys it
+*/
+func nftTxs(t *testing.T) []txtypes.TxInfo {
+	collectionInfo, err := txtypes.ConstructCreateCollectionTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.CreateCollectionSegmentFormat{
+		AccountIndex:      testutil.Carol,
+		Name:              "collection",
+		Introduction:      "collection",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	mintInfo, err := txtypes.ConstructMintNftTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.MintNftSegmentFormat{
+		CreatorAccountIndex: testutil.Carol,
+		ToAccountIndex:      testutil.Bob,
+		ToAccountNameHash:   common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		NftContentHash:      common.Bytes2Hex(testutil.NameHash(100)),
+		NftCollectionId:     0,
+		CreatorTreasuryRate: 100,
+		GasAccountIndex:     testutil.GasAccount,
+		GasFeeAssetId:       0,
+		GasFeeAssetAmount:   "1000",
+		ExpiredAt:           expiredAt,
+		Nonce:               1,
+	}))
+	assert.NoError(t, err)
+	mintInfo.NftIndex = 0
+	price := txtypes.OfferSegmentFormat{NftIndex: 0, AssetId: 0, AssetAmount: "1000000", TreasuryRate: 200}
+	buy, sell := price, price
+	buy.Type, buy.OfferId = txtypes.BuyOfferType, 130
+	sell.Type, sell.OfferId = txtypes.SellOfferType, 1
+	return []txtypes.TxInfo{collectionInfo, mintInfo, matchTx(t, testutil.Alice, 0, offer(t, testutil.Alice, buy), offer(t, testutil.Bob, sell))}
+}
+
+func TestExecutorMatchesBuilder(t *testing.T) {
+	s, err := state.NewState()
+	assert.NoError(t, err)
+	builder := witness.NewBuilder(s)
+	rules := executor.NewExecutor(s, createdAt, testutil.GasAccount, gasAssetIds)
+	e := newTestExecutor(t)
+	for _, txInfo := range registerTxs(t) {
+		_, err = builder.ConstructTx(rules, txInfo)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, builder.State().StateRoot(), e.State().StateRoot())
+
+	withdrawNftInfo, err := txtypes.ConstructWithdrawNftTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.WithdrawNftSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		NftIndex:          0,
+		ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             3,
+	}))
+	assert.NoError(t, err)
+	cancelInfo, err := txtypes.ConstructCancelOfferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.CancelOfferSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		OfferId:           131,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             2,
+	}))
+	assert.NoError(t, err)
+	withdrawInfo, err := txtypes.ConstructWithdrawTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.WithdrawSegmentFormat{
+		FromAccountIndex:  testutil.Bob,
+		AssetId:           0,
+		AssetAmount:       "12345",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	txInfos := append([]txtypes.TxInfo{transferTx(t, privateKey(t, testutil.Alice), aliceToBob())}, nftTxs(t)...)
+	txInfos[3] = matchTx(t, testutil.Alice, 1, txInfos[3].(*txtypes.AtomicMatchTxInfo).BuyOffer, txInfos[3].(*txtypes.AtomicMatchTxInfo).SellOffer)
+	txInfos = append(txInfos,
+		cancelInfo,
+		withdrawNftInfo,
+		withdrawInfo,
+		&txtypes.DepositNftTxInfo{
+			TxType:              txtypes.TxTypeDepositNft,
+			AccountNameHash:     testutil.NameHash(testutil.Bob),
+			CreatorAccountIndex: testutil.Carol,
+			CreatorTreasuryRate: 50,
+			NftL1Address:        "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+			NftL1TokenId:        big.NewInt(7),
+			NftContentHash:      testutil.NameHash(101),
+			NftIndex:            5,
+			AccountIndex:        testutil.Bob,
+		},
+		// carol does not own the nft, it stays with bob
+		&txtypes.FullExitNftTxInfo{
+			TxType:                 txtypes.TxTypeFullExitNft,
+			NftIndex:               5,
+			AccountNameHash:        testutil.NameHash(testutil.Carol),
+			AccountIndex:           testutil.Carol,
+			CreatorAccountNameHash: testutil.NameHash(testutil.Carol),
+		},
+		&txtypes.FullExitNftTxInfo{
+			TxType:                 txtypes.TxTypeFullExitNft,
+			NftIndex:               5,
+			AccountNameHash:        testutil.NameHash(testutil.Bob),
+			AccountIndex:           testutil.Bob,
+			CreatorAccountNameHash: testutil.NameHash(testutil.Carol),
+		},
+		&txtypes.FullExitTxInfo{
+			TxType:          txtypes.TxTypeFullExit,
+			AccountNameHash: testutil.NameHash(testutil.Carol),
+			AssetId:         0,
+			AccountIndex:    testutil.Carol,
+		},
+	)
+	for _, txInfo := range txInfos {
+		_, err = builder.ConstructTx(rules, txInfo)
+		assert.NoError(t, err, "tx type %d", txInfo.GetTxType())
+		assert.NoError(t, e.CheckTx(txInfo), "tx type %d", txInfo.GetTxType())
+		assert.NoError(t, e.ExecuteTx(txInfo), "tx type %d", txInfo.GetTxType())
+		assert.Equal(t, builder.State().StateRoot(), e.State().StateRoot(), "tx type %d", txInfo.GetTxType())
+	}
+	assert.Equal(t, builder.PendingGas(), e.PendingGas())
+	assert.Equal(t, map[int64]*big.Int{0: big.NewInt(7000 + 20000)}, e.PendingGas())
+	assert.Equal(t, int64(0), e.State().GetAsset(testutil.Carol, 0).Balance.Int64())
+
+	assert.NoError(t, e.SettleGas())
+	assert.Equal(t, int64(27000), e.State().GetAsset(testutil.GasAccount, 0).Balance.Int64())
+	assert.Empty(t, e.PendingGas())
+}
+
+func TestExecuteInvalidTx(t *testing.T) {
+	e := newTestExecutor(t)
+	aliceKey, bobKey := privateKey(t, testutil.Alice), privateKey(t, testutil.Bob)
+	expired := executor.NewExecutor(e.State(), expiredAt+1, testutil.GasAccount, gasAssetIds)
+	otherGasAccount := executor.NewExecutor(e.State(), createdAt, testutil.Bob, gasAssetIds)
+
+	withTransfer := func(f func(format *txtypes.TransferSegmentFormat)) txtypes.TxInfo {
+		format := aliceToBob()
+		f(&format)
+		return transferTx(t, aliceKey, format)
+	}
+	mintTx := func(nftContentHash []byte) txtypes.TxInfo {
+		txInfo, err := txtypes.ConstructMintNftTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.MintNftSegmentFormat{
+			CreatorAccountIndex: testutil.Carol,
+			ToAccountIndex:      testutil.Bob,
+			ToAccountNameHash:   common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+			NftContentHash:      common.Bytes2Hex(nftContentHash),
+			NftCollectionId:     0,
+			CreatorTreasuryRate: 100,
+			GasAccountIndex:     testutil.GasAccount,
+			GasFeeAssetId:       0,
+			GasFeeAssetAmount:   "1000",
+			ExpiredAt:           expiredAt,
+			Nonce:               0,
+		}))
+		assert.NoError(t, err)
+		return txInfo
+	}
+	transferNftInfo, err := txtypes.ConstructTransferNftTxInfo(aliceKey, testutil.Segment(&txtypes.TransferNftSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		NftIndex:          0,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+
+	// the fee is rounded when the tx is constructed
+	notPackable := transferTx(t, aliceKey, aliceToBob())
+	notPackable.(*txtypes.TransferTxInfo).GasFeeAssetAmount = big.NewInt(2049)
+
+	cases := []struct {
+		name   string
+		exec   *executor.Executor
+		txInfo txtypes.TxInfo
+		err    error
+	}{
+		{"range check", e, &txtypes.DepositTxInfo{TxType: txtypes.TxTypeDeposit, AccountIndex: -1, AssetAmount: big.NewInt(1)}, executor.ErrInvalidTx},
+		{"not packable", e, notPackable, executor.ErrInvalidTx},
+		{"expired", expired, transferTx(t, aliceKey, aliceToBob()), executor.ErrTxExpired},
+		{"gas account", otherGasAccount, transferTx(t, aliceKey, aliceToBob()), executor.ErrInvalidGasAccount},
+		{"gas asset", e, withTransfer(func(f *txtypes.TransferSegmentFormat) { f.GasFeeAssetId = 2 }), executor.ErrInvalidGasAsset},
+		{"nonce", e, withTransfer(func(f *txtypes.TransferSegmentFormat) { f.Nonce = 1 }), executor.ErrInvalidNonce},
+		{"signature", e, transferTx(t, bobKey, aliceToBob()), executor.ErrInvalidSignature},
+		{"balance", e, withTransfer(func(f *txtypes.TransferSegmentFormat) { f.AssetAmount = "1000000000" }), executor.ErrInsufficientBalance},
+		{"name hash", e, withTransfer(func(f *txtypes.TransferSegmentFormat) {
+			f.ToAccountNameHash = common.Bytes2Hex(testutil.NameHash(testutil.Carol))
+		}), executor.ErrAccountNameHashMismatch},
+		{"registered", e, registerTxs(t)[testutil.Alice], executor.ErrAccountNotEmpty},
+		{"collection", e, mintTx(testutil.NameHash(100)), executor.ErrInvalidCollectionId},
+		// zero in the field of the circuit
+		{"content hash", e, mintTx(fr.Modulus().FillBytes(make([]byte, 32))), executor.ErrInvalidNftContentHash},
+		{"nft owner", e, transferNftInfo, executor.ErrNftNotOwned},
+		{"full exit amount", e, &txtypes.FullExitTxInfo{
+			TxType:          txtypes.TxTypeFullExit,
+			AccountNameHash: testutil.NameHash(testutil.Alice),
+			AssetId:         0,
+			AssetAmount:     big.NewInt(1),
+			AccountIndex:    testutil.Alice,
+		}, executor.ErrInvalidFullExitAmount},
+	}
+	root := e.State().StateRoot()
+	for _, c := range cases {
+		err := c.exec.CheckTx(c.txInfo)
+		assert.True(t, errors.Is(err, c.err), "%s: %v", c.name, err)
+		assert.IsType(t, &executor.TxError{}, err, c.name)
+		assert.Equal(t, err, c.exec.ExecuteTx(c.txInfo), c.name)
+		assert.Equal(t, root, e.State().StateRoot(), c.name)
+		assert.Empty(t, c.exec.PendingGas(), c.name)
+	}
+}
+
+/*
+	TestExecuteWithHasher: an empty account is told by the empty leaf of the hash function of the state
+*/
+func TestExecuteWithHasher(t *testing.T) {
+	s, err := state.NewStateWithHasher(hasher.Poseidon)
+	assert.NoError(t, err)
+	e := executor.NewExecutor(s, createdAt, testutil.GasAccount, gasAssetIds)
+	for _, txInfo := range registerTxs(t) {
+		assert.NoError(t, e.ExecuteTx(txInfo), "tx type %d", txInfo.GetTxType())
+	}
+	assert.True(t, errors.Is(e.CheckTx(registerTxs(t)[testutil.Alice]), executor.ErrAccountNotEmpty))
+}
+
+func TestExecuteInvalidAtomicMatch(t *testing.T) {
+	e := newTestExecutor(t)
+	txInfos := nftTxs(t)
+	for _, txInfo := range txInfos[:2] {
+		assert.NoError(t, e.ExecuteTx(txInfo))
+	}
+	price := txtypes.OfferSegmentFormat{NftIndex: 0, AssetId: 0, AssetAmount: "1000000", TreasuryRate: 200}
+	withOffer := func(accountIndex int64, offerType int64, offerId int64, f func(format *txtypes.OfferSegmentFormat)) *txtypes.OfferTxInfo {
+		format := price
+		format.Type, format.OfferId = offerType, offerId
+		f(&format)
+		return offer(t, accountIndex, format)
+	}
+	noop := func(*txtypes.OfferSegmentFormat) {}
+	buy := withOffer(testutil.Alice, txtypes.BuyOfferType, 130, noop)
+	sell := withOffer(testutil.Bob, txtypes.SellOfferType, 1, noop)
+	// an offer of bob signed by carol
+	forged := withOffer(testutil.Carol, txtypes.SellOfferType, 1, noop)
+	forged.AccountIndex = testutil.Bob
+
+	cases := []struct {
+		name      string
+		buy, sell *txtypes.OfferTxInfo
+		err       error
+	}{
+		{"offer type", buy, withOffer(testutil.Bob, txtypes.BuyOfferType, 1, noop), executor.ErrInvalidOfferType},
+		{"offer amount", buy, withOffer(testutil.Bob, txtypes.SellOfferType, 1, func(f *txtypes.OfferSegmentFormat) {
+			f.AssetAmount = "2000000"
+		}), executor.ErrOfferMismatch},
+		{"offer expired", buy, withOffer(testutil.Bob, txtypes.SellOfferType, 1, func(f *txtypes.OfferSegmentFormat) {
+			f.ExpiredAt = createdAt - 1
+		}), executor.ErrOfferExpired},
+		{"offer signature", buy, forged, executor.ErrInvalidOfferSignature},
+		{"offer asset", withOffer(testutil.Alice, txtypes.BuyOfferType, 130, func(f *txtypes.OfferSegmentFormat) { f.AssetId = 2 }),
+			withOffer(testutil.Bob, txtypes.SellOfferType, 1, func(f *txtypes.OfferSegmentFormat) { f.AssetId = 2 }), executor.ErrInvalidGasAsset},
+		{"treasury rate", withOffer(testutil.Alice, txtypes.BuyOfferType, 130, func(f *txtypes.OfferSegmentFormat) { f.TreasuryRate = 9950 }),
+			withOffer(testutil.Bob, txtypes.SellOfferType, 1, func(f *txtypes.OfferSegmentFormat) { f.TreasuryRate = 9950 }), executor.ErrInvalidTreasuryRate},
+		{"inexact share", withOffer(testutil.Alice, txtypes.BuyOfferType, 130, func(f *txtypes.OfferSegmentFormat) { f.AssetAmount = "1000001" }),
+			withOffer(testutil.Bob, txtypes.SellOfferType, 1, func(f *txtypes.OfferSegmentFormat) { f.AssetAmount = "1000001" }), executor.ErrInvalidRoyaltyAmount},
+		{"buyer balance", withOffer(testutil.Alice, txtypes.BuyOfferType, 130, func(f *txtypes.OfferSegmentFormat) { f.AssetAmount = "2000000000" }),
+			withOffer(testutil.Bob, txtypes.SellOfferType, 1, func(f *txtypes.OfferSegmentFormat) { f.AssetAmount = "2000000000" }), executor.ErrInsufficientBalance},
+	}
+	root := e.State().StateRoot()
+	for _, c := range cases {
+		err := e.ExecuteTx(matchTx(t, testutil.Alice, 0, c.buy, c.sell))
+		assert.True(t, errors.Is(err, c.err), "%s: %v", c.name, err)
+		assert.Equal(t, root, e.State().StateRoot(), c.name)
+	}
+
+	// the offers are finalized, they can not be matched twice
+	assert.NoError(t, e.ExecuteTx(matchTx(t, testutil.Alice, 0, buy, sell)))
+	err := e.ExecuteTx(matchTx(t, testutil.Alice, 1, buy, sell))
+	assert.True(t, errors.Is(err, executor.ErrNftNotOwned), err)
+	err = e.ExecuteTx(matchTx(t, testutil.Bob, 0, withOffer(testutil.Bob, txtypes.BuyOfferType, 1, noop), withOffer(testutil.Alice, txtypes.SellOfferType, 130, noop)))
+	assert.True(t, errors.Is(err, executor.ErrOfferCanceledOrFinalized), err)
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/poseidon"
+)
+
+/*
+	Type: hash function of merkle trees, each type has a native and an in-circuit version
+	which give the same output for the same field elements
+*/
+type Type uint8
+
+const (
+	MiMC Type = iota
+	Poseidon
+)
+
+// Default: hash function used by zkbnb trees and circuits
+const Default = MiMC
+
+func (t Type) String() string {
+	switch t {
+	case MiMC:
+		return "mimc"
+	case Poseidon:
+		return "poseidon"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(t))
+	}
+}
+
+/*
+	FromString: parse a hash function name, case insensitive
+*/
+func FromString(name string) (Type, error) {
+	switch strings.ToLower(name) {
+	case "mimc":
+		return MiMC, nil
+	case "poseidon":
+		return Poseidon, nil
+	default:
+		errInfo := fmt.Sprintf("[hasher.FromString] unknown hash function: %s", name)
+		log.Println(errInfo)
+		return 0, errors.New(errInfo)
+	}
+}
+
+/*
+	New: native hash function, usable as merkleTree hash function
+*/
+func (t Type) New() (hash.Hash, error) {
+	switch t {
+	case MiMC:
+		return mimc.NewMiMC(), nil
+	case Poseidon:
+		return poseidon.NewPoseidon(), nil
+	default:
+		errInfo := fmt.Sprintf("[hasher.New] unknown hash function: %s", t.String())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+}
+
+/*
+	Factory: creates independent native instances, usable as merkleTree.Tree.HashFactory
+*/
+func (t Type) Factory() (func() hash.Hash, error) {
+	if _, err := t.New(); err != nil {
+		return nil, err
+	}
+	return func() hash.Hash {
+		h, _ := t.New()
+		return h
+	}, nil
+}
+
+/*
+	EmptyAssetRoot: root of an asset tree of height levels whose leaves are all empty,
+	an empty asset leaf hashes a zero balance and a zero offer bit.
+	For MiMC and AssetMerkleLevels it is types.EmptyAssetRoot.
+*/
+func (t Type) EmptyAssetRoot(levels int) (*big.Int, error) {
+	hFunc, err := t.New()
+	if err != nil {
+		return nil, err
+	}
+	zero := make([]byte, 32)
+	hFunc.Write(zero)
+	hFunc.Write(zero)
+	node := hFunc.Sum(nil)
+	for i := 0; i < levels; i++ {
+		hFunc.Reset()
+		hFunc.Write(node)
+		hFunc.Write(node)
+		node = hFunc.Sum(nil)
+	}
+	return new(big.Int).SetBytes(node), nil
+}
+
+/*
+	NewCircuit: in-circuit hash function, usable by types.VerifyMerkleProof and types.UpdateMerkleProof
+*/
+func (t Type) NewCircuit(api frontend.API) (types.Hasher, error) {
+	switch t {
+	case MiMC:
+		h := types.NewMiMC(api)
+		return &h, nil
+	case Poseidon:
+		h := poseidon.NewPoseidonCircuit(api)
+		return &h, nil
+	default:
+		errInfo := fmt.Sprintf("[hasher.NewCircuit] unknown hash function: %s", t.String())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hasher
+
+import (
+	"hash"
+	"strconv"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+const testMerkleLevels = 8
+
+type MerkleConstraints struct {
+	Hasher   Type
+	OldRoot  frontend.Variable
+	NewRoot  frontend.Variable
+	Index    frontend.Variable
+	OldLeaf  frontend.Variable
+	NewLeaf  frontend.Variable
+	ProofSet [testMerkleLevels]frontend.Variable
+}
+
+func (circuit MerkleConstraints) Define(api frontend.API) error {
+	h, err := circuit.Hasher.NewCircuit(api)
+	if err != nil {
+		return err
+	}
+	helper := api.ToBinary(circuit.Index, testMerkleLevels)
+	types.VerifyMerkleProof(api, 1, h, circuit.OldRoot, circuit.OldLeaf, circuit.ProofSet[:], helper)
+	newRoot := types.UpdateMerkleProof(api, h, circuit.NewLeaf, circuit.ProofSet[:], helper)
+	api.AssertIsEqual(newRoot, circuit.NewRoot)
+	return nil
+}
+
+func TestFromString(t *testing.T) {
+	for _, hType := range []Type{MiMC, Poseidon} {
+		parsed, err := FromString(hType.String())
+		assert.NoError(t, err)
+		assert.Equal(t, hType, parsed)
+	}
+	_, err := FromString("sha256")
+	assert.Error(t, err)
+}
+
+func TestEmptyAssetRoot(t *testing.T) {
+	root, err := MiMC.EmptyAssetRoot(16)
+	assert.NoError(t, err)
+	assert.Equal(t, types.EmptyAssetRoot, root)
+	root, err = Poseidon.EmptyAssetRoot(16)
+	assert.NoError(t, err)
+	assert.NotEqual(t, types.EmptyAssetRoot, root)
+}
+
+func mockLeaves(hFunc hash.Hash, size int) [][]byte {
+	var leaves [][]byte
+	for i := 0; i < size; i++ {
+		hFunc.Reset()
+		hFunc.Write([]byte(strconv.Itoa(i)))
+		leaves = append(leaves, hFunc.Sum([]byte{}))
+	}
+	return leaves
+}
+
+func TestNativeAndCircuitMerkleRoots(t *testing.T) {
+	assert := test.NewAssert(t)
+	for _, hType := range []Type{MiMC, Poseidon} {
+		hFunc, err := hType.New()
+		assert.NoError(err)
+		leaves := mockLeaves(hFunc, 3)
+		tree, err := merkleTree.NewSparseTree(testMerkleLevels, merkleTree.NilHash, hFunc)
+		assert.NoError(err)
+		assert.NoError(tree.Update(1, leaves[0]))
+		assert.NoError(tree.Update(200, leaves[1]))
+		proof, err := tree.GetProof(5)
+		assert.NoError(err)
+		assert.NoError(tree.Update(5, leaves[2]))
+
+		var circuit, witness MerkleConstraints
+		circuit.Hasher = hType
+		witness.Hasher = hType
+		witness.OldRoot = proof.Root
+		witness.NewRoot = tree.RootNode.Value
+		witness.Index = 5
+		witness.OldLeaf = proof.Leaf
+		witness.NewLeaf = leaves[2]
+		for i := 0; i < testMerkleLevels; i++ {
+			witness.ProofSet[i] = proof.ProofSet[i]
+		}
+		assert.SolvingSucceeded(
+			&circuit, &witness, test.WithBackends(backend.GROTH16),
+			test.WithCurves(ecc.BN254))
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+/*
+	Package mimc is the mimc of zkbnb over the bn254 scalar field, as gnark-crypto v0.7.0 and
+	gnark v0.7.0 implemented it: 91 rounds of x^5 with constants derived from "seed".
+
+	Later gnark versions run 110 rounds and their native hash.Hash only takes canonical field
+	elements, which would change every tree node, tx hash and signature of zkbnb.
+*/
+package mimc
+
+import (
+	"hash"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	NbRounds  = 91
+	BlockSize = fr.Bytes
+	Size      = fr.Bytes
+	seed      = "seed"
+)
+
+var (
+	constants [NbRounds]fr.Element
+	once      sync.Once
+)
+
+// params: the round constants are the keccak chain of the seed
+func params() {
+	once.Do(func() {
+		h := sha3.NewLegacyKeccak256()
+		h.Write([]byte(seed))
+		rnd := h.Sum(nil)
+		for i := 0; i < NbRounds; i++ {
+			h.Reset()
+			h.Write(rnd)
+			rnd = h.Sum(nil)
+			constants[i].SetBytes(rnd)
+		}
+	})
+}
+
+/*
+	GetConstants: the round constants, for the in-circuit version of types.MiMC
+*/
+func GetConstants() []big.Int {
+	params()
+	res := make([]big.Int, NbRounds)
+	for i := range constants {
+		constants[i].BigInt(&res[i])
+	}
+	return res
+}
+
+/*
+	Encrypt: the mimc permutation of m keyed by k
+*/
+func Encrypt(m, k fr.Element) fr.Element {
+	params()
+	var tmp fr.Element
+	for i := 0; i < NbRounds; i++ {
+		// m = (m+k+c)^5
+		tmp.Add(&m, &k).Add(&tmp, &constants[i])
+		m.Square(&tmp).Square(&m).Mul(&m, &tmp)
+	}
+	return *m.Add(&m, &k)
+}
+
+type digest struct {
+	h    fr.Element
+	data []byte
+}
+
+/*
+	NewMiMC: hash.Hash over 32 bytes big endian blocks, Miyaguchi-Preneel over Encrypt.
+	Blocks are reduced modulo the field, the last one is left padded and no data is a zero block.
+*/
+func NewMiMC() hash.Hash {
+	d := new(digest)
+	d.Reset()
+	return d
+}
+
+func (d *digest) Reset() {
+	d.data = nil
+	d.h.SetZero()
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	d.data = append(d.data, p...)
+	return len(p), nil
+}
+
+/*
+	Sum: the data is flushed but not the state, a second Sum hashes a zero block more
+*/
+func (d *digest) Sum(b []byte) []byte {
+	data := d.data
+	// left pad the last block: .. || 0xaf8 -> .. || 0x0000...0af8
+	if r := len(data) % BlockSize; r != 0 || len(data) == 0 {
+		q := len(data) / BlockSize
+		padded := make([]byte, (q+1)*BlockSize)
+		copy(padded, data[:q*BlockSize])
+		copy(padded[(q+1)*BlockSize-r:], data[q*BlockSize:])
+		data = padded
+	}
+	var x fr.Element
+	for i := 0; i < len(data); i += BlockSize {
+		x.SetBytes(data[i : i+BlockSize])
+		r := Encrypt(x, d.h)
+		d.h.Add(&r, &d.h).Add(&d.h, &x)
+	}
+	d.data = nil
+	res := d.h.Bytes()
+	return append(b, res[:]...)
+}
+
+func (d *digest) Size() int {
+	return Size
+}
+
+func (d *digest) BlockSize() int {
+	return BlockSize
+}
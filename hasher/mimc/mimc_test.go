@@ -0,0 +1,44 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package mimc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// outputs of the mimc of gnark-crypto v0.7.0
+func TestMiMC(t *testing.T) {
+	h := NewMiMC()
+	assert.Equal(t, common.FromHex("0dde7a022857fec1b8ffa7664a937a250d3ae68f356061754d3531e2674103d8"), h.Sum(nil))
+
+	h.Reset()
+	h.Write([]byte("call data"))
+	assert.Equal(t, common.FromHex("083973df16954af4da55e0a090fcb6cbe2dfec7ba6479ca0a88dcf1db17f679f"), h.Sum(nil))
+
+	// a block larger than the modulus, and a short block written in two parts
+	h.Reset()
+	h.Write(bytes.Repeat([]byte{0xff}, BlockSize))
+	h.Write([]byte{1, 2})
+	h.Write([]byte{3})
+	assert.Equal(t, common.FromHex("08e558d0bed61707d15663326f2046344424d95a474dadb6ca7ae2bcceba8288"), h.Sum(nil))
+	assert.Equal(t, common.FromHex("1775410d6429baff52093b2c0484cfaada5bb3c64d090ef5e4a81d162f2c4836"), h.Sum(nil))
+}
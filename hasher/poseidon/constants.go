@@ -0,0 +1,147 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package poseidon
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+const (
+	// state width t, one capacity element and two rate elements
+	Width         = 3
+	Rate          = Width - 1
+	FullRounds    = 8
+	PartialRounds = 57
+	// bits of the bn254 scalar field
+	fieldSize = 254
+)
+
+var (
+	paramsOnce sync.Once
+	// round constants, Width per round
+	roundConstants    []fr.Element
+	roundConstantsBig []*big.Int
+	mds               [Width][Width]fr.Element
+	mdsBig            [Width][Width]*big.Int
+)
+
+/*
+	grain: Grain LFSR used by the reference implementation to derive the parameters
+*/
+type grain struct {
+	bits [80]uint8
+	head int
+}
+
+func newGrain() *grain {
+	g := &grain{}
+	var pos int
+	appendBits := func(v uint64, size int) {
+		for i := size - 1; i >= 0; i-- {
+			g.bits[pos] = uint8(v>>uint(i)) & 1
+			pos++
+		}
+	}
+	// field: prime field, sbox: x^alpha
+	appendBits(1, 2)
+	appendBits(0, 4)
+	appendBits(fieldSize, 12)
+	appendBits(Width, 12)
+	appendBits(FullRounds, 10)
+	appendBits(PartialRounds, 10)
+	appendBits(1<<30-1, 30)
+	for i := 0; i < 160; i++ {
+		g.step()
+	}
+	return g
+}
+
+func (g *grain) step() uint8 {
+	b := func(i int) uint8 {
+		return g.bits[(g.head+i)%80]
+	}
+	newBit := b(62) ^ b(51) ^ b(38) ^ b(23) ^ b(13) ^ b(0)
+	g.bits[g.head] = newBit
+	g.head = (g.head + 1) % 80
+	return newBit
+}
+
+// nextBit: bits are taken in pairs, the second one is kept only if the first one is 1
+func (g *grain) nextBit() uint8 {
+	for g.step() == 0 {
+		g.step()
+	}
+	return g.step()
+}
+
+func (g *grain) nextInt(size int) *big.Int {
+	v := new(big.Int)
+	for i := 0; i < size; i++ {
+		v.Lsh(v, 1)
+		if g.nextBit() == 1 {
+			v.SetBit(v, 0, 1)
+		}
+	}
+	return v
+}
+
+/*
+	initParams: derive round constants and the cauchy mds matrix for bn254, t = 3, alpha = 5,
+	they match the reference implementation and circomlib
+*/
+func initParams() {
+	modulus := fr.Modulus()
+	g := newGrain()
+	nbConstants := (FullRounds + PartialRounds) * Width
+	roundConstants = make([]fr.Element, nbConstants)
+	roundConstantsBig = make([]*big.Int, nbConstants)
+	for i := 0; i < nbConstants; i++ {
+		c := g.nextInt(fieldSize)
+		for c.Cmp(modulus) >= 0 {
+			c = g.nextInt(fieldSize)
+		}
+		roundConstantsBig[i] = c
+		roundConstants[i].SetBigInt(c)
+	}
+	var xs, ys [Width]*big.Int
+	for i := 0; i < Width; i++ {
+		xs[i] = new(big.Int).Mod(g.nextInt(fieldSize), modulus)
+	}
+	for i := 0; i < Width; i++ {
+		ys[i] = new(big.Int).Mod(g.nextInt(fieldSize), modulus)
+	}
+	for i := 0; i < Width; i++ {
+		for j := 0; j < Width; j++ {
+			entry := new(big.Int).Add(xs[i], ys[j])
+			entry.ModInverse(entry.Mod(entry, modulus), modulus)
+			mdsBig[i][j] = entry
+			mds[i][j].SetBigInt(entry)
+		}
+	}
+}
+
+func params() {
+	paramsOnce.Do(initParams)
+}
+
+func isFullRound(round int) bool {
+	return round < FullRounds/2 || round >= FullRounds/2+PartialRounds
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package poseidon
+
+import (
+	"hash"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+const (
+	BlockSize = fr.Bytes
+	Size      = fr.Bytes
+)
+
+/*
+	Permute: poseidon permutation over the bn254 scalar field
+*/
+func Permute(state *[Width]fr.Element) {
+	params()
+	var next [Width]fr.Element
+	for round := 0; round < FullRounds+PartialRounds; round++ {
+		for i := 0; i < Width; i++ {
+			state[i].Add(&state[i], &roundConstants[round*Width+i])
+		}
+		if isFullRound(round) {
+			for i := 0; i < Width; i++ {
+				sbox(&state[i])
+			}
+		} else {
+			sbox(&state[0])
+		}
+		var tmp fr.Element
+		for i := 0; i < Width; i++ {
+			next[i].SetZero()
+			for j := 0; j < Width; j++ {
+				tmp.Mul(&mds[i][j], &state[j])
+				next[i].Add(&next[i], &tmp)
+			}
+		}
+		*state = next
+	}
+}
+
+// sbox: x^5
+func sbox(x *fr.Element) {
+	var x2 fr.Element
+	x2.Square(x)
+	x2.Square(&x2)
+	x.Mul(x, &x2)
+}
+
+/*
+	HashElements: sponge with capacity 1 and rate 2, inputs are absorbed two by two
+	and an odd number of inputs is padded with zero.
+	For two inputs it equals circomlib poseidon([a, b]).
+*/
+func HashElements(inputs ...fr.Element) fr.Element {
+	var state [Width]fr.Element
+	if len(inputs) == 0 {
+		Permute(&state)
+		return state[0]
+	}
+	for i := 0; i < len(inputs); i += Rate {
+		for j := 0; j < Rate && i+j < len(inputs); j++ {
+			state[1+j].Add(&state[1+j], &inputs[i+j])
+		}
+		Permute(&state)
+	}
+	return state[0]
+}
+
+type digest struct {
+	data []byte
+}
+
+/*
+	NewPoseidon: hash.Hash over 32 bytes big endian field elements, same block layout as mimc
+*/
+func NewPoseidon() hash.Hash {
+	d := new(digest)
+	d.Reset()
+	return d
+}
+
+func (d *digest) Reset() {
+	d.data = nil
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	d.data = append(d.data, p...)
+	return len(p), nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	data := d.data
+	// left pad the last block like mimc: .. || 0xaf8 -> .. || 0x0000...0af8
+	if r := len(data) % BlockSize; r != 0 {
+		q := len(data) / BlockSize
+		padded := make([]byte, (q+1)*BlockSize)
+		copy(padded, data[:q*BlockSize])
+		copy(padded[(q+1)*BlockSize-r:], data[q*BlockSize:])
+		data = padded
+	}
+	inputs := make([]fr.Element, len(data)/BlockSize)
+	for i := range inputs {
+		inputs[i].SetBytes(data[i*BlockSize : (i+1)*BlockSize])
+	}
+	h := HashElements(inputs...)
+	res := h.Bytes()
+	return append(b, res[:]...)
+}
+
+func (d *digest) Size() int {
+	return Size
+}
+
+func (d *digest) BlockSize() int {
+	return BlockSize
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package poseidon
+
+import (
+	"github.com/consensys/gnark/frontend"
+)
+
+/*
+	Poseidon: in-circuit version of HashElements
+*/
+type Poseidon struct {
+	api  frontend.API
+	data []frontend.Variable
+}
+
+func NewPoseidonCircuit(api frontend.API) Poseidon {
+	params()
+	return Poseidon{
+		api: api,
+	}
+}
+
+func (h *Poseidon) Write(data ...frontend.Variable) {
+	h.data = append(h.data, data...)
+}
+
+func (h *Poseidon) Reset() {
+	h.data = nil
+}
+
+/*
+	Sum: hash the written data and flush it
+*/
+func (h *Poseidon) Sum() frontend.Variable {
+	var state [Width]frontend.Variable
+	for i := 0; i < Width; i++ {
+		state[i] = 0
+	}
+	if len(h.data) == 0 {
+		state = h.permute(state)
+	}
+	for i := 0; i < len(h.data); i += Rate {
+		for j := 0; j < Rate && i+j < len(h.data); j++ {
+			state[1+j] = h.api.Add(state[1+j], h.data[i+j])
+		}
+		state = h.permute(state)
+	}
+	h.data = nil
+	return state[0]
+}
+
+func (h *Poseidon) permute(state [Width]frontend.Variable) [Width]frontend.Variable {
+	api := h.api
+	for round := 0; round < FullRounds+PartialRounds; round++ {
+		for i := 0; i < Width; i++ {
+			state[i] = api.Add(state[i], roundConstantsBig[round*Width+i])
+		}
+		if isFullRound(round) {
+			for i := 0; i < Width; i++ {
+				state[i] = h.sbox(state[i])
+			}
+		} else {
+			state[0] = h.sbox(state[0])
+		}
+		var next [Width]frontend.Variable
+		for i := 0; i < Width; i++ {
+			next[i] = api.Mul(mdsBig[i][0], state[0])
+			for j := 1; j < Width; j++ {
+				next[i] = api.Add(next[i], api.Mul(mdsBig[i][j], state[j]))
+			}
+		}
+		state = next
+	}
+	return state
+}
+
+func (h *Poseidon) sbox(x frontend.Variable) frontend.Variable {
+	x2 := h.api.Mul(x, x)
+	x4 := h.api.Mul(x2, x2)
+	return h.api.Mul(x4, x)
+}
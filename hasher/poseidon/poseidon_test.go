@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package poseidon
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashElements(t *testing.T) {
+	var a, b fr.Element
+	a.SetBigInt(big.NewInt(1))
+	b.SetBigInt(big.NewInt(2))
+	// reference implementation test vector, poseidonperm_x5_254_3([0, 1, 2])[0]
+	expected := common.FromHex("115cc0f5e7d690413df64c6b9662e9cf2a3617f2743245519e19607a4417189a")
+	res := HashElements(a, b)
+	resBytes := res.Bytes()
+	assert.Equal(t, expected, resBytes[:])
+
+	h := NewPoseidon()
+	aBytes := a.Bytes()
+	h.Write(aBytes[:])
+	// short blocks are left padded
+	h.Write([]byte{2})
+	assert.Equal(t, expected, h.Sum(nil))
+}
+
+type PoseidonConstraints struct {
+	Inputs [5]frontend.Variable
+	Output frontend.Variable
+}
+
+func (circuit PoseidonConstraints) Define(api frontend.API) error {
+	h := NewPoseidonCircuit(api)
+	h.Write(circuit.Inputs[:]...)
+	api.AssertIsEqual(h.Sum(), circuit.Output)
+	return nil
+}
+
+func TestPoseidonCircuit(t *testing.T) {
+	var (
+		circuit, witness PoseidonConstraints
+		inputs           [5]fr.Element
+	)
+	for i := range inputs {
+		inputs[i].SetBigInt(big.NewInt(int64(i + 1)))
+		witness.Inputs[i] = i + 1
+	}
+	output := HashElements(inputs[:]...)
+	outputBytes := output.Bytes()
+	witness.Output = outputBytes[:]
+	assert := test.NewAssert(t)
+	assert.SolvingSucceeded(
+		&circuit, &witness, test.WithBackends(backend.GROTH16),
+		test.WithCurves(ecc.BN254))
+}
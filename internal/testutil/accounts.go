@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package testutil holds the accounts the tests of the executor, the witness and difftest share.
+package testutil
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/pubdata"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+// accounts registered by RegisterTx, in order
+const (
+	GasAccount = iota
+	Alice
+	Bob
+	Carol
+)
+
+var Names = []string{"gas", "alice", "bob", "carol"}
+
+/*
+	NameHash: name hash of an account, it is derived from the index so that
+	accounts registered by the tests get distinct hashes
+*/
+func NameHash(accountIndex int64) []byte {
+	buf := make([]byte, 32)
+	buf[30] = byte(accountIndex >> 8)
+	buf[31] = byte(accountIndex + 1)
+	return buf
+}
+
+/*
+	PrivateKey: deterministic key of an account
+*/
+func PrivateKey(accountIndex int64) (*txtypes.PrivateKey, error) {
+	return curve.GenerateEddsaPrivateKey(name(accountIndex))
+}
+
+/*
+	Segment: the json segment the txtypes.Construct* functions take
+*/
+func Segment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+/*
+	RegisterTx: registers accountIndex with the key of PrivateKey
+*/
+func RegisterTx(accountIndex int64) (*txtypes.RegisterZnsTxInfo, error) {
+	sk, err := PrivateKey(accountIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &txtypes.RegisterZnsTxInfo{
+		TxType:          txtypes.TxTypeRegisterZns,
+		AccountIndex:    accountIndex,
+		AccountName:     name(accountIndex) + pubdata.AccountNameSuffix,
+		AccountNameHash: NameHash(accountIndex),
+		PubKey:          hex.EncodeToString(sk.PublicKey.Bytes()),
+	}, nil
+}
+
+func DepositTx(accountIndex int64, assetId int64, amount int64) *txtypes.DepositTxInfo {
+	return &txtypes.DepositTxInfo{
+		TxType:          txtypes.TxTypeDeposit,
+		AccountNameHash: NameHash(accountIndex),
+		AssetId:         assetId,
+		AssetAmount:     big.NewInt(amount),
+		AccountIndex:    accountIndex,
+	}
+}
+
+func name(accountIndex int64) string {
+	if accountIndex >= 0 && accountIndex < int64(len(Names)) {
+		return Names[accountIndex]
+	}
+	return fmt.Sprintf("account%d", accountIndex)
+}
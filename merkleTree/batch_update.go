@@ -0,0 +1,186 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// levels with fewer nodes are hashed on the calling goroutine
+const minParallelHashes = 64
+
+/*
+	BatchUpdate: apply several leaf updates at once, shared ancestors are only hashed once
+	and the nodes of a level are hashed concurrently when HashFactory is set.
+	The resulting root is the same as calling Update for every leaf.
+*/
+func (t *Tree) BatchUpdate(updates map[int64][]byte) (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.batchUpdate(updates)
+}
+
+func (t *Tree) batchUpdate(updates map[int64][]byte) (err error) {
+	if len(updates) == 0 {
+		return nil
+	}
+	indexes := make([]int64, 0, len(updates))
+	for index := range updates {
+		if index < 0 || index >= 1<<t.MaxHeight {
+			errInfo := fmt.Sprintf("[BatchUpdate] invalid index: %d", index)
+			log.Println(errInfo)
+			return errors.New(errInfo)
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return t.ops.batchUpdateSorted(indexes, updates)
+}
+
+func (t *pathTree) batchUpdateSorted(indexes []int64, updates map[int64][]byte) error {
+	for _, index := range indexes {
+		t.nodes.setNode(0, index, updates[index])
+	}
+	if last := indexes[len(indexes)-1]; last >= t.nbLeaves {
+		t.nbLeaves = last + 1
+	}
+	for height := 0; height < t.MaxHeight; height++ {
+		// indexes are sorted, so are the parents
+		var parents []int64
+		for _, index := range indexes {
+			parent := index >> 1
+			if len(parents) == 0 || parents[len(parents)-1] != parent {
+				parents = append(parents, parent)
+			}
+		}
+		lefts := make([][]byte, len(parents))
+		rights := make([][]byte, len(parents))
+		for i, parent := range parents {
+			var err error
+			lefts[i], err = t.nodes.getNode(height, parent<<1)
+			if err != nil {
+				log.Println("[batchUpdateSorted] unable to read node:", err)
+				return err
+			}
+			rights[i], err = t.nodes.getNode(height, parent<<1|1)
+			if err != nil {
+				log.Println("[batchUpdateSorted] unable to read node:", err)
+				return err
+			}
+		}
+		values := t.hashPairs(lefts, rights)
+		for i, parent := range parents {
+			t.nodes.setNode(height+1, parent, values[i])
+		}
+		indexes = parents
+	}
+	root, err := t.nodes.getNode(t.MaxHeight, 0)
+	if err != nil {
+		log.Println("[batchUpdateSorted] unable to read root:", err)
+		return err
+	}
+	t.RootNode.Value = root
+	return nil
+}
+
+func (t *Tree) batchUpdateSorted(indexes []int64, updates map[int64][]byte) (err error) {
+	// leaves beyond the current ones change the tree shape, append them one by one
+	var nodes []*Node
+	for _, index := range indexes {
+		if index >= int64(len(t.Leaves)) {
+			err = t.update(index, updates[index])
+			if err != nil {
+				log.Println("[batchUpdateSorted] unable to append leaf:", err)
+				return err
+			}
+			continue
+		}
+		node := t.Leaves[index]
+		node.Value = updates[index]
+		nodes = append(nodes, node)
+	}
+	for len(nodes) > 0 {
+		var parents []*Node
+		seen := make(map[*Node]bool)
+		for _, node := range nodes {
+			if node.Parent != nil && !seen[node.Parent] {
+				seen[node.Parent] = true
+				parents = append(parents, node.Parent)
+			}
+		}
+		lefts := make([][]byte, len(parents))
+		rights := make([][]byte, len(parents))
+		for i, parent := range parents {
+			lefts[i] = parent.Left.Value
+			if parent.Right != nil {
+				rights[i] = parent.Right.Value
+			} else {
+				rights[i] = t.NilHashValueConst[parent.Left.Height]
+			}
+		}
+		values := t.hashPairs(lefts, rights)
+		for i, parent := range parents {
+			parent.Value = values[i]
+		}
+		nodes = parents
+	}
+	return nil
+}
+
+/*
+	hashPairs: hash(lefts[i], rights[i]) for every i, one hash instance per worker
+*/
+func (t *Tree) hashPairs(lefts, rights [][]byte) [][]byte {
+	values := make([][]byte, len(lefts))
+	workers := runtime.NumCPU()
+	if t.HashFactory == nil || len(lefts) < minParallelHashes || workers < 2 {
+		for i := range lefts {
+			values[i] = t.HashSubTrees(lefts[i], rights[i])
+		}
+		return values
+	}
+	if workers > len(lefts)/minParallelHashes {
+		workers = len(lefts) / minParallelHashes
+	}
+	var wg sync.WaitGroup
+	chunk := (len(lefts) + workers - 1) / workers
+	for start := 0; start < len(lefts); start += chunk {
+		end := start + chunk
+		if end > len(lefts) {
+			end = len(lefts)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			hFunc := t.HashFactory()
+			for i := start; i < end; i++ {
+				hFunc.Reset()
+				hFunc.Write(lefts[i])
+				hFunc.Write(rights[i])
+				values[i] = hFunc.Sum([]byte{})
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return values
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"hash"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func mockUpdates(size int, maxIndex int64) map[int64][]byte {
+	hashState := MockState(size)
+	r := rand.New(rand.NewSource(1))
+	updates := make(map[int64][]byte)
+	for _, leaf := range hashState {
+		updates[r.Int63n(maxIndex)] = leaf
+	}
+	return updates
+}
+
+func TestBatchUpdateMatchesUpdate(t *testing.T) {
+	initial := mockUpdates(50, 500)
+	updates := mockUpdates(300, 600)
+	newTrees := map[string]func() (*Tree, error){
+		"pointer": func() (*Tree, error) {
+			return NewEmptyTree(16, NilHash, mimc.NewMiMC())
+		},
+		"sparse": func() (*Tree, error) {
+			return NewSparseTree(16, NilHash, mimc.NewMiMC())
+		},
+		"store": func() (*Tree, error) {
+			return OpenTree(NewMemoryStore(), 16, NilHash, mimc.NewMiMC())
+		},
+	}
+	for name, newTree := range newTrees {
+		expected, err := newTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		batched, err := newTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		batched.HashFactory = func() hash.Hash { return mimc.NewMiMC() }
+		for i := int64(0); i < 500; i++ {
+			if leaf, ok := initial[i]; ok {
+				assert.NoError(t, expected.Update(i, leaf))
+				assert.NoError(t, batched.Update(i, leaf))
+			}
+		}
+		for i := int64(0); i < 600; i++ {
+			if leaf, ok := updates[i]; ok {
+				assert.NoError(t, expected.Update(i, leaf))
+			}
+		}
+		assert.NoError(t, batched.BatchUpdate(updates))
+		assert.Equal(t, expected.GetRoot(), batched.GetRoot(), name)
+	}
+	tree, err := NewSparseTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.RootNode.Value
+	assert.Error(t, tree.BatchUpdate(map[int64][]byte{0: NilHash, 1 << 16: NilHash}))
+	assert.Equal(t, root, tree.RootNode.Value)
+}
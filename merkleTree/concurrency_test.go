@@ -0,0 +1,105 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func verifyWithRoot(root []byte, leaf []byte, proofs [][]byte, helpers []int) bool {
+	h := mimc.NewMiMC()
+	node := leaf
+	for i := range proofs {
+		h.Reset()
+		if helpers[i] == Left {
+			h.Write(node)
+			h.Write(proofs[i])
+		} else {
+			h.Write(proofs[i])
+			h.Write(node)
+		}
+		node = h.Sum([]byte{})
+	}
+	return bytes.Equal(root, node)
+}
+
+func TestConcurrentReadsWithOneWriter(t *testing.T) {
+	hashState := MockState(64)
+	newTrees := map[string]func() (*Tree, error){
+		"pointer": func() (*Tree, error) {
+			return NewEmptyTree(16, NilHash, mimc.NewMiMC())
+		},
+		"sparse": func() (*Tree, error) {
+			return NewSparseTree(16, NilHash, mimc.NewMiMC())
+		},
+		"store": func() (*Tree, error) {
+			return OpenVersionedTree(NewMemoryStore(), 16, NilHash, mimc.NewMiMC(), 4)
+		},
+	}
+	for name, newTree := range newTrees {
+		tree, err := newTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every leaf only ever holds hashState[index], so the leaf value is known to readers
+		assert.NoError(t, tree.Update(0, hashState[0]))
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i < len(hashState); i++ {
+				assert.NoError(t, tree.Update(int64(i), hashState[i]))
+				if tree.IsPersistent() {
+					assert.NoError(t, tree.Commit())
+				}
+			}
+		}()
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func(r int) {
+				defer wg.Done()
+				for i := 0; i < len(hashState); i++ {
+					index := int64((i + r) % len(hashState))
+					root, proofs, helpers, err := tree.BuildMerkleProofsWithRoot(index)
+					if !assert.NoError(t, err, name) {
+						return
+					}
+					leaf, err := tree.GetLeaf(index)
+					if !assert.NoError(t, err, name) {
+						return
+					}
+					assert.True(t, bytes.Equal(leaf, NilHash) || bytes.Equal(leaf, hashState[index]), name)
+					// the leaf may be written between the proof and GetLeaf, the proof is
+					// against either the nil leaf or the written one
+					assert.True(t, verifyWithRoot(root, NilHash, proofs, helpers) ||
+						verifyWithRoot(root, hashState[index], proofs, helpers), name)
+				}
+			}(r)
+		}
+		wg.Wait()
+		root, proofs, helpers, err := tree.BuildMerkleProofsWithRoot(7)
+		assert.NoError(t, err)
+		assert.True(t, verifyWithRoot(root, hashState[7], proofs, helpers), name)
+	}
+}
@@ -0,0 +1,382 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+)
+
+/*
+	Export format, all integers are big endian:
+	magic "ZKBT" | version uint8 | maxHeight uint8 | nilHashLen uint16 | nilHash | nbLeaves uint64 |
+	(index uint64 | valueLen uint16 | value)* | rootLen uint16 | root | sha256 of all previous bytes.
+	Only non-empty leaves are written, in ascending index order, so the same state always gives the same bytes.
+*/
+var exportMagic = []byte("ZKBT")
+
+const exportVersion = 1
+
+/*
+	nilHashAt: root of an empty subtree of height, pointer trees do not keep the one of the root
+*/
+func (t *Tree) nilHashAt(height int) []byte {
+	if height < len(t.NilHashValueConst) && t.NilHashValueConst[height] != nil {
+		return t.NilHashValueConst[height]
+	}
+	nilHash := t.nilHashAt(height - 1)
+	t.hashMu.Lock()
+	defer t.hashMu.Unlock()
+	t.HashFunc.Reset()
+	t.HashFunc.Write(nilHash)
+	t.HashFunc.Write(nilHash)
+	return t.HashFunc.Sum([]byte{})
+}
+
+/*
+	walkNonEmptyLeaves: visit non-empty leaves in ascending index order, empty subtrees are skipped
+*/
+func (t *Tree) walkNonEmptyLeaves(height int, index int64, visit func(index int64, value []byte)) error {
+	value, err := t.ops.readNode(height, index)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(value, t.nilHashAt(height)) {
+		return nil
+	}
+	if height == 0 {
+		visit(index, value)
+		return nil
+	}
+	err = t.walkNonEmptyLeaves(height-1, index<<1, visit)
+	if err != nil {
+		return err
+	}
+	return t.walkNonEmptyLeaves(height-1, index<<1|1, visit)
+}
+
+func writeBytes16(w io.Writer, value []byte) error {
+	if len(value) > 1<<16-1 {
+		return errors.New("value too long")
+	}
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, uint16(len(value)))
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	_, err := w.Write(value)
+	return err
+}
+
+func readBytes16(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	value := make([]byte, binary.BigEndian.Uint16(buf))
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+/*
+	Export: write the non-empty leaves and the root of the tree to w
+*/
+func (t *Tree) Export(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var (
+		indexes []int64
+		values  [][]byte
+	)
+	err := t.walkNonEmptyLeaves(t.MaxHeight, 0, func(index int64, value []byte) {
+		indexes = append(indexes, index)
+		values = append(values, value)
+	})
+	if err != nil {
+		log.Println("[Export] unable to read leaves:", err)
+		return err
+	}
+	checksum := sha256.New()
+	bw := bufio.NewWriter(w)
+	out := io.MultiWriter(bw, checksum)
+	header := append(append([]byte{}, exportMagic...), exportVersion, byte(t.MaxHeight))
+	if _, err = out.Write(header); err != nil {
+		return err
+	}
+	if err = writeBytes16(out, t.NilHashValueConst[0]); err != nil {
+		return err
+	}
+	if _, err = out.Write(uint64Bytes(uint64(len(indexes)))); err != nil {
+		return err
+	}
+	for i, index := range indexes {
+		if _, err = out.Write(uint64Bytes(uint64(index))); err != nil {
+			return err
+		}
+		if err = writeBytes16(out, values[i]); err != nil {
+			return err
+		}
+	}
+	if err = writeBytes16(out, t.RootNode.Value); err != nil {
+		return err
+	}
+	if _, err = bw.Write(checksum.Sum(nil)); err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		log.Println("[Export] unable to write:", err)
+		return err
+	}
+	return nil
+}
+
+type exportedTree struct {
+	maxHeight int
+	nilHash   []byte
+	leaves    map[int64][]byte
+	root      []byte
+}
+
+func readExport(r io.Reader) (*exportedTree, error) {
+	checksum := sha256.New()
+	in := io.TeeReader(bufio.NewReader(r), checksum)
+	header := make([]byte, len(exportMagic)+2)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(exportMagic)], exportMagic) {
+		return nil, errors.New("invalid magic")
+	}
+	if header[len(exportMagic)] != exportVersion {
+		return nil, fmt.Errorf("unsupported version: %d", header[len(exportMagic)])
+	}
+	exported := &exportedTree{
+		maxHeight: int(header[len(exportMagic)+1]),
+		leaves:    make(map[int64][]byte),
+	}
+	if exported.maxHeight <= 0 || exported.maxHeight > 63 {
+		return nil, fmt.Errorf("invalid max height: %d", exported.maxHeight)
+	}
+	var err error
+	exported.nilHash, err = readBytes16(in)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8)
+	if _, err = io.ReadFull(in, buf); err != nil {
+		return nil, err
+	}
+	nbLeaves := binary.BigEndian.Uint64(buf)
+	if nbLeaves > 1<<uint(exported.maxHeight) {
+		return nil, fmt.Errorf("invalid leaves count: %d", nbLeaves)
+	}
+	lastIndex := int64(-1)
+	for i := uint64(0); i < nbLeaves; i++ {
+		if _, err = io.ReadFull(in, buf); err != nil {
+			return nil, err
+		}
+		index := int64(binary.BigEndian.Uint64(buf))
+		if index <= lastIndex || index >= 1<<uint(exported.maxHeight) {
+			return nil, fmt.Errorf("invalid leaf index: %d", index)
+		}
+		lastIndex = index
+		exported.leaves[index], err = readBytes16(in)
+		if err != nil {
+			return nil, err
+		}
+	}
+	exported.root, err = readBytes16(in)
+	if err != nil {
+		return nil, err
+	}
+	expected := checksum.Sum(nil)
+	actual := make([]byte, sha256.Size)
+	if _, err = io.ReadFull(in, actual); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(expected, actual) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return exported, nil
+}
+
+/*
+	Import: read an exported tree into a new in-memory sparse tree and verify its root
+*/
+func Import(r io.Reader, hFunc hash.Hash) (*Tree, error) {
+	exported, err := readExport(r)
+	if err != nil {
+		errInfo := fmt.Sprintf("[Import] unable to read export: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	tree, err := NewSparseTree(exported.maxHeight, exported.nilHash, hFunc)
+	if err != nil {
+		return nil, err
+	}
+	err = tree.load(exported)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+/*
+	Load: read an exported tree into an empty tree of the same shape and verify its root,
+	a store backed tree still needs to be committed
+*/
+func (t *Tree) Load(r io.Reader) error {
+	exported, err := readExport(r)
+	if err != nil {
+		errInfo := fmt.Sprintf("[Load] unable to read export: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.ops.isEmptyTree() {
+		log.Println("[Load] tree is not empty")
+		return errors.New("[Load] tree is not empty")
+	}
+	if exported.maxHeight != t.MaxHeight || !bytes.Equal(exported.nilHash, t.NilHashValueConst[0]) {
+		log.Println("[Load] tree shape mismatch")
+		return errors.New("[Load] tree shape mismatch")
+	}
+	// the root is checked on a scratch tree first, so that a failed load leaves t empty
+	if err = t.verify(exported); err != nil {
+		return err
+	}
+	return t.load(exported)
+}
+
+/*
+	verify: rebuild the exported tree on an in-memory scratch tree and check its root, t is left untouched
+*/
+func (t *Tree) verify(exported *exportedTree) error {
+	hFunc := t.HashFunc
+	if t.HashFactory != nil {
+		hFunc = t.HashFactory()
+	} else {
+		// the scratch tree borrows the hash function of t
+		t.hashMu.Lock()
+		defer t.hashMu.Unlock()
+	}
+	scratch, err := NewSparseTree(exported.maxHeight, exported.nilHash, hFunc)
+	if err != nil {
+		return err
+	}
+	return scratch.load(exported)
+}
+
+func (t *Tree) load(exported *exportedTree) error {
+	err := t.batchUpdate(exported.leaves)
+	if err != nil {
+		log.Println("[Load] unable to update leaves:", err)
+		return err
+	}
+	if !bytes.Equal(t.RootNode.Value, exported.root) {
+		errInfo := fmt.Sprintf("[Load] root mismatch, expected: %x, actual: %x", exported.root, t.RootNode.Value)
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	return nil
+}
+
+/*
+	LeafDiff: leaf which differs between two trees
+*/
+type LeafDiff struct {
+	Index int64
+	Old   []byte
+	New   []byte
+}
+
+/*
+	Diff: list the leaves which differ from a to b in ascending index order,
+	subtrees with the same hash are skipped so the cost depends on the number of changes
+*/
+func Diff(a, b *Tree) ([]LeafDiff, error) {
+	if a == b {
+		return nil, nil
+	}
+	if a.MaxHeight != b.MaxHeight {
+		errInfo := fmt.Sprintf("[Diff] height mismatch: %d, %d", a.MaxHeight, b.MaxHeight)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	// trees of other nil hashes or hash functions differ everywhere
+	if !bytes.Equal(a.NilHashValueConst[0], b.NilHashValueConst[0]) ||
+		!bytes.Equal(a.nilHashAt(a.MaxHeight), b.nilHashAt(b.MaxHeight)) {
+		log.Println("[Diff] nil hash mismatch")
+		return nil, errors.New("[Diff] nil hash mismatch")
+	}
+	// lock in id order so that concurrent Diff(a, b) and Diff(b, a)
+	// cannot deadlock when writers are queued on both trees
+	first, second := a, b
+	if b.id < a.id {
+		first, second = b, a
+	}
+	first.mu.RLock()
+	defer first.mu.RUnlock()
+	second.mu.RLock()
+	defer second.mu.RUnlock()
+	var diffs []LeafDiff
+	err := diffNodes(a, b, a.MaxHeight, 0, &diffs)
+	if err != nil {
+		log.Println("[Diff] unable to compare trees:", err)
+		return nil, err
+	}
+	return diffs, nil
+}
+
+func diffNodes(a, b *Tree, height int, index int64, diffs *[]LeafDiff) error {
+	aValue, err := a.ops.readNode(height, index)
+	if err != nil {
+		return err
+	}
+	bValue, err := b.ops.readNode(height, index)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(aValue, bValue) {
+		return nil
+	}
+	if height == 0 {
+		*diffs = append(*diffs, LeafDiff{
+			Index: index,
+			Old:   aValue,
+			New:   bValue,
+		})
+		return nil
+	}
+	err = diffNodes(a, b, height-1, index<<1, diffs)
+	if err != nil {
+		return err
+	}
+	return diffNodes(a, b, height-1, index<<1|1, diffs)
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func TestExportImport(t *testing.T) {
+	hashState := MockState(6)
+	pointer, err := NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse, err := NewSparseTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, leaf := range hashState {
+		assert.NoError(t, pointer.Update(int64(i*7), leaf))
+		assert.NoError(t, sparse.Update(int64(i*7), leaf))
+	}
+	// clearing a leaf must not export it
+	assert.NoError(t, pointer.Update(14, NilHash))
+	assert.NoError(t, sparse.Update(14, NilHash))
+
+	var pointerExport, sparseExport bytes.Buffer
+	assert.NoError(t, pointer.Export(&pointerExport))
+	assert.NoError(t, sparse.Export(&sparseExport))
+	assert.Equal(t, pointerExport.Bytes(), sparseExport.Bytes())
+
+	imported, err := Import(bytes.NewReader(sparseExport.Bytes()), mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sparse.RootNode.Value, imported.RootNode.Value)
+	assert.Equal(t, sparse.NbNodes(), imported.NbNodes())
+
+	store, err := OpenTree(NewMemoryStore(), 16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, store.Load(bytes.NewReader(sparseExport.Bytes())))
+	assert.NoError(t, store.Commit())
+	assert.Equal(t, sparse.RootNode.Value, store.RootNode.Value)
+	assert.Error(t, store.Load(bytes.NewReader(sparseExport.Bytes())))
+
+	// a tampered root with a valid checksum is rejected, and leaves the tree empty
+	tampered := append([]byte{}, sparseExport.Bytes()[:sparseExport.Len()-sha256.Size]...)
+	tampered[len(tampered)-1] ^= 1
+	checksum := sha256.Sum256(tampered)
+	tampered = append(tampered, checksum[:]...)
+	_, err = Import(bytes.NewReader(tampered), mimc.NewMiMC())
+	assert.Error(t, err)
+	empty, err := OpenTree(NewMemoryStore(), 16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyRoot := append([]byte{}, empty.RootNode.Value...)
+	assert.Error(t, empty.Load(bytes.NewReader(tampered)))
+	assert.Equal(t, emptyRoot, empty.RootNode.Value)
+	assert.NoError(t, empty.Load(bytes.NewReader(sparseExport.Bytes())))
+	assert.Equal(t, sparse.RootNode.Value, empty.RootNode.Value)
+
+	// corrupted exports are rejected
+	corrupted := append([]byte{}, sparseExport.Bytes()...)
+	corrupted[30] ^= 1
+	_, err = Import(bytes.NewReader(corrupted), mimc.NewMiMC())
+	assert.Error(t, err)
+	_, err = Import(bytes.NewReader(corrupted[:len(corrupted)-1]), mimc.NewMiMC())
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	hashState := MockState(4)
+	a, err := NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSparseTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, a.Update(int64(i), hashState[i]))
+		assert.NoError(t, b.Update(int64(i), hashState[i]))
+	}
+	diffs, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	assert.NoError(t, b.Update(1, hashState[3]))
+	assert.NoError(t, b.Update(1000, hashState[0]))
+	diffs, err = Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []LeafDiff{
+		{Index: 1, Old: hashState[1], New: hashState[3]},
+		{Index: 1000, Old: NilHash, New: hashState[0]},
+	}, diffs)
+
+	c, err := NewSparseTree(8, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Diff(a, c)
+	assert.Error(t, err)
+
+	// same height, another nil hash
+	d, err := NewSparseTree(16, hashState[0], mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Diff(a, d)
+	assert.Error(t, err)
+}
+
+func TestConcurrentDiff(t *testing.T) {
+	hashState := MockState(4)
+	a, err := NewSparseTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSparseTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// opposite argument orders with writers queued on both trees must not deadlock
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := Diff(a, b)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := Diff(b, a)
+			assert.NoError(t, err)
+		}()
+		go func(r int) {
+			defer wg.Done()
+			assert.NoError(t, a.Update(int64(r), hashState[r]))
+			assert.NoError(t, b.Update(int64(r+4), hashState[r]))
+		}(r)
+	}
+	wg.Wait()
+	diffs, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 8)
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"sort"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+/*
+	MultiProof: merkle proof of several leaves against one root.
+	ProofSet only holds the siblings which can not be computed from the proven leaves,
+	ordered by height and then by index, which is the order they are consumed by the verifier.
+*/
+type MultiProof struct {
+	// sorted and unique
+	Indexes []int64
+	Leaves  [][]byte
+	Root    []byte
+	Height  int
+	// deduplicated siblings
+	ProofSet [][]byte
+}
+
+type nodePos struct {
+	height int
+	index  int64
+}
+
+func sortedUniqueIndexes(indexes []int64) []int64 {
+	sorted := append([]int64{}, indexes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var unique []int64
+	for _, index := range sorted {
+		if len(unique) == 0 || unique[len(unique)-1] != index {
+			unique = append(unique, index)
+		}
+	}
+	return unique
+}
+
+func parentIndexes(indexes []int64) []int64 {
+	var parents []int64
+	for _, index := range indexes {
+		parent := index >> 1
+		if len(parents) == 0 || parents[len(parents)-1] != parent {
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+/*
+	GetMultiProof: build a multiproof of the leaves at indexes against the current root
+*/
+func (t *Tree) GetMultiProof(indexes []int64) (*MultiProof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(indexes) == 0 {
+		log.Println("[GetMultiProof] no index")
+		return nil, errors.New("[GetMultiProof] no index")
+	}
+	indexes = sortedUniqueIndexes(indexes)
+	leaves := make([][]byte, len(indexes))
+	siblings := make(map[nodePos][]byte)
+	for i, index := range indexes {
+		if index < 0 || index >= 1<<t.MaxHeight {
+			errInfo := fmt.Sprintf("[GetMultiProof] index error, index: %v is out of tree capacity: %v.",
+				index, int64(1)<<t.MaxHeight)
+			log.Println(errInfo)
+			return nil, errors.New(errInfo)
+		}
+		leaf, err := t.ops.getLeaf(index)
+		if err != nil {
+			log.Println("[GetMultiProof] unable to get leaf:", err)
+			return nil, err
+		}
+		leaves[i] = leaf
+		proofSet, _, err := t.ops.buildMerkleProofs(index)
+		if err != nil {
+			log.Println("[GetMultiProof] unable to build merkle proofs:", err)
+			return nil, err
+		}
+		for height, sibling := range proofSet {
+			siblings[nodePos{height: height, index: (index >> uint(height)) ^ 1}] = sibling
+		}
+	}
+	var proofSet [][]byte
+	known := indexes
+	for height := 0; height < t.MaxHeight; height++ {
+		for i, index := range known {
+			// the sibling is either proven as well or taken from the proof set
+			if index%2 == 0 && i+1 < len(known) && known[i+1] == index+1 {
+				continue
+			}
+			if index%2 == 1 && i > 0 && known[i-1] == index-1 {
+				continue
+			}
+			proofSet = append(proofSet, siblings[nodePos{height: height, index: index ^ 1}])
+		}
+		known = parentIndexes(known)
+	}
+	return &MultiProof{
+		Indexes:  indexes,
+		Leaves:   leaves,
+		Root:     t.RootNode.Value,
+		Height:   t.MaxHeight,
+		ProofSet: proofSet,
+	}, nil
+}
+
+/*
+	VerifyMultiProof: verify a multiproof of a MiMC tree against root
+*/
+func VerifyMultiProof(root []byte, proof *MultiProof) bool {
+	return VerifyMultiProofWithHash(root, proof, mimc.NewMiMC())
+}
+
+/*
+	VerifyMultiProofWithHash: verify a multiproof against root
+*/
+func VerifyMultiProofWithHash(root []byte, proof *MultiProof, hFunc hash.Hash) bool {
+	if proof == nil || len(proof.Indexes) == 0 || len(proof.Indexes) != len(proof.Leaves) {
+		return false
+	}
+	if proof.Height <= 0 || proof.Height > 63 || !bytes.Equal(root, proof.Root) {
+		return false
+	}
+	for i, index := range proof.Indexes {
+		if index < 0 || index >= 1<<proof.Height || (i > 0 && proof.Indexes[i-1] >= index) {
+			return false
+		}
+	}
+	known := proof.Indexes
+	values := proof.Leaves
+	next := 0
+	for height := 0; height < proof.Height; height++ {
+		var (
+			parents      []int64
+			parentValues [][]byte
+		)
+		for i := 0; i < len(known); i++ {
+			index := known[i]
+			var left, right []byte
+			if index%2 == 0 {
+				left = values[i]
+				if i+1 < len(known) && known[i+1] == index+1 {
+					right = values[i+1]
+					i++
+				} else {
+					if next >= len(proof.ProofSet) {
+						return false
+					}
+					right = proof.ProofSet[next]
+					next++
+				}
+			} else {
+				if next >= len(proof.ProofSet) {
+					return false
+				}
+				left = proof.ProofSet[next]
+				next++
+				right = values[i]
+			}
+			hFunc.Reset()
+			hFunc.Write(left)
+			hFunc.Write(right)
+			parents = append(parents, index>>1)
+			parentValues = append(parentValues, hFunc.Sum([]byte{}))
+		}
+		known = parents
+		values = parentValues
+	}
+	return next == len(proof.ProofSet) && len(values) == 1 && bytes.Equal(root, values[0])
+}
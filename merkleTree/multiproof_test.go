@@ -0,0 +1,77 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func TestMultiProof(t *testing.T) {
+	hashState := MockState(8)
+	newTrees := map[string]func() (*Tree, error){
+		"pointer": func() (*Tree, error) {
+			return NewEmptyTree(16, NilHash, mimc.NewMiMC())
+		},
+		"sparse": func() (*Tree, error) {
+			return NewSparseTree(16, NilHash, mimc.NewMiMC())
+		},
+	}
+	for name, newTree := range newTrees {
+		tree, err := newTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, leaf := range hashState {
+			assert.NoError(t, tree.Update(int64(i*5), leaf))
+		}
+		root := tree.GetRoot()
+		indexes := []int64{35, 0, 1, 5, 35, 100}
+		proof, err := tree.GetMultiProof(indexes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []int64{0, 1, 5, 35, 100}, proof.Indexes, name)
+		assert.Equal(t, hashState[1], proof.Leaves[2], name)
+		assert.Equal(t, NilHash, proof.Leaves[4], name)
+		// five separate proofs would carry 5*16 siblings
+		assert.True(t, len(proof.ProofSet) < 5*16, name)
+		assert.True(t, VerifyMultiProof(root, proof), name)
+
+		// tampered proofs
+		proof.Leaves[0], proof.Leaves[1] = proof.Leaves[1], proof.Leaves[0]
+		assert.False(t, VerifyMultiProof(root, proof), name)
+		proof.Leaves[0], proof.Leaves[1] = proof.Leaves[1], proof.Leaves[0]
+		proof.ProofSet = proof.ProofSet[1:]
+		assert.False(t, VerifyMultiProof(root, proof), name)
+
+		// a single index gives the same siblings as GetProof
+		single, err := tree.GetProof(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		multi, err := tree.GetMultiProof([]int64{5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, single.ProofSet, multi.ProofSet, name)
+	}
+}
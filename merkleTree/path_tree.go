@@ -0,0 +1,156 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+)
+
+/*
+	pathNodes: nodes addressed by (height, index), an unset node is the nil hash of its height
+*/
+type pathNodes interface {
+	getNode(height int, index int64) ([]byte, error)
+	setNode(height int, index int64, value []byte)
+}
+
+/*
+	pathTree: node layout addressing the nodes by (height, index) instead of pointers,
+	shared by the in-memory sparse layout and the store backed layouts
+*/
+type pathTree struct {
+	// the tree using this layout
+	*Tree
+	// last leaf index + 1
+	nbLeaves int64
+	// the layout embedding this path tree
+	nodes pathNodes
+}
+
+/*
+	init: create an empty tree using nodes as its layout
+*/
+func (t *pathTree) init(maxHeight int, nilHash []byte, hFunc hash.Hash, nodes pathNodes, ops treeOps) error {
+	if maxHeight <= 0 || maxHeight > 63 {
+		return fmt.Errorf("invalid max height: %d", maxHeight)
+	}
+	// nil hash for every height including the root
+	nilHashValueConst := make([][]byte, maxHeight+1)
+	nilHashValueConst[0] = nilHash
+	t.Tree = &Tree{
+		id: nextTreeId(),
+		RootNode: &Node{
+			Height: maxHeight,
+		},
+		MaxHeight:         maxHeight,
+		NilHashValueConst: nilHashValueConst,
+		HashFunc:          hFunc,
+		ops:               ops,
+	}
+	t.nodes = nodes
+	for i := 1; i <= maxHeight; i++ {
+		nilHashValueConst[i] = t.HashSubTrees(nilHashValueConst[i-1], nilHashValueConst[i-1])
+	}
+	t.RootNode.Value = nilHashValueConst[maxHeight]
+	return nil
+}
+
+func (t *pathTree) update(index int64, nVal []byte) (err error) {
+	err = t.updatePath(index, nVal)
+	if err != nil {
+		log.Println("[Update] unable to update path:", err)
+		return err
+	}
+	if index >= t.nbLeaves {
+		t.nbLeaves = index + 1
+	}
+	return nil
+}
+
+func (t *pathTree) updatePath(index int64, nVal []byte) (err error) {
+	if index < 0 || index >= 1<<t.MaxHeight {
+		log.Println("[updatePath] invalid index")
+		return errors.New("[updatePath] invalid index")
+	}
+	t.nodes.setNode(0, index, nVal)
+	node := nVal
+	for height := 0; height < t.MaxHeight; height++ {
+		sibling, err := t.nodes.getNode(height, index^1)
+		if err != nil {
+			log.Println("[updatePath] unable to read sibling:", err)
+			return err
+		}
+		if index%2 == 0 {
+			node = t.HashSubTrees(node, sibling)
+		} else {
+			node = t.HashSubTrees(sibling, node)
+		}
+		index >>= 1
+		t.nodes.setNode(height+1, index, node)
+	}
+	t.RootNode.Value = node
+	return nil
+}
+
+func (t *pathTree) buildMerkleProofs(index int64) (
+	rMerkleProof [][]byte,
+	rProofHelper []int,
+	err error,
+) {
+	if index < 0 || index >= 1<<t.MaxHeight {
+		errInfo := fmt.Sprintf("[BuildMerkleProofs] index error, index: %v is out of tree capacity: %v.",
+			index, int64(1)<<t.MaxHeight)
+		log.Println(errInfo)
+		return nil, nil, errors.New(errInfo)
+	}
+	rMerkleProof = make([][]byte, t.MaxHeight)
+	rProofHelper = make([]int, t.MaxHeight)
+	for height := 0; height < t.MaxHeight; height++ {
+		rMerkleProof[height], err = t.nodes.getNode(height, index^1)
+		if err != nil {
+			log.Println("[buildMerkleProofs] unable to read sibling:", err)
+			return nil, nil, err
+		}
+		if index%2 == 0 {
+			rProofHelper[height] = Left
+		} else {
+			rProofHelper[height] = Right
+		}
+		index >>= 1
+	}
+	return rMerkleProof, rProofHelper, nil
+}
+
+func (t *pathTree) getLeaf(index int64) ([]byte, error) {
+	if index < 0 || index >= 1<<t.MaxHeight {
+		log.Println("[GetLeaf] invalid index")
+		return nil, errors.New("[GetLeaf] invalid index")
+	}
+	return t.nodes.getNode(0, index)
+}
+
+func (t *pathTree) readNode(height int, index int64) ([]byte, error) {
+	return t.nodes.getNode(height, index)
+}
+
+func (t *pathTree) isEmptyTree() bool {
+	return t.nbLeaves == 0
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+)
+
+var (
+	metaHeightKey   = []byte("m:height")
+	metaNbLeavesKey = []byte("m:leaves")
+)
+
+/*
+	nodeKey: store key of the node at (height, index), leaves are at height 0
+*/
+func nodeKey(height int, index int64) []byte {
+	key := make([]byte, 10)
+	key[0] = 'n'
+	key[1] = byte(height)
+	binary.BigEndian.PutUint64(key[2:], uint64(index))
+	return key
+}
+
+/*
+	storeNodes: layout backed by a NodeStore, nodes equal to the nil hash of their height
+	are never written, so the store only holds non-empty paths
+*/
+type storeNodes struct {
+	pathTree
+	// node store
+	store NodeStore
+	// updates not yet committed to the store
+	pending map[string][]byte
+}
+
+/*
+	OpenTree: open a tree backed by store, an empty store gives an empty tree.
+	Updates are kept in memory until Commit is called.
+*/
+func OpenTree(store NodeStore, maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
+	layout := &storeNodes{}
+	err := layout.open(store, maxHeight, nilHash, hFunc, layout)
+	if err != nil {
+		return nil, err
+	}
+	return layout.Tree, nil
+}
+
+func (t *storeNodes) open(store NodeStore, maxHeight int, nilHash []byte, hFunc hash.Hash, ops treeOps) error {
+	if store == nil {
+		log.Println("[OpenTree] invalid store")
+		return errors.New("[OpenTree] invalid store")
+	}
+	err := t.init(maxHeight, nilHash, hFunc, t, ops)
+	if err != nil {
+		errInfo := fmt.Sprintf("[OpenTree] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	heightBytes, err := store.Get(metaHeightKey)
+	if err == nil {
+		if int(binary.BigEndian.Uint64(heightBytes)) != maxHeight {
+			errInfo := fmt.Sprintf("[OpenTree] height mismatch, stored: %d, expected: %d",
+				binary.BigEndian.Uint64(heightBytes), maxHeight)
+			log.Println(errInfo)
+			return errors.New(errInfo)
+		}
+	} else if err != ErrNodeNotFound {
+		log.Println("[OpenTree] unable to read tree height:", err)
+		return err
+	}
+	nbLeavesBytes, err := store.Get(metaNbLeavesKey)
+	if err == nil {
+		t.nbLeaves = int64(binary.BigEndian.Uint64(nbLeavesBytes))
+	} else if err != ErrNodeNotFound {
+		log.Println("[OpenTree] unable to read leaves count:", err)
+		return err
+	}
+	t.store = store
+	t.pending = make(map[string][]byte)
+	t.RootNode.Value, err = t.getNode(maxHeight, 0)
+	if err != nil {
+		log.Println("[OpenTree] unable to read root:", err)
+		return err
+	}
+	return nil
+}
+
+/*
+	getNode: read node value from pending updates first, then from the store
+*/
+func (t *storeNodes) getNode(height int, index int64) ([]byte, error) {
+	key := nodeKey(height, index)
+	if value, ok := t.pending[string(key)]; ok {
+		if value == nil {
+			return t.NilHashValueConst[height], nil
+		}
+		return value, nil
+	}
+	value, err := t.store.Get(key)
+	if err == ErrNodeNotFound {
+		return t.NilHashValueConst[height], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (t *storeNodes) setNode(height int, index int64, value []byte) {
+	key := string(nodeKey(height, index))
+	if bytes.Equal(value, t.NilHashValueConst[height]) {
+		// nil value means delete
+		t.pending[key] = nil
+		return
+	}
+	t.pending[key] = value
+}
+
+/*
+	committer: layout keeping updates pending until they are committed
+*/
+type committer interface {
+	commit() error
+	discard() error
+}
+
+/*
+	IsPersistent: whether the nodes of the tree are kept in a NodeStore
+*/
+func (t *Tree) IsPersistent() bool {
+	_, ok := t.ops.(committer)
+	return ok
+}
+
+/*
+	Commit: write pending updates to the NodeStore of the tree in one batch,
+	a versioned tree commits them as its next version
+*/
+func (t *Tree) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	layout, ok := t.ops.(committer)
+	if !ok {
+		log.Println("[Commit] tree is not backed by a store")
+		return errors.New("[Commit] tree is not backed by a store")
+	}
+	return layout.commit()
+}
+
+/*
+	Discard: drop pending updates and reload the committed root
+*/
+func (t *Tree) Discard() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	layout, ok := t.ops.(committer)
+	if !ok {
+		log.Println("[Discard] tree is not backed by a store")
+		return errors.New("[Discard] tree is not backed by a store")
+	}
+	return layout.discard()
+}
+
+func (t *storeNodes) commit() error {
+	if len(t.pending) == 0 {
+		return nil
+	}
+	batch := t.store.NewBatch()
+	for key, value := range t.pending {
+		if value == nil {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Put([]byte(key), value)
+		}
+	}
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(t.MaxHeight))
+	batch.Put(metaHeightKey, buf)
+	buf = make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(t.nbLeaves))
+	batch.Put(metaNbLeavesKey, buf)
+	err := batch.Write()
+	if err != nil {
+		log.Println("[Commit] unable to write batch:", err)
+		return err
+	}
+	t.pending = make(map[string][]byte)
+	return nil
+}
+
+func (t *storeNodes) discard() error {
+	t.pending = make(map[string][]byte)
+	nbLeavesBytes, err := t.store.Get(metaNbLeavesKey)
+	if err == nil {
+		t.nbLeaves = int64(binary.BigEndian.Uint64(nbLeavesBytes))
+	} else if err == ErrNodeNotFound {
+		t.nbLeaves = 0
+	} else {
+		return err
+	}
+	t.RootNode.Value, err = t.getNode(t.MaxHeight, 0)
+	return err
+}
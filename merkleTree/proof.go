@@ -0,0 +1,135 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+/*
+	Proof: merkle proof of the leaf at Index against Root.
+	ProofSet holds the siblings from the leaf up, Helpers[i] is Right when the node at height i is a right child,
+	which is the layout expected by types.VerifyMerkleProof in the circuit.
+*/
+type Proof struct {
+	Index int64
+	// leaf value, the nil hash of the tree for an empty leaf
+	Leaf []byte
+	// whether the leaf is empty
+	IsEmpty  bool
+	Root     []byte
+	ProofSet [][]byte
+	Helpers  []int
+}
+
+/*
+	GetProof: build the proof of the leaf at index against the current root
+*/
+func (t *Tree) GetProof(index int64) (*Proof, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if index < 0 || index >= 1<<t.MaxHeight {
+		errInfo := fmt.Sprintf("[GetProof] index error, index: %v is out of tree capacity: %v.",
+			index, int64(1)<<t.MaxHeight)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	leaf, err := t.ops.getLeaf(index)
+	if err != nil {
+		log.Println("[GetProof] unable to get leaf:", err)
+		return nil, err
+	}
+	proofSet, helpers, err := t.ops.buildMerkleProofs(index)
+	if err != nil {
+		log.Println("[GetProof] unable to build merkle proofs:", err)
+		return nil, err
+	}
+	return &Proof{
+		Index:    index,
+		Leaf:     leaf,
+		IsEmpty:  bytes.Equal(leaf, t.NilHashValueConst[0]),
+		Root:     t.RootNode.Value,
+		ProofSet: proofSet,
+		Helpers:  helpers,
+	}, nil
+}
+
+/*
+	VerifyProof: verify a proof of a MiMC tree against root
+*/
+func VerifyProof(root []byte, proof *Proof) bool {
+	return VerifyProofWithHash(root, proof, mimc.NewMiMC())
+}
+
+/*
+	VerifyProofWithHash: verify a proof against root, the helpers must match the bits of the index
+*/
+func VerifyProofWithHash(root []byte, proof *Proof, hFunc hash.Hash) bool {
+	if proof == nil || len(proof.ProofSet) != len(proof.Helpers) || len(proof.ProofSet) > 63 {
+		return false
+	}
+	if proof.Index < 0 || proof.Index >= 1<<len(proof.ProofSet) {
+		return false
+	}
+	if !bytes.Equal(root, proof.Root) {
+		return false
+	}
+	node := proof.Leaf
+	for i := 0; i < len(proof.ProofSet); i++ {
+		if int(proof.Index>>uint(i))&1 != proof.Helpers[i] {
+			return false
+		}
+		hFunc.Reset()
+		switch proof.Helpers[i] {
+		case Left:
+			hFunc.Write(node)
+			hFunc.Write(proof.ProofSet[i])
+		case Right:
+			hFunc.Write(proof.ProofSet[i])
+			hFunc.Write(node)
+		default:
+			return false
+		}
+		node = hFunc.Sum([]byte{})
+	}
+	return bytes.Equal(root, node)
+}
+
+/*
+	VerifyEmptyProof: verify a proof against root and check the leaf is the nil leaf,
+	used to make sure an account or nft index is not taken yet
+*/
+func VerifyEmptyProof(root []byte, proof *Proof, nilHash []byte) bool {
+	return VerifyEmptyProofWithHash(root, proof, nilHash, mimc.NewMiMC())
+}
+
+/*
+	VerifyEmptyProofWithHash: same as VerifyEmptyProof for trees built with another hash
+*/
+func VerifyEmptyProofWithHash(root []byte, proof *Proof, nilHash []byte, hFunc hash.Hash) bool {
+	if proof == nil || !proof.IsEmpty || !bytes.Equal(proof.Leaf, nilHash) {
+		return false
+	}
+	return VerifyProofWithHash(root, proof, hFunc)
+}
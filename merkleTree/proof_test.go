@@ -0,0 +1,96 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/poseidon"
+)
+
+func TestEmptyLeafProof(t *testing.T) {
+	hashState := MockState(3)
+	newTrees := map[string]func() (*Tree, error){
+		"pointer": func() (*Tree, error) {
+			return NewEmptyTree(16, NilHash, mimc.NewMiMC())
+		},
+		"sparse": func() (*Tree, error) {
+			return NewSparseTree(16, NilHash, mimc.NewMiMC())
+		},
+	}
+	for name, newTree := range newTrees {
+		tree, err := newTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// empty tree
+		proof, err := tree.GetProof(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, VerifyEmptyProof(tree.GetRoot(), proof, NilHash), name)
+		for i, leaf := range hashState {
+			assert.NoError(t, tree.Update(int64(i), leaf))
+		}
+		root := tree.GetRoot()
+		// index right after the last leaf, like a new account
+		proof, err = tree.GetProof(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, VerifyEmptyProof(root, proof, NilHash), name)
+		proof, err = tree.GetProof(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, proof.IsEmpty, name)
+		assert.True(t, VerifyProof(root, proof), name)
+		assert.False(t, VerifyEmptyProof(root, proof, NilHash), name)
+
+		// tampered proofs
+		assert.False(t, VerifyProof(NilHash, proof), name)
+		proof.Index = 0
+		assert.False(t, VerifyProof(root, proof), name)
+		proof.Index = 1
+		proof.Leaf = hashState[0]
+		assert.False(t, VerifyProof(root, proof), name)
+	}
+}
+
+func TestEmptyLeafProofWithHash(t *testing.T) {
+	tree, err := NewEmptyTree(8, NilHash, poseidon.NewPoseidon())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, tree.Update(0, MockState(1)[0]))
+	root := tree.GetRoot()
+	proof, err := tree.GetProof(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, VerifyEmptyProofWithHash(root, proof, NilHash, poseidon.NewPoseidon()))
+	assert.False(t, VerifyEmptyProof(root, proof, NilHash))
+	proof, err = tree.GetProof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, VerifyEmptyProofWithHash(root, proof, NilHash, poseidon.NewPoseidon()))
+}
@@ -18,11 +18,11 @@
 package merkleTree
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"hash"
 	"log"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -37,12 +37,21 @@ var (
 )
 
 /*
-	Tree: sparse merkle tree
+	Tree: sparse merkle tree, safe for many readers and one writer.
+	By default the leaves are kept in order and linked to their parents, the indexes after
+	the last leaf are empty. NewSparseTree, OpenTree and OpenVersionedTree keep the nodes
+	in a node layout instead, in memory or in a NodeStore.
 */
 type Tree struct {
+	// unique and increasing, orders the locks of operations on two trees
+	id uint64
+	// guards the nodes, held for writing by updates and for reading by queries
+	mu sync.RWMutex
+	// guards HashFunc
+	hashMu sync.Mutex
 	// root Node
 	RootNode *Node
-	// leaves
+	// leaves, empty when the nodes are kept by a node layout
 	Leaves []*Node
 	// max height
 	MaxHeight int
@@ -50,6 +59,10 @@ type Tree struct {
 	NilHashValueConst [][]byte
 	// hash function
 	HashFunc hash.Hash
+	// creates independent hash instances for concurrent hashing, optional
+	HashFactory func() hash.Hash
+	// node layout, the tree itself when the nodes are linked by pointers
+	ops treeOps
 }
 
 /*
@@ -124,12 +137,14 @@ func NewEmptyTree(maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error)
 	nilHashValueConst[0] = nilHash
 	// init tree
 	tree := &Tree{
+		id:                nextTreeId(),
 		RootNode:          root,
-		MaxHeight:         maxHeight,
 		Leaves:            *new([]*Node),
+		MaxHeight:         maxHeight,
 		NilHashValueConst: nilHashValueConst,
 		HashFunc:          hFunc,
 	}
+	tree.ops = tree
 	err := tree.InitNilHashValueConst()
 	if err != nil {
 		errInfo := fmt.Sprintf("[smt.NewEmptyTree] InitNilHashValueConst error: %s", err.Error())
@@ -196,12 +211,14 @@ func NewTreeByMap(leaves map[int64]*Node, maxHeight int, nilHash []byte, hFunc h
 	}
 	// init tree
 	tree := &Tree{
+		id:                nextTreeId(),
 		RootNode:          root,
 		Leaves:            nodes,
 		MaxHeight:         maxHeight,
 		NilHashValueConst: nilHashValueConst,
 		HashFunc:          hFunc,
 	}
+	tree.ops = tree
 	err := tree.InitNilHashValueConst()
 	if err != nil {
 		errInfo := fmt.Sprintf("[smt.NewTree] InitNilHashValueConst error: %s", err.Error())
@@ -245,12 +262,14 @@ func NewTree(leaves []*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*T
 	nilHashValueConst[0] = nilHash
 	// init tree
 	tree := &Tree{
+		id:                nextTreeId(),
 		RootNode:          root,
 		Leaves:            leaves,
 		MaxHeight:         maxHeight,
 		NilHashValueConst: nilHashValueConst,
 		HashFunc:          hFunc,
 	}
+	tree.ops = tree
 	err := tree.InitNilHashValueConst()
 	if err != nil {
 		errInfo := fmt.Sprintf("[smt.NewTree] InitNilHashValueConst error: %s", err.Error())
@@ -266,17 +285,6 @@ func NewTree(leaves []*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*T
 	return tree, nil
 }
 
-/*
-	HashSubTrees: hash sub-tree nodes
-*/
-func (t *Tree) HashSubTrees(l []byte, r []byte) []byte {
-	t.HashFunc.Reset()
-	t.HashFunc.Write(l)
-	t.HashFunc.Write(r)
-	val := t.HashFunc.Sum([]byte{})
-	return val
-}
-
 /*
 	BuildTree: build sparse merkle tree
 */
@@ -317,10 +325,7 @@ func (t *Tree) BuildTree(nodes []*Node) (err error) {
 	return err
 }
 
-/*
-	BuildMerkleProofs: construct merkle proofs
-*/
-func (t *Tree) BuildMerkleProofs(index int64) (
+func (t *Tree) buildMerkleProofs(index int64) (
 	rMerkleProof [][]byte,
 	rProofHelper []int,
 	err error,
@@ -335,11 +340,13 @@ func (t *Tree) BuildMerkleProofs(index int64) (
 	}
 	// empty tree
 	if len(t.Leaves) == 0 {
+		rMerkleProof = make([][]byte, t.MaxHeight)
 		rProofHelper = make([]int, t.MaxHeight)
 		for i := 0; i < t.MaxHeight; i++ {
-			rProofHelper[i] = 0
+			rMerkleProof[i] = t.NilHashValueConst[i]
+			rProofHelper[i] = int(index>>uint(i)) & 1
 		}
-		return t.NilHashValueConst, rProofHelper, nil
+		return rMerkleProof, rProofHelper, nil
 	}
 	// if index belongs to leaves
 	if index < int64(len(t.Leaves)) {
@@ -370,12 +377,13 @@ func (t *Tree) BuildMerkleProofs(index int64) (
 		lastIndex := int64(len(t.Leaves) - 1)
 		// get last leave node
 		node := t.Leaves[lastIndex]
+		// helpers describe the position of the target node, not of the last leaf
 		for lastIndex+1 != index {
 			proofs = append(proofs, t.NilHashValueConst[node.Height])
 			if index%2 == 0 {
-				proofHelpers = append(proofHelpers, Right)
-			} else {
 				proofHelpers = append(proofHelpers, Left)
+			} else {
+				proofHelpers = append(proofHelpers, Right)
 			}
 			// update value
 			lastIndex /= 2
@@ -384,11 +392,11 @@ func (t *Tree) BuildMerkleProofs(index int64) (
 		}
 		for node.Parent != nil && node.Parent.Right == node {
 			proofs = append(proofs, t.NilHashValueConst[node.Height])
-			proofHelpers = append(proofHelpers, Right)
+			proofHelpers = append(proofHelpers, Left)
 			node = node.Parent
 		}
 		proofs = append(proofs, node.Value)
-		proofHelpers = append(proofHelpers, Left)
+		proofHelpers = append(proofHelpers, Right)
 		node = node.Parent
 		for node.Parent != nil {
 			if node.Parent.Left == node {
@@ -412,7 +420,7 @@ func (t *Tree) BuildMerkleProofs(index int64) (
 	return proofs, proofHelpers, nil
 }
 
-func (t *Tree) Update(index int64, nVal []byte) (err error) {
+func (t *Tree) update(index int64, nVal []byte) (err error) {
 	if index >= 1<<t.MaxHeight {
 		log.Println("[Update] invalid index")
 		return errors.New("[Update] invalid index")
@@ -590,36 +598,35 @@ func (t *Tree) insert(nodes []*Node) {
 	t.insert(parents)
 }
 
-/*
-	VerifyMerkleProofs: verify merkle proofs
-	@inclusionProofs: inclusion proofs
-	@helperProofs: helper function
-*/
-func (t *Tree) VerifyMerkleProofs(inclusionProofs [][]byte, helperProofs []int) bool {
-	if len(inclusionProofs) != len(helperProofs)+1 {
-		return false
+func (t *Tree) isEmptyTree() bool {
+	return len(t.Leaves) == 0
+}
+
+func (t *Tree) getLeaf(index int64) ([]byte, error) {
+	if index < 0 || index >= 1<<t.MaxHeight {
+		log.Println("[GetLeaf] invalid index")
+		return nil, errors.New("[GetLeaf] invalid index")
 	}
-	// empty tree
-	if len(t.Leaves) == 0 {
-		return true
-	}
-	root := t.RootNode.Value
-	node := inclusionProofs[0]
-	for i := 1; i < len(inclusionProofs); i++ {
-		switch helperProofs[i-1] {
-		case Left:
-			node = t.HashSubTrees(node, inclusionProofs[i])
-			continue
-		case Right:
-			node = t.HashSubTrees(inclusionProofs[i], node)
-			continue
-		default:
-			return false
-		}
+	if index < int64(len(t.Leaves)) {
+		return t.Leaves[index].Value, nil
 	}
-	return bytes.Equal(root, node)
+	return t.NilHashValueConst[0], nil
 }
 
-func (t *Tree) IsEmptyTree() bool {
-	return len(t.Leaves) == 0
+/*
+	readNode: walk down from the root, a missing child is an empty subtree
+*/
+func (t *Tree) readNode(height int, index int64) ([]byte, error) {
+	node := t.RootNode
+	for h := t.MaxHeight; h > height; h-- {
+		if (index>>uint(h-1-height))&1 == 0 {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+		if node == nil {
+			return t.NilHashValueConst[height], nil
+		}
+	}
+	return node.Value, nil
 }
@@ -25,9 +25,10 @@ import (
 	"testing"
 	"time"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
 )
 
 func MockState(size int) [][]byte {
@@ -48,6 +49,14 @@ func ToString(buf []byte) string {
 	return hex.EncodeToString(buf)
 }
 
+func verifyIndex(tree *Tree, index int64) bool {
+	proof, err := tree.GetProof(index)
+	if err != nil {
+		return false
+	}
+	return VerifyProof(tree.RootNode.Value, proof)
+}
+
 func TestCreateLeafNode(t *testing.T) {
 	tree, err := NewEmptyTree(32, NilHash, mimc.NewMiMC())
 	if err != nil {
@@ -92,14 +101,14 @@ func TestNewTree(t *testing.T) {
 	fmt.Println("len:", len(merkleProofs))
 	fmt.Println("BuildTree proofs time:", time.Since(elapse))
 	fmt.Println("merkle proof helper:", helperMerkleProofs)
-	res := tree.VerifyMerkleProofs(merkleProofs, helperMerkleProofs)
+	res := verifyIndex(tree, 4)
 	assert.Equal(t, res, true, "BuildTree merkle proofs successfully")
 	// if len(t.leaves) % 2 != 0 && index == len(t.leaves) + 1
 	merkleProofs, helperMerkleProofs, err = tree.BuildMerkleProofs(0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	res = tree.VerifyMerkleProofs(merkleProofs, helperMerkleProofs)
+	res = verifyIndex(tree, 0)
 	fmt.Println("merkle proof helper:", helperMerkleProofs)
 	assert.Equal(t, res, true, "BuildTree merkle proofs successfully")
 	// verify index >= len(t.leaves) + 1
@@ -109,7 +118,7 @@ func TestNewTree(t *testing.T) {
 		t.Fatal(err)
 	}
 	fmt.Println("before proofs:", merkleProofs)
-	res = tree.VerifyMerkleProofs(merkleProofs, helperMerkleProofs)
+	res = verifyIndex(tree, 2)
 	fmt.Println("merkle proof helper:", helperMerkleProofs)
 	assert.Equal(t, res, true, "BuildTree merkle proofs successfully")
 	h.Reset()
@@ -185,17 +194,17 @@ func TestNewTreeByMap(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	proofs, proofsHelper, err := tree.BuildMerkleProofs(3)
+	proofs, _, err := tree.BuildMerkleProofs(3)
 	if err != nil {
 		t.Fatal(err)
 	}
-	isValid := tree.VerifyMerkleProofs(proofs, proofsHelper)
+	isValid := verifyIndex(tree, 3)
 	assert.Equal(t, true, isValid, "invalid proof")
-	proofs, proofsHelper, err = tree.BuildMerkleProofs(110)
+	proofs, _, err = tree.BuildMerkleProofs(110)
 	if err != nil {
 		t.Fatal(err)
 	}
-	isValid = tree.VerifyMerkleProofs(proofs, proofsHelper)
+	isValid = verifyIndex(tree, 110)
 	assert.Equal(t, true, isValid, "invalid proof")
 	log.Println(common.Bytes2Hex(proofs[0]))
 	h.Reset()
@@ -206,11 +215,11 @@ func TestNewTreeByMap(t *testing.T) {
 		t.Fatal(err)
 	}
 	log.Println(common.Bytes2Hex(nVal))
-	proofs, proofsHelper, err = tree.BuildMerkleProofs(110)
+	proofs, _, err = tree.BuildMerkleProofs(110)
 	if err != nil {
 		t.Fatal(err)
 	}
-	isValid = tree.VerifyMerkleProofs(proofs, proofsHelper)
+	isValid = verifyIndex(tree, 110)
 	assert.Equal(t, true, isValid, "invalid proof")
 	log.Println(common.Bytes2Hex(proofs[0]))
 }
@@ -222,11 +231,7 @@ func TestNewEmptyTree(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	merkleProofs, merkleProofsHelper, err := tree.BuildMerkleProofs(5)
-	if err != nil {
-		t.Fatal(err)
-	}
-	isValid := tree.VerifyMerkleProofs(merkleProofs, merkleProofsHelper)
+	isValid := verifyIndex(tree, 5)
 	assert.Equal(t, true, isValid, "invalid proof")
 	h.Reset()
 	h.Write([]byte("1"))
@@ -235,8 +240,7 @@ func TestNewEmptyTree(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	merkleProofs, merkleProofsHelper, err = tree.BuildMerkleProofs(0)
-	isValid = tree.VerifyMerkleProofs(merkleProofs, merkleProofsHelper)
+	isValid = verifyIndex(tree, 0)
 	assert.Equal(t, true, isValid, "invalid proof")
 }
 
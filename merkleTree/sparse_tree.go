@@ -0,0 +1,112 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+)
+
+/*
+	sparseNodes: in-memory layout which only keeps the non-empty nodes
+*/
+type sparseNodes struct {
+	pathTree
+	// non-empty nodes, indexed by height
+	levels []map[int64][]byte
+}
+
+/*
+	NewSparseTree: create an in-memory sparse tree, only the non-empty nodes are kept
+	and empty subtrees are represented by NilHashValueConst, so the cost of Update and
+	BuildMerkleProofs only depends on MaxHeight, not on the largest index.
+	Use it for deep trees like the 40 levels nft tree.
+*/
+func NewSparseTree(maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
+	layout := &sparseNodes{}
+	err := layout.init(maxHeight, nilHash, hFunc, layout, layout)
+	if err != nil {
+		errInfo := fmt.Sprintf("[NewSparseTree] %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	layout.levels = make([]map[int64][]byte, maxHeight+1)
+	for i := range layout.levels {
+		layout.levels[i] = make(map[int64][]byte)
+	}
+	return layout.Tree, nil
+}
+
+/*
+	NewSparseTreeByMap: create an in-memory sparse tree from the populated leaves
+*/
+func NewSparseTreeByMap(leaves map[int64]*Node, maxHeight int, nilHash []byte, hFunc hash.Hash) (*Tree, error) {
+	tree, err := NewSparseTree(maxHeight, nilHash, hFunc)
+	if err != nil {
+		return nil, err
+	}
+	updates := make(map[int64][]byte, len(leaves))
+	for index, leaf := range leaves {
+		if leaf != nil {
+			updates[index] = leaf.Value
+		}
+	}
+	err = tree.BatchUpdate(updates)
+	if err != nil {
+		errInfo := fmt.Sprintf("[NewSparseTreeByMap] unable to update leaves: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return tree, nil
+}
+
+func (t *sparseNodes) getNode(height int, index int64) ([]byte, error) {
+	if value, ok := t.levels[height][index]; ok {
+		return value, nil
+	}
+	return t.NilHashValueConst[height], nil
+}
+
+func (t *sparseNodes) setNode(height int, index int64, value []byte) {
+	if bytes.Equal(value, t.NilHashValueConst[height]) {
+		delete(t.levels[height], index)
+		return
+	}
+	t.levels[height][index] = value
+}
+
+/*
+	NbNodes: number of non-empty nodes kept by a tree created by NewSparseTree,
+	0 for the other trees
+*/
+func (t *Tree) NbNodes() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	layout, ok := t.ops.(*sparseNodes)
+	if !ok {
+		return 0
+	}
+	var count int
+	for _, level := range layout.levels {
+		count += len(level)
+	}
+	return count
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func TestSparseTreeMatchesDenseTree(t *testing.T) {
+	hashState := MockState(3)
+	leaves := map[int64]*Node{
+		0:   CreateLeafNode(hashState[0]),
+		100: CreateLeafNode(hashState[1]),
+		513: CreateLeafNode(hashState[2]),
+	}
+	dense, err := NewTreeByMap(leaves, 16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse, err := NewSparseTreeByMap(leaves, 16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, dense.RootNode.Value, sparse.RootNode.Value)
+	// 0 and 100 share ancestors from height 7, 513 joins them from height 10
+	assert.Equal(t, 7*3+3*2+7, sparse.NbNodes())
+}
+
+func TestSparseTreeNftLevels(t *testing.T) {
+	const nftMerkleLevels = 40
+	tree, err := NewSparseTree(nftMerkleLevels, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyRoot := tree.RootNode.Value
+	hashState := MockState(2)
+	lastNftIndex := int64(1)<<nftMerkleLevels - 1
+	assert.NoError(t, tree.Update(lastNftIndex, hashState[0]))
+	assert.NoError(t, tree.Update(1<<39, hashState[1]))
+	assert.Error(t, tree.Update(lastNftIndex+1, hashState[1]))
+	// both paths only share the node at height 39 and the root
+	assert.Equal(t, 2*(nftMerkleLevels-1)+2, tree.NbNodes())
+
+	// proofs follow types.VerifyMerkleProof: siblings from the leaf up, helper bit 1 for right nodes
+	proofs, helpers, err := tree.BuildMerkleProofs(lastNftIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, nftMerkleLevels, len(proofs))
+	for _, helper := range helpers {
+		assert.Equal(t, Right, helper)
+	}
+	proof, err := tree.GetProof(lastNftIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, proofs, proof.ProofSet)
+	assert.True(t, VerifyProof(tree.RootNode.Value, proof))
+
+	// clearing the leaves gives back the empty tree
+	assert.NoError(t, tree.Update(lastNftIndex, NilHash))
+	assert.NoError(t, tree.Update(1<<39, NilHash))
+	assert.Equal(t, emptyRoot, tree.RootNode.Value)
+	assert.Equal(t, 0, tree.NbNodes())
+}
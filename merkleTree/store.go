@@ -0,0 +1,453 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrNodeNotFound = errors.New("[NodeStore] node not found")
+	ErrStoreClosed  = errors.New("[NodeStore] store is closed")
+)
+
+/*
+	NodeStore: key-value backend used to persist tree nodes
+*/
+type NodeStore interface {
+	// Get returns ErrNodeNotFound if the key does not exist
+	Get(key []byte) ([]byte, error)
+	// NewBatch creates a write batch, nothing is written until Batch.Write is called
+	NewBatch() Batch
+	// Close releases the underlying resources
+	Close() error
+}
+
+/*
+	Batch: atomic group of writes to a NodeStore
+*/
+type Batch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+	Write() error
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+type opBatch struct {
+	ops   []batchOp
+	write func(ops []batchOp) error
+}
+
+func (b *opBatch) Put(key []byte, value []byte) {
+	b.ops = append(b.ops, batchOp{key: copyBytes(key), value: copyBytes(value)})
+}
+
+func (b *opBatch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: copyBytes(key), delete: true})
+}
+
+func (b *opBatch) Write() error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	err := b.write(b.ops)
+	b.ops = nil
+	return err
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+/*
+	MemoryStore: in-memory NodeStore
+*/
+type MemoryStore struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.values == nil {
+		return nil, ErrStoreClosed
+	}
+	value, ok := s.values[string(key)]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return copyBytes(value), nil
+}
+
+func (s *MemoryStore) NewBatch() Batch {
+	return &opBatch{write: s.writeOps}
+}
+
+func (s *MemoryStore) writeOps(ops []batchOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		return ErrStoreClosed
+	}
+	for _, op := range ops {
+		if op.delete {
+			delete(s.values, string(op.key))
+		} else {
+			s.values[string(op.key)] = op.value
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = nil
+	return nil
+}
+
+/*
+	FileStore: append-only log file NodeStore.
+	Every record is crc32 | flag | keyLen | valueLen | key | value, the latest record of a key wins.
+	Only the offsets are kept in memory, values are read from disk on demand.
+*/
+type FileStore struct {
+	mu    sync.RWMutex
+	file  *os.File
+	index map[string]recordPos
+	// end of the last valid record
+	size int64
+	// bytes taken by overwritten or deleted records
+	garbage int64
+}
+
+type recordPos struct {
+	offset int64
+	length uint32
+	// full record length, used to account garbage
+	recordLen int64
+}
+
+const (
+	recordHeaderSize = 4 + 1 + 4 + 4
+	recordFlagPut    = 0
+	recordFlagDelete = 1
+)
+
+/*
+	NewFileStore: open or create a file store at path, a torn record at the end of the file is truncated,
+	a corrupted record in the middle of the file is an error
+*/
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		errInfo := fmt.Sprintf("[NewFileStore] unable to open file: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	s := &FileStore{
+		file:  file,
+		index: make(map[string]recordPos),
+	}
+	err = s.load()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+/*
+	load: index the records of the log, only a torn record at the end of the file is truncated.
+	A record is torn when it runs past the end of the file or when it is the last record and
+	its checksum fails, a bad record followed by more data is a corruption and is returned as an error.
+*/
+func (s *FileStore) load() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+	reader := bufio.NewReader(io.NewSectionReader(s.file, 0, fileSize))
+	var offset int64
+	header := make([]byte, recordHeaderSize)
+	for offset < fileSize {
+		remaining := fileSize - offset
+		if remaining < recordHeaderSize {
+			break
+		}
+		_, err = io.ReadFull(reader, header)
+		if err != nil {
+			return err
+		}
+		checksum := binary.BigEndian.Uint32(header[0:4])
+		flag := header[4]
+		keyLen := binary.BigEndian.Uint32(header[5:9])
+		valueLen := binary.BigEndian.Uint32(header[9:13])
+		// the lengths are not verified yet, never allocate more than the file holds
+		bodyLen := int64(keyLen) + int64(valueLen)
+		if bodyLen > remaining-recordHeaderSize {
+			break
+		}
+		body := make([]byte, bodyLen)
+		_, err = io.ReadFull(reader, body)
+		if err != nil {
+			return err
+		}
+		recordLen := recordHeaderSize + bodyLen
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(body)
+		if crc.Sum32() != checksum || flag > recordFlagDelete {
+			if offset+recordLen == fileSize {
+				break
+			}
+			errInfo := fmt.Sprintf("[FileStore] corrupted record at offset %d, %d bytes follow it", offset, fileSize-offset-recordLen)
+			log.Println(errInfo)
+			return errors.New(errInfo)
+		}
+		key := string(body[:keyLen])
+		if old, ok := s.index[key]; ok {
+			s.garbage += old.recordLen
+		}
+		if flag == recordFlagDelete {
+			delete(s.index, key)
+			s.garbage += recordLen
+		} else {
+			s.index[key] = recordPos{
+				offset:    offset + recordHeaderSize + int64(keyLen),
+				length:    valueLen,
+				recordLen: recordLen,
+			}
+		}
+		offset += recordLen
+	}
+	if fileSize != offset {
+		log.Printf("[FileStore] truncate a torn record, file size: %d, valid size: %d\n", fileSize, offset)
+		if err = s.file.Truncate(offset); err != nil {
+			return err
+		}
+	}
+	s.size = offset
+	return nil
+}
+
+func (s *FileStore) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.file == nil {
+		return nil, ErrStoreClosed
+	}
+	pos, ok := s.index[string(key)]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	value := make([]byte, pos.length)
+	_, err := s.file.ReadAt(value, pos.offset)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *FileStore) NewBatch() Batch {
+	return &opBatch{write: s.writeOps}
+}
+
+func encodeRecord(buf *bytes.Buffer, op batchOp) {
+	header := make([]byte, recordHeaderSize)
+	if op.delete {
+		header[4] = recordFlagDelete
+	} else {
+		header[4] = recordFlagPut
+	}
+	binary.BigEndian.PutUint32(header[5:9], uint32(len(op.key)))
+	binary.BigEndian.PutUint32(header[9:13], uint32(len(op.value)))
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(op.key)
+	crc.Write(op.value)
+	binary.BigEndian.PutUint32(header[0:4], crc.Sum32())
+	buf.Write(header)
+	buf.Write(op.key)
+	buf.Write(op.value)
+}
+
+func (s *FileStore) writeOps(ops []batchOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	var buf bytes.Buffer
+	for _, op := range ops {
+		encodeRecord(&buf, op)
+	}
+	_, err := s.file.WriteAt(buf.Bytes(), s.size)
+	if err != nil {
+		errInfo := fmt.Sprintf("[FileStore.Write] unable to write records: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	err = s.file.Sync()
+	if err != nil {
+		errInfo := fmt.Sprintf("[FileStore.Write] unable to sync file: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	// only update the index once the records are durable
+	offset := s.size
+	for _, op := range ops {
+		recordLen := int64(recordHeaderSize + len(op.key) + len(op.value))
+		key := string(op.key)
+		if old, ok := s.index[key]; ok {
+			s.garbage += old.recordLen
+		}
+		if op.delete {
+			delete(s.index, key)
+			s.garbage += recordLen
+		} else {
+			s.index[key] = recordPos{
+				offset:    offset + recordHeaderSize + int64(len(op.key)),
+				length:    uint32(len(op.value)),
+				recordLen: recordLen,
+			}
+		}
+		offset += recordLen
+	}
+	s.size = offset
+	return nil
+}
+
+/*
+	Garbage: bytes taken by stale records, used to decide when to call Compact
+*/
+func (s *FileStore) Garbage() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.garbage
+}
+
+/*
+	Compact: rewrite the live records into a new file and replace the old one
+*/
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	path := s.file.Name()
+	tmpPath := path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		errInfo := fmt.Sprintf("[FileStore.Compact] unable to create file: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	var (
+		buf    bytes.Buffer
+		offset int64
+	)
+	index := make(map[string]recordPos, len(s.index))
+	for key, pos := range s.index {
+		value := make([]byte, pos.length)
+		if _, err = s.file.ReadAt(value, pos.offset); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+		buf.Reset()
+		encodeRecord(&buf, batchOp{key: []byte(key), value: value})
+		if _, err = tmp.Write(buf.Bytes()); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+		index[key] = recordPos{
+			offset:    offset + recordHeaderSize + int64(len(key)),
+			length:    pos.length,
+			recordLen: int64(buf.Len()),
+		}
+		offset += int64(buf.Len())
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	s.file.Close()
+	s.file = tmp
+	s.index = index
+	s.size = offset
+	s.garbage = 0
+	// the rename is only durable once the parent directory entry is flushed
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func TestOpenTreeMatchesInMemoryTree(t *testing.T) {
+	hashState := MockState(11)
+	memTree, err := NewEmptyTree(16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeTree, err := OpenTree(NewMemoryStore(), 16, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, memTree.RootNode.Value, storeTree.RootNode.Value)
+	assert.True(t, storeTree.IsEmptyTree())
+	for i, leaf := range hashState {
+		index := int64(i * 3)
+		assert.NoError(t, memTree.Update(index, leaf))
+		assert.NoError(t, storeTree.Update(index, leaf))
+		assert.Equal(t, memTree.RootNode.Value, storeTree.RootNode.Value)
+	}
+	for _, index := range []int64{0, 3, 4, 30, 31, 1000} {
+		proof, err := storeTree.GetProof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, VerifyProof(memTree.RootNode.Value, proof))
+	}
+	// the store operations are only available on store backed trees
+	assert.True(t, storeTree.IsPersistent())
+	assert.False(t, memTree.IsPersistent())
+	assert.NoError(t, storeTree.Commit())
+	assert.Error(t, memTree.Commit())
+	assert.Error(t, storeTree.Rollback(0))
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.db")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := OpenTree(store, 40, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashState := MockState(4)
+	assert.NoError(t, tree.Update(0, hashState[0]))
+	assert.NoError(t, tree.Update(1<<39, hashState[1]))
+	assert.NoError(t, tree.Commit())
+	committedRoot := tree.RootNode.Value
+	// uncommitted updates are lost on reopen
+	assert.NoError(t, tree.Update(7, hashState[2]))
+	assert.NoError(t, store.Close())
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenTree(store, 40, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, committedRoot, reopened.RootNode.Value)
+	assert.False(t, reopened.IsEmptyTree())
+
+	// overwrite a leaf and compact, the root must survive
+	assert.NoError(t, reopened.Update(0, hashState[3]))
+	assert.NoError(t, reopened.Commit())
+	assert.True(t, store.Garbage() > 0)
+	assert.NoError(t, store.Compact())
+	assert.Equal(t, int64(0), store.Garbage())
+	root := reopened.RootNode.Value
+	assert.NoError(t, store.Close())
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	reopened, err = OpenTree(store, 40, NilHash, mimc.NewMiMC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, root, reopened.RootNode.Value)
+	_, err = OpenTree(store, 32, NilHash, mimc.NewMiMC())
+	assert.Error(t, err)
+}
+
+func TestFileStoreTornAndCorruptedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.db")
+	write := func(data []byte) {
+		assert.NoError(t, os.WriteFile(path, data, 0644))
+	}
+	reopen := func() (*FileStore, error) {
+		return NewFileStore(path)
+	}
+	store, err := reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := store.NewBatch()
+	batch.Put([]byte("a"), []byte("first"))
+	assert.NoError(t, batch.Write())
+	batch = store.NewBatch()
+	batch.Put([]byte("b"), []byte("second"))
+	assert.NoError(t, batch.Write())
+	assert.NoError(t, store.Close())
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstLen := recordHeaderSize + len("a") + len("first")
+
+	// a torn final record is dropped, the records before it survive
+	write(log[:len(log)-3])
+	store, err = reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := store.Get([]byte("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("first"), value)
+	_, err = store.Get([]byte("b"))
+	assert.Equal(t, ErrNodeNotFound, err)
+	assert.NoError(t, store.Close())
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(firstLen), info.Size())
+
+	// the last record fails its checksum
+	corrupted := append([]byte{}, log...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	write(corrupted)
+	store, err = reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get([]byte("b"))
+	assert.Equal(t, ErrNodeNotFound, err)
+	assert.NoError(t, store.Close())
+
+	// a length larger than the file is a torn record, it is never allocated
+	corrupted = append([]byte{}, log...)
+	binary.BigEndian.PutUint32(corrupted[firstLen+9:firstLen+13], 0xffffffff)
+	write(corrupted)
+	store, err = reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err = store.Get([]byte("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("first"), value)
+	assert.NoError(t, store.Close())
+
+	// a bad record followed by more data is not truncated
+	corrupted = append([]byte{}, log...)
+	corrupted[firstLen-1] ^= 0xff
+	write(corrupted)
+	_, err = reopen()
+	assert.Error(t, err)
+	stored, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, corrupted, stored)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"sync/atomic"
+)
+
+/*
+	treeOps: layout of the nodes of a tree, called with the lock of the tree held
+*/
+type treeOps interface {
+	update(index int64, nVal []byte) error
+	// indexes are sorted and in range
+	batchUpdateSorted(indexes []int64, updates map[int64][]byte) error
+	getLeaf(index int64) ([]byte, error)
+	buildMerkleProofs(index int64) ([][]byte, []int, error)
+	readNode(height int, index int64) ([]byte, error)
+	isEmptyTree() bool
+}
+
+// last id given to a tree
+var lastTreeId uint64
+
+func nextTreeId() uint64 {
+	return atomic.AddUint64(&lastTreeId, 1)
+}
+
+/*
+	HashSubTrees: hash sub-tree nodes
+*/
+func (t *Tree) HashSubTrees(l []byte, r []byte) []byte {
+	t.hashMu.Lock()
+	defer t.hashMu.Unlock()
+	t.HashFunc.Reset()
+	t.HashFunc.Write(l)
+	t.HashFunc.Write(r)
+	val := t.HashFunc.Sum([]byte{})
+	return val
+}
+
+/*
+	GetRoot: current root, including uncommitted updates
+*/
+func (t *Tree) GetRoot() []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.RootNode.Value
+}
+
+/*
+	GetLeaf: get the leaf value at index, the nil hash is returned for unset leaves
+*/
+func (t *Tree) GetLeaf(index int64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.ops.getLeaf(index)
+}
+
+func (t *Tree) Update(index int64, nVal []byte) (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ops.update(index, nVal)
+}
+
+func (t *Tree) IsEmptyTree() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.ops.isEmptyTree()
+}
+
+/*
+	BuildMerkleProofs: construct merkle proofs
+*/
+func (t *Tree) BuildMerkleProofs(index int64) (
+	rMerkleProof [][]byte,
+	rProofHelper []int,
+	err error,
+) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.ops.buildMerkleProofs(index)
+}
+
+/*
+	BuildMerkleProofsWithRoot: construct merkle proofs together with the root they belong to,
+	both are read under the same lock so a concurrent update can not mix two states
+*/
+func (t *Tree) BuildMerkleProofsWithRoot(index int64) (
+	root []byte,
+	rMerkleProof [][]byte,
+	rProofHelper []int,
+	err error,
+) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rMerkleProof, rProofHelper, err = t.ops.buildMerkleProofs(index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return t.RootNode.Value, rMerkleProof, rProofHelper, nil
+}
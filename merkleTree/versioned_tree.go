@@ -0,0 +1,501 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"sync"
+)
+
+var (
+	metaVersionKey  = []byte("m:version")
+	metaOldestKey   = []byte("m:oldest")
+	metaVersionsKey = []byte("m:versions")
+)
+
+/*
+	journalKey: store key of the undo journal of version, it holds the values
+	overwritten by the commit of that version
+*/
+func journalKey(version uint64) []byte {
+	key := make([]byte, 10)
+	key[0] = 'j'
+	key[1] = ':'
+	binary.BigEndian.PutUint64(key[2:], version)
+	return key
+}
+
+/*
+	versionedNodes: store backed layout which keeps the last versionDepth versions,
+	the values overwritten by every commit are kept in an undo journal
+*/
+type versionedNodes struct {
+	storeNodes
+	// number of versions kept
+	versionDepth uint64
+	// latest committed version
+	version uint64
+	// oldest version available
+	oldestVersion uint64
+	// versions holding an undo journal, ascending
+	versions []uint64
+	// decoded undo journals
+	journals map[uint64]map[string][]byte
+	// guards journals, which is filled lazily by readers
+	journalMu sync.Mutex
+}
+
+/*
+	OpenVersionedTree: open a store backed tree which keeps the last depth versions,
+	every commit creates a new version which can be queried or rolled back to.
+*/
+func OpenVersionedTree(store NodeStore, maxHeight int, nilHash []byte, hFunc hash.Hash, depth uint64) (*Tree, error) {
+	if depth == 0 {
+		log.Println("[OpenVersionedTree] depth should be larger than 0")
+		return nil, errors.New("[OpenVersionedTree] depth should be larger than 0")
+	}
+	layout := &versionedNodes{
+		versionDepth: depth,
+		journals:     make(map[uint64]map[string][]byte),
+	}
+	err := layout.open(store, maxHeight, nilHash, hFunc, layout)
+	if err != nil {
+		log.Println("[OpenVersionedTree] unable to open tree:", err)
+		return nil, err
+	}
+	err = layout.loadVersions()
+	if err != nil {
+		log.Println("[OpenVersionedTree] unable to load versions:", err)
+		return nil, err
+	}
+	return layout.Tree, nil
+}
+
+/*
+	versioned: layout of a tree opened by OpenVersionedTree
+*/
+func (t *Tree) versioned(caller string) (*versionedNodes, error) {
+	layout, ok := t.ops.(*versionedNodes)
+	if !ok {
+		errInfo := fmt.Sprintf("[%s] tree is not versioned", caller)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return layout, nil
+}
+
+func (t *versionedNodes) loadVersions() error {
+	buf, err := t.store.Get(metaVersionKey)
+	if err == nil {
+		t.version = binary.BigEndian.Uint64(buf)
+	} else if err != ErrNodeNotFound {
+		return err
+	}
+	buf, err = t.store.Get(metaOldestKey)
+	if err == nil {
+		t.oldestVersion = binary.BigEndian.Uint64(buf)
+	} else if err == ErrNodeNotFound {
+		t.oldestVersion = t.version
+	} else {
+		return err
+	}
+	t.versions = nil
+	buf, err = t.store.Get(metaVersionsKey)
+	if err == nil {
+		for i := 0; i+8 <= len(buf); i += 8 {
+			t.versions = append(t.versions, binary.BigEndian.Uint64(buf[i:i+8]))
+		}
+	} else if err != ErrNodeNotFound {
+		return err
+	}
+	return nil
+}
+
+func uint64Bytes(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
+
+func uint32Bytes(v uint32) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, v)
+	return buf
+}
+
+func (t *versionedNodes) encodeVersions() []byte {
+	buf := make([]byte, 0, 8*len(t.versions))
+	for _, v := range t.versions {
+		buf = append(buf, uint64Bytes(v)...)
+	}
+	return buf
+}
+
+/*
+	encodeJournal: count | (keyLen | key | exists | valueLen | value)*
+*/
+func encodeJournal(journal map[string][]byte) []byte {
+	buf := uint64Bytes(uint64(len(journal)))
+	for key, value := range journal {
+		buf = append(buf, uint32Bytes(uint32(len(key)))...)
+		buf = append(buf, key...)
+		if value == nil {
+			buf = append(buf, 0)
+			continue
+		}
+		buf = append(buf, 1)
+		buf = append(buf, uint32Bytes(uint32(len(value)))...)
+		buf = append(buf, value...)
+	}
+	return buf
+}
+
+func decodeJournal(buf []byte) (map[string][]byte, error) {
+	errMalformed := errors.New("[decodeJournal] malformed journal")
+	if len(buf) < 8 {
+		return nil, errMalformed
+	}
+	count := binary.BigEndian.Uint64(buf[:8])
+	buf = buf[8:]
+	journal := make(map[string][]byte)
+	for i := uint64(0); i < count; i++ {
+		if len(buf) < 4 {
+			return nil, errMalformed
+		}
+		keyLen := int(binary.BigEndian.Uint32(buf[:4]))
+		buf = buf[4:]
+		if len(buf) < keyLen+1 {
+			return nil, errMalformed
+		}
+		key := string(buf[:keyLen])
+		exists := buf[keyLen]
+		buf = buf[keyLen+1:]
+		if exists == 0 {
+			journal[key] = nil
+			continue
+		}
+		if len(buf) < 4 {
+			return nil, errMalformed
+		}
+		valueLen := int(binary.BigEndian.Uint32(buf[:4]))
+		buf = buf[4:]
+		if len(buf) < valueLen {
+			return nil, errMalformed
+		}
+		journal[key] = copyBytes(buf[:valueLen])
+		buf = buf[valueLen:]
+	}
+	return journal, nil
+}
+
+func (t *versionedNodes) getJournal(version uint64) (map[string][]byte, error) {
+	// readers share the journal cache
+	t.journalMu.Lock()
+	defer t.journalMu.Unlock()
+	if journal, ok := t.journals[version]; ok {
+		return journal, nil
+	}
+	buf, err := t.store.Get(journalKey(version))
+	if err != nil {
+		return nil, err
+	}
+	journal, err := decodeJournal(buf)
+	if err != nil {
+		return nil, err
+	}
+	t.journals[version] = journal
+	return journal, nil
+}
+
+/*
+	Version: latest committed version of a versioned tree, 0 for the other trees
+*/
+func (t *Tree) Version() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	layout, ok := t.ops.(*versionedNodes)
+	if !ok {
+		return 0
+	}
+	return layout.version
+}
+
+/*
+	OldestVersion: oldest version still available for queries and rollback, 0 for unversioned trees
+*/
+func (t *Tree) OldestVersion() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	layout, ok := t.ops.(*versionedNodes)
+	if !ok {
+		return 0
+	}
+	return layout.oldestVersion
+}
+
+/*
+	CommitVersion: commit pending updates as version, version should be larger than the current one.
+	Versions older than version - depth are pruned.
+*/
+func (t *Tree) CommitVersion(version uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	layout, err := t.versioned("CommitVersion")
+	if err != nil {
+		return err
+	}
+	return layout.commitVersion(version)
+}
+
+/*
+	commit: commit pending updates as the next version
+*/
+func (t *versionedNodes) commit() error {
+	return t.commitVersion(t.version + 1)
+}
+
+func (t *versionedNodes) commitVersion(version uint64) error {
+	if version <= t.version {
+		errInfo := fmt.Sprintf("[CommitVersion] invalid version: %d, current version: %d", version, t.version)
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	writes := make(map[string][]byte, len(t.pending)+1)
+	for key, value := range t.pending {
+		writes[key] = value
+	}
+	writes[string(metaNbLeavesKey)] = uint64Bytes(uint64(t.nbLeaves))
+	// record the values being overwritten
+	journal := make(map[string][]byte, len(writes))
+	for key := range writes {
+		prev, err := t.store.Get([]byte(key))
+		if err == ErrNodeNotFound {
+			journal[key] = nil
+		} else if err != nil {
+			log.Println("[CommitVersion] unable to read previous value:", err)
+			return err
+		} else {
+			journal[key] = prev
+		}
+	}
+	versions := append(append([]uint64{}, t.versions...), version)
+	oldest := t.oldestVersion
+	var pruned []uint64
+	for len(versions) > 0 && version >= t.versionDepth && versions[0] <= version-t.versionDepth {
+		oldest = versions[0]
+		pruned = append(pruned, versions[0])
+		versions = versions[1:]
+	}
+
+	batch := t.store.NewBatch()
+	for key, value := range writes {
+		if value == nil {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Put([]byte(key), value)
+		}
+	}
+	batch.Put(metaHeightKey, uint64Bytes(uint64(t.MaxHeight)))
+	batch.Put(journalKey(version), encodeJournal(journal))
+	for _, v := range pruned {
+		batch.Delete(journalKey(v))
+	}
+	batch.Put(metaVersionKey, uint64Bytes(version))
+	batch.Put(metaOldestKey, uint64Bytes(oldest))
+	prevVersions := t.versions
+	t.versions = versions
+	batch.Put(metaVersionsKey, t.encodeVersions())
+	err := batch.Write()
+	if err != nil {
+		t.versions = prevVersions
+		log.Println("[CommitVersion] unable to write batch:", err)
+		return err
+	}
+	t.journalMu.Lock()
+	t.journals[version] = journal
+	for _, v := range pruned {
+		delete(t.journals, v)
+	}
+	t.journalMu.Unlock()
+	t.version = version
+	t.oldestVersion = oldest
+	t.pending = make(map[string][]byte)
+	return nil
+}
+
+func (t *versionedNodes) checkVersion(version uint64) error {
+	if version < t.oldestVersion || version > t.version {
+		return fmt.Errorf("version %d out of range [%d, %d]", version, t.oldestVersion, t.version)
+	}
+	return nil
+}
+
+/*
+	getNodeAt: read committed node value at version, the journals of the later
+	versions are searched in order and the first one holding the key wins
+*/
+func (t *versionedNodes) getNodeAt(height int, index int64, version uint64) ([]byte, error) {
+	key := nodeKey(height, index)
+	for _, v := range t.versions {
+		if v <= version {
+			continue
+		}
+		journal, err := t.getJournal(v)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := journal[string(key)]; ok {
+			if value == nil {
+				return t.NilHashValueConst[height], nil
+			}
+			return value, nil
+		}
+	}
+	value, err := t.store.Get(key)
+	if err == ErrNodeNotFound {
+		return t.NilHashValueConst[height], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+/*
+	Root: tree root at a committed version
+*/
+func (t *Tree) Root(version uint64) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	layout, err := t.versioned("Root")
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.checkVersion(version); err != nil {
+		errInfo := fmt.Sprintf("[Root] %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return layout.getNodeAt(t.MaxHeight, 0, version)
+}
+
+/*
+	BuildMerkleProofsAt: construct merkle proofs against a committed version
+*/
+func (t *Tree) BuildMerkleProofsAt(index int64, version uint64) (
+	rMerkleProof [][]byte,
+	rProofHelper []int,
+	err error,
+) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	layout, err := t.versioned("BuildMerkleProofsAt")
+	if err != nil {
+		return nil, nil, err
+	}
+	if index < 0 || index >= 1<<t.MaxHeight {
+		errInfo := fmt.Sprintf("[BuildMerkleProofsAt] index error, index: %v is out of tree capacity: %v.",
+			index, int64(1)<<t.MaxHeight)
+		log.Println(errInfo)
+		return nil, nil, errors.New(errInfo)
+	}
+	if err = layout.checkVersion(version); err != nil {
+		errInfo := fmt.Sprintf("[BuildMerkleProofsAt] %s", err.Error())
+		log.Println(errInfo)
+		return nil, nil, errors.New(errInfo)
+	}
+	rMerkleProof = make([][]byte, t.MaxHeight)
+	rProofHelper = make([]int, t.MaxHeight)
+	for height := 0; height < t.MaxHeight; height++ {
+		rMerkleProof[height], err = layout.getNodeAt(height, index^1, version)
+		if err != nil {
+			log.Println("[BuildMerkleProofsAt] unable to read sibling:", err)
+			return nil, nil, err
+		}
+		if index%2 == 0 {
+			rProofHelper[height] = Left
+		} else {
+			rProofHelper[height] = Right
+		}
+		index >>= 1
+	}
+	return rMerkleProof, rProofHelper, nil
+}
+
+/*
+	Rollback: drop pending updates and revert the tree to version
+*/
+func (t *Tree) Rollback(version uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	layout, err := t.versioned("Rollback")
+	if err != nil {
+		return err
+	}
+	return layout.rollback(version)
+}
+
+func (t *versionedNodes) rollback(version uint64) error {
+	if err := t.checkVersion(version); err != nil {
+		errInfo := fmt.Sprintf("[Rollback] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	t.pending = make(map[string][]byte)
+	batch := t.store.NewBatch()
+	// undo from the newest version, the oldest journal is applied last and wins
+	keep := len(t.versions)
+	for i := len(t.versions) - 1; i >= 0 && t.versions[i] > version; i-- {
+		journal, err := t.getJournal(t.versions[i])
+		if err != nil {
+			log.Println("[Rollback] unable to read journal:", err)
+			return err
+		}
+		for key, value := range journal {
+			if value == nil {
+				batch.Delete([]byte(key))
+			} else {
+				batch.Put([]byte(key), value)
+			}
+		}
+		batch.Delete(journalKey(t.versions[i]))
+		keep = i
+	}
+	dropped := t.versions[keep:]
+	prevVersions := t.versions
+	t.versions = t.versions[:keep]
+	batch.Put(metaVersionKey, uint64Bytes(version))
+	batch.Put(metaVersionsKey, t.encodeVersions())
+	err := batch.Write()
+	if err != nil {
+		t.versions = prevVersions
+		log.Println("[Rollback] unable to write batch:", err)
+		return err
+	}
+	t.journalMu.Lock()
+	for _, v := range dropped {
+		delete(t.journals, v)
+	}
+	t.journalMu.Unlock()
+	t.version = version
+	return t.discard()
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package merkleTree
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+func TestVersionedTreeRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.db")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := OpenVersionedTree(store, 16, NilHash, mimc.NewMiMC(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashState := MockState(5)
+	roots := [][]byte{tree.RootNode.Value}
+	for i, leaf := range hashState {
+		assert.NoError(t, tree.Update(int64(i%3), leaf))
+		assert.NoError(t, tree.CommitVersion(uint64(i+1)))
+		roots = append(roots, tree.RootNode.Value)
+	}
+	assert.Equal(t, uint64(5), tree.Version())
+	assert.Equal(t, uint64(2), tree.OldestVersion())
+	_, err = tree.Root(1)
+	assert.Error(t, err)
+	for version := uint64(2); version <= 5; version++ {
+		root, err := tree.Root(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, roots[version], root)
+		proofs, helpers, err := tree.BuildMerkleProofsAt(1, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf := NilHash
+		if version >= 2 {
+			leaf = hashState[1]
+		}
+		if version >= 5 {
+			leaf = hashState[4]
+		}
+		node := leaf
+		for i := range proofs {
+			if helpers[i] == Left {
+				node = tree.HashSubTrees(node, proofs[i])
+			} else {
+				node = tree.HashSubTrees(proofs[i], node)
+			}
+		}
+		assert.Equal(t, roots[version], node)
+	}
+
+	// pending updates are dropped by rollback
+	assert.NoError(t, tree.Update(9, hashState[0]))
+	assert.NoError(t, tree.Rollback(3))
+	assert.Equal(t, roots[3], tree.RootNode.Value)
+	assert.Equal(t, uint64(3), tree.Version())
+	assert.NoError(t, store.Close())
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	tree, err = OpenVersionedTree(store, 16, NilHash, mimc.NewMiMC(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(3), tree.Version())
+	assert.Equal(t, roots[3], tree.RootNode.Value)
+	root, err := tree.Root(2)
+	assert.NoError(t, err)
+	assert.Equal(t, roots[2], root)
+	assert.Error(t, tree.CommitVersion(3))
+	assert.NoError(t, tree.CommitVersion(4))
+}
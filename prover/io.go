@@ -0,0 +1,219 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+)
+
+/*
+	writeFile: create path and write it with write
+*/
+func writeFile(path string, write func(w io.Writer) error) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	w := bufio.NewWriter(f)
+	if err = write(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func readFile(path string, read func(r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return read(bufio.NewReaderSize(f, 1<<20))
+}
+
+func ioError(funcName string, path string, err error) error {
+	errInfo := fmt.Sprintf("[%s] %s: %s", funcName, path, err.Error())
+	log.Println(errInfo)
+	return errors.New(errInfo)
+}
+
+func WriteConstraintSystem(path string, ccs constraint.ConstraintSystem) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := ccs.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WriteConstraintSystem", path, err)
+	}
+	return nil
+}
+
+func ReadConstraintSystem(path string) (constraint.ConstraintSystem, error) {
+	ccs := groth16.NewCS(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := ccs.ReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadConstraintSystem", path, err)
+	}
+	return ccs, nil
+}
+
+/*
+	WriteProvingKey: keys are written uncompressed, which is larger but much faster to read
+*/
+func WriteProvingKey(path string, pk groth16.ProvingKey) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := pk.WriteRawTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WriteProvingKey", path, err)
+	}
+	return nil
+}
+
+/*
+	ReadProvingKey: the points of the proving key are not checked to be in the subgroup,
+	it is produced locally by Setup and a corrupted key only yields proofs that do not verify
+*/
+func ReadProvingKey(path string) (groth16.ProvingKey, error) {
+	pk := groth16.NewProvingKey(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := pk.UnsafeReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadProvingKey", path, err)
+	}
+	return pk, nil
+}
+
+func WriteVerifyingKey(path string, vk groth16.VerifyingKey) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := vk.WriteRawTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WriteVerifyingKey", path, err)
+	}
+	return nil
+}
+
+func ReadVerifyingKey(path string) (groth16.VerifyingKey, error) {
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := vk.ReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadVerifyingKey", path, err)
+	}
+	return vk, nil
+}
+
+/*
+	WriteProof: the proof is written uncompressed, its 256 bytes are the 8 words
+	a, b and c taken by the verifier contract
+*/
+func WriteProof(path string, proof groth16.Proof) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := proof.WriteRawTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WriteProof", path, err)
+	}
+	return nil
+}
+
+func ReadProof(path string) (groth16.Proof, error) {
+	proof := groth16.NewProof(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := proof.ReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadProof", path, err)
+	}
+	return proof, nil
+}
+
+/*
+	WriteSolidity: the verifier contract of vk
+*/
+func WriteSolidity(path string, vk groth16.VerifyingKey) error {
+	err := writeFile(path, vk.ExportSolidity)
+	if err != nil {
+		return ioError("WriteSolidity", path, err)
+	}
+	return nil
+}
+
+/*
+	ReadBlock: a block witness in the json or the binary format of circuit.Block
+*/
+func ReadBlock(path string) (*circuit.Block, error) {
+	blockBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, ioError("ReadBlock", path, err)
+	}
+	oBlock, err := decodeBlock(blockBytes)
+	if err != nil {
+		return nil, ioError("ReadBlock", path, err)
+	}
+	return oBlock, nil
+}
+
+/*
+	decodeBlock: json blocks are objects, anything else is taken as the binary format
+*/
+func decodeBlock(blockBytes []byte) (*circuit.Block, error) {
+	var (
+		oBlock circuit.Block
+		err    error
+	)
+	if trimmed := bytes.TrimSpace(blockBytes); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(blockBytes, &oBlock)
+	} else {
+		err = oBlock.UnmarshalBinary(blockBytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &oBlock, nil
+}
@@ -0,0 +1,274 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+)
+
+/*
+	CompilePlonk: compile a circuit into the sparse R1CS plonk proves
+*/
+func CompilePlonk(c frontend.Circuit) (constraint.ConstraintSystem, error) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), scs.NewBuilder, c, frontend.IgnoreUnconstrainedInputs())
+	if err != nil {
+		errInfo := fmt.Sprintf("[CompilePlonk] unable to compile circuit: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return ccs, nil
+}
+
+/*
+	SRSSize: the smallest srs ccs can be set up with. An srs is universal, the one of the
+	largest block circuit sets up every smaller one.
+*/
+func SRSSize(ccs constraint.ConstraintSystem) uint64 {
+	_, _, nbPublicVariables := ccs.GetNbVariables()
+	return ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints()+nbPublicVariables)) + 3
+}
+
+/*
+	NewSRS: a kzg srs of size points from a random secret, for tests only. The secret is not kept,
+	but a single party knew it and could forge proofs. Deployed keys are set up from the srs of a
+	ceremony, read by ReadPtauSRS.
+*/
+func NewSRS(size uint64) (kzg.SRS, error) {
+	alpha, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		errInfo := fmt.Sprintf("[NewSRS] unable to sample secret: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	srs, err := kzg_bn254.NewSRS(size, alpha)
+	if err != nil {
+		errInfo := fmt.Sprintf("[NewSRS] unable to create srs: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return srs, nil
+}
+
+/*
+	SetupPlonk: the plonk keys of ccs. No secret is involved, the keys are determined by
+	ccs and srs, so a new block size is set up from the srs of the largest block.
+*/
+func SetupPlonk(ccs constraint.ConstraintSystem, srs kzg.SRS) (plonk.ProvingKey, plonk.VerifyingKey, error) {
+	pk, vk, err := plonk.Setup(ccs, srs)
+	if err != nil {
+		errInfo := fmt.Sprintf("[SetupPlonk] unable to setup: %s", err.Error())
+		log.Println(errInfo)
+		return nil, nil, errors.New(errInfo)
+	}
+	return pk, vk, nil
+}
+
+/*
+	ProvePlonk: prove that assignment solves ccs
+*/
+func ProvePlonk(ccs constraint.ConstraintSystem, pk plonk.ProvingKey, assignment frontend.Circuit) (plonk.Proof, error) {
+	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		errInfo := fmt.Sprintf("[ProvePlonk] invalid witness: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	proof, err := plonk.Prove(ccs, pk, witness, backend.WithHints(types.Keccak256))
+	if err != nil {
+		errInfo := fmt.Sprintf("[ProvePlonk] unable to prove: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return proof, nil
+}
+
+/*
+	ProveBlockPlonk: prove oBlock with the block circuit ccs was compiled from by CompilePlonk
+*/
+func ProveBlockPlonk(ccs constraint.ConstraintSystem, pk plonk.ProvingKey, oBlock *circuit.Block) (plonk.Proof, error) {
+	witness, err := circuit.SetBlockWitness(oBlock)
+	if err != nil {
+		errInfo := fmt.Sprintf("[ProveBlockPlonk] unable to set block witness: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return ProvePlonk(ccs, pk, &witness)
+}
+
+/*
+	VerifyPlonk: verify proof against the public part of assignment
+*/
+func VerifyPlonk(vk plonk.VerifyingKey, proof plonk.Proof, assignment frontend.Circuit) error {
+	publicWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		errInfo := fmt.Sprintf("[VerifyPlonk] invalid public witness: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	if err = plonk.Verify(proof, vk, publicWitness); err != nil {
+		errInfo := fmt.Sprintf("[VerifyPlonk] invalid proof: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	return nil
+}
+
+func VerifyBlockPlonk(vk plonk.VerifyingKey, proof plonk.Proof, blockCommitment []byte) error {
+	return VerifyPlonk(vk, proof, &circuit.BlockConstraints{BlockCommitment: blockCommitment})
+}
+
+func ReadPlonkConstraintSystem(path string) (constraint.ConstraintSystem, error) {
+	ccs := plonk.NewCS(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := ccs.ReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadPlonkConstraintSystem", path, err)
+	}
+	return ccs, nil
+}
+
+func WriteSRS(path string, srs kzg.SRS) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := srs.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WriteSRS", path, err)
+	}
+	return nil
+}
+
+func ReadSRS(path string) (kzg.SRS, error) {
+	srs := kzg.NewSRS(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := srs.ReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadSRS", path, err)
+	}
+	return srs, nil
+}
+
+/*
+	WritePlonkProvingKey: the srs is not written with the key, ReadPlonkProvingKey needs it
+*/
+func WritePlonkProvingKey(path string, pk plonk.ProvingKey) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := pk.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WritePlonkProvingKey", path, err)
+	}
+	return nil
+}
+
+/*
+	ReadPlonkProvingKey: read a proving key written by WritePlonkProvingKey, srs is the one it was set up with
+*/
+func ReadPlonkProvingKey(path string, srs kzg.SRS) (plonk.ProvingKey, error) {
+	pk := plonk.NewProvingKey(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		if _, err := pk.ReadFrom(r); err != nil {
+			return err
+		}
+		return pk.InitKZG(srs)
+	})
+	if err != nil {
+		return nil, ioError("ReadPlonkProvingKey", path, err)
+	}
+	return pk, nil
+}
+
+func WritePlonkVerifyingKey(path string, vk plonk.VerifyingKey) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := vk.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WritePlonkVerifyingKey", path, err)
+	}
+	return nil
+}
+
+func ReadPlonkVerifyingKey(path string, srs kzg.SRS) (plonk.VerifyingKey, error) {
+	vk := plonk.NewVerifyingKey(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		if _, err := vk.ReadFrom(r); err != nil {
+			return err
+		}
+		return vk.InitKZG(srs)
+	})
+	if err != nil {
+		return nil, ioError("ReadPlonkVerifyingKey", path, err)
+	}
+	return vk, nil
+}
+
+/*
+	WritePlonkSolidity: the verifier contract of the plonk proofs of vk
+*/
+func WritePlonkSolidity(path string, vk plonk.VerifyingKey) error {
+	err := writeFile(path, vk.ExportSolidity)
+	if err != nil {
+		return ioError("WritePlonkSolidity", path, err)
+	}
+	return nil
+}
+
+func WritePlonkProof(path string, proof plonk.Proof) error {
+	err := writeFile(path, func(w io.Writer) error {
+		_, err := proof.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return ioError("WritePlonkProof", path, err)
+	}
+	return nil
+}
+
+func ReadPlonkProof(path string) (plonk.Proof, error) {
+	proof := plonk.NewProof(ecc.BN254)
+	err := readFile(path, func(r io.Reader) error {
+		_, err := proof.ReadFrom(r)
+		return err
+	})
+	if err != nil {
+		return nil, ioError("ReadPlonkProof", path, err)
+	}
+	return proof, nil
+}
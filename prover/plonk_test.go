@@ -0,0 +1,172 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+func TestPlonkProveAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	ccs, err := CompilePlonk(&cubicCircuit{})
+	require.NoError(t, err)
+
+	_, _, err = SetupPlonk(ccs, mustSRS(t, SRSSize(ccs)/2))
+	assert.Error(t, err)
+	// an srs larger than the circuit needs sets it up as well
+	srs := mustSRS(t, 2*SRSSize(ccs))
+	csPath, srsPath := filepath.Join(dir, "cubic.scs"), filepath.Join(dir, "cubic.srs")
+	require.NoError(t, WriteConstraintSystem(csPath, ccs))
+	require.NoError(t, WriteSRS(srsPath, srs))
+	pk, vk, err := SetupPlonk(ccs, srs)
+	require.NoError(t, err)
+	pkPath, vkPath := filepath.Join(dir, "cubic.pk"), filepath.Join(dir, "cubic.vk")
+	require.NoError(t, WritePlonkProvingKey(pkPath, pk))
+	require.NoError(t, WritePlonkVerifyingKey(vkPath, vk))
+	require.NoError(t, WritePlonkSolidity(filepath.Join(dir, "CubicVerifier.sol"), vk))
+
+	// the keys derived again from the files are the same as the ones read back
+	ccs, err = ReadPlonkConstraintSystem(csPath)
+	require.NoError(t, err)
+	srs, err = ReadSRS(srsPath)
+	require.NoError(t, err)
+	_, setupVk, err := SetupPlonk(ccs, srs)
+	require.NoError(t, err)
+	pk, err = ReadPlonkProvingKey(pkPath, srs)
+	require.NoError(t, err)
+	readVk, err := ReadPlonkVerifyingKey(vkPath, srs)
+	require.NoError(t, err)
+	assert.Equal(t, keyBytes(t, setupVk), keyBytes(t, readVk))
+
+	proof, err := ProvePlonk(ccs, pk, &cubicCircuit{X: 3, Y: 35})
+	require.NoError(t, err)
+	proofPath := filepath.Join(dir, "cubic.proof")
+	require.NoError(t, WritePlonkProof(proofPath, proof))
+	proof, err = ReadPlonkProof(proofPath)
+	require.NoError(t, err)
+
+	assert.NoError(t, VerifyPlonk(readVk, proof, &cubicCircuit{Y: 35}))
+	assert.Error(t, VerifyPlonk(readVk, proof, &cubicCircuit{Y: 36}))
+	_, err = ProvePlonk(ccs, pk, &cubicCircuit{X: 3, Y: 36})
+	assert.Error(t, err)
+
+	_, err = ReadSRS(filepath.Join(dir, "missing.srs"))
+	assert.Error(t, err)
+	_, err = ReadPlonkProof(csPath)
+	assert.Error(t, err)
+	_, err = ReadPlonkVerifyingKey(vkPath, mustSRS(t, 2))
+	assert.Error(t, err)
+}
+
+/*
+	TestProveBlockPlonk: the block circuit is proven with plonk, it takes minutes
+*/
+func TestProveBlockPlonk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("proving a block is slow")
+	}
+	oBlock := registerBlock(t)
+	blockConstraints, err := NewBlockConstraints(1, 0, gasAssetIds)
+	require.NoError(t, err)
+	ccs, err := CompilePlonk(blockConstraints)
+	require.NoError(t, err)
+	pk, vk, err := SetupPlonk(ccs, mustSRS(t, SRSSize(ccs)))
+	require.NoError(t, err)
+
+	proof, err := ProveBlockPlonk(ccs, pk, oBlock)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyBlockPlonk(vk, proof, oBlock.BlockCommitment))
+	assert.Error(t, VerifyBlockPlonk(vk, proof, make([]byte, 32)))
+}
+
+/*
+	BenchmarkProveBlock: constraints and prover time of groth16 and plonk, by block size.
+	The setups are not timed, the 10 txs circuits take a long time to set up all the same.
+
+	go test ./prover -run ^$ -bench ProveBlock -benchtime 1x -timeout 99999s
+*/
+func BenchmarkProveBlock(b *testing.B) {
+	oBlock := registerBlock(b)
+	for _, txsCount := range []int{1, 10} {
+		paddedBlock, err := witness.PadBlock(oBlock, txsCount)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("groth16/txs=%d", txsCount), func(b *testing.B) {
+			blockConstraints, err := NewBlockConstraints(txsCount, 0, gasAssetIds)
+			require.NoError(b, err)
+			ccs, err := Compile(blockConstraints)
+			require.NoError(b, err)
+			pk, vk, err := Setup(ccs)
+			require.NoError(b, err)
+			b.ResetTimer()
+			var proof groth16.Proof
+			for i := 0; i < b.N; i++ {
+				proof, err = ProveBlock(ccs, pk, paddedBlock)
+				require.NoError(b, err)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(ccs.GetNbConstraints()), "constraints")
+			require.NoError(b, VerifyBlock(vk, proof, paddedBlock.BlockCommitment))
+		})
+
+		b.Run(fmt.Sprintf("plonk/txs=%d", txsCount), func(b *testing.B) {
+			blockConstraints, err := NewBlockConstraints(txsCount, 0, gasAssetIds)
+			require.NoError(b, err)
+			ccs, err := CompilePlonk(blockConstraints)
+			require.NoError(b, err)
+			pk, vk, err := SetupPlonk(ccs, mustSRS(b, SRSSize(ccs)))
+			require.NoError(b, err)
+			b.ResetTimer()
+			var proof plonk.Proof
+			for i := 0; i < b.N; i++ {
+				proof, err = ProveBlockPlonk(ccs, pk, paddedBlock)
+				require.NoError(b, err)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(ccs.GetNbConstraints()), "constraints")
+			require.NoError(b, VerifyBlockPlonk(vk, proof, paddedBlock.BlockCommitment))
+		})
+	}
+}
+
+func mustSRS(t testing.TB, size uint64) kzg.SRS {
+	start := time.Now()
+	srs, err := NewSRS(size)
+	require.NoError(t, err)
+	t.Logf("srs of %d points in %s", size, time.Since(start))
+	return srs
+}
+
+func keyBytes(t testing.TB, key plonk.VerifyingKey) []byte {
+	var buf bytes.Buffer
+	_, err := key.WriteTo(&buf)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+)
+
+/*
+	NewBlockConstraints: the block circuit of txsCount txs, whose gas is collected
+	by gasAccountIndex in gasAssetIds
+*/
+func NewBlockConstraints(txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*circuit.BlockConstraints, error) {
+	return NewBlockConstraintsWithHasher(hasher.Default, txsCount, gasAccountIndex, gasAssetIds)
+}
+
+/*
+	NewBlockConstraintsWithHasher: the block circuit of txsCount txs whose trees are hashed with h
+*/
+func NewBlockConstraintsWithHasher(h hasher.Type, txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*circuit.BlockConstraints, error) {
+	if txsCount <= 0 {
+		errInfo := fmt.Sprintf("[NewBlockConstraints] invalid txs count: %d", txsCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if len(gasAssetIds) == 0 {
+		errInfo := "[NewBlockConstraints] gas asset ids should not be empty"
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	seen := make(map[int64]bool, len(gasAssetIds))
+	for _, assetId := range gasAssetIds {
+		if seen[assetId] {
+			errInfo := fmt.Sprintf("[NewBlockConstraints] duplicated gas asset id: %d", assetId)
+			log.Println(errInfo)
+			return nil, errors.New(errInfo)
+		}
+		seen[assetId] = true
+	}
+	blockConstraints := &circuit.BlockConstraints{
+		TxsCount:        txsCount,
+		Txs:             make([]circuit.TxConstraints, txsCount),
+		GasAssetIds:     append([]int64{}, gasAssetIds...),
+		GasAccountIndex: gasAccountIndex,
+		Gas:             circuit.GetZeroGasConstraints(gasAssetIds),
+		Hasher:          h,
+	}
+	for i := 0; i < txsCount; i++ {
+		blockConstraints.Txs[i] = circuit.GetZeroTxConstraint()
+	}
+	return blockConstraints, nil
+}
+
+/*
+	Compile: compile a circuit into the R1CS groth16 proves
+*/
+func Compile(c frontend.Circuit) (constraint.ConstraintSystem, error) {
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, c, frontend.IgnoreUnconstrainedInputs())
+	if err != nil {
+		errInfo := fmt.Sprintf("[Compile] unable to compile circuit: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return ccs, nil
+}
+
+/*
+	Setup: run the groth16 setup of ccs. The toxic waste is not kept, but the setup is done
+	by a single party, so the keys are only fit for tests.
+*/
+func Setup(ccs constraint.ConstraintSystem) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		errInfo := fmt.Sprintf("[Setup] unable to setup: %s", err.Error())
+		log.Println(errInfo)
+		return nil, nil, errors.New(errInfo)
+	}
+	return pk, vk, nil
+}
+
+/*
+	Prove: prove that assignment solves ccs
+*/
+func Prove(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, assignment frontend.Circuit) (groth16.Proof, error) {
+	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		errInfo := fmt.Sprintf("[Prove] invalid witness: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness, backend.WithHints(types.Keccak256))
+	if err != nil {
+		errInfo := fmt.Sprintf("[Prove] unable to prove: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return proof, nil
+}
+
+/*
+	ProveBlock: prove oBlock with the block circuit ccs was compiled from, the block
+	has to hold as many txs and gas assets as the circuit
+*/
+func ProveBlock(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, oBlock *circuit.Block) (groth16.Proof, error) {
+	witness, err := circuit.SetBlockWitness(oBlock)
+	if err != nil {
+		errInfo := fmt.Sprintf("[ProveBlock] unable to set block witness: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return Prove(ccs, pk, &witness)
+}
+
+/*
+	Verify: verify proof against the public part of assignment
+*/
+func Verify(vk groth16.VerifyingKey, proof groth16.Proof, assignment frontend.Circuit) error {
+	publicWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		errInfo := fmt.Sprintf("[Verify] invalid public witness: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	if err = groth16.Verify(proof, vk, publicWitness); err != nil {
+		errInfo := fmt.Sprintf("[Verify] invalid proof: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	return nil
+}
+
+/*
+	VerifyBlock: verify the proof of a block, BlockCommitment is its only public input
+*/
+func VerifyBlock(vk groth16.VerifyingKey, proof groth16.Proof, blockCommitment []byte) error {
+	return Verify(vk, proof, &circuit.BlockConstraints{BlockCommitment: blockCommitment})
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+type cubicCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(c.Y, api.Add(api.Mul(c.X, c.X, c.X), c.X, 5))
+	return nil
+}
+
+func TestProveAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	ccs, err := Compile(&cubicCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := Setup(ccs)
+	require.NoError(t, err)
+
+	csPath, pkPath, vkPath := filepath.Join(dir, "cubic.r1cs"), filepath.Join(dir, "cubic.pk"), filepath.Join(dir, "cubic.vk")
+	require.NoError(t, WriteConstraintSystem(csPath, ccs))
+	require.NoError(t, WriteProvingKey(pkPath, pk))
+	require.NoError(t, WriteVerifyingKey(vkPath, vk))
+	ccs, err = ReadConstraintSystem(csPath)
+	require.NoError(t, err)
+	pk, err = ReadProvingKey(pkPath)
+	require.NoError(t, err)
+	vk, err = ReadVerifyingKey(vkPath)
+	require.NoError(t, err)
+
+	proof, err := Prove(ccs, pk, &cubicCircuit{X: 3, Y: 35})
+	require.NoError(t, err)
+	proofPath := filepath.Join(dir, "cubic.proof")
+	require.NoError(t, WriteProof(proofPath, proof))
+	proofBytes, err := ioutil.ReadFile(proofPath)
+	require.NoError(t, err)
+	assert.Equal(t, 256, len(proofBytes))
+	proof, err = ReadProof(proofPath)
+	require.NoError(t, err)
+
+	assert.NoError(t, Verify(vk, proof, &cubicCircuit{Y: 35}))
+	assert.Error(t, Verify(vk, proof, &cubicCircuit{Y: 36}))
+	_, err = Prove(ccs, pk, &cubicCircuit{X: 3, Y: 36})
+	assert.Error(t, err)
+
+	solPath := filepath.Join(dir, "Verifier.sol")
+	require.NoError(t, WriteSolidity(solPath, vk))
+	sol, err := ioutil.ReadFile(solPath)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(sol), "contract Verifier"))
+
+	_, err = ReadProvingKey(filepath.Join(dir, "missing.pk"))
+	assert.Error(t, err)
+	_, err = ReadVerifyingKey(proofPath)
+	assert.Error(t, err)
+	assert.Error(t, WriteProof(filepath.Join(dir, "missing", "cubic.proof"), proof))
+}
+
+func TestNewBlockConstraints(t *testing.T) {
+	blockConstraints, err := NewBlockConstraints(2, 1, []int64{0, 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(blockConstraints.Txs))
+	assert.Equal(t, 2, blockConstraints.Gas.GasAssetCount)
+
+	_, err = NewBlockConstraints(0, 1, []int64{0})
+	assert.Error(t, err)
+	_, err = NewBlockConstraints(1, 1, nil)
+	assert.Error(t, err)
+	_, err = NewBlockConstraints(1, 1, []int64{0, 0})
+	assert.Error(t, err)
+}
+
+/*
+	TestBlockWitness: a block read back from its json solves the block circuit, proving it
+	takes too long for a unit test
+*/
+var gasAssetIds = []int64{0, 1}
+
+/*
+	registerBlock: a block of 1 tx registering the gas account
+*/
+func registerBlock(t testing.TB) *circuit.Block {
+	s, err := state.NewState()
+	require.NoError(t, err)
+	sk, err := curve.GenerateEddsaPrivateKey("gas")
+	require.NoError(t, err)
+	blockBuilder, err := witness.NewBlockBuilder(witness.NewBuilder(s), 1, 0, gasAssetIds)
+	require.NoError(t, err)
+	oBlock, err := blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          txtypes.TxTypeRegisterZns,
+			AccountIndex:    0,
+			AccountName:     "gas.legend",
+			AccountNameHash: []byte{1},
+			PubKey:          hex.EncodeToString(sk.PublicKey.Bytes()),
+		},
+	})
+	require.NoError(t, err)
+	return oBlock
+}
+
+func TestBlockWitness(t *testing.T) {
+	oBlock := registerBlock(t)
+	blockPath := filepath.Join(t.TempDir(), "block.json")
+	blockBytes, err := json.Marshal(oBlock)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(blockPath, blockBytes, 0644))
+	readBlock, err := ReadBlock(blockPath)
+	require.NoError(t, err)
+	assert.Equal(t, oBlock, readBlock)
+
+	binaryPath := filepath.Join(t.TempDir(), "block.bin")
+	blockBytes, err = oBlock.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(binaryPath, blockBytes, 0644))
+	binaryBlock, err := ReadBlock(binaryPath)
+	require.NoError(t, err)
+	assert.Equal(t, oBlock, binaryBlock)
+
+	blockConstraints, err := NewBlockConstraints(1, 0, gasAssetIds)
+	require.NoError(t, err)
+	ccs, err := Compile(blockConstraints)
+	require.NoError(t, err)
+	blockWitness, err := circuit.SetBlockWitness(readBlock)
+	require.NoError(t, err)
+	fullWitness, err := frontend.NewWitness(&blockWitness, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	assert.NoError(t, ccs.IsSolved(fullWitness, backend.WithHints(types.Keccak256)))
+
+	// the public witness VerifyBlock checks the proof against
+	publicWitness, err := frontend.NewWitness(&circuit.BlockConstraints{BlockCommitment: oBlock.BlockCommitment}, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	require.NoError(t, err)
+	expected, err := fullWitness.Public()
+	require.NoError(t, err)
+	assert.Equal(t, expected.Vector(), publicWitness.Vector())
+
+	_, err = ReadBlock(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
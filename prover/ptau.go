@@ -0,0 +1,268 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark-crypto/kzg"
+)
+
+/*
+	Powers of tau transcript of the snarkjs format (.ptau), as published by the perpetual powers of tau
+	ceremony. All integers are little endian:
+	magic "ptau" | version uint32 | nbSections uint32 | (sectionType uint32 | sectionSize uint64 | section)*.
+	The header section holds n8 uint32 | q (n8 bytes) | power uint32 | ceremonyPower uint32.
+	The tauG1 section holds 2^(power+1)-1 points of G1, the tauG2 section 2^power points of G2,
+	both as affine coordinates of n8 bytes in montgomery form, the c0 of a coordinate of G2 first.
+*/
+var ptauMagic = []byte("ptau")
+
+const (
+	ptauSectionHeader = 1
+	ptauSectionTauG1  = 2
+	ptauSectionTauG2  = 3
+	ptauN8            = fp.Bytes
+)
+
+// limbs of the base field modulus, the least significant first
+var ptauModulus = func() [fp.Limbs]uint64 {
+	b := fp.Modulus().FillBytes(make([]byte, ptauN8))
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return fpLimbs(b)
+}()
+
+/*
+	fpLimbs: the limbs of a little endian integer of ptauN8 bytes, the least significant first
+*/
+func fpLimbs(b []byte) (limbs [fp.Limbs]uint64) {
+	for i := range limbs {
+		limbs[i] = binary.LittleEndian.Uint64(b[8*i : 8*(i+1)])
+	}
+	return limbs
+}
+
+/*
+	readPtauElement: a coordinate in montgomery form, which is the representation of fp.Element
+*/
+func readPtauElement(r io.Reader, e *fp.Element) error {
+	b := make([]byte, ptauN8)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return err
+	}
+	limbs := fpLimbs(b)
+	for i := fp.Limbs - 1; i >= 0; i-- {
+		if limbs[i] < ptauModulus[i] {
+			*e = limbs
+			return nil
+		}
+		if limbs[i] > ptauModulus[i] {
+			break
+		}
+	}
+	return errors.New("coordinate out of the field")
+}
+
+func readPtauG1(r io.Reader, p *bn254.G1Affine) error {
+	for _, e := range []*fp.Element{&p.X, &p.Y} {
+		if err := readPtauElement(r, e); err != nil {
+			return err
+		}
+	}
+	if !p.IsOnCurve() {
+		return errors.New("point of G1 not on the curve")
+	}
+	return nil
+}
+
+func readPtauG2(r io.Reader, p *bn254.G2Affine) error {
+	for _, e := range []*fp.Element{&p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1} {
+		if err := readPtauElement(r, e); err != nil {
+			return err
+		}
+	}
+	if !p.IsInSubGroup() {
+		return errors.New("point of G2 not in the subgroup")
+	}
+	return nil
+}
+
+type ptauSection struct {
+	offset int64
+	size   int64
+}
+
+/*
+	ReadPtauSRS: the kzg srs of size points taken from the powers of tau transcript at path.
+	The points are checked to be successive powers of the same tau, so the srs is as safe as the ceremony.
+*/
+func ReadPtauSRS(path string, size uint64) (kzg.SRS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ioError("ReadPtauSRS", path, err)
+	}
+	defer f.Close()
+	srs, err := readPtauSRS(f, size)
+	if err != nil {
+		return nil, ioError("ReadPtauSRS", path, err)
+	}
+	return srs, nil
+}
+
+func readPtauSRS(r io.ReadSeeker, size uint64) (*kzg_bn254.SRS, error) {
+	if size < 2 {
+		return nil, fmt.Errorf("invalid srs size: %d", size)
+	}
+	header := make([]byte, len(ptauMagic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(ptauMagic)], ptauMagic) {
+		return nil, errors.New("invalid magic")
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != 1 {
+		return nil, fmt.Errorf("unsupported version: %d", version)
+	}
+	sections := make(map[uint32]ptauSection)
+	nbSections := binary.LittleEndian.Uint32(header[8:])
+	buf := make([]byte, 12)
+	for i := uint32(0); i < nbSections; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		sectionType, sectionSize := binary.LittleEndian.Uint32(buf), int64(binary.LittleEndian.Uint64(buf[4:]))
+		if sectionSize < 0 {
+			return nil, fmt.Errorf("invalid size of section %d", sectionType)
+		}
+		if _, ok := sections[sectionType]; ok {
+			return nil, fmt.Errorf("duplicated section %d", sectionType)
+		}
+		sections[sectionType] = ptauSection{offset: offset, size: sectionSize}
+		if _, err = r.Seek(sectionSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	section := func(sectionType uint32, minSize int64) (io.Reader, error) {
+		s, ok := sections[sectionType]
+		if !ok {
+			return nil, fmt.Errorf("missing section %d", sectionType)
+		}
+		if s.size < minSize {
+			return nil, fmt.Errorf("section %d too short: %d bytes", sectionType, s.size)
+		}
+		if _, err := r.Seek(s.offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.LimitReader(r, s.size), nil
+	}
+
+	// header
+	sr, err := section(ptauSectionHeader, 4+ptauN8+8)
+	if err != nil {
+		return nil, err
+	}
+	buf = make([]byte, 4+ptauN8+8)
+	if _, err = io.ReadFull(sr, buf); err != nil {
+		return nil, err
+	}
+	if n8 := binary.LittleEndian.Uint32(buf); n8 != ptauN8 {
+		return nil, fmt.Errorf("invalid field size: %d", n8)
+	}
+	if fpLimbs(buf[4 : 4+ptauN8]) != ptauModulus {
+		return nil, errors.New("not a transcript of BN254")
+	}
+	power := binary.LittleEndian.Uint32(buf[4+ptauN8:])
+	if power >= 63 || size > uint64(1)<<(power+1)-1 {
+		return nil, fmt.Errorf("srs of %d points larger than the transcript of power %d", size, power)
+	}
+
+	srs := &kzg_bn254.SRS{G1: make([]bn254.G1Affine, size)}
+	if sr, err = section(ptauSectionTauG1, int64(size)*2*ptauN8); err != nil {
+		return nil, err
+	}
+	sr = bufio.NewReaderSize(sr, 1<<20)
+	for i := range srs.G1 {
+		if err = readPtauG1(sr, &srs.G1[i]); err != nil {
+			return nil, fmt.Errorf("tau g1 %d: %w", i, err)
+		}
+	}
+	if sr, err = section(ptauSectionTauG2, 2*4*ptauN8); err != nil {
+		return nil, err
+	}
+	for i := range srs.G2 {
+		if err = readPtauG2(sr, &srs.G2[i]); err != nil {
+			return nil, fmt.Errorf("tau g2 %d: %w", i, err)
+		}
+	}
+	if err = checkPowersOfTau(srs); err != nil {
+		return nil, err
+	}
+	return srs, nil
+}
+
+/*
+	checkPowersOfTau: the srs starts from the generators, and G1[i+1] is tau times G1[i] for the tau of G2[1].
+	A random linear combination of the powers is checked with a single pairing:
+	e(sum r_i G1[i+1], G2[0]) == e(sum r_i G1[i], G2[1])
+*/
+func checkPowersOfTau(srs *kzg_bn254.SRS) error {
+	_, _, g1, g2 := bn254.Generators()
+	if !srs.G1[0].Equal(&g1) || !srs.G2[0].Equal(&g2) {
+		return errors.New("the powers do not start from the generators")
+	}
+	n := len(srs.G1) - 1
+	scalars := make([]fr.Element, n)
+	for i := range scalars {
+		if _, err := scalars[i].SetRandom(); err != nil {
+			return err
+		}
+	}
+	var lower, upper bn254.G1Affine
+	if _, err := lower.MultiExp(srs.G1[:n], scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if _, err := upper.MultiExp(srs.G1[1:], scalars, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	lower.Neg(&lower)
+	ok, err := bn254.PairingCheck([]bn254.G1Affine{upper, lower}, []bn254.G2Affine{srs.G2[0], srs.G2[1]})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("the points are not successive powers of tau")
+	}
+	return nil
+}
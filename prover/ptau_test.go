@@ -0,0 +1,144 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+	writePtau: a transcript of the given power for tau, in the format of the snarkjs ceremonies,
+	tamper may change its points before they are written
+*/
+func writePtau(t *testing.T, path string, power uint32, tau int64, tamper func(g1 []bn254.G1Affine, g2 []bn254.G2Affine)) {
+	srs, err := kzg_bn254.NewSRS(uint64(1)<<(power+1)-1, big.NewInt(tau))
+	require.NoError(t, err)
+	g2 := make([]bn254.G2Affine, 1<<power)
+	g2[0] = srs.G2[0]
+	for i := 1; i < len(g2); i++ {
+		g2[i].ScalarMultiplication(&g2[i-1], big.NewInt(tau))
+	}
+	if tamper != nil {
+		tamper(srs.G1, g2)
+	}
+
+	element := func(w *bytes.Buffer, e fp.Element) {
+		// the limbs of fp.Element are in montgomery form, least significant first
+		for _, limb := range e {
+			require.NoError(t, binary.Write(w, binary.LittleEndian, limb))
+		}
+	}
+	var header, tauG1, tauG2 bytes.Buffer
+	require.NoError(t, binary.Write(&header, binary.LittleEndian, uint32(ptauN8)))
+	q := fp.Modulus().FillBytes(make([]byte, ptauN8))
+	for i := len(q) - 1; i >= 0; i-- {
+		header.WriteByte(q[i])
+	}
+	require.NoError(t, binary.Write(&header, binary.LittleEndian, power))
+	require.NoError(t, binary.Write(&header, binary.LittleEndian, power))
+	for _, p := range srs.G1 {
+		element(&tauG1, p.X)
+		element(&tauG1, p.Y)
+	}
+	for _, p := range g2 {
+		element(&tauG2, p.X.A0)
+		element(&tauG2, p.X.A1)
+		element(&tauG2, p.Y.A0)
+		element(&tauG2, p.Y.A1)
+	}
+
+	var ptau bytes.Buffer
+	ptau.Write(ptauMagic)
+	require.NoError(t, binary.Write(&ptau, binary.LittleEndian, []uint32{1, 3}))
+	// the sections need not be in order
+	for _, s := range []struct {
+		sectionType uint32
+		data        []byte
+	}{{ptauSectionTauG2, tauG2.Bytes()}, {ptauSectionHeader, header.Bytes()}, {ptauSectionTauG1, tauG1.Bytes()}} {
+		require.NoError(t, binary.Write(&ptau, binary.LittleEndian, s.sectionType))
+		require.NoError(t, binary.Write(&ptau, binary.LittleEndian, uint64(len(s.data))))
+		ptau.Write(s.data)
+	}
+	require.NoError(t, ioutil.WriteFile(path, ptau.Bytes(), 0644))
+}
+
+func TestReadPtauSRS(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.ptau")
+	writePtau(t, path, 3, 1234567, nil)
+
+	expected, err := kzg_bn254.NewSRS(10, big.NewInt(1234567))
+	require.NoError(t, err)
+	srs, err := ReadPtauSRS(path, 10)
+	require.NoError(t, err)
+	assert.Equal(t, expected, srs)
+	// 2^(power+1)-1 points of G1
+	_, err = ReadPtauSRS(path, 15)
+	assert.NoError(t, err)
+	_, err = ReadPtauSRS(path, 16)
+	assert.Error(t, err)
+	_, err = ReadPtauSRS(path, 1)
+	assert.Error(t, err)
+	_, err = ReadPtauSRS(filepath.Join(dir, "missing.ptau"), 10)
+	assert.Error(t, err)
+
+	// points which are not successive powers of tau
+	writePtau(t, path, 3, 1234567, func(g1 []bn254.G1Affine, _ []bn254.G2Affine) {
+		g1[4], g1[5] = g1[5], g1[4]
+	})
+	_, err = ReadPtauSRS(path, 10)
+	assert.Error(t, err)
+	// the powers of tau in G2 are for another tau
+	writePtau(t, path, 3, 1234567, func(_ []bn254.G1Affine, g2 []bn254.G2Affine) {
+		g2[1].ScalarMultiplication(&g2[1], big.NewInt(2))
+	})
+	_, err = ReadPtauSRS(path, 10)
+	assert.Error(t, err)
+	// the powers do not start from the generator
+	writePtau(t, path, 3, 1234567, func(g1 []bn254.G1Affine, _ []bn254.G2Affine) {
+		for i := range g1 {
+			g1[i].ScalarMultiplication(&g1[i], big.NewInt(2))
+		}
+	})
+	_, err = ReadPtauSRS(path, 10)
+	assert.Error(t, err)
+	// a point off the curve
+	writePtau(t, path, 3, 1234567, func(g1 []bn254.G1Affine, _ []bn254.G2Affine) {
+		g1[2].Y.SetOne()
+	})
+	_, err = ReadPtauSRS(path, 10)
+	assert.Error(t, err)
+
+	ptau, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	ptau[0] = 'x'
+	require.NoError(t, ioutil.WriteFile(path, ptau, 0644))
+	_, err = ReadPtauSRS(path, 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,395 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+var ErrNoCircuit = errors.New("no circuit fits the block")
+
+/*
+	Circuit: a compiled block circuit and its keys, each block size has its own
+*/
+type Circuit struct {
+	TxsCount        int
+	GasAccountIndex int64
+	GasAssetIds     []int64
+	// hash function of the trees
+	Hasher hasher.Type
+	CS     constraint.ConstraintSystem
+	PK     groth16.ProvingKey
+	VK     groth16.VerifyingKey
+}
+
+/*
+	NewCircuit: compile the block circuit and run its groth16 setup, the keys are only for test purpose
+*/
+func NewCircuit(txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*Circuit, error) {
+	return NewCircuitWithHasher(hasher.Default, txsCount, gasAccountIndex, gasAssetIds)
+}
+
+/*
+	NewCircuitWithHasher: compile the block circuit whose trees are hashed with h and run its groth16 setup
+*/
+func NewCircuitWithHasher(h hasher.Type, txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*Circuit, error) {
+	blockConstraints, err := NewBlockConstraintsWithHasher(h, txsCount, gasAccountIndex, gasAssetIds)
+	if err != nil {
+		return nil, err
+	}
+	ccs, err := Compile(blockConstraints)
+	if err != nil {
+		return nil, err
+	}
+	pk, vk, err := Setup(ccs)
+	if err != nil {
+		return nil, err
+	}
+	return &Circuit{
+		TxsCount:        txsCount,
+		GasAccountIndex: gasAccountIndex,
+		GasAssetIds:     blockConstraints.GasAssetIds,
+		Hasher:          h,
+		CS:              ccs,
+		PK:              pk,
+		VK:              vk,
+	}, nil
+}
+
+/*
+	CircuitPaths: the files of the circuit of txsCount txs in dir, as written by WriteCircuit.
+	A directory holds the circuits of a single hash function, gas account and gas asset set.
+*/
+func CircuitPaths(dir string, txsCount int) (csPath string, pkPath string, vkPath string) {
+	name := filepath.Join(dir, fmt.Sprintf("zkbnb%d", txsCount))
+	return name + ".r1cs", name + ".pk", name + ".vk"
+}
+
+/*
+	CircuitMetadata: what a constraint system was compiled for, which its file does not record
+*/
+type CircuitMetadata struct {
+	TxsCount        int     `json:"txs_count"`
+	GasAccountIndex int64   `json:"gas_account_index"`
+	GasAssetIds     []int64 `json:"gas_asset_ids"`
+	// name of the hash function of the trees, mimc when empty
+	Hasher string `json:"hasher,omitempty"`
+}
+
+/*
+	HasherType: the hash function of the trees the circuit was compiled for
+*/
+func (m *CircuitMetadata) HasherType() (hasher.Type, error) {
+	if m.Hasher == "" {
+		return hasher.Default, nil
+	}
+	return hasher.FromString(m.Hasher)
+}
+
+/*
+	MetadataPath: the metadata file of the constraint system at csPath, zkbnb1.r1cs has zkbnb1.json
+*/
+func MetadataPath(csPath string) string {
+	return strings.TrimSuffix(csPath, filepath.Ext(csPath)) + ".json"
+}
+
+func WriteCircuitMetadata(path string, metadata *CircuitMetadata) error {
+	err := writeFile(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(metadata)
+	})
+	if err != nil {
+		return ioError("WriteCircuitMetadata", path, err)
+	}
+	return nil
+}
+
+func ReadCircuitMetadata(path string) (*CircuitMetadata, error) {
+	var metadata CircuitMetadata
+	err := readFile(path, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&metadata)
+	})
+	if err != nil {
+		return nil, ioError("ReadCircuitMetadata", path, err)
+	}
+	return &metadata, nil
+}
+
+func WriteCircuit(dir string, c *Circuit) error {
+	csPath, pkPath, vkPath := CircuitPaths(dir, c.TxsCount)
+	if err := WriteConstraintSystem(csPath, c.CS); err != nil {
+		return err
+	}
+	err := WriteCircuitMetadata(MetadataPath(csPath), &CircuitMetadata{
+		TxsCount:        c.TxsCount,
+		GasAccountIndex: c.GasAccountIndex,
+		GasAssetIds:     c.GasAssetIds,
+		Hasher:          c.Hasher.String(),
+	})
+	if err != nil {
+		return err
+	}
+	if err = WriteProvingKey(pkPath, c.PK); err != nil {
+		return err
+	}
+	return WriteVerifyingKey(vkPath, c.VK)
+}
+
+/*
+	LoadCircuit: read the circuit of txsCount txs from dir, it must have been compiled for
+	the default hash function, gasAccountIndex and gasAssetIds
+*/
+func LoadCircuit(dir string, txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*Circuit, error) {
+	return LoadCircuitWithHasher(dir, hasher.Default, txsCount, gasAccountIndex, gasAssetIds)
+}
+
+/*
+	LoadCircuitWithHasher: read the circuit of txsCount txs from dir, it must have been compiled for
+	h, gasAccountIndex and gasAssetIds
+*/
+func LoadCircuitWithHasher(dir string, h hasher.Type, txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*Circuit, error) {
+	csPath, pkPath, vkPath := CircuitPaths(dir, txsCount)
+	metadata, err := ReadCircuitMetadata(MetadataPath(csPath))
+	if err != nil {
+		return nil, err
+	}
+	metadataHasher, err := metadata.HasherType()
+	if err != nil {
+		return nil, err
+	}
+	if metadata.TxsCount != txsCount ||
+		circuitKey(metadataHasher, metadata.GasAccountIndex, metadata.GasAssetIds) != circuitKey(h, gasAccountIndex, gasAssetIds) {
+		errInfo := fmt.Sprintf("[LoadCircuit] %s is compiled for %d txs and %s, expected %d txs and %s",
+			csPath, metadata.TxsCount, circuitKey(metadataHasher, metadata.GasAccountIndex, metadata.GasAssetIds),
+			txsCount, circuitKey(h, gasAccountIndex, gasAssetIds))
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	ccs, err := ReadConstraintSystem(csPath)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := ReadProvingKey(pkPath)
+	if err != nil {
+		return nil, err
+	}
+	vk, err := ReadVerifyingKey(vkPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Circuit{
+		TxsCount:        txsCount,
+		GasAccountIndex: gasAccountIndex,
+		GasAssetIds:     append([]int64{}, gasAssetIds...),
+		Hasher:          h,
+		CS:              ccs,
+		PK:              pk,
+		VK:              vk,
+	}, nil
+}
+
+/*
+	BlockGas: the gas account and the gas asset ids a block was built for
+*/
+func BlockGas(oBlock *circuit.Block) (gasAccountIndex int64, gasAssetIds []int64) {
+	for _, asset := range oBlock.Gas.AccountInfoBefore.AssetsInfo {
+		gasAssetIds = append(gasAssetIds, asset.AssetId)
+	}
+	return oBlock.Gas.AccountInfoBefore.AccountIndex, gasAssetIds
+}
+
+func gasKey(gasAccountIndex int64, gasAssetIds []int64) string {
+	return fmt.Sprint(gasAccountIndex, gasAssetIds)
+}
+
+/*
+	circuitKey: circuits of the same key only differ by their size, circuits of different
+	hash functions are never interchangeable
+*/
+func circuitKey(h hasher.Type, gasAccountIndex int64, gasAssetIds []int64) string {
+	return fmt.Sprintf("%s gas %s", h, gasKey(gasAccountIndex, gasAssetIds))
+}
+
+/*
+	Prove: prove a block of exactly the size and the gas of the circuit
+*/
+func (c *Circuit) Prove(oBlock *circuit.Block) (groth16.Proof, error) {
+	if len(oBlock.Txs) != c.TxsCount {
+		errInfo := fmt.Sprintf("[Circuit.Prove] block of %d txs, circuit of %d txs", len(oBlock.Txs), c.TxsCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if key := gasKey(BlockGas(oBlock)); key != gasKey(c.GasAccountIndex, c.GasAssetIds) {
+		errInfo := fmt.Sprintf("[Circuit.Prove] block gas %s, circuit gas %s", key, gasKey(c.GasAccountIndex, c.GasAssetIds))
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return ProveBlock(c.CS, c.PK, oBlock)
+}
+
+func (c *Circuit) Verify(proof groth16.Proof, blockCommitment []byte) error {
+	if c.VK == nil {
+		errInfo := fmt.Sprintf("[Circuit.Verify] no verifying key for the circuit of %d txs", c.TxsCount)
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	return VerifyBlock(c.VK, proof, blockCommitment)
+}
+
+/*
+	Registry: compiled block circuits of one hash function by gas account, gas asset ids and block size.
+	A batch of txs is proven by the smallest circuit it fits in, padded with empty txs.
+*/
+type Registry struct {
+	mu sync.RWMutex
+	// hash function of the trees of the blocks
+	hasher hasher.Type
+	// circuits of a circuit key, by increasing size
+	circuits map[string][]*Circuit
+}
+
+func NewRegistry() *Registry {
+	return NewRegistryWithHasher(hasher.Default)
+}
+
+/*
+	NewRegistryWithHasher: a registry of the circuits whose trees are hashed with h
+*/
+func NewRegistryWithHasher(h hasher.Type) *Registry {
+	return &Registry{hasher: h, circuits: make(map[string][]*Circuit)}
+}
+
+func (r *Registry) Register(c *Circuit) error {
+	if c.TxsCount <= 0 || len(c.GasAssetIds) == 0 || c.CS == nil || c.PK == nil {
+		log.Println("[Register] invalid circuit")
+		return errors.New("[Register] invalid circuit")
+	}
+	if c.Hasher != r.hasher {
+		errInfo := fmt.Sprintf("[Register] circuit of %s, registry of %s", c.Hasher, r.hasher)
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := circuitKey(c.Hasher, c.GasAccountIndex, c.GasAssetIds)
+	circuits := r.circuits[key]
+	i := sort.Search(len(circuits), func(i int) bool { return circuits[i].TxsCount >= c.TxsCount })
+	if i < len(circuits) && circuits[i].TxsCount == c.TxsCount {
+		errInfo := fmt.Sprintf("[Register] duplicated circuit of %d txs for %s", c.TxsCount, key)
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	circuits = append(circuits, nil)
+	copy(circuits[i+1:], circuits[i:])
+	circuits[i] = c
+	r.circuits[key] = circuits
+	return nil
+}
+
+/*
+	Select: the smallest circuit of at least txsCount txs for the gas, among the circuits of the hash function of the registry
+*/
+func (r *Registry) Select(txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*Circuit, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	circuits := r.circuits[circuitKey(r.hasher, gasAccountIndex, gasAssetIds)]
+	i := sort.Search(len(circuits), func(i int) bool { return circuits[i].TxsCount >= txsCount })
+	if i == len(circuits) {
+		return nil, ErrNoCircuit
+	}
+	return circuits[i], nil
+}
+
+/*
+	Circuits: every registered circuit, by gas and size
+*/
+func (r *Registry) Circuits() []*Circuit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var keys []string
+	for key := range r.circuits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var circuits []*Circuit
+	for _, key := range keys {
+		circuits = append(circuits, r.circuits[key]...)
+	}
+	return circuits
+}
+
+/*
+	BuildBlock: build txInfos into a block of the smallest circuit they fit in
+*/
+func (r *Registry) BuildBlock(
+	builder *witness.Builder, gasAccountIndex int64, gasAssetIds []int64,
+	blockNumber int64, createdAt int64, txInfos []txtypes.TxInfo,
+) (*circuit.Block, *Circuit, error) {
+	if h := builder.State().Hasher(); h != r.hasher {
+		errInfo := fmt.Sprintf("[Registry.BuildBlock] state of %s, registry of %s", h, r.hasher)
+		log.Println(errInfo)
+		return nil, nil, errors.New(errInfo)
+	}
+	c, err := r.Select(len(txInfos), gasAccountIndex, gasAssetIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockBuilder, err := witness.NewBlockBuilder(builder, c.TxsCount, gasAccountIndex, gasAssetIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	oBlock, err := blockBuilder.BuildBlock(blockNumber, createdAt, txInfos)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oBlock, c, nil
+}
+
+/*
+	PadBlock: the circuit of a built block and the block padded to its size, oBlock is not modified
+*/
+func (r *Registry) PadBlock(oBlock *circuit.Block) (*circuit.Block, *Circuit, error) {
+	gasAccountIndex, gasAssetIds := BlockGas(oBlock)
+	c, err := r.Select(len(oBlock.Txs), gasAccountIndex, gasAssetIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.TxsCount == len(oBlock.Txs) {
+		return oBlock, c, nil
+	}
+	padded, err := witness.PadBlock(oBlock, c.TxsCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	return padded, c, nil
+}
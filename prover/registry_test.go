@@ -0,0 +1,197 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+/*
+	fakeCircuit: a circuit of txsCount txs with the keys of a tiny circuit, to select circuits
+	without compiling block circuits
+*/
+func fakeCircuit(t *testing.T, txsCount int, gasAccountIndex int64, gasAssetIds []int64) *Circuit {
+	ccs, err := Compile(&cubicCircuit{})
+	require.NoError(t, err)
+	pk, vk, err := Setup(ccs)
+	require.NoError(t, err)
+	return &Circuit{
+		TxsCount:        txsCount,
+		GasAccountIndex: gasAccountIndex,
+		GasAssetIds:     gasAssetIds,
+		CS:              ccs,
+		PK:              pk,
+		VK:              vk,
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	for _, txsCount := range []int{10, 1, 4} {
+		require.NoError(t, registry.Register(fakeCircuit(t, txsCount, 1, []int64{0, 1})))
+	}
+	require.NoError(t, registry.Register(fakeCircuit(t, 2, 1, []int64{0})))
+	assert.Error(t, registry.Register(fakeCircuit(t, 4, 1, []int64{0, 1})))
+	assert.Error(t, registry.Register(&Circuit{TxsCount: 1, GasAssetIds: []int64{0}}))
+	assert.Error(t, registry.Register(fakeCircuit(t, 0, 1, []int64{0})))
+
+	testCases := []struct {
+		txsCount        int
+		gasAccountIndex int64
+		gasAssetIds     []int64
+		expected        int
+	}{
+		{1, 1, []int64{0, 1}, 1},
+		{2, 1, []int64{0, 1}, 4},
+		{4, 1, []int64{0, 1}, 4},
+		{5, 1, []int64{0, 1}, 10},
+		{11, 1, []int64{0, 1}, 0},
+		{1, 1, []int64{0}, 2},
+		{3, 1, []int64{0}, 0},
+		// the order of the gas assets is the one of the witness
+		{1, 1, []int64{1, 0}, 0},
+		{1, 0, []int64{0, 1}, 0},
+	}
+	for _, testCase := range testCases {
+		c, err := registry.Select(testCase.txsCount, testCase.gasAccountIndex, testCase.gasAssetIds)
+		if testCase.expected == 0 {
+			assert.Equal(t, ErrNoCircuit, err, "%+v", testCase)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, c.TxsCount, "%+v", testCase)
+	}
+
+	var sizes []int
+	for _, c := range registry.Circuits() {
+		sizes = append(sizes, c.TxsCount)
+	}
+	assert.Equal(t, []int{1, 4, 10, 2}, sizes)
+}
+
+func TestLoadCircuit(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WriteCircuit(dir, fakeCircuit(t, 4, 1, []int64{0, 1})))
+	c, err := LoadCircuit(dir, 4, 1, []int64{0, 1})
+	require.NoError(t, err)
+	assert.Equal(t, 4, c.TxsCount)
+
+	// the gas the files were written for must be the one the circuit is loaded for
+	_, err = LoadCircuit(dir, 4, 2, []int64{0, 1})
+	assert.Error(t, err)
+	_, err = LoadCircuit(dir, 4, 1, []int64{1, 0})
+	assert.Error(t, err)
+	_, err = LoadCircuit(dir, 1, 1, []int64{0, 1})
+	assert.Error(t, err)
+
+	// so must the hash function
+	poseidonDir := t.TempDir()
+	poseidonCircuit := fakeCircuit(t, 4, 1, []int64{0, 1})
+	poseidonCircuit.Hasher = hasher.Poseidon
+	require.NoError(t, WriteCircuit(poseidonDir, poseidonCircuit))
+	_, err = LoadCircuit(poseidonDir, 4, 1, []int64{0, 1})
+	assert.Error(t, err)
+	c, err = LoadCircuitWithHasher(poseidonDir, hasher.Poseidon, 4, 1, []int64{0, 1})
+	require.NoError(t, err)
+	assert.Equal(t, hasher.Poseidon, c.Hasher)
+	_, err = LoadCircuitWithHasher(dir, hasher.Poseidon, 4, 1, []int64{0, 1})
+	assert.Error(t, err)
+}
+
+/*
+	TestRegistryHasher: circuits of the same shape and another hash function never replace each other
+*/
+func TestRegistryHasher(t *testing.T) {
+	poseidonCircuit := fakeCircuit(t, 1, 1, []int64{0})
+	poseidonCircuit.Hasher = hasher.Poseidon
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(fakeCircuit(t, 1, 1, []int64{0})))
+	assert.Error(t, registry.Register(poseidonCircuit))
+
+	poseidonRegistry := NewRegistryWithHasher(hasher.Poseidon)
+	require.NoError(t, poseidonRegistry.Register(poseidonCircuit))
+	assert.Error(t, poseidonRegistry.Register(fakeCircuit(t, 2, 1, []int64{0})))
+	c, err := poseidonRegistry.Select(1, 1, []int64{0})
+	require.NoError(t, err)
+	assert.Equal(t, hasher.Poseidon, c.Hasher)
+
+	s, err := state.NewState()
+	require.NoError(t, err)
+	_, _, err = poseidonRegistry.BuildBlock(witness.NewBuilder(s), 1, []int64{0}, 1, time.Now().UnixMilli(), nil)
+	assert.Error(t, err)
+}
+
+func TestRegistryBlocks(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(fakeCircuit(t, 1, 0, gasAssetIds)))
+	require.NoError(t, registry.Register(fakeCircuit(t, 3, 0, gasAssetIds)))
+
+	oBlock := registerBlock(t)
+	gasAccountIndex, blockGasAssetIds := BlockGas(oBlock)
+	assert.Equal(t, int64(0), gasAccountIndex)
+	assert.Equal(t, gasAssetIds, blockGasAssetIds)
+	padded, c, err := registry.PadBlock(oBlock)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.TxsCount)
+	assert.Equal(t, oBlock, padded)
+
+	// 2 txs are built into the circuit of 3 txs
+	s, err := state.NewState()
+	require.NoError(t, err)
+	sk, err := curve.GenerateEddsaPrivateKey("gas")
+	require.NoError(t, err)
+	txInfos := []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          txtypes.TxTypeRegisterZns,
+			AccountIndex:    0,
+			AccountName:     "gas.legend",
+			AccountNameHash: []byte{1},
+			PubKey:          hex.EncodeToString(sk.PublicKey.Bytes()),
+		},
+		&txtypes.DepositTxInfo{
+			TxType:          txtypes.TxTypeDeposit,
+			AccountIndex:    0,
+			AccountNameHash: []byte{1},
+			AssetId:         1,
+			AssetAmount:     big.NewInt(100),
+		},
+	}
+	oBlock, c, err = registry.BuildBlock(witness.NewBuilder(s), 0, gasAssetIds, 1, time.Now().UnixMilli(), txInfos)
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.TxsCount)
+	require.Equal(t, 3, len(oBlock.Txs))
+	assert.Equal(t, uint8(types.TxTypeEmptyTx), oBlock.Txs[2].TxType)
+
+	_, err = c.Prove(registerBlock(t))
+	assert.Error(t, err)
+	_, _, err = registry.BuildBlock(witness.NewBuilder(s), 0, []int64{0}, 2, time.Now().UnixMilli(), txInfos)
+	assert.Equal(t, ErrNoCircuit, err)
+}
@@ -0,0 +1,278 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+)
+
+const (
+	// finished jobs kept for their results to be fetched, the oldest ones are dropped first
+	maxFinishedJobs = 1024
+	// a block of 1000 txs is about 10 MB in binary and 30 MB in json
+	maxBlockBytes = 256 << 20
+)
+
+var (
+	ErrQueueFull     = errors.New("job queue is full")
+	ErrServiceClosed = errors.New("service is closed")
+)
+
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobProving JobStatus = "proving"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+/*
+	Job: a block to prove, padded to the TxsCount of its circuit. Proof is the hex of the 256 bytes
+	written by WriteProof and BlockCommitment, of the padded block, the public input it is verified against
+*/
+type Job struct {
+	Id              int64     `json:"id"`
+	Status          JobStatus `json:"status"`
+	BlockNumber     int64     `json:"block_number"`
+	TxsCount        int       `json:"txs_count"`
+	BlockCommitment string    `json:"block_commitment"`
+	Proof           string    `json:"proof,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+type queuedJob struct {
+	id      int64
+	oBlock  *circuit.Block
+	circuit *Circuit
+}
+
+/*
+	Service: proves blocks with at most workers proofs at a time, jobs wait in a queue of queueSize
+*/
+type Service struct {
+	registry *Registry
+	queue    chan *queuedJob
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	nextId   int64
+	jobs     map[int64]*Job
+	finished []int64
+}
+
+func NewService(registry *Registry, workers int, queueSize int) (*Service, error) {
+	if registry == nil || workers <= 0 || queueSize < 0 {
+		log.Println("[NewService] invalid params")
+		return nil, errors.New("[NewService] invalid params")
+	}
+	s := &Service{
+		registry: registry,
+		queue:    make(chan *queuedJob, queueSize),
+		jobs:     make(map[int64]*Job),
+	}
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s, nil
+}
+
+/*
+	Submit: queue a block for the smallest circuit it fits in, its job can then be fetched with Job
+*/
+func (s *Service) Submit(oBlock *circuit.Block) (*Job, error) {
+	if err := circuit.ValidateBlock(oBlock); err != nil {
+		return nil, err
+	}
+	oBlock, c, err := s.registry.PadBlock(oBlock)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrServiceClosed
+	}
+	id := s.nextId + 1
+	select {
+	case s.queue <- &queuedJob{id: id, oBlock: oBlock, circuit: c}:
+	default:
+		return nil, ErrQueueFull
+	}
+	s.nextId = id
+	job := &Job{
+		Id:              id,
+		Status:          JobQueued,
+		BlockNumber:     oBlock.BlockNumber,
+		TxsCount:        c.TxsCount,
+		BlockCommitment: hex.EncodeToString(oBlock.BlockCommitment),
+	}
+	s.jobs[job.Id] = job
+	jobCopy := *job
+	return &jobCopy, nil
+}
+
+/*
+	Job: a copy of the job of id, finished jobs are only kept until maxFinishedJobs newer ones finish
+*/
+func (s *Service) Job(id int64) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	jobCopy := *job
+	return &jobCopy, true
+}
+
+/*
+	Close: stop taking jobs and wait for the queued ones to be proven
+*/
+func (s *Service) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Service) work() {
+	defer s.wg.Done()
+	for queued := range s.queue {
+		s.setStatus(queued.id, JobProving, "", nil)
+		proof, err := queued.circuit.Prove(queued.oBlock)
+		if err != nil {
+			s.setStatus(queued.id, JobFailed, "", err)
+			continue
+		}
+		var buf bytes.Buffer
+		if _, err = proof.WriteRawTo(&buf); err != nil {
+			s.setStatus(queued.id, JobFailed, "", err)
+			continue
+		}
+		s.setStatus(queued.id, JobDone, hex.EncodeToString(buf.Bytes()), nil)
+	}
+}
+
+func (s *Service) setStatus(id int64, status JobStatus, proof string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[id]
+	job.Status = status
+	job.Proof = proof
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if status != JobDone && status != JobFailed {
+		return
+	}
+	s.finished = append(s.finished, id)
+	if len(s.finished) > maxFinishedJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
+
+/*
+	Handler: the http api of the service
+	POST /jobs with a block in the json or the binary format queues it and answers its job
+	GET /jobs/<id> answers the job, with the proof once it is done
+*/
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleSubmit)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return mux
+}
+
+func (s *Service) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	blockBytes, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBlockBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	oBlock, err := decodeBlock(blockBytes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	job, err := s.Submit(oBlock)
+	switch {
+	case err == ErrNoCircuit:
+		gasAccountIndex, gasAssetIds := BlockGas(oBlock)
+		writeError(w, http.StatusUnprocessableEntity,
+			fmt.Errorf("%s: %d txs, gas account %d, gas assets %v", err, len(oBlock.Txs), gasAccountIndex, gasAssetIds))
+	case err == ErrQueueFull || err == ErrServiceClosed:
+		writeError(w, http.StatusServiceUnavailable, err)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeJSON(w, http.StatusAccepted, job)
+	}
+}
+
+func (s *Service) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/jobs/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	job, ok := s.Job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package prover
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/witness"
+)
+
+func postBlock(t *testing.T, url string, oBlock *circuit.Block) (int, map[string]interface{}) {
+	blockBytes, err := oBlock.MarshalBinary()
+	require.NoError(t, err)
+	return post(t, url+"/jobs", blockBytes)
+}
+
+func post(t *testing.T, url string, body []byte) (int, map[string]interface{}) {
+	resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var res map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	return resp.StatusCode, res
+}
+
+func getJob(t *testing.T, url string, id interface{}) (int, *Job) {
+	resp, err := http.Get(url + "/jobs/" + jsonString(t, id))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var job Job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	return resp.StatusCode, &job
+}
+
+func jsonString(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+/*
+	waitJob: poll the job until it is finished
+*/
+func waitJob(t *testing.T, url string, id interface{}, timeout time.Duration) *Job {
+	deadline := time.Now().Add(timeout)
+	for {
+		statusCode, job := getJob(t, url, id)
+		require.Equal(t, http.StatusOK, statusCode)
+		if job.Status == JobDone || job.Status == JobFailed {
+			return job
+		}
+		require.True(t, time.Now().Before(deadline), "job %v is still %s", id, job.Status)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	_, err := NewService(nil, 1, 1)
+	assert.Error(t, err)
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(fakeCircuit(t, 2, 0, gasAssetIds)))
+	s, err := NewService(registry, 1, 1)
+	require.NoError(t, err)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	// the block is padded to the circuit of 2 txs, whose keys are of another circuit so that proving fails
+	oBlock := registerBlock(t)
+	padded, err := witness.PadBlock(oBlock, 2)
+	require.NoError(t, err)
+	statusCode, res := postBlock(t, server.URL, oBlock)
+	require.Equal(t, http.StatusAccepted, statusCode)
+	assert.Equal(t, string(JobQueued), res["status"])
+	assert.Equal(t, float64(2), res["txs_count"])
+	assert.Equal(t, hex.EncodeToString(padded.BlockCommitment), res["block_commitment"])
+	job := waitJob(t, server.URL, res["id"], time.Minute)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.NotEmpty(t, job.Error)
+	assert.Empty(t, job.Proof)
+
+	threeTxs := *padded
+	threeTxs.Txs = append(threeTxs.Txs, padded.Txs[1])
+	statusCode, _ = postBlock(t, server.URL, &threeTxs)
+	assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+
+	statusCode, _ = post(t, server.URL+"/jobs", []byte("{}"))
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _ = getJob(t, server.URL, 100)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	statusCode, _ = getJob(t, server.URL, "x")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	resp, err := http.Get(server.URL + "/jobs")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	s.Close()
+	statusCode, _ = postBlock(t, server.URL, oBlock)
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+}
+
+/*
+	TestServiceEndToEnd: a 1-tx block is proven by the service and its proof verifies against its commitment,
+	the groth16 setup of the block circuit takes minutes
+*/
+func TestServiceEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the groth16 setup of the block circuit in short mode")
+	}
+	dir := t.TempDir()
+	c, err := NewCircuit(1, 0, gasAssetIds)
+	require.NoError(t, err)
+	require.NoError(t, WriteCircuit(dir, c))
+
+	c, err = LoadCircuit(dir, 1, 0, gasAssetIds)
+	require.NoError(t, err)
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(c))
+	s, err := NewService(registry, 1, 4)
+	require.NoError(t, err)
+	defer s.Close()
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	oBlock := registerBlock(t)
+	statusCode, res := postBlock(t, server.URL, oBlock)
+	require.Equal(t, http.StatusAccepted, statusCode)
+	job := waitJob(t, server.URL, res["id"], 30*time.Minute)
+	require.Equal(t, JobDone, job.Status, job.Error)
+
+	proofBytes, err := hex.DecodeString(job.Proof)
+	require.NoError(t, err)
+	assert.Len(t, proofBytes, 256)
+	proof := groth16.NewProof(ecc.BN254)
+	_, err = proof.ReadFrom(bytes.NewReader(proofBytes))
+	require.NoError(t, err)
+	blockCommitment, err := hex.DecodeString(job.BlockCommitment)
+	require.NoError(t, err)
+	assert.NoError(t, c.Verify(proof, blockCommitment))
+}
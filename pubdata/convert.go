@@ -0,0 +1,349 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+const (
+	AccountNameSuffix    = ".legend"
+	AccountNameMaxLength = 20
+)
+
+/*
+	PackAmount: the packed form of amount, which must be exactly packable
+*/
+func PackAmount(amount *big.Int) (int64, error) {
+	if amount == nil {
+		return 0, errors.New("nil amount")
+	}
+	cleaned, err := util.CleanPackedAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	if cleaned.Cmp(amount) != 0 {
+		return 0, errors.New(fmt.Sprintf("amount %s can not be packed", amount.String()))
+	}
+	return util.ToPackedAmount(amount)
+}
+
+/*
+	PackFee: the packed form of fee, which must be exactly packable
+*/
+func PackFee(fee *big.Int) (int64, error) {
+	if fee == nil {
+		return 0, errors.New("nil fee")
+	}
+	cleaned, err := util.CleanPackedFee(fee)
+	if err != nil {
+		return 0, err
+	}
+	if cleaned.Cmp(fee) != 0 {
+		return 0, errors.New(fmt.Sprintf("fee %s can not be packed", fee.String()))
+	}
+	return util.ToPackedFee(fee)
+}
+
+func AddressToBigInt(address string) *big.Int {
+	return new(big.Int).SetBytes(common.FromHex(address))
+}
+
+/*
+	AccountNameToBytes: the name without suffix, right padded to 20 bytes
+*/
+func AccountNameToBytes(name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, AccountNameSuffix)
+	if len(name) > AccountNameMaxLength {
+		return nil, errors.New(fmt.Sprintf("account name is too long: %s", name))
+	}
+	buf := make([]byte, AccountNameMaxLength)
+	copy(buf, name)
+	return buf, nil
+}
+
+func ParseSignature(sig []byte) (*eddsa.Signature, error) {
+	res := new(eddsa.Signature)
+	if _, err := res.SetBytes(sig); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func ToRegisterZnsTx(txInfo *txtypes.RegisterZnsTxInfo) (*types.RegisterZnsTx, error) {
+	pk, err := txtypes.ParsePublicKey(txInfo.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	accountName, err := AccountNameToBytes(txInfo.AccountName)
+	if err != nil {
+		return nil, err
+	}
+	return &types.RegisterZnsTx{
+		AccountIndex:    txInfo.AccountIndex,
+		AccountName:     accountName,
+		AccountNameHash: txInfo.AccountNameHash,
+		PubKey:          pk,
+	}, nil
+}
+
+func ToDepositTx(txInfo *txtypes.DepositTxInfo) (*types.DepositTx, error) {
+	return &types.DepositTx{
+		AccountIndex:    txInfo.AccountIndex,
+		AccountNameHash: txInfo.AccountNameHash,
+		AssetId:         txInfo.AssetId,
+		AssetAmount:     txInfo.AssetAmount,
+	}, nil
+}
+
+func ToDepositNftTx(txInfo *txtypes.DepositNftTxInfo) (*types.DepositNftTx, error) {
+	return &types.DepositNftTx{
+		AccountIndex:        txInfo.AccountIndex,
+		NftIndex:            txInfo.NftIndex,
+		NftL1Address:        AddressToBigInt(txInfo.NftL1Address).String(),
+		AccountNameHash:     txInfo.AccountNameHash,
+		NftContentHash:      txInfo.NftContentHash,
+		NftL1TokenId:        txInfo.NftL1TokenId,
+		CreatorAccountIndex: txInfo.CreatorAccountIndex,
+		CreatorTreasuryRate: txInfo.CreatorTreasuryRate,
+		CollectionId:        txInfo.CollectionId,
+	}, nil
+}
+
+func ToTransferTx(txInfo *txtypes.TransferTxInfo) (*types.TransferTx, error) {
+	packedAmount, err := PackAmount(txInfo.AssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TransferTx{
+		FromAccountIndex:  txInfo.FromAccountIndex,
+		ToAccountIndex:    txInfo.ToAccountIndex,
+		ToAccountNameHash: common.FromHex(txInfo.ToAccountNameHash),
+		AssetId:           txInfo.AssetId,
+		AssetAmount:       packedAmount,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+		CallDataHash:      txInfo.CallDataHash,
+	}, nil
+}
+
+func ToWithdrawTx(txInfo *txtypes.WithdrawTxInfo) (*types.WithdrawTx, error) {
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.WithdrawTx{
+		FromAccountIndex:  txInfo.FromAccountIndex,
+		AssetId:           txInfo.AssetId,
+		AssetAmount:       txInfo.AssetAmount,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+		ToAddress:         AddressToBigInt(txInfo.ToAddress),
+	}, nil
+}
+
+func ToCreateCollectionTx(txInfo *txtypes.CreateCollectionTxInfo) (*types.CreateCollectionTx, error) {
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.CreateCollectionTx{
+		AccountIndex:      txInfo.AccountIndex,
+		CollectionId:      txInfo.CollectionId,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+		ExpiredAt:         txInfo.ExpiredAt,
+		Nonce:             txInfo.Nonce,
+	}, nil
+}
+
+func ToMintNftTx(txInfo *txtypes.MintNftTxInfo) (*types.MintNftTx, error) {
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.MintNftTx{
+		CreatorAccountIndex: txInfo.CreatorAccountIndex,
+		ToAccountIndex:      txInfo.ToAccountIndex,
+		ToAccountNameHash:   common.FromHex(txInfo.ToAccountNameHash),
+		NftIndex:            txInfo.NftIndex,
+		NftContentHash:      common.FromHex(txInfo.NftContentHash),
+		CreatorTreasuryRate: txInfo.CreatorTreasuryRate,
+		GasAccountIndex:     txInfo.GasAccountIndex,
+		GasFeeAssetId:       txInfo.GasFeeAssetId,
+		GasFeeAssetAmount:   packedFee,
+		CollectionId:        txInfo.NftCollectionId,
+		ExpiredAt:           txInfo.ExpiredAt,
+	}, nil
+}
+
+func ToTransferNftTx(txInfo *txtypes.TransferNftTxInfo) (*types.TransferNftTx, error) {
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TransferNftTx{
+		FromAccountIndex:  txInfo.FromAccountIndex,
+		ToAccountIndex:    txInfo.ToAccountIndex,
+		ToAccountNameHash: common.FromHex(txInfo.ToAccountNameHash),
+		NftIndex:          txInfo.NftIndex,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+		CallDataHash:      txInfo.CallDataHash,
+	}, nil
+}
+
+func ToOfferTx(offer *txtypes.OfferTxInfo) (*types.OfferTx, error) {
+	packedAmount, err := PackAmount(offer.AssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ParseSignature(offer.Sig)
+	if err != nil {
+		return nil, err
+	}
+	return &types.OfferTx{
+		Type:         offer.Type,
+		OfferId:      offer.OfferId,
+		AccountIndex: offer.AccountIndex,
+		NftIndex:     offer.NftIndex,
+		AssetId:      offer.AssetId,
+		AssetAmount:  packedAmount,
+		ListedAt:     offer.ListedAt,
+		ExpiredAt:    offer.ExpiredAt,
+		TreasuryRate: offer.TreasuryRate,
+		Sig:          sig,
+	}, nil
+}
+
+/*
+	ToAtomicMatchTx: CreatorAmount and TreasuryAmount are set by the layer 2 and must be present
+*/
+func ToAtomicMatchTx(txInfo *txtypes.AtomicMatchTxInfo) (*types.AtomicMatchTx, error) {
+	if txInfo.BuyOffer == nil || txInfo.SellOffer == nil {
+		return nil, errors.New("offers should not be nil")
+	}
+	packedCreatorAmount, err := PackAmount(txInfo.CreatorAmount)
+	if err != nil {
+		return nil, err
+	}
+	packedTreasuryAmount, err := PackAmount(txInfo.TreasuryAmount)
+	if err != nil {
+		return nil, err
+	}
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	buyOffer, err := ToOfferTx(txInfo.BuyOffer)
+	if err != nil {
+		return nil, err
+	}
+	sellOffer, err := ToOfferTx(txInfo.SellOffer)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AtomicMatchTx{
+		AccountIndex:      txInfo.AccountIndex,
+		BuyOffer:          buyOffer,
+		SellOffer:         sellOffer,
+		CreatorAmount:     packedCreatorAmount,
+		TreasuryAmount:    packedTreasuryAmount,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+	}, nil
+}
+
+func ToCancelOfferTx(txInfo *txtypes.CancelOfferTxInfo) (*types.CancelOfferTx, error) {
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.CancelOfferTx{
+		AccountIndex:      txInfo.AccountIndex,
+		OfferId:           txInfo.OfferId,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+	}, nil
+}
+
+func ToWithdrawNftTx(txInfo *txtypes.WithdrawNftTxInfo) (*types.WithdrawNftTx, error) {
+	packedFee, err := PackFee(txInfo.GasFeeAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &types.WithdrawNftTx{
+		AccountIndex:           txInfo.AccountIndex,
+		CreatorAccountIndex:    txInfo.CreatorAccountIndex,
+		CreatorAccountNameHash: txInfo.CreatorAccountNameHash,
+		CreatorTreasuryRate:    txInfo.CreatorTreasuryRate,
+		NftIndex:               txInfo.NftIndex,
+		NftContentHash:         txInfo.NftContentHash,
+		NftL1Address:           AddressToBigInt(txInfo.NftL1Address).String(),
+		NftL1TokenId:           txInfo.NftL1TokenId,
+		ToAddress:              AddressToBigInt(txInfo.ToAddress).String(),
+		GasAccountIndex:        txInfo.GasAccountIndex,
+		GasFeeAssetId:          txInfo.GasFeeAssetId,
+		GasFeeAssetAmount:      packedFee,
+		CollectionId:           txInfo.CollectionId,
+	}, nil
+}
+
+func ToFullExitTx(txInfo *txtypes.FullExitTxInfo) (*types.FullExitTx, error) {
+	return &types.FullExitTx{
+		AccountIndex:    txInfo.AccountIndex,
+		AccountNameHash: txInfo.AccountNameHash,
+		AssetId:         txInfo.AssetId,
+		AssetAmount:     txInfo.AssetAmount,
+	}, nil
+}
+
+func ToFullExitNftTx(txInfo *txtypes.FullExitNftTxInfo) (*types.FullExitNftTx, error) {
+	return &types.FullExitNftTx{
+		AccountIndex:           txInfo.AccountIndex,
+		AccountNameHash:        txInfo.AccountNameHash,
+		CreatorAccountIndex:    txInfo.CreatorAccountIndex,
+		CreatorAccountNameHash: txInfo.CreatorAccountNameHash,
+		CreatorTreasuryRate:    txInfo.CreatorTreasuryRate,
+		NftIndex:               txInfo.NftIndex,
+		CollectionId:           txInfo.CollectionId,
+		NftContentHash:         txInfo.NftContentHash,
+		NftL1Address:           AddressToBigInt(txInfo.NftL1Address).String(),
+		NftL1TokenId:           txInfo.NftL1TokenId,
+	}, nil
+}
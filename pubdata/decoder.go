@@ -0,0 +1,686 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+)
+
+/*
+	TxPubData: a tx decoded from the pubdata of a block, one type per TxType* constant
+*/
+type TxPubData interface {
+	GetTxType() int
+}
+
+type EmptyTxPubData struct{}
+
+type RegisterZnsPubData struct {
+	AccountIndex    int64
+	AccountName     string
+	AccountNameHash []byte
+	PubKey          string
+}
+
+type DepositPubData struct {
+	AccountIndex    int64
+	AssetId         int64
+	AssetAmount     *big.Int
+	AccountNameHash []byte
+}
+
+type DepositNftPubData struct {
+	AccountIndex        int64
+	NftIndex            int64
+	NftL1Address        string
+	CreatorAccountIndex int64
+	CreatorTreasuryRate int64
+	CollectionId        int64
+	NftContentHash      []byte
+	NftL1TokenId        *big.Int
+	AccountNameHash     []byte
+}
+
+type TransferPubData struct {
+	FromAccountIndex  int64
+	ToAccountIndex    int64
+	AssetId           int64
+	AssetAmount       *big.Int
+	GasAccountIndex   int64
+	GasFeeAssetId     int64
+	GasFeeAssetAmount *big.Int
+	CallDataHash      []byte
+}
+
+type WithdrawPubData struct {
+	FromAccountIndex  int64
+	ToAddress         string
+	AssetId           int64
+	AssetAmount       *big.Int
+	GasAccountIndex   int64
+	GasFeeAssetId     int64
+	GasFeeAssetAmount *big.Int
+}
+
+type CreateCollectionPubData struct {
+	AccountIndex      int64
+	CollectionId      int64
+	GasAccountIndex   int64
+	GasFeeAssetId     int64
+	GasFeeAssetAmount *big.Int
+}
+
+type MintNftPubData struct {
+	CreatorAccountIndex int64
+	ToAccountIndex      int64
+	NftIndex            int64
+	GasAccountIndex     int64
+	GasFeeAssetId       int64
+	GasFeeAssetAmount   *big.Int
+	CreatorTreasuryRate int64
+	CollectionId        int64
+	NftContentHash      []byte
+}
+
+type TransferNftPubData struct {
+	FromAccountIndex  int64
+	ToAccountIndex    int64
+	NftIndex          int64
+	GasAccountIndex   int64
+	GasFeeAssetId     int64
+	GasFeeAssetAmount *big.Int
+	CallDataHash      []byte
+}
+
+type AtomicMatchPubData struct {
+	AccountIndex       int64
+	BuyerAccountIndex  int64
+	BuyOfferId         int64
+	SellerAccountIndex int64
+	SellOfferId        int64
+	NftIndex           int64
+	AssetId            int64
+	AssetAmount        *big.Int
+	CreatorAmount      *big.Int
+	TreasuryAmount     *big.Int
+	GasAccountIndex    int64
+	GasFeeAssetId      int64
+	GasFeeAssetAmount  *big.Int
+}
+
+type CancelOfferPubData struct {
+	AccountIndex      int64
+	OfferId           int64
+	GasAccountIndex   int64
+	GasFeeAssetId     int64
+	GasFeeAssetAmount *big.Int
+}
+
+type WithdrawNftPubData struct {
+	AccountIndex           int64
+	CreatorAccountIndex    int64
+	CreatorTreasuryRate    int64
+	NftIndex               int64
+	CollectionId           int64
+	NftL1Address           string
+	ToAddress              string
+	GasAccountIndex        int64
+	GasFeeAssetId          int64
+	GasFeeAssetAmount      *big.Int
+	NftContentHash         []byte
+	NftL1TokenId           *big.Int
+	CreatorAccountNameHash []byte
+}
+
+type FullExitPubData struct {
+	AccountIndex    int64
+	AssetId         int64
+	AssetAmount     *big.Int
+	AccountNameHash []byte
+}
+
+type FullExitNftPubData struct {
+	AccountIndex           int64
+	CreatorAccountIndex    int64
+	CreatorTreasuryRate    int64
+	NftIndex               int64
+	CollectionId           int64
+	NftL1Address           string
+	AccountNameHash        []byte
+	CreatorAccountNameHash []byte
+	NftContentHash         []byte
+	NftL1TokenId           *big.Int
+}
+
+func (*EmptyTxPubData) GetTxType() int          { return types.TxTypeEmptyTx }
+func (*RegisterZnsPubData) GetTxType() int      { return types.TxTypeRegisterZns }
+func (*DepositPubData) GetTxType() int          { return types.TxTypeDeposit }
+func (*DepositNftPubData) GetTxType() int       { return types.TxTypeDepositNft }
+func (*TransferPubData) GetTxType() int         { return types.TxTypeTransfer }
+func (*WithdrawPubData) GetTxType() int         { return types.TxTypeWithdraw }
+func (*CreateCollectionPubData) GetTxType() int { return types.TxTypeCreateCollection }
+func (*MintNftPubData) GetTxType() int          { return types.TxTypeMintNft }
+func (*TransferNftPubData) GetTxType() int      { return types.TxTypeTransferNft }
+func (*AtomicMatchPubData) GetTxType() int      { return types.TxTypeAtomicMatch }
+func (*CancelOfferPubData) GetTxType() int      { return types.TxTypeCancelOffer }
+func (*WithdrawNftPubData) GetTxType() int      { return types.TxTypeWithdrawNft }
+func (*FullExitPubData) GetTxType() int         { return types.TxTypeFullExit }
+func (*FullExitNftPubData) GetTxType() int      { return types.TxTypeFullExitNft }
+
+/*
+	PubDataError: the tx and the chunk of the pubdata that could not be decoded
+*/
+type PubDataError struct {
+	TxIndex int
+	Chunk   int
+	Reason  string
+}
+
+func (e *PubDataError) Error() string {
+	return fmt.Sprintf("tx %d chunk %d: %s", e.TxIndex, e.Chunk, e.Reason)
+}
+
+// number of chunks used by each tx type, the remaining chunks are zero
+var txPubDataChunks = map[int]int{
+	types.TxTypeEmptyTx:          0,
+	types.TxTypeRegisterZns:      5,
+	types.TxTypeDeposit:          2,
+	types.TxTypeDepositNft:       5,
+	types.TxTypeTransfer:         2,
+	types.TxTypeWithdraw:         2,
+	types.TxTypeCreateCollection: 1,
+	types.TxTypeMintNft:          2,
+	types.TxTypeTransferNft:      2,
+	types.TxTypeAtomicMatch:      2,
+	types.TxTypeCancelOffer:      1,
+	types.TxTypeWithdrawNft:      6,
+	types.TxTypeFullExit:         2,
+	types.TxTypeFullExitNft:      6,
+}
+
+/*
+	DecodeTxPubData: the tx committed as data, the TxPubDataSize bytes written by ComputeTxPubData
+*/
+func DecodeTxPubData(data []byte) (tx TxPubData, err error) {
+	if len(data) != TxPubDataSize {
+		errInfo := fmt.Sprintf("[DecodeTxPubData] invalid pubdata size: %d, expected: %d", len(data), TxPubDataSize)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	tx, err = decodeTxPubData(data)
+	if err != nil {
+		log.Println("[DecodeTxPubData]", err.Error())
+		return nil, err
+	}
+	return tx, nil
+}
+
+/*
+	DecodeBlockPubData: the txs of a block from the pubdata commitBlock is called with,
+	the empty txs padding the block are decoded as EmptyTxPubData
+*/
+func DecodeBlockPubData(pubData []byte) (txs []TxPubData, err error) {
+	if len(pubData)%TxPubDataSize != 0 {
+		errInfo := fmt.Sprintf("[DecodeBlockPubData] invalid pubdata size: %d, not a multiple of %d", len(pubData), TxPubDataSize)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	txs = make([]TxPubData, len(pubData)/TxPubDataSize)
+	for i := range txs {
+		txs[i], err = decodeTxPubData(pubData[i*TxPubDataSize : (i+1)*TxPubDataSize])
+		if err != nil {
+			var pe *PubDataError
+			if errors.As(err, &pe) {
+				pe.TxIndex = i
+			}
+			log.Println("[DecodeBlockPubData]", err.Error())
+			return nil, err
+		}
+	}
+	return txs, nil
+}
+
+func decodeTxPubData(data []byte) (tx TxPubData, err error) {
+	for i := 0; i < types.PubDataSizePerTx; i++ {
+		if new(big.Int).SetBytes(chunkBytes(data, i)).Cmp(fr.Modulus()) >= 0 {
+			return nil, &PubDataError{Chunk: i, Reason: "not a field element"}
+		}
+	}
+	txType := int(data[0])
+	chunksCount, ok := txPubDataChunks[txType]
+	if !ok {
+		return nil, &PubDataError{Chunk: 0, Reason: fmt.Sprintf("unknown tx type %d", txType)}
+	}
+	for i := chunksCount; i < types.PubDataSizePerTx; i++ {
+		if !isZeroChunk(data, i) {
+			return nil, &PubDataError{Chunk: i, Reason: fmt.Sprintf("unused chunk of tx type %d is not zero", txType)}
+		}
+	}
+	switch txType {
+	case types.TxTypeEmptyTx:
+		return &EmptyTxPubData{}, nil
+	case types.TxTypeRegisterZns:
+		return decodeRegisterZns(data)
+	case types.TxTypeDeposit:
+		return decodeDeposit(data)
+	case types.TxTypeDepositNft:
+		return decodeDepositNft(data)
+	case types.TxTypeTransfer:
+		return decodeTransfer(data)
+	case types.TxTypeWithdraw:
+		return decodeWithdraw(data)
+	case types.TxTypeCreateCollection:
+		return decodeCreateCollection(data)
+	case types.TxTypeMintNft:
+		return decodeMintNft(data)
+	case types.TxTypeTransferNft:
+		return decodeTransferNft(data)
+	case types.TxTypeAtomicMatch:
+		return decodeAtomicMatch(data)
+	case types.TxTypeCancelOffer:
+		return decodeCancelOffer(data)
+	case types.TxTypeWithdrawNft:
+		return decodeWithdrawNft(data)
+	case types.TxTypeFullExit:
+		return decodeFullExit(data)
+	default:
+		return decodeFullExitNft(data)
+	}
+}
+
+func decodeRegisterZns(data []byte) (TxPubData, error) {
+	tx := new(RegisterZnsPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	r.pad(216)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 1, AccountNameMaxLength*8)
+	name := r.readBig(AccountNameMaxLength * 8).FillBytes(make([]byte, AccountNameMaxLength))
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.AccountName = string(bytes.TrimRight(name, "\x00")) + AccountNameSuffix
+	tx.AccountNameHash = chunkBytes(data, 2)
+	pk := new(eddsa.PublicKey)
+	pk.A.X.SetBigInt(new(big.Int).SetBytes(chunkBytes(data, 3)))
+	pk.A.Y.SetBigInt(new(big.Int).SetBytes(chunkBytes(data, 4)))
+	tx.PubKey = hex.EncodeToString(pk.Bytes())
+	return tx, nil
+}
+
+func decodeDeposit(data []byte) (TxPubData, error) {
+	tx := new(DepositPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.AssetId = r.read(types.AssetIdBitsSize)
+	tx.AssetAmount = r.readBig(types.StateAmountBitsSize)
+	r.pad(72)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.AccountNameHash = chunkBytes(data, 1)
+	return tx, nil
+}
+
+func decodeDepositNft(data []byte) (TxPubData, error) {
+	tx := new(DepositNftPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.NftIndex = r.read(types.NftIndexBitsSize)
+	tx.NftL1Address = r.readAddress()
+	r.pad(16)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 1, types.AccountIndexBitsSize+types.CreatorTreasuryRateBitsSize+types.CollectionIdBitsSize)
+	tx.CreatorAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.CreatorTreasuryRate = r.read(types.CreatorTreasuryRateBitsSize)
+	tx.CollectionId = r.read(types.CollectionIdBitsSize)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.NftContentHash = chunkBytes(data, 2)
+	tx.NftL1TokenId = new(big.Int).SetBytes(chunkBytes(data, 3))
+	tx.AccountNameHash = chunkBytes(data, 4)
+	return tx, nil
+}
+
+func decodeTransfer(data []byte) (TxPubData, error) {
+	tx := new(TransferPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.FromAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.ToAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.AssetId = r.read(types.AssetIdBitsSize)
+	tx.AssetAmount = r.readPackedAmount()
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	r.pad(64)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.CallDataHash = chunkBytes(data, 1)
+	return tx, nil
+}
+
+func decodeWithdraw(data []byte) (TxPubData, error) {
+	tx := new(WithdrawPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.FromAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.ToAddress = r.readAddress()
+	tx.AssetId = r.read(types.AssetIdBitsSize)
+	r.pad(40)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 1, types.StateAmountBitsSize+types.AccountIndexBitsSize+types.AssetIdBitsSize+types.PackedFeeBitsSize)
+	tx.AssetAmount = r.readBig(types.StateAmountBitsSize)
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func decodeCreateCollection(data []byte) (TxPubData, error) {
+	tx := new(CreateCollectionPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.CollectionId = r.read(types.CollectionIdBitsSize)
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	r.pad(136)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func decodeMintNft(data []byte) (TxPubData, error) {
+	tx := new(MintNftPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.CreatorAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.ToAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.NftIndex = r.read(types.NftIndexBitsSize)
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	tx.CreatorTreasuryRate = r.read(types.CreatorTreasuryRateBitsSize)
+	tx.CollectionId = r.read(types.CollectionIdBitsSize)
+	r.pad(48)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.NftContentHash = chunkBytes(data, 1)
+	return tx, nil
+}
+
+func decodeTransferNft(data []byte) (TxPubData, error) {
+	tx := new(TransferNftPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.FromAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.ToAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.NftIndex = r.read(types.NftIndexBitsSize)
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	r.pad(80)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.CallDataHash = chunkBytes(data, 1)
+	return tx, nil
+}
+
+func decodeAtomicMatch(data []byte) (TxPubData, error) {
+	tx := new(AtomicMatchPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.BuyerAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.BuyOfferId = r.read(types.OfferIdBitsSize)
+	tx.SellerAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.SellOfferId = r.read(types.OfferIdBitsSize)
+	tx.NftIndex = r.read(types.NftIndexBitsSize)
+	tx.AssetId = r.read(types.AssetIdBitsSize)
+	r.pad(48)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 1, 3*types.PackedAmountBitsSize+types.AccountIndexBitsSize+types.AssetIdBitsSize+types.PackedFeeBitsSize)
+	tx.AssetAmount = r.readPackedAmount()
+	tx.CreatorAmount = r.readPackedAmount()
+	tx.TreasuryAmount = r.readPackedAmount()
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func decodeCancelOffer(data []byte) (TxPubData, error) {
+	tx := new(CancelOfferPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.OfferId = r.read(types.OfferIdBitsSize)
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	r.pad(128)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func decodeWithdrawNft(data []byte) (TxPubData, error) {
+	tx := new(WithdrawNftPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.CreatorAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.CreatorTreasuryRate = r.read(types.FeeRateBitsSize)
+	tx.NftIndex = r.read(types.NftIndexBitsSize)
+	tx.CollectionId = r.read(types.CollectionIdBitsSize)
+	r.pad(112)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 1, types.AddressBitsSize)
+	tx.NftL1Address = r.readAddress()
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 2, types.AddressBitsSize+types.AccountIndexBitsSize+types.AssetIdBitsSize+types.PackedFeeBitsSize)
+	tx.ToAddress = r.readAddress()
+	tx.GasAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.GasFeeAssetId = r.read(types.AssetIdBitsSize)
+	tx.GasFeeAssetAmount = r.readPackedFee()
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.NftContentHash = chunkBytes(data, 3)
+	tx.NftL1TokenId = new(big.Int).SetBytes(chunkBytes(data, 4))
+	tx.CreatorAccountNameHash = chunkBytes(data, 5)
+	return tx, nil
+}
+
+func decodeFullExit(data []byte) (TxPubData, error) {
+	tx := new(FullExitPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.AssetId = r.read(types.AssetIdBitsSize)
+	tx.AssetAmount = r.readBig(types.StateAmountBitsSize)
+	r.pad(72)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.AccountNameHash = chunkBytes(data, 1)
+	return tx, nil
+}
+
+func decodeFullExitNft(data []byte) (TxPubData, error) {
+	tx := new(FullExitNftPubData)
+	r := newChunkReader(data, 0, 256)
+	r.read(types.TxTypeBitsSize)
+	tx.AccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.CreatorAccountIndex = r.read(types.AccountIndexBitsSize)
+	tx.CreatorTreasuryRate = r.read(types.FeeRateBitsSize)
+	tx.NftIndex = r.read(types.NftIndexBitsSize)
+	tx.CollectionId = r.read(types.CollectionIdBitsSize)
+	r.pad(112)
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	r = newChunkReader(data, 1, types.AddressBitsSize)
+	tx.NftL1Address = r.readAddress()
+	if err := r.close(); err != nil {
+		return nil, err
+	}
+	tx.AccountNameHash = chunkBytes(data, 2)
+	tx.CreatorAccountNameHash = chunkBytes(data, 3)
+	tx.NftContentHash = chunkBytes(data, 4)
+	tx.NftL1TokenId = new(big.Int).SetBytes(chunkBytes(data, 5))
+	return tx, nil
+}
+
+/*
+	chunkReader: reverses pubDataChunk, fields are read from the most significant of the
+	size bits down, the bits above size are the leading zeros of a chunk that is not full
+*/
+type chunkReader struct {
+	index  int
+	value  *big.Int
+	size   int
+	offset int
+	err    error
+}
+
+func newChunkReader(data []byte, index int, size int) *chunkReader {
+	r := &chunkReader{
+		index: index,
+		value: new(big.Int).SetBytes(chunkBytes(data, index)),
+		size:  size,
+	}
+	if r.value.BitLen() > size {
+		r.fail(fmt.Sprintf("non-zero padding above bit %d", size))
+	}
+	return r
+}
+
+func (r *chunkReader) fail(reason string) {
+	if r.err == nil {
+		r.err = &PubDataError{Chunk: r.index, Reason: reason}
+	}
+}
+
+func (r *chunkReader) readBig(bitsSize int) *big.Int {
+	if r.err != nil {
+		return big.NewInt(0)
+	}
+	if r.offset+bitsSize > r.size {
+		r.fail(fmt.Sprintf("reading %d bits past the %d bits of the chunk", bitsSize, r.size))
+		return big.NewInt(0)
+	}
+	r.offset += bitsSize
+	v := new(big.Int).Rsh(r.value, uint(r.size-r.offset))
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bitsSize)), big.NewInt(1))
+	return v.And(v, mask)
+}
+
+func (r *chunkReader) read(bitsSize int) int64 {
+	return r.readBig(bitsSize).Int64()
+}
+
+func (r *chunkReader) readAddress() string {
+	return common.BigToAddress(r.readBig(types.AddressBitsSize)).Hex()
+}
+
+func (r *chunkReader) readPackedAmount() *big.Int {
+	amount, err := util.UnpackAmount(r.read(types.PackedAmountBitsSize))
+	if err != nil {
+		r.fail(err.Error())
+		return big.NewInt(0)
+	}
+	return amount
+}
+
+func (r *chunkReader) readPackedFee() *big.Int {
+	fee, err := util.UnpackFee(r.read(types.PackedFeeBitsSize))
+	if err != nil {
+		r.fail(err.Error())
+		return big.NewInt(0)
+	}
+	return fee
+}
+
+func (r *chunkReader) pad(bitsSize int) {
+	if r.readBig(bitsSize).Sign() != 0 {
+		r.fail(fmt.Sprintf("non-zero padding at bit %d", r.size-r.offset))
+	}
+}
+
+func (r *chunkReader) close() error {
+	if r.err == nil && r.offset != r.size {
+		r.fail(fmt.Sprintf("%d bits of the chunk are not read", r.size-r.offset))
+	}
+	return r.err
+}
+
+func chunkBytes(data []byte, index int) []byte {
+	return common.CopyBytes(data[index*circuit.PubDataChunkSize : (index+1)*circuit.PubDataChunkSize])
+}
+
+func isZeroChunk(data []byte, index int) bool {
+	for _, b := range data[index*circuit.PubDataChunkSize : (index+1)*circuit.PubDataChunkSize] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
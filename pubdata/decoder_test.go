@@ -0,0 +1,239 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+// a hash as committed, reduced into the field
+func committedHash(b []byte) []byte {
+	return types.BytesToPubData(b).FillBytes(make([]byte, 32))
+}
+
+func TestDecodeTxPubData(t *testing.T) {
+	for _, txInfo := range testTxInfos(t) {
+		pubData, err := ComputeTxPubData(txInfo)
+		assert.NoError(t, err)
+		decoded, err := DecodeTxPubData(pubData)
+		assert.NoError(t, err)
+		assert.Equal(t, txInfo.GetTxType(), decoded.GetTxType())
+
+		switch info := txInfo.(type) {
+		case *txtypes.RegisterZnsTxInfo:
+			tx := decoded.(*RegisterZnsPubData)
+			assert.Equal(t, info.AccountIndex, tx.AccountIndex)
+			assert.Equal(t, info.AccountName, tx.AccountName)
+			assert.Equal(t, committedHash(info.AccountNameHash), tx.AccountNameHash)
+			assert.Equal(t, info.PubKey, tx.PubKey)
+		case *txtypes.DepositTxInfo:
+			tx := decoded.(*DepositPubData)
+			assert.Equal(t, info.AccountIndex, tx.AccountIndex)
+			assert.Equal(t, info.AssetId, tx.AssetId)
+			assert.Equal(t, info.AssetAmount, tx.AssetAmount)
+			assert.Equal(t, info.AccountNameHash, tx.AccountNameHash)
+		case *txtypes.DepositNftTxInfo:
+			tx := decoded.(*DepositNftPubData)
+			assert.Equal(t, info.AccountIndex, tx.AccountIndex)
+			assert.Equal(t, info.NftIndex, tx.NftIndex)
+			assert.True(t, strings.EqualFold(info.NftL1Address, tx.NftL1Address))
+			assert.Equal(t, info.CreatorAccountIndex, tx.CreatorAccountIndex)
+			assert.Equal(t, info.CreatorTreasuryRate, tx.CreatorTreasuryRate)
+			assert.Equal(t, info.CollectionId, tx.CollectionId)
+			assert.Equal(t, committedHash(info.NftContentHash), tx.NftContentHash)
+			assert.Equal(t, info.NftL1TokenId, tx.NftL1TokenId)
+		case *txtypes.TransferTxInfo:
+			tx := decoded.(*TransferPubData)
+			assert.Equal(t, info.FromAccountIndex, tx.FromAccountIndex)
+			assert.Equal(t, info.ToAccountIndex, tx.ToAccountIndex)
+			assert.Equal(t, info.AssetId, tx.AssetId)
+			assert.Equal(t, info.AssetAmount, tx.AssetAmount)
+			assert.Equal(t, info.GasAccountIndex, tx.GasAccountIndex)
+			assert.Equal(t, info.GasFeeAssetId, tx.GasFeeAssetId)
+			assert.Equal(t, info.GasFeeAssetAmount, tx.GasFeeAssetAmount)
+			assert.Equal(t, info.CallDataHash, tx.CallDataHash)
+		case *txtypes.WithdrawTxInfo:
+			tx := decoded.(*WithdrawPubData)
+			assert.Equal(t, info.FromAccountIndex, tx.FromAccountIndex)
+			assert.True(t, strings.EqualFold(info.ToAddress, tx.ToAddress))
+			assert.Equal(t, info.AssetAmount, tx.AssetAmount)
+			assert.Equal(t, info.GasFeeAssetAmount, tx.GasFeeAssetAmount)
+		case *txtypes.CreateCollectionTxInfo:
+			tx := decoded.(*CreateCollectionPubData)
+			assert.Equal(t, info.CollectionId, tx.CollectionId)
+			assert.Equal(t, info.GasFeeAssetAmount, tx.GasFeeAssetAmount)
+		case *txtypes.MintNftTxInfo:
+			tx := decoded.(*MintNftPubData)
+			assert.Equal(t, info.CreatorAccountIndex, tx.CreatorAccountIndex)
+			assert.Equal(t, info.ToAccountIndex, tx.ToAccountIndex)
+			assert.Equal(t, info.NftIndex, tx.NftIndex)
+			assert.Equal(t, info.CreatorTreasuryRate, tx.CreatorTreasuryRate)
+			assert.Equal(t, info.NftCollectionId, tx.CollectionId)
+		case *txtypes.TransferNftTxInfo:
+			tx := decoded.(*TransferNftPubData)
+			assert.Equal(t, info.NftIndex, tx.NftIndex)
+			assert.Equal(t, committedHash(info.CallDataHash), tx.CallDataHash)
+		case *txtypes.AtomicMatchTxInfo:
+			tx := decoded.(*AtomicMatchPubData)
+			assert.Equal(t, info.AccountIndex, tx.AccountIndex)
+			assert.Equal(t, info.BuyOffer.AccountIndex, tx.BuyerAccountIndex)
+			assert.Equal(t, info.BuyOffer.OfferId, tx.BuyOfferId)
+			assert.Equal(t, info.SellOffer.AccountIndex, tx.SellerAccountIndex)
+			assert.Equal(t, info.SellOffer.OfferId, tx.SellOfferId)
+			assert.Equal(t, info.BuyOffer.NftIndex, tx.NftIndex)
+			assert.Equal(t, info.SellOffer.AssetId, tx.AssetId)
+			assert.Equal(t, info.SellOffer.AssetAmount, tx.AssetAmount)
+			assert.Equal(t, info.CreatorAmount, tx.CreatorAmount)
+			assert.Equal(t, info.TreasuryAmount, tx.TreasuryAmount)
+			assert.Equal(t, info.GasFeeAssetAmount, tx.GasFeeAssetAmount)
+		case *txtypes.CancelOfferTxInfo:
+			tx := decoded.(*CancelOfferPubData)
+			assert.Equal(t, info.OfferId, tx.OfferId)
+			assert.Equal(t, info.GasFeeAssetAmount, tx.GasFeeAssetAmount)
+		case *txtypes.WithdrawNftTxInfo:
+			tx := decoded.(*WithdrawNftPubData)
+			assert.Equal(t, info.AccountIndex, tx.AccountIndex)
+			assert.Equal(t, info.CreatorAccountIndex, tx.CreatorAccountIndex)
+			assert.Equal(t, info.CreatorTreasuryRate, tx.CreatorTreasuryRate)
+			assert.Equal(t, info.NftIndex, tx.NftIndex)
+			assert.Equal(t, info.CollectionId, tx.CollectionId)
+			assert.True(t, strings.EqualFold(info.NftL1Address, tx.NftL1Address))
+			assert.True(t, strings.EqualFold(info.ToAddress, tx.ToAddress))
+			assert.Equal(t, info.GasFeeAssetAmount, tx.GasFeeAssetAmount)
+			assert.Equal(t, info.NftL1TokenId, tx.NftL1TokenId)
+			assert.Equal(t, committedHash(info.CreatorAccountNameHash), tx.CreatorAccountNameHash)
+		case *txtypes.FullExitTxInfo:
+			tx := decoded.(*FullExitPubData)
+			assert.Equal(t, info.AssetId, tx.AssetId)
+			assert.Equal(t, info.AssetAmount, tx.AssetAmount)
+		case *txtypes.FullExitNftTxInfo:
+			tx := decoded.(*FullExitNftPubData)
+			assert.Equal(t, info.CreatorAccountIndex, tx.CreatorAccountIndex)
+			assert.Equal(t, info.NftIndex, tx.NftIndex)
+			assert.True(t, strings.EqualFold(info.NftL1Address, tx.NftL1Address))
+			assert.Equal(t, info.AccountNameHash, tx.AccountNameHash)
+			assert.Equal(t, info.NftL1TokenId, tx.NftL1TokenId)
+		}
+	}
+}
+
+func TestDecodeBlockPubData(t *testing.T) {
+	txInfos := testTxInfos(t)
+	pubData, _, err := ComputeBlockPubData(txInfos, len(txInfos)+2)
+	assert.NoError(t, err)
+	txs, err := DecodeBlockPubData(pubData)
+	assert.NoError(t, err)
+	assert.Equal(t, len(txInfos)+2, len(txs))
+	for i, txInfo := range txInfos {
+		assert.Equal(t, txInfo.GetTxType(), txs[i].GetTxType())
+	}
+	assert.Equal(t, &EmptyTxPubData{}, txs[len(txInfos)])
+
+	_, err = DecodeBlockPubData(pubData[1:])
+	assert.Error(t, err)
+
+	// the failing tx and chunk are reported
+	malformed := append([]byte{}, pubData...)
+	malformed[4*TxPubDataSize+TxPubDataSize-1] = 1
+	_, err = DecodeBlockPubData(malformed)
+	assert.Equal(t, &PubDataError{TxIndex: 4, Chunk: 5, Reason: "unused chunk of tx type 5 is not zero"}, err)
+}
+
+func TestDecodeMalformedPubData(t *testing.T) {
+	txInfos := testTxInfos(t)
+	txPubData := func(i int) []byte {
+		pubData, err := ComputeTxPubData(txInfos[i])
+		assert.NoError(t, err)
+		return pubData
+	}
+	chunkOf := func(err error) int {
+		assert.IsType(t, &PubDataError{}, err)
+		if e, ok := err.(*PubDataError); ok {
+			return e.Chunk
+		}
+		return -1
+	}
+
+	// the trailing padding of the transfer
+	data := txPubData(3)
+	data[31] = 1
+	_, err := DecodeTxPubData(data)
+	assert.Equal(t, 0, chunkOf(err))
+
+	// the leading zeros of the second chunk of the withdraw
+	data = txPubData(4)
+	data[32] = 1
+	_, err = DecodeTxPubData(data)
+	assert.Equal(t, 1, chunkOf(err))
+
+	// the leading zeros of the nft l1 address of the withdraw nft
+	data = txPubData(10)
+	data[32+11] = 1
+	_, err = DecodeTxPubData(data)
+	assert.Equal(t, 1, chunkOf(err))
+
+	// the account name above 20 bytes
+	data = txPubData(0)
+	data[32+11] = 1
+	_, err = DecodeTxPubData(data)
+	assert.Equal(t, 1, chunkOf(err))
+
+	// unknown tx type
+	data = txPubData(0)
+	data[0] = 14
+	_, err = DecodeTxPubData(data)
+	assert.Equal(t, 0, chunkOf(err))
+
+	// not a field element
+	data = txPubData(3)
+	copy(data[32:64], bytes.Repeat([]byte{0xff}, 32))
+	_, err = DecodeTxPubData(data)
+	assert.Equal(t, 1, chunkOf(err))
+
+	// an empty tx is all zeros
+	data = make([]byte, TxPubDataSize)
+	data[100] = 1
+	_, err = DecodeTxPubData(data)
+	assert.Equal(t, 3, chunkOf(err))
+
+	_, err = DecodeTxPubData(make([]byte, TxPubDataSize-1))
+	assert.Error(t, err)
+}
+
+func TestDecodePackedAmounts(t *testing.T) {
+	// the largest packed amount, 34359738367 * 10^31
+	amount := new(big.Int).Mul(big.NewInt(34359738367), new(big.Int).Exp(big.NewInt(10), big.NewInt(31), nil))
+	pubData, err := ComputeTxPubData(&txtypes.TransferTxInfo{
+		AssetAmount:       amount,
+		GasFeeAssetAmount: big.NewInt(0),
+		CallDataHash:      make([]byte, 32),
+	})
+	assert.NoError(t, err)
+	decoded, err := DecodeTxPubData(pubData)
+	assert.NoError(t, err)
+	assert.Equal(t, amount, decoded.(*TransferPubData).AssetAmount)
+	assert.Equal(t, big.NewInt(0), decoded.(*TransferPubData).GasFeeAssetAmount)
+}
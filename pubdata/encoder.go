@@ -0,0 +1,151 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+// size in bytes of the pubdata of a tx
+const TxPubDataSize = types.PubDataSizePerTx * circuit.PubDataChunkSize
+
+/*
+	ComputeTxPubDataChunks: the PubDataSizePerTx field elements the circuit commits to for txInfo
+*/
+func ComputeTxPubDataChunks(txInfo txtypes.TxInfo) (pubData [types.PubDataSizePerTx]*big.Int, err error) {
+	switch info := txInfo.(type) {
+	case *txtypes.RegisterZnsTxInfo:
+		var tx *types.RegisterZnsTx
+		if tx, err = ToRegisterZnsTx(info); err == nil {
+			pubData, err = types.RegisterZnsPubData(tx)
+		}
+	case *txtypes.DepositTxInfo:
+		var tx *types.DepositTx
+		if tx, err = ToDepositTx(info); err == nil {
+			pubData, err = types.DepositPubData(tx)
+		}
+	case *txtypes.DepositNftTxInfo:
+		var tx *types.DepositNftTx
+		if tx, err = ToDepositNftTx(info); err == nil {
+			pubData, err = types.DepositNftPubData(tx)
+		}
+	case *txtypes.TransferTxInfo:
+		var tx *types.TransferTx
+		if tx, err = ToTransferTx(info); err == nil {
+			pubData, err = types.TransferPubData(tx)
+		}
+	case *txtypes.WithdrawTxInfo:
+		var tx *types.WithdrawTx
+		if tx, err = ToWithdrawTx(info); err == nil {
+			pubData, err = types.WithdrawPubData(tx)
+		}
+	case *txtypes.CreateCollectionTxInfo:
+		var tx *types.CreateCollectionTx
+		if tx, err = ToCreateCollectionTx(info); err == nil {
+			pubData, err = types.CreateCollectionPubData(tx)
+		}
+	case *txtypes.MintNftTxInfo:
+		var tx *types.MintNftTx
+		if tx, err = ToMintNftTx(info); err == nil {
+			pubData, err = types.MintNftPubData(tx)
+		}
+	case *txtypes.TransferNftTxInfo:
+		var tx *types.TransferNftTx
+		if tx, err = ToTransferNftTx(info); err == nil {
+			pubData, err = types.TransferNftPubData(tx)
+		}
+	case *txtypes.AtomicMatchTxInfo:
+		var tx *types.AtomicMatchTx
+		if tx, err = ToAtomicMatchTx(info); err == nil {
+			pubData, err = types.AtomicMatchPubData(tx)
+		}
+	case *txtypes.CancelOfferTxInfo:
+		var tx *types.CancelOfferTx
+		if tx, err = ToCancelOfferTx(info); err == nil {
+			pubData, err = types.CancelOfferPubData(tx)
+		}
+	case *txtypes.WithdrawNftTxInfo:
+		var tx *types.WithdrawNftTx
+		if tx, err = ToWithdrawNftTx(info); err == nil {
+			pubData, err = types.WithdrawNftPubData(tx)
+		}
+	case *txtypes.FullExitTxInfo:
+		var tx *types.FullExitTx
+		if tx, err = ToFullExitTx(info); err == nil {
+			pubData, err = types.FullExitPubData(tx)
+		}
+	case *txtypes.FullExitNftTxInfo:
+		var tx *types.FullExitNftTx
+		if tx, err = ToFullExitNftTx(info); err == nil {
+			pubData, err = types.FullExitNftPubData(tx)
+		}
+	default:
+		err = errors.New(fmt.Sprintf("unsupported tx type: %d", txInfo.GetTxType()))
+	}
+	if err != nil {
+		errInfo := fmt.Sprintf("[ComputeTxPubDataChunks] unable to compute pubdata: %s", err.Error())
+		log.Println(errInfo)
+		return pubData, errors.New(errInfo)
+	}
+	return pubData, nil
+}
+
+/*
+	ComputeTxPubData: the pubdata of txInfo as PubDataSizePerTx big endian words of 32 bytes
+*/
+func ComputeTxPubData(txInfo txtypes.TxInfo) ([]byte, error) {
+	pubData, err := ComputeTxPubDataChunks(txInfo)
+	if err != nil {
+		return nil, err
+	}
+	return types.PubDataToBytes(pubData), nil
+}
+
+/*
+	ComputeBlockPubData: the pubdata of a block of txsCount txs, the block is padded with
+	empty txs. This is the pubdata commitBlock is called with.
+*/
+func ComputeBlockPubData(txInfos []txtypes.TxInfo, txsCount int) (pubData []byte, onChainOpsCount int64, err error) {
+	if len(txInfos) > txsCount {
+		errInfo := fmt.Sprintf("[ComputeBlockPubData] too many txs: %d, block size: %d", len(txInfos), txsCount)
+		log.Println(errInfo)
+		return nil, 0, errors.New(errInfo)
+	}
+	pubData = make([]byte, 0, txsCount*TxPubDataSize)
+	for i, txInfo := range txInfos {
+		txPubData, err := ComputeTxPubData(txInfo)
+		if err != nil {
+			errInfo := fmt.Sprintf("[ComputeBlockPubData] tx %d: %s", i, err.Error())
+			log.Println(errInfo)
+			return nil, 0, errors.New(errInfo)
+		}
+		pubData = append(pubData, txPubData...)
+		if circuit.IsOnChainOp(uint8(txInfo.GetTxType())) {
+			onChainOpsCount++
+		}
+	}
+	pubData = append(pubData, make([]byte, (txsCount-len(txInfos))*TxPubDataSize)...)
+	return pubData, onChainOpsCount, nil
+}
@@ -0,0 +1,369 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pubdata
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+type pubDataCircuit struct {
+	TxType  int
+	Tx      circuit.TxConstraints
+	PubData [types.PubDataSizePerTx]circuit.Variable
+}
+
+func (c pubDataCircuit) Define(api circuit.API) error {
+	var pubData [types.PubDataSizePerTx]circuit.Variable
+	switch c.TxType {
+	case types.TxTypeRegisterZns:
+		pubData = types.CollectPubDataFromRegisterZNS(api, c.Tx.RegisterZnsTxInfo)
+	case types.TxTypeDeposit:
+		pubData = types.CollectPubDataFromDeposit(api, c.Tx.DepositTxInfo)
+	case types.TxTypeDepositNft:
+		pubData = types.CollectPubDataFromDepositNft(api, c.Tx.DepositNftTxInfo)
+	case types.TxTypeTransfer:
+		pubData = types.CollectPubDataFromTransfer(api, c.Tx.TransferTxInfo)
+	case types.TxTypeWithdraw:
+		pubData = types.CollectPubDataFromWithdraw(api, c.Tx.WithdrawTxInfo)
+	case types.TxTypeCreateCollection:
+		pubData = types.CollectPubDataFromCreateCollection(api, c.Tx.CreateCollectionTxInfo)
+	case types.TxTypeMintNft:
+		pubData = types.CollectPubDataFromMintNft(api, c.Tx.MintNftTxInfo)
+	case types.TxTypeTransferNft:
+		pubData = types.CollectPubDataFromTransferNft(api, c.Tx.TransferNftTxInfo)
+	case types.TxTypeAtomicMatch:
+		pubData = types.CollectPubDataFromAtomicMatch(api, c.Tx.AtomicMatchTxInfo)
+	case types.TxTypeCancelOffer:
+		pubData = types.CollectPubDataFromCancelOffer(api, c.Tx.CancelOfferTxInfo)
+	case types.TxTypeWithdrawNft:
+		pubData = types.CollectPubDataFromWithdrawNft(api, c.Tx.WithdrawNftTxInfo)
+	case types.TxTypeFullExit:
+		pubData = types.CollectPubDataFromFullExit(api, c.Tx.FullExitTxInfo)
+	case types.TxTypeFullExitNft:
+		pubData = types.CollectPubDataFromFullExitNft(api, c.Tx.FullExitNftTxInfo)
+	}
+	for i := 0; i < types.PubDataSizePerTx; i++ {
+		api.AssertIsEqual(pubData[i], c.PubData[i])
+	}
+	return nil
+}
+
+/*
+	setTxWitness: the type specific witness of txInfo, the rest of the tx is left empty
+*/
+func setTxWitness(t *testing.T, txInfo txtypes.TxInfo) circuit.TxConstraints {
+	witness := circuit.GetZeroTxConstraint()
+	var err error
+	switch info := txInfo.(type) {
+	case *txtypes.RegisterZnsTxInfo:
+		var tx *types.RegisterZnsTx
+		tx, err = ToRegisterZnsTx(info)
+		witness.RegisterZnsTxInfo = types.SetRegisterZnsTxWitness(tx)
+	case *txtypes.DepositTxInfo:
+		var tx *types.DepositTx
+		tx, err = ToDepositTx(info)
+		witness.DepositTxInfo = types.SetDepositTxWitness(tx)
+	case *txtypes.DepositNftTxInfo:
+		var tx *types.DepositNftTx
+		tx, err = ToDepositNftTx(info)
+		witness.DepositNftTxInfo = types.SetDepositNftTxWitness(tx)
+	case *txtypes.TransferTxInfo:
+		var tx *types.TransferTx
+		tx, err = ToTransferTx(info)
+		witness.TransferTxInfo = types.SetTransferTxWitness(tx)
+	case *txtypes.WithdrawTxInfo:
+		var tx *types.WithdrawTx
+		tx, err = ToWithdrawTx(info)
+		witness.WithdrawTxInfo = types.SetWithdrawTxWitness(tx)
+	case *txtypes.CreateCollectionTxInfo:
+		var tx *types.CreateCollectionTx
+		tx, err = ToCreateCollectionTx(info)
+		witness.CreateCollectionTxInfo = types.SetCreateCollectionTxWitness(tx)
+	case *txtypes.MintNftTxInfo:
+		var tx *types.MintNftTx
+		tx, err = ToMintNftTx(info)
+		witness.MintNftTxInfo = types.SetMintNftTxWitness(tx)
+	case *txtypes.TransferNftTxInfo:
+		var tx *types.TransferNftTx
+		tx, err = ToTransferNftTx(info)
+		witness.TransferNftTxInfo = types.SetTransferNftTxWitness(tx)
+	case *txtypes.AtomicMatchTxInfo:
+		var tx *types.AtomicMatchTx
+		tx, err = ToAtomicMatchTx(info)
+		witness.AtomicMatchTxInfo = types.SetAtomicMatchTxWitness(tx)
+	case *txtypes.CancelOfferTxInfo:
+		var tx *types.CancelOfferTx
+		tx, err = ToCancelOfferTx(info)
+		witness.CancelOfferTxInfo = types.SetCancelOfferTxWitness(tx)
+	case *txtypes.WithdrawNftTxInfo:
+		var tx *types.WithdrawNftTx
+		tx, err = ToWithdrawNftTx(info)
+		witness.WithdrawNftTxInfo = types.SetWithdrawNftTxWitness(tx)
+	case *txtypes.FullExitTxInfo:
+		var tx *types.FullExitTx
+		tx, err = ToFullExitTx(info)
+		witness.FullExitTxInfo = types.SetFullExitTxWitness(tx)
+	case *txtypes.FullExitNftTxInfo:
+		var tx *types.FullExitNftTx
+		tx, err = ToFullExitNftTx(info)
+		witness.FullExitNftTxInfo = types.SetFullExitNftTxWitness(tx)
+	}
+	assert.NoError(t, err)
+	return witness
+}
+
+func newOffer(t *testing.T, sk *txtypes.PrivateKey, offerType int64, offerId int64, accountIndex int64) *txtypes.OfferTxInfo {
+	segment, err := json.Marshal(&txtypes.OfferSegmentFormat{
+		Type:         offerType,
+		OfferId:      offerId,
+		AccountIndex: accountIndex,
+		NftIndex:     1099511627774,
+		AssetId:      3,
+		AssetAmount:  "34359738367000",
+		ListedAt:     1654656761000,
+		ExpiredAt:    1654656781000,
+		TreasuryRate: 200,
+	})
+	assert.NoError(t, err)
+	offer, err := txtypes.ConstructOfferTxInfo(sk, string(segment))
+	assert.NoError(t, err)
+	return offer
+}
+
+/*
+	testTxInfos: one tx per type, with the fields close to their bit sizes
+*/
+func testTxInfos(t *testing.T) []txtypes.TxInfo {
+	sk, err := curve.GenerateEddsaPrivateKey("pubdata")
+	assert.NoError(t, err)
+	// larger than the field modulus, it is reduced as the circuit does
+	largeHash := bytes.Repeat([]byte{0xff}, 32)
+	nameHash := bytes.Repeat([]byte{0x12}, 32)
+	address := "0x5b38da6a701c568545dcfcb03fcb875f56beddc4"
+	tokenId, _ := new(big.Int).SetString("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 16)
+	// 2047 * 10^31 and 2047 * 10^15
+	amount, _ := new(big.Int).SetString("20470000000000000000000000000000000", 10)
+	fee := big.NewInt(2047000000000000000)
+	return []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          txtypes.TxTypeRegisterZns,
+			AccountIndex:    4294967294,
+			AccountName:     "abcdefghijklmnopqrst.legend",
+			AccountNameHash: largeHash,
+			PubKey:          hex.EncodeToString(sk.PublicKey.Bytes()),
+		},
+		&txtypes.DepositTxInfo{
+			TxType:          txtypes.TxTypeDeposit,
+			AccountIndex:    4294967294,
+			AccountNameHash: nameHash,
+			AssetId:         65534,
+			AssetAmount:     new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1)),
+		},
+		&txtypes.DepositNftTxInfo{
+			TxType:              txtypes.TxTypeDepositNft,
+			AccountIndex:        4294967294,
+			NftIndex:            1099511627774,
+			NftL1Address:        address,
+			NftL1TokenId:        tokenId,
+			NftContentHash:      largeHash,
+			AccountNameHash:     nameHash,
+			CreatorAccountIndex: 7,
+			CreatorTreasuryRate: 65535,
+			CollectionId:        65535,
+		},
+		&txtypes.TransferTxInfo{
+			FromAccountIndex:  4294967294,
+			ToAccountIndex:    12,
+			ToAccountNameHash: hex.EncodeToString(nameHash),
+			AssetId:           65534,
+			AssetAmount:       amount,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     3,
+			GasFeeAssetAmount: fee,
+			CallDataHash:      nameHash,
+		},
+		&txtypes.WithdrawTxInfo{
+			FromAccountIndex:  4294967294,
+			AssetId:           65534,
+			AssetAmount:       new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1)),
+			GasAccountIndex:   1,
+			GasFeeAssetId:     3,
+			GasFeeAssetAmount: fee,
+			ToAddress:         address,
+		},
+		&txtypes.CreateCollectionTxInfo{
+			AccountIndex:      4294967294,
+			CollectionId:      65535,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     3,
+			GasFeeAssetAmount: fee,
+		},
+		&txtypes.MintNftTxInfo{
+			CreatorAccountIndex: 4294967294,
+			ToAccountIndex:      12,
+			ToAccountNameHash:   hex.EncodeToString(nameHash),
+			NftIndex:            1099511627774,
+			NftContentHash:      hex.EncodeToString(largeHash),
+			NftCollectionId:     65535,
+			CreatorTreasuryRate: 65535,
+			GasAccountIndex:     1,
+			GasFeeAssetId:       3,
+			GasFeeAssetAmount:   fee,
+		},
+		&txtypes.TransferNftTxInfo{
+			FromAccountIndex:  4294967294,
+			ToAccountIndex:    12,
+			ToAccountNameHash: hex.EncodeToString(nameHash),
+			NftIndex:          1099511627774,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     3,
+			GasFeeAssetAmount: fee,
+			CallDataHash:      largeHash,
+		},
+		&txtypes.AtomicMatchTxInfo{
+			AccountIndex:      4294967294,
+			BuyOffer:          newOffer(t, sk, txtypes.BuyOfferType, 16777215, 12),
+			SellOffer:         newOffer(t, sk, txtypes.SellOfferType, 16777214, 13),
+			GasAccountIndex:   1,
+			GasFeeAssetId:     3,
+			GasFeeAssetAmount: fee,
+			CreatorAmount:     big.NewInt(34359738367),
+			TreasuryAmount:    big.NewInt(343597383670),
+		},
+		&txtypes.CancelOfferTxInfo{
+			AccountIndex:      4294967294,
+			OfferId:           16777215,
+			GasAccountIndex:   1,
+			GasFeeAssetId:     3,
+			GasFeeAssetAmount: fee,
+		},
+		&txtypes.WithdrawNftTxInfo{
+			AccountIndex:           4294967294,
+			CreatorAccountIndex:    7,
+			CreatorAccountNameHash: largeHash,
+			CreatorTreasuryRate:    65535,
+			NftIndex:               1099511627774,
+			NftContentHash:         nameHash,
+			NftL1Address:           address,
+			NftL1TokenId:           tokenId,
+			CollectionId:           65535,
+			ToAddress:              "0xffffffffffffffffffffffffffffffffffffffff",
+			GasAccountIndex:        1,
+			GasFeeAssetId:          3,
+			GasFeeAssetAmount:      fee,
+		},
+		&txtypes.FullExitTxInfo{
+			TxType:          txtypes.TxTypeFullExit,
+			AccountIndex:    4294967294,
+			AccountNameHash: largeHash,
+			AssetId:         65534,
+			AssetAmount:     big.NewInt(1),
+		},
+		&txtypes.FullExitNftTxInfo{
+			TxType:                 txtypes.TxTypeFullExitNft,
+			AccountIndex:           4294967294,
+			AccountNameHash:        nameHash,
+			CreatorAccountIndex:    7,
+			CreatorAccountNameHash: largeHash,
+			CreatorTreasuryRate:    65535,
+			NftIndex:               1099511627774,
+			CollectionId:           65535,
+			NftContentHash:         largeHash,
+			NftL1Address:           address,
+			NftL1TokenId:           tokenId,
+		},
+	}
+}
+
+func TestTxPubDataMatchesCircuit(t *testing.T) {
+	for _, txInfo := range testTxInfos(t) {
+		pubData, err := ComputeTxPubDataChunks(txInfo)
+		assert.NoError(t, err)
+		witness := pubDataCircuit{
+			TxType: txInfo.GetTxType(),
+			Tx:     setTxWitness(t, txInfo),
+		}
+		for i := 0; i < types.PubDataSizePerTx; i++ {
+			witness.PubData[i] = pubData[i]
+		}
+		c := pubDataCircuit{
+			TxType: txInfo.GetTxType(),
+			Tx:     circuit.GetZeroTxConstraint(),
+		}
+		assert.NoError(t, test.IsSolved(&c, &witness, ecc.BN254.ScalarField()), "tx type %d", txInfo.GetTxType())
+
+		// a single bit off
+		witness.PubData[0] = new(big.Int).Xor(pubData[0], big.NewInt(1<<20))
+		assert.Error(t, test.IsSolved(&c, &witness, ecc.BN254.ScalarField()), "tx type %d", txInfo.GetTxType())
+	}
+}
+
+func TestBlockPubData(t *testing.T) {
+	txInfos := testTxInfos(t)
+	pubData, onChainOpsCount, err := ComputeBlockPubData(txInfos, len(txInfos)+2)
+	assert.NoError(t, err)
+	assert.Equal(t, (len(txInfos)+2)*TxPubDataSize, len(pubData))
+	assert.Equal(t, int64(7), onChainOpsCount)
+	for i, txInfo := range txInfos {
+		txPubData, err := ComputeTxPubData(txInfo)
+		assert.NoError(t, err)
+		assert.Equal(t, txPubData, pubData[i*TxPubDataSize:(i+1)*TxPubDataSize])
+		// the tx type is the first byte
+		assert.Equal(t, byte(txInfo.GetTxType()), txPubData[0])
+	}
+	assert.Equal(t, make([]byte, 2*TxPubDataSize), pubData[len(txInfos)*TxPubDataSize:])
+
+	_, _, err = ComputeBlockPubData(txInfos, len(txInfos)-1)
+	assert.Error(t, err)
+}
+
+func TestInvalidTxPubData(t *testing.T) {
+	// not exactly packable
+	_, err := ComputeTxPubData(&txtypes.TransferTxInfo{
+		AssetAmount:       big.NewInt(34359738369),
+		GasFeeAssetAmount: big.NewInt(1),
+	})
+	assert.Error(t, err)
+	// the creator and treasury amounts are set by the layer 2
+	_, err = ComputeTxPubData(&txtypes.AtomicMatchTxInfo{
+		BuyOffer:          &txtypes.OfferTxInfo{},
+		SellOffer:         &txtypes.OfferTxInfo{},
+		GasFeeAssetAmount: big.NewInt(1),
+	})
+	assert.Error(t, err)
+	// the account index does not fit in 32 bits
+	_, err = ComputeTxPubData(&txtypes.DepositTxInfo{
+		TxType:       txtypes.TxTypeDeposit,
+		AccountIndex: 1 << 32,
+		AssetAmount:  big.NewInt(1),
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+
+	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+/*
+	AssetState: leaf of an account asset tree, indexed by asset id
+*/
+type AssetState struct {
+	AssetId                  int64
+	Balance                  *big.Int
+	OfferCanceledOrFinalized *big.Int
+}
+
+func EmptyAssetState(assetId int64) *AssetState {
+	return &AssetState{
+		AssetId:                  assetId,
+		Balance:                  big.NewInt(0),
+		OfferCanceledOrFinalized: big.NewInt(0),
+	}
+}
+
+/*
+	Hash: MiMC(Balance, OfferCanceledOrFinalized), same as the asset node hash of the circuit
+*/
+func (a *AssetState) Hash() []byte {
+	return a.hash(mimc.NewMiMC())
+}
+
+func (a *AssetState) hash(hFunc hash.Hash) []byte {
+	hFunc.Reset()
+	writeInt(hFunc, a.Balance)
+	writeInt(hFunc, a.OfferCanceledOrFinalized)
+	return hFunc.Sum(nil)
+}
+
+func (a *AssetState) Copy() *AssetState {
+	return &AssetState{
+		AssetId:                  a.AssetId,
+		Balance:                  new(big.Int).Set(a.Balance),
+		OfferCanceledOrFinalized: new(big.Int).Set(a.OfferCanceledOrFinalized),
+	}
+}
+
+/*
+	AccountState: leaf of the account tree, indexed by account index
+*/
+type AccountState struct {
+	AccountIndex    int64
+	AccountNameHash []byte
+	AccountPk       *eddsa.PublicKey
+	Nonce           int64
+	CollectionNonce int64
+	// root of the account asset tree, maintained by State
+	AssetRoot []byte
+}
+
+func EmptyAccountState(accountIndex int64) *AccountState {
+	return &AccountState{
+		AccountIndex:    accountIndex,
+		AccountNameHash: []byte{},
+		AccountPk: &eddsa.PublicKey{
+			A: curve.Point{
+				X: fr.NewElement(0),
+				Y: fr.NewElement(0),
+			},
+		},
+		Nonce:           0,
+		CollectionNonce: 0,
+		AssetRoot:       NilAssetRoot,
+	}
+}
+
+/*
+	Hash: MiMC(AccountNameHash, Pk.X, Pk.Y, Nonce, CollectionNonce, AssetRoot),
+	same as the account node hash of the circuit
+*/
+func (a *AccountState) Hash() []byte {
+	return a.hash(mimc.NewMiMC())
+}
+
+func (a *AccountState) hash(hFunc hash.Hash) []byte {
+	hFunc.Reset()
+	writeBytes(hFunc, a.AccountNameHash)
+	writeElement(hFunc, &a.AccountPk.A.X)
+	writeElement(hFunc, &a.AccountPk.A.Y)
+	writeInt(hFunc, big.NewInt(a.Nonce))
+	writeInt(hFunc, big.NewInt(a.CollectionNonce))
+	writeBytes(hFunc, a.AssetRoot)
+	return hFunc.Sum(nil)
+}
+
+func (a *AccountState) Copy() *AccountState {
+	pk := *a.AccountPk
+	return &AccountState{
+		AccountIndex:    a.AccountIndex,
+		AccountNameHash: append([]byte{}, a.AccountNameHash...),
+		AccountPk:       &pk,
+		Nonce:           a.Nonce,
+		CollectionNonce: a.CollectionNonce,
+		AssetRoot:       append([]byte{}, a.AssetRoot...),
+	}
+}
+
+/*
+	writeInt: write v as a 32 bytes big endian field element
+*/
+func writeInt(hFunc hash.Hash, v *big.Int) {
+	hFunc.Write(new(big.Int).Mod(v, fr.Modulus()).FillBytes(make([]byte, fr.Bytes)))
+}
+
+func writeBytes(hFunc hash.Hash, b []byte) {
+	writeInt(hFunc, new(big.Int).SetBytes(b))
+}
+
+func writeElement(hFunc hash.Hash, e *fr.Element) {
+	b := e.Bytes()
+	hFunc.Write(b[:])
+}
+
+func checkField(name string, b []byte) error {
+	if len(b) > fr.Bytes || new(big.Int).SetBytes(b).Cmp(fr.Modulus()) >= 0 {
+		return errors.New(fmt.Sprintf("%s is not a field element", name))
+	}
+	return nil
+}
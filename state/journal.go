@@ -0,0 +1,90 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+/*
+	Journal: previous values of the leaves touched by a set of updates, so that they can be undone
+*/
+type Journal struct {
+	accounts []*AccountState
+	assets   []journalAsset
+	nfts     []*NftState
+}
+
+type journalAsset struct {
+	accountIndex int64
+	asset        *AssetState
+}
+
+/*
+	RecordAccount: keep a copy of the account before it is updated
+*/
+func (j *Journal) RecordAccount(account *AccountState) {
+	j.accounts = append(j.accounts, account.Copy())
+}
+
+func (j *Journal) RecordAsset(accountIndex int64, asset *AssetState) {
+	j.assets = append(j.assets, journalAsset{accountIndex, asset.Copy()})
+}
+
+func (j *Journal) RecordNft(nft *NftState) {
+	j.nfts = append(j.nfts, nft.Copy())
+}
+
+/*
+	Append: record the leaves of other after the leaves of j
+*/
+func (j *Journal) Append(other *Journal) {
+	j.accounts = append(j.accounts, other.accounts...)
+	j.assets = append(j.assets, other.assets...)
+	j.nfts = append(j.nfts, other.nfts...)
+}
+
+/*
+	Revert: restore the leaves newest first, so that every leaf ends up with
+	the first value recorded for it. A leaf that can not be restored does not stop
+	the others, the first error is returned and the state is then only partly reverted.
+*/
+func (j *Journal) Revert(s *State) (err error) {
+	keep := func(setErr error) {
+		if setErr != nil && err == nil {
+			err = setErr
+		}
+	}
+	for i := len(j.nfts) - 1; i >= 0; i-- {
+		keep(s.SetNft(j.nfts[i]))
+	}
+	for i := len(j.assets) - 1; i >= 0; i-- {
+		keep(s.SetAsset(j.assets[i].accountIndex, j.assets[i].asset))
+	}
+	for i := len(j.accounts) - 1; i >= 0; i-- {
+		keep(s.SetAccount(j.accounts[i]))
+	}
+	if err != nil {
+		errInfo := fmt.Sprintf("[Revert] unable to restore the state: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package state
+
+import (
+	"hash"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+)
+
+/*
+	NftState: leaf of the nft tree, indexed by nft index
+*/
+type NftState struct {
+	NftIndex            int64
+	CreatorAccountIndex int64
+	OwnerAccountIndex   int64
+	NftContentHash      []byte
+	NftL1Address        *big.Int
+	NftL1TokenId        *big.Int
+	CreatorTreasuryRate int64
+	CollectionId        int64
+}
+
+func EmptyNftState(nftIndex int64) *NftState {
+	return &NftState{
+		NftIndex:            nftIndex,
+		CreatorAccountIndex: 0,
+		OwnerAccountIndex:   0,
+		NftContentHash:      []byte{0},
+		NftL1Address:        big.NewInt(0),
+		NftL1TokenId:        big.NewInt(0),
+		CreatorTreasuryRate: 0,
+		CollectionId:        0,
+	}
+}
+
+/*
+	Hash: MiMC(CreatorAccountIndex, OwnerAccountIndex, NftContentHash, NftL1Address, NftL1TokenId,
+	CreatorTreasuryRate, CollectionId), same as the nft node hash of the circuit
+*/
+func (n *NftState) Hash() []byte {
+	return n.hash(mimc.NewMiMC())
+}
+
+func (n *NftState) hash(hFunc hash.Hash) []byte {
+	hFunc.Reset()
+	writeInt(hFunc, big.NewInt(n.CreatorAccountIndex))
+	writeInt(hFunc, big.NewInt(n.OwnerAccountIndex))
+	writeBytes(hFunc, n.NftContentHash)
+	writeInt(hFunc, n.NftL1Address)
+	writeInt(hFunc, n.NftL1TokenId)
+	writeInt(hFunc, big.NewInt(n.CreatorTreasuryRate))
+	writeInt(hFunc, big.NewInt(n.CollectionId))
+	return hFunc.Sum(nil)
+}
+
+func (n *NftState) Copy() *NftState {
+	return &NftState{
+		NftIndex:            n.NftIndex,
+		CreatorAccountIndex: n.CreatorAccountIndex,
+		OwnerAccountIndex:   n.OwnerAccountIndex,
+		NftContentHash:      append([]byte{}, n.NftContentHash...),
+		NftL1Address:        new(big.Int).Set(n.NftL1Address),
+		NftL1TokenId:        new(big.Int).Set(n.NftL1TokenId),
+		CreatorTreasuryRate: n.CreatorTreasuryRate,
+		CollectionId:        n.CollectionId,
+	}
+}
@@ -0,0 +1,447 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"sync"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+var (
+	// leaf of an empty asset tree: MiMC(0, 0)
+	NilAssetNodeHash []byte
+	// root of an empty asset tree, equals types.EmptyAssetRoot
+	NilAssetRoot []byte
+	// leaf of an empty account tree
+	NilAccountNodeHash []byte
+	// leaf of an empty nft tree
+	NilNftNodeHash []byte
+
+	// empty nodes of every hash function
+	nilNodesOf = make(map[hasher.Type]*nilNodes)
+)
+
+/*
+	nilNodes: the empty leaves of a hash function, and its shared empty asset tree
+	used to build proofs of accounts without any asset
+*/
+type nilNodes struct {
+	assetNodeHash   []byte
+	assetRoot       []byte
+	accountNodeHash []byte
+	nftNodeHash     []byte
+	assetTree       *merkleTree.Tree
+}
+
+func newNilNodes(h hasher.Type) (*nilNodes, error) {
+	hFunc, err := h.New()
+	if err != nil {
+		return nil, err
+	}
+	n := &nilNodes{assetNodeHash: EmptyAssetState(0).hash(hFunc)}
+	n.assetTree, err = newTree(h, circuit.AssetMerkleLevels, n.assetNodeHash)
+	if err != nil {
+		return nil, err
+	}
+	n.assetRoot = n.assetTree.GetRoot()
+	account := EmptyAccountState(0)
+	account.AssetRoot = n.assetRoot
+	n.accountNodeHash = account.hash(hFunc)
+	n.nftNodeHash = EmptyNftState(0).hash(hFunc)
+	return n, nil
+}
+
+func init() {
+	for _, h := range []hasher.Type{hasher.MiMC, hasher.Poseidon} {
+		n, err := newNilNodes(h)
+		if err != nil {
+			panic(err)
+		}
+		nilNodesOf[h] = n
+	}
+	n := nilNodesOf[hasher.MiMC]
+	NilAssetNodeHash = n.assetNodeHash
+	NilAssetRoot = n.assetRoot
+	NilAccountNodeHash = n.accountNodeHash
+	NilNftNodeHash = n.nftNodeHash
+}
+
+/*
+	State: native mirror of the three trees committed by the circuit,
+	the account tree, one asset tree per account and the nft tree
+*/
+type State struct {
+	mu          sync.RWMutex
+	hasher      hasher.Type
+	nilNodes    *nilNodes
+	accountTree *merkleTree.Tree
+	nftTree     *merkleTree.Tree
+	assetTrees  map[int64]*merkleTree.Tree
+	accounts    map[int64]*AccountState
+	assets      map[int64]map[int64]*AssetState
+	nfts        map[int64]*NftState
+}
+
+func NewState() (*State, error) {
+	return NewStateWithHasher(hasher.Default)
+}
+
+/*
+	NewStateWithHasher: the trees, the leaves and the state root are hashed with h,
+	like the circuit whose Hasher is h. The leaf Hash methods stay on MiMC.
+*/
+func NewStateWithHasher(h hasher.Type) (*State, error) {
+	n, ok := nilNodesOf[h]
+	if !ok {
+		errInfo := fmt.Sprintf("[NewState] unknown hash function: %s", h.String())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	accountTree, err := newTree(h, circuit.AccountMerkleLevels, n.accountNodeHash)
+	if err != nil {
+		log.Println("[NewState] unable to create account tree:", err)
+		return nil, err
+	}
+	nftTree, err := newTree(h, circuit.NftMerkleLevels, n.nftNodeHash)
+	if err != nil {
+		log.Println("[NewState] unable to create nft tree:", err)
+		return nil, err
+	}
+	return &State{
+		hasher:      h,
+		nilNodes:    n,
+		accountTree: accountTree,
+		nftTree:     nftTree,
+		assetTrees:  make(map[int64]*merkleTree.Tree),
+		accounts:    make(map[int64]*AccountState),
+		assets:      make(map[int64]map[int64]*AssetState),
+		nfts:        make(map[int64]*NftState),
+	}, nil
+}
+
+func newTree(h hasher.Type, maxHeight int, nilHash []byte) (*merkleTree.Tree, error) {
+	hashFactory, err := h.Factory()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := merkleTree.NewSparseTree(maxHeight, nilHash, hashFactory())
+	if err != nil {
+		return nil, err
+	}
+	tree.HashFactory = hashFactory
+	return tree, nil
+}
+
+// Hasher: the hash function of the trees
+func (s *State) Hasher() hasher.Type {
+	return s.hasher
+}
+
+func (s *State) newHash() hash.Hash {
+	hFunc, _ := s.hasher.New()
+	return hFunc
+}
+
+func checkIndex(name string, index int64, levels int) error {
+	if index < 0 || index >= int64(1)<<levels {
+		return errors.New(fmt.Sprintf("invalid %s: %d", name, index))
+	}
+	return nil
+}
+
+/*
+	GetAccount: get a copy of the account, an empty account is returned if it is not set
+*/
+func (s *State) GetAccount(accountIndex int64) *AccountState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getAccount(accountIndex).Copy()
+}
+
+func (s *State) getAccount(accountIndex int64) *AccountState {
+	if account, ok := s.accounts[accountIndex]; ok {
+		return account
+	}
+	account := EmptyAccountState(accountIndex)
+	account.AssetRoot = s.nilNodes.assetRoot
+	return account
+}
+
+/*
+	IsEmptyAccount: the account leaf is the empty leaf of the hash function of the state,
+	the leaf Hash methods stay on MiMC so they can not tell it on another hash function
+*/
+func (s *State) IsEmptyAccount(accountIndex int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return bytes.Equal(s.getAccount(accountIndex).hash(s.newHash()), s.nilNodes.accountNodeHash)
+}
+
+/*
+	SetAccount: set the account leaf, the asset root is always taken from the asset tree
+	of the account, so AssetRoot of the given account is ignored
+*/
+func (s *State) SetAccount(account *AccountState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkIndex("account index", account.AccountIndex, circuit.AccountMerkleLevels); err != nil {
+		errInfo := fmt.Sprintf("[SetAccount] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	if err := checkField("account name hash", account.AccountNameHash); err != nil {
+		errInfo := fmt.Sprintf("[SetAccount] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	account = account.Copy()
+	account.AssetRoot = s.assetRoot(account.AccountIndex)
+	return s.setAccount(account)
+}
+
+func (s *State) setAccount(account *AccountState) error {
+	err := s.accountTree.Update(account.AccountIndex, account.hash(s.newHash()))
+	if err != nil {
+		errInfo := fmt.Sprintf("[setAccount] unable to update account tree: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	s.accounts[account.AccountIndex] = account
+	return nil
+}
+
+/*
+	GetAsset: get a copy of the account asset, an empty asset is returned if it is not set
+*/
+func (s *State) GetAsset(accountIndex, assetId int64) *AssetState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getAsset(accountIndex, assetId).Copy()
+}
+
+func (s *State) getAsset(accountIndex, assetId int64) *AssetState {
+	if asset, ok := s.assets[accountIndex][assetId]; ok {
+		return asset
+	}
+	return EmptyAssetState(assetId)
+}
+
+/*
+	SetAsset: set the asset leaf of the account asset tree and refresh the account leaf
+*/
+func (s *State) SetAsset(accountIndex int64, asset *AssetState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkIndex("account index", accountIndex, circuit.AccountMerkleLevels); err != nil {
+		errInfo := fmt.Sprintf("[SetAsset] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	if err := checkIndex("asset id", asset.AssetId, circuit.AssetMerkleLevels); err != nil {
+		errInfo := fmt.Sprintf("[SetAsset] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	if asset.Balance.Sign() < 0 || asset.OfferCanceledOrFinalized.Sign() < 0 {
+		errInfo := fmt.Sprintf("[SetAsset] negative asset, account index: %d, asset id: %d",
+			accountIndex, asset.AssetId)
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	tree, ok := s.assetTrees[accountIndex]
+	if !ok {
+		var err error
+		tree, err = newTree(s.hasher, circuit.AssetMerkleLevels, s.nilNodes.assetNodeHash)
+		if err != nil {
+			log.Println("[SetAsset] unable to create asset tree:", err)
+			return err
+		}
+		s.assetTrees[accountIndex] = tree
+		s.assets[accountIndex] = make(map[int64]*AssetState)
+	}
+	asset = asset.Copy()
+	err := tree.Update(asset.AssetId, asset.hash(s.newHash()))
+	if err != nil {
+		errInfo := fmt.Sprintf("[SetAsset] unable to update asset tree: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	s.assets[accountIndex][asset.AssetId] = asset
+	account := s.getAccount(accountIndex).Copy()
+	account.AssetRoot = tree.GetRoot()
+	return s.setAccount(account)
+}
+
+/*
+	GetNft: get a copy of the nft, an empty nft is returned if it is not set
+*/
+func (s *State) GetNft(nftIndex int64) *NftState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if nft, ok := s.nfts[nftIndex]; ok {
+		return nft.Copy()
+	}
+	return EmptyNftState(nftIndex)
+}
+
+func (s *State) SetNft(nft *NftState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkIndex("nft index", nft.NftIndex, circuit.NftMerkleLevels); err != nil {
+		errInfo := fmt.Sprintf("[SetNft] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	if err := checkField("nft content hash", nft.NftContentHash); err != nil {
+		errInfo := fmt.Sprintf("[SetNft] %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	nft = nft.Copy()
+	err := s.nftTree.Update(nft.NftIndex, nft.hash(s.newHash()))
+	if err != nil {
+		errInfo := fmt.Sprintf("[SetNft] unable to update nft tree: %s", err.Error())
+		log.Println(errInfo)
+		return errors.New(errInfo)
+	}
+	s.nfts[nft.NftIndex] = nft
+	return nil
+}
+
+func (s *State) AccountRoot() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accountTree.GetRoot()
+}
+
+func (s *State) NftRoot() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nftTree.GetRoot()
+}
+
+func (s *State) AssetRoot(accountIndex int64) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.assetRoot(accountIndex)
+}
+
+func (s *State) assetRoot(accountIndex int64) []byte {
+	if tree, ok := s.assetTrees[accountIndex]; ok {
+		return tree.GetRoot()
+	}
+	return s.nilNodes.assetRoot
+}
+
+/*
+	StateRoot: hash(accountRoot, nftRoot) with the hash function of the trees, same as the state root of the circuit
+*/
+func (s *State) StateRoot() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return computeStateRoot(s.newHash(), s.accountTree.GetRoot(), s.nftTree.GetRoot())
+}
+
+func ComputeStateRoot(accountRoot, nftRoot []byte) []byte {
+	return computeStateRoot(mimc.NewMiMC(), accountRoot, nftRoot)
+}
+
+func computeStateRoot(hFunc hash.Hash, accountRoot, nftRoot []byte) []byte {
+	hFunc.Write(accountRoot)
+	hFunc.Write(nftRoot)
+	return hFunc.Sum(nil)
+}
+
+func (s *State) AccountProof(accountIndex int64) (*merkleTree.Proof, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accountTree.GetProof(accountIndex)
+}
+
+/*
+	AssetProof: proof of the asset in the asset tree of the account, accounts without
+	any asset get the proof of the empty asset tree
+*/
+func (s *State) AssetProof(accountIndex, assetId int64) (*merkleTree.Proof, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if tree, ok := s.assetTrees[accountIndex]; ok {
+		return tree.GetProof(assetId)
+	}
+	return s.nilNodes.assetTree.GetProof(assetId)
+}
+
+func (s *State) NftProof(nftIndex int64) (*merkleTree.Proof, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nftTree.GetProof(nftIndex)
+}
+
+/*
+	Copy: deep copy of the state, used to run transactions speculatively
+*/
+func (s *State) Copy() (*State, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, err := NewStateWithHasher(s.hasher)
+	if err != nil {
+		return nil, err
+	}
+	for accountIndex, assets := range s.assets {
+		tree, err := newTree(s.hasher, circuit.AssetMerkleLevels, s.nilNodes.assetNodeHash)
+		if err != nil {
+			return nil, err
+		}
+		updates := make(map[int64][]byte, len(assets))
+		c.assets[accountIndex] = make(map[int64]*AssetState, len(assets))
+		for assetId, asset := range assets {
+			updates[assetId] = asset.hash(s.newHash())
+			c.assets[accountIndex][assetId] = asset.Copy()
+		}
+		if err = tree.BatchUpdate(updates); err != nil {
+			return nil, err
+		}
+		c.assetTrees[accountIndex] = tree
+	}
+	accountUpdates := make(map[int64][]byte, len(s.accounts))
+	for accountIndex, account := range s.accounts {
+		accountUpdates[accountIndex] = account.hash(s.newHash())
+		c.accounts[accountIndex] = account.Copy()
+	}
+	if err = c.accountTree.BatchUpdate(accountUpdates); err != nil {
+		return nil, err
+	}
+	nftUpdates := make(map[int64][]byte, len(s.nfts))
+	for nftIndex, nft := range s.nfts {
+		nftUpdates[nftIndex] = nft.hash(s.newHash())
+		c.nfts[nftIndex] = nft.Copy()
+	}
+	if err = c.nftTree.BatchUpdate(nftUpdates); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/hasher"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+)
+
+func TestNilAssetRoot(t *testing.T) {
+	assert.Equal(t, types.EmptyAssetRoot.String(), new(big.Int).SetBytes(NilAssetRoot).String())
+}
+
+func TestEmptyState(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	assert.Equal(t, ComputeStateRoot(s.AccountRoot(), s.NftRoot()), s.StateRoot())
+	assert.Equal(t, NilAssetRoot, s.AssetRoot(3))
+	assert.Equal(t, NilAccountNodeHash, s.GetAccount(3).Hash())
+	assert.True(t, s.IsEmptyAccount(3))
+	assert.Equal(t, NilNftNodeHash, s.GetNft(3).Hash())
+}
+
+func TestStateHasher(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	assert.Equal(t, hasher.MiMC, s.Hasher())
+	p, err := NewStateWithHasher(hasher.Poseidon)
+	assert.NoError(t, err)
+	assert.NotEqual(t, s.StateRoot(), p.StateRoot())
+	assert.NotEqual(t, NilAssetRoot, p.AssetRoot(3))
+	assert.Equal(t, p.AssetRoot(3), p.GetAccount(3).AssetRoot)
+	assert.True(t, p.IsEmptyAccount(3))
+
+	asset := EmptyAssetState(1)
+	asset.Balance = big.NewInt(5)
+	assert.NoError(t, p.SetAsset(3, asset))
+	assert.False(t, p.IsEmptyAccount(3))
+	assetProof, err := p.AssetProof(3, 1)
+	assert.NoError(t, err)
+	hFunc, err := hasher.Poseidon.New()
+	assert.NoError(t, err)
+	assert.True(t, merkleTree.VerifyProofWithHash(p.AssetRoot(3), assetProof, hFunc))
+	assert.False(t, merkleTree.VerifyProof(p.AssetRoot(3), assetProof))
+	c, err := p.Copy()
+	assert.NoError(t, err)
+	assert.Equal(t, hasher.Poseidon, c.Hasher())
+	assert.Equal(t, p.StateRoot(), c.StateRoot())
+
+	_, err = NewStateWithHasher(hasher.Type(9))
+	assert.Error(t, err)
+}
+
+func TestSetAsset(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	account := s.GetAccount(2)
+	account.AccountNameHash = []byte{1, 2, 3}
+	account.Nonce = 1
+	assert.NoError(t, s.SetAccount(account))
+	oldRoot := s.StateRoot()
+
+	asset := s.GetAsset(2, 5)
+	asset.Balance = big.NewInt(100)
+	assert.NoError(t, s.SetAsset(2, asset))
+	assert.NotEqual(t, oldRoot, s.StateRoot())
+	assert.NotEqual(t, NilAssetRoot, s.AssetRoot(2))
+
+	// the account leaf commits to the new asset root
+	account = s.GetAccount(2)
+	assert.Equal(t, s.AssetRoot(2), account.AssetRoot)
+	assert.Equal(t, int64(1), account.Nonce)
+	assetProof, err := s.AssetProof(2, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, asset.Hash(), assetProof.Leaf)
+	assert.True(t, merkleTree.VerifyProof(account.AssetRoot, assetProof))
+	accountProof, err := s.AccountProof(2)
+	assert.NoError(t, err)
+	assert.Equal(t, account.Hash(), accountProof.Leaf)
+	assert.True(t, merkleTree.VerifyProof(s.AccountRoot(), accountProof))
+
+	// the asset root given to SetAccount is ignored
+	account.AssetRoot = NilAssetRoot
+	assert.NoError(t, s.SetAccount(account))
+	assert.Equal(t, s.AssetRoot(2), s.GetAccount(2).AssetRoot)
+
+	// accounts without assets prove against the empty asset tree
+	assetProof, err = s.AssetProof(7, 5)
+	assert.NoError(t, err)
+	assert.True(t, assetProof.IsEmpty)
+	assert.True(t, merkleTree.VerifyProof(NilAssetRoot, assetProof))
+}
+
+func TestSetNft(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	nft := s.GetNft(1 << 39)
+	nft.CreatorAccountIndex = 2
+	nft.OwnerAccountIndex = 3
+	nft.NftContentHash = []byte{4, 5}
+	nft.NftL1Address = big.NewInt(6)
+	assert.NoError(t, s.SetNft(nft))
+	assert.Equal(t, nft, s.GetNft(1<<39))
+	proof, err := s.NftProof(1 << 39)
+	assert.NoError(t, err)
+	assert.Equal(t, nft.Hash(), proof.Leaf)
+	assert.True(t, merkleTree.VerifyProof(s.NftRoot(), proof))
+	assert.Equal(t, ComputeStateRoot(s.AccountRoot(), s.NftRoot()), s.StateRoot())
+}
+
+func TestInvalidState(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	assert.Error(t, s.SetAccount(EmptyAccountState(1<<32)))
+	assert.Error(t, s.SetAsset(1, EmptyAssetState(1<<16)))
+	assert.Error(t, s.SetNft(EmptyNftState(-1)))
+	asset := EmptyAssetState(1)
+	asset.Balance = big.NewInt(-1)
+	assert.Error(t, s.SetAsset(1, asset))
+	account := EmptyAccountState(1)
+	account.AccountNameHash = make([]byte, 33)
+	account.AccountNameHash[0] = 1
+	assert.Error(t, s.SetAccount(account))
+}
+
+func TestCopyState(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	for i := int64(0); i < 4; i++ {
+		asset := EmptyAssetState(i)
+		asset.Balance = big.NewInt(i + 1)
+		assert.NoError(t, s.SetAsset(i, asset))
+		nft := EmptyNftState(i)
+		nft.OwnerAccountIndex = i
+		assert.NoError(t, s.SetNft(nft))
+	}
+	c, err := s.Copy()
+	assert.NoError(t, err)
+	assert.Equal(t, s.StateRoot(), c.StateRoot())
+
+	asset := c.GetAsset(1, 1)
+	asset.Balance = big.NewInt(10)
+	assert.NoError(t, c.SetAsset(1, asset))
+	assert.NotEqual(t, s.StateRoot(), c.StateRoot())
+	assert.Equal(t, int64(2), s.GetAsset(1, 1).Balance.Int64())
+}
+
+func TestJournal(t *testing.T) {
+	s, err := NewState()
+	assert.NoError(t, err)
+	root := s.StateRoot()
+
+	var j Journal
+	// the same leaves are updated twice, the first recorded values win
+	for i := int64(1); i <= 2; i++ {
+		account := s.GetAccount(1)
+		j.RecordAccount(account)
+		account.AccountNameHash = []byte{byte(i)}
+		assert.NoError(t, s.SetAccount(account))
+
+		asset := s.GetAsset(1, 0)
+		j.RecordAsset(1, asset)
+		asset.Balance.Add(asset.Balance, big.NewInt(i))
+		assert.NoError(t, s.SetAsset(1, asset))
+	}
+	var nftJournal Journal
+	nft := s.GetNft(3)
+	nftJournal.RecordNft(nft)
+	nft.OwnerAccountIndex = 1
+	assert.NoError(t, s.SetNft(nft))
+	j.Append(&nftJournal)
+	assert.NotEqual(t, root, s.StateRoot())
+
+	assert.NoError(t, j.Revert(s))
+	assert.Equal(t, root, s.StateRoot())
+	assert.Equal(t, int64(0), s.GetAsset(1, 0).Balance.Int64())
+
+	// a leaf that can not be set is reported, the others are still restored
+	var broken Journal
+	broken.RecordAsset(1<<circuit.AccountMerkleLevels, s.GetAsset(1, 0))
+	broken.Append(&nftJournal)
+	nft.OwnerAccountIndex = 2
+	assert.NoError(t, s.SetNft(nft))
+	assert.Error(t, broken.Revert(s))
+	assert.Equal(t, root, s.StateRoot())
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestComputeAccountNameHash(t *testing.T) {
+	q, _ := new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+	seen := make(map[string]bool)
+	for _, name := range []string{"alice.legend", "bob.legend", "alice.zkbnb"} {
+		nameHash, err := ComputeAccountNameHash(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nameHash) != 64 {
+			t.Fatalf("name hash of %s is not 32 bytes: %s", name, nameHash)
+		}
+		if new(big.Int).SetBytes(common.FromHex(nameHash)).Cmp(q) >= 0 {
+			t.Fatalf("name hash of %s is not reduced: %s", name, nameHash)
+		}
+		if seen[nameHash] {
+			t.Fatalf("name hash of %s is not unique", name)
+		}
+		seen[nameHash] = true
+	}
+	for _, name := range []string{"alice", "alice.bob.legend", ""} {
+		if _, err := ComputeAccountNameHash(name); err == nil {
+			t.Fatalf("invalid account name %q should be rejected", name)
+		}
+	}
+}
@@ -104,3 +104,31 @@ func CleanPackedFee(amount *big.Int) (nAmount *big.Int, err error) {
 	nAmount = ffmath.Multiply(oAmount, new(big.Int).Exp(big.NewInt(10), big.NewInt(exponent), nil))
 	return nAmount, nil
 }
+
+/*
+UnpackAmount: inverse of ToPackedAmount, a * 10^x from the 35 bits of a and the 5 bits of x
+*/
+func UnpackAmount(packedAmount int64) (amount *big.Int, err error) {
+	if packedAmount < 0 || packedAmount >= 1<<40 {
+		log.Println("[UnpackAmount] invalid packed amount")
+		return nil, errors.New("[UnpackAmount] invalid packed amount")
+	}
+	return unpack(packedAmount), nil
+}
+
+/*
+UnpackFee: inverse of ToPackedFee, a * 10^x from the 11 bits of a and the 5 bits of x
+*/
+func UnpackFee(packedFee int64) (amount *big.Int, err error) {
+	if packedFee < 0 || packedFee >= 1<<16 {
+		log.Println("[UnpackFee] invalid packed fee")
+		return nil, errors.New("[UnpackFee] invalid packed fee")
+	}
+	return unpack(packedFee), nil
+}
+
+func unpack(packed int64) *big.Int {
+	mantissa := big.NewInt(packed >> 5)
+	exponent := big.NewInt(packed & 31)
+	return ffmath.Multiply(mantissa, new(big.Int).Exp(big.NewInt(10), exponent, nil))
+}
@@ -14,3 +14,43 @@ func TestToPackedAmount(t *testing.T) {
 	}
 	fmt.Println(amount)
 }
+
+func TestUnpackAmount(t *testing.T) {
+	for _, s := range []string{"0", "1", "34359738367", "343597383670", "1000000000000000000", "34359738367" + "0000000000000000000000000000000"} {
+		a, _ := new(big.Int).SetString(s, 10)
+		packedAmount, err := ToPackedAmount(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		amount, err := UnpackAmount(packedAmount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amount.Cmp(a) != 0 {
+			t.Fatalf("unpacked %s, expected %s", amount.String(), s)
+		}
+	}
+	if _, err := UnpackAmount(1 << 40); err == nil {
+		t.Fatal("packed amount of 41 bits should be rejected")
+	}
+}
+
+func TestUnpackFee(t *testing.T) {
+	for _, s := range []string{"0", "1", "2047", "20470", "1000000000000000"} {
+		a, _ := new(big.Int).SetString(s, 10)
+		packedFee, err := ToPackedFee(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fee, err := UnpackFee(packedFee)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee.Cmp(a) != 0 {
+			t.Fatalf("unpacked %s, expected %s", fee.String(), s)
+		}
+	}
+	if _, err := UnpackFee(-1); err == nil {
+		t.Fatal("negative packed fee should be rejected")
+	}
+}
@@ -22,7 +22,7 @@ import (
 	"hash"
 	"math/big"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
 )
 
 func HashToInt(b bytes.Buffer, h hash.Hash) (*big.Int, error) {
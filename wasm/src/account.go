@@ -1,45 +1,17 @@
 package src
 
 import (
-	"errors"
-	"math/big"
-	"strings"
-
 	"syscall/js"
 
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/bnb-chain/zkbnb-crypto/util"
 )
 
 func KeccakHash(value []byte) []byte {
-	hashVal := crypto.Keccak256Hash(value)
-	return hashVal[:]
+	return util.KeccakHash(value)
 }
 
 func ComputeAccountNameHash(accountName string) (res string, err error) {
-	words := strings.Split(accountName, ".")
-	if len(words) != 2 {
-		return "", errors.New("[AccountNameHash] invalid account name")
-	}
-
-	q, _ := big.NewInt(0).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
-
-	rootNode := make([]byte, 32)
-	hashOfBaseNode := KeccakHash(append(rootNode, KeccakHash([]byte(words[1]))...))
-
-	baseNode := big.NewInt(0).Mod(big.NewInt(0).SetBytes(hashOfBaseNode), q)
-	baseNodeBytes := make([]byte, 32)
-	baseNode.FillBytes(baseNodeBytes)
-
-	nameHash := KeccakHash([]byte(words[0]))
-	subNameHash := KeccakHash(append(baseNodeBytes, nameHash...))
-
-	subNode := big.NewInt(0).Mod(big.NewInt(0).SetBytes(subNameHash), q)
-	subNodeBytes := make([]byte, 32)
-	subNode.FillBytes(subNodeBytes)
-
-	res = common.Bytes2Hex(subNodeBytes)
-	return res, nil
+	return util.ComputeAccountNameHash(accountName)
 }
 
 func AccountNameHash() js.Func {
@@ -21,11 +21,11 @@ import (
 	"bytes"
 	"encoding/hex"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
 	"syscall/js"
 
 	curve "github.com/bnb-chain/zkbnb-crypto/ecc/ztwistededwards/tebn254"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
 )
 
 func GetEddsaPublicKey() js.Func {
@@ -25,9 +25,10 @@ import (
 	"log"
 	"math/big"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
 	"github.com/pkg/errors"
+
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
 )
 
 type AtomicMatchSegmentFormat struct {
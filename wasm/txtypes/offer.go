@@ -26,7 +26,7 @@ import (
 	"log"
 	"math/big"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/bnb-chain/zkbnb-crypto/hasher/mimc"
 )
 
 const (
@@ -0,0 +1,254 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package witness
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	BlockBuilder: assembles circuit.Block for a BlockConstraints compiled with
+	the same TxsCount, GasAccountIndex and GasAssetIds
+*/
+type BlockBuilder struct {
+	builder         *Builder
+	txsCount        int
+	gasAccountIndex int64
+	gasAssetIds     []int64
+}
+
+func NewBlockBuilder(builder *Builder, txsCount int, gasAccountIndex int64, gasAssetIds []int64) (*BlockBuilder, error) {
+	if txsCount <= 0 {
+		errInfo := fmt.Sprintf("[NewBlockBuilder] invalid txs count: %d", txsCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if len(gasAssetIds) == 0 {
+		errInfo := "[NewBlockBuilder] gas asset ids should not be empty"
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	seen := make(map[int64]bool, len(gasAssetIds))
+	for _, assetId := range gasAssetIds {
+		if seen[assetId] {
+			errInfo := fmt.Sprintf("[NewBlockBuilder] duplicated gas asset id: %d", assetId)
+			log.Println(errInfo)
+			return nil, errors.New(errInfo)
+		}
+		seen[assetId] = true
+	}
+	return &BlockBuilder{
+		builder:         builder,
+		txsCount:        txsCount,
+		gasAccountIndex: gasAccountIndex,
+		gasAssetIds:     append([]int64{}, gasAssetIds...),
+	}, nil
+}
+
+func (bb *BlockBuilder) Builder() *Builder {
+	return bb.builder
+}
+
+/*
+	BuildBlock: apply txInfos in order, pad the block with empty txs and credit the
+	gas collected by the block to the gas account. The state is left unchanged if an error is returned.
+*/
+func (bb *BlockBuilder) BuildBlock(blockNumber int64, createdAt int64, txInfos []txtypes.TxInfo) (oBlock *circuit.Block, err error) {
+	return bb.buildBlock(blockNumber, createdAt, txInfos, true)
+}
+
+/*
+	BuildUncheckedBlock: BuildBlock without the rules of the executor, the block of an
+	invalid tx is built and BlockConstraints rejects it. Gas paid in an asset that is not
+	a gas asset is not credited, like the circuit does. Meant for tests of the circuit.
+*/
+func (bb *BlockBuilder) BuildUncheckedBlock(blockNumber int64, createdAt int64, txInfos []txtypes.TxInfo) (oBlock *circuit.Block, err error) {
+	return bb.buildBlock(blockNumber, createdAt, txInfos, false)
+}
+
+func (bb *BlockBuilder) buildBlock(blockNumber int64, createdAt int64, txInfos []txtypes.TxInfo, check bool) (oBlock *circuit.Block, err error) {
+	if len(txInfos) > bb.txsCount {
+		errInfo := fmt.Sprintf("[BuildBlock] too many txs: %d, block size: %d", len(txInfos), bb.txsCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	b := bb.builder
+	if len(b.pendingGas) != 0 {
+		errInfo := "[BuildBlock] builder has unsettled gas"
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	b.Checkpoint()
+	defer func() {
+		if err == nil {
+			b.Commit()
+			return
+		}
+		if rollbackErr := b.Rollback(); rollbackErr != nil {
+			err = fmt.Errorf("%w, %s", err, rollbackErr.Error())
+		}
+	}()
+
+	oBlock = &circuit.Block{
+		BlockNumber:  blockNumber,
+		CreatedAt:    createdAt,
+		OldStateRoot: b.state.StateRoot(),
+		Txs:          make([]*circuit.Tx, 0, bb.txsCount),
+	}
+	// the executor holds the rules of the circuit, it rejects a tx before the state is touched
+	e := executor.NewExecutor(b.state, createdAt, bb.gasAccountIndex, bb.gasAssetIds)
+	for i, txInfo := range txInfos {
+		var oTx *circuit.Tx
+		if check {
+			oTx, err = b.ConstructTx(e, txInfo)
+		} else {
+			oTx, err = b.constructTx(txInfo)
+		}
+		if err != nil {
+			// keep the *executor.TxError so that callers can tell which rule failed
+			err = fmt.Errorf("[BuildBlock] unable to construct tx %d: %w", i, err)
+			log.Println(err.Error())
+			return nil, err
+		}
+		oBlock.Txs = append(oBlock.Txs, oTx)
+	}
+	for len(oBlock.Txs) < bb.txsCount {
+		oBlock.Txs = append(oBlock.Txs, circuit.EmptyTx(b.state.StateRoot()))
+	}
+	oBlock.Gas, err = bb.settleGas(check)
+	if err != nil {
+		errInfo := fmt.Sprintf("[BuildBlock] unable to settle gas: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	oBlock.NewStateRoot = b.state.StateRoot()
+	oBlock.BlockCommitment, err = circuit.ComputeBlockCommitment(oBlock)
+	if err != nil {
+		errInfo := fmt.Sprintf("[BuildBlock] unable to compute commitment: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return oBlock, nil
+}
+
+/*
+	PadBlock: a copy of oBlock padded with empty txs up to txsCount, for a circuit bigger than the block.
+	Empty txs do not change the state, so only the commitment is recomputed.
+*/
+func PadBlock(oBlock *circuit.Block, txsCount int) (*circuit.Block, error) {
+	if len(oBlock.Txs) == 0 || len(oBlock.Txs) > txsCount {
+		errInfo := fmt.Sprintf("[PadBlock] unable to pad a block of %d txs to %d txs", len(oBlock.Txs), txsCount)
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	padded := *oBlock
+	padded.Txs = make([]*circuit.Tx, len(oBlock.Txs), txsCount)
+	copy(padded.Txs, oBlock.Txs)
+	stateRoot := oBlock.Txs[len(oBlock.Txs)-1].StateRootAfter
+	for len(padded.Txs) < txsCount {
+		padded.Txs = append(padded.Txs, circuit.EmptyTx(stateRoot))
+	}
+	var err error
+	padded.BlockCommitment, err = circuit.ComputeBlockCommitment(&padded)
+	if err != nil {
+		errInfo := fmt.Sprintf("[PadBlock] unable to compute commitment: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	return &padded, nil
+}
+
+/*
+	settleGas: credit the pending gas to the gas account, in the order of the gas asset ids.
+	The account proof is taken before the assets are updated and every asset proof against
+	the asset root updated by the previous ones, as VerifyGas checks them. Unless check is
+	set, gas in other assets is dropped.
+*/
+func (bb *BlockBuilder) settleGas(check bool) (oGas *circuit.Gas, err error) {
+	b := bb.builder
+	s := b.state
+	pendingGas := b.PendingGas()
+	account := s.GetAccount(bb.gasAccountIndex)
+	if len(pendingGas) != 0 && new(big.Int).SetBytes(account.AccountNameHash).Sign() == 0 {
+		return nil, errors.New(fmt.Sprintf("gas account %d is not registered", bb.gasAccountIndex))
+	}
+	oGas = &circuit.Gas{
+		GasAssetCount:                   len(bb.gasAssetIds),
+		MerkleProofsAccountAssetsBefore: make([][circuit.AssetMerkleLevels][]byte, len(bb.gasAssetIds)),
+	}
+	var proof *merkleTree.Proof
+	proof, err = s.AccountProof(bb.gasAccountIndex)
+	if err != nil {
+		return nil, err
+	}
+	copyProof(oGas.MerkleProofsAccountBefore[:], proof)
+	accountBefore := toCircuitAccount(account)
+	oGas.AccountInfoBefore = &types.GasAccount{
+		AccountIndex:    accountBefore.AccountIndex,
+		AccountNameHash: accountBefore.AccountNameHash,
+		AccountPk:       accountBefore.AccountPk,
+		Nonce:           accountBefore.Nonce,
+		CollectionNonce: accountBefore.CollectionNonce,
+		AssetRoot:       accountBefore.AssetRoot,
+		AssetsInfo:      make([]*types.AccountAsset, len(bb.gasAssetIds)),
+	}
+	undo := new(state.Journal)
+	for i, assetId := range bb.gasAssetIds {
+		proof, err = s.AssetProof(bb.gasAccountIndex, assetId)
+		if err != nil {
+			break
+		}
+		copyProof(oGas.MerkleProofsAccountAssetsBefore[i][:], proof)
+		asset := s.GetAsset(bb.gasAccountIndex, assetId)
+		oGas.AccountInfoBefore.AssetsInfo[i] = toCircuitAsset(asset)
+		delta, ok := pendingGas[assetId]
+		delete(pendingGas, assetId)
+		if !ok || delta.Sign() == 0 {
+			continue
+		}
+		undo.RecordAsset(bb.gasAccountIndex, asset)
+		asset.Balance.Add(asset.Balance, delta)
+		if err = s.SetAsset(bb.gasAccountIndex, asset); err != nil {
+			break
+		}
+	}
+	if err == nil && check && len(pendingGas) != 0 {
+		err = errors.New("gas collected in assets that are not gas assets")
+	}
+	if err != nil {
+		if revertErr := undo.Revert(s); revertErr != nil {
+			err = errors.New(fmt.Sprintf("%s, %s", err.Error(), revertErr.Error()))
+		}
+		return nil, err
+	}
+	if b.checkpoint != nil {
+		b.checkpoint.Append(undo)
+	}
+	b.ResetPendingGas()
+	return oGas, nil
+}
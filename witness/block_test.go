@@ -0,0 +1,463 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package witness
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+type gasCircuit struct {
+	Gas            circuit.GasConstraints
+	Deltas         []circuit.Variable
+	AccountRoot    circuit.Variable
+	NewAccountRoot circuit.Variable
+}
+
+func (c gasCircuit) Define(api circuit.API) error {
+	hFunc := types.NewMiMC(api)
+	newAccountRoot, err := circuit.VerifyGas(api, c.Gas, 1, c.Deltas, &hFunc, c.AccountRoot)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(newAccountRoot, c.NewAccountRoot)
+	return nil
+}
+
+func transferTx(t *testing.T, from, to, assetId, gasFeeAssetId int64, nonce int64) *txtypes.TransferTxInfo {
+	txInfo, err := txtypes.ConstructTransferTxInfo(privateKey(t, from), testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  from,
+		ToAccountIndex:    to,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(to)),
+		AssetId:           assetId,
+		AssetAmount:       "1000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     gasFeeAssetId,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         expiredAt,
+		Nonce:             nonce,
+	}))
+	assert.NoError(t, err)
+	return txInfo
+}
+
+func TestBuildBlock(t *testing.T) {
+	builder := newTestBuilder(t)
+	_, err := builder.ConstructTx(rules(builder), &txtypes.DepositTxInfo{
+		TxType:          txtypes.TxTypeDeposit,
+		AccountNameHash: testutil.NameHash(testutil.Bob),
+		AssetId:         1,
+		AssetAmount:     big.NewInt(1000),
+		AccountIndex:    testutil.Bob,
+	})
+	assert.NoError(t, err)
+	txInfos := []txtypes.TxInfo{
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+		transferTx(t, testutil.Bob, testutil.Carol, 0, 1, 0),
+		transferTx(t, testutil.Alice, testutil.Carol, 0, 0, 1),
+	}
+	// the root the gas is settled on
+	s, err := builder.State().Copy()
+	assert.NoError(t, err)
+	for _, txInfo := range txInfos {
+		b := NewBuilder(s)
+		_, err = b.ConstructTx(rules(b), txInfo)
+		assert.NoError(t, err)
+	}
+	accountRoot := s.AccountRoot()
+
+	blockBuilder, err := NewBlockBuilder(builder, 5, testutil.GasAccount, []int64{0, 1, 2})
+	assert.NoError(t, err)
+	oldRoot := builder.State().StateRoot()
+	oBlock, err := blockBuilder.BuildBlock(1, time.Now().UnixMilli(), txInfos)
+	assert.NoError(t, err)
+	assert.Equal(t, oldRoot, oBlock.OldStateRoot)
+	assert.Equal(t, builder.State().StateRoot(), oBlock.NewStateRoot)
+	assert.Equal(t, 5, len(oBlock.Txs))
+	assert.Equal(t, oBlock.OldStateRoot, oBlock.Txs[0].StateRootBefore)
+	for i := 1; i < len(oBlock.Txs); i++ {
+		assert.Equal(t, oBlock.Txs[i-1].StateRootAfter, oBlock.Txs[i].StateRootBefore)
+	}
+	assert.Equal(t, uint8(types.TxTypeEmptyTx), oBlock.Txs[3].TxType)
+	assert.Equal(t, uint8(types.TxTypeEmptyTx), oBlock.Txs[4].TxType)
+	assert.NotEqual(t, oBlock.Txs[4].StateRootAfter, oBlock.NewStateRoot)
+
+	assert.Equal(t, int64(20), balance(builder, testutil.GasAccount, 0))
+	assert.Equal(t, int64(10), balance(builder, testutil.GasAccount, 1))
+	assert.Empty(t, builder.PendingGas())
+
+	gasWitness, err := circuit.SetGasWitness(oBlock.Gas)
+	assert.NoError(t, err)
+	assert.NoError(t, test.IsSolved(
+		&gasCircuit{
+			Gas:    circuit.GetZeroGasConstraints([]int64{0, 1, 2}),
+			Deltas: make([]circuit.Variable, 3),
+		},
+		&gasCircuit{
+			Gas:            gasWitness,
+			Deltas:         []circuit.Variable{20, 10, 0},
+			AccountRoot:    accountRoot,
+			NewAccountRoot: builder.State().AccountRoot(),
+		},
+		ecc.BN254.ScalarField()))
+}
+
+func TestPadBlock(t *testing.T) {
+	builder := newTestBuilder(t)
+	s, err := builder.State().Copy()
+	assert.NoError(t, err)
+	txInfos := []txtypes.TxInfo{
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+		transferTx(t, testutil.Alice, testutil.Carol, 0, 0, 1),
+	}
+	createdAt := time.Now().UnixMilli()
+	blockBuilder, err := NewBlockBuilder(builder, 2, testutil.GasAccount, []int64{0, 1})
+	assert.NoError(t, err)
+	oBlock, err := blockBuilder.BuildBlock(1, createdAt, txInfos)
+	assert.NoError(t, err)
+	// the same txs built for a circuit of 4 txs
+	blockBuilder, err = NewBlockBuilder(NewBuilder(s), 4, testutil.GasAccount, []int64{0, 1})
+	assert.NoError(t, err)
+	expected, err := blockBuilder.BuildBlock(1, createdAt, txInfos)
+	assert.NoError(t, err)
+
+	padded, err := PadBlock(oBlock, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, padded)
+	assert.Equal(t, 2, len(oBlock.Txs))
+
+	_, err = PadBlock(oBlock, 1)
+	assert.Error(t, err)
+}
+
+func TestBuildBlockWithoutGas(t *testing.T) {
+	builder := newTestBuilder(t)
+	blockBuilder, err := NewBlockBuilder(builder, 2, testutil.GasAccount, []int64{0})
+	assert.NoError(t, err)
+	oBlock, err := blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		&txtypes.DepositTxInfo{
+			TxType:          txtypes.TxTypeDeposit,
+			AccountNameHash: testutil.NameHash(testutil.Bob),
+			AssetId:         0,
+			AssetAmount:     big.NewInt(1000),
+			AccountIndex:    testutil.Bob,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, oBlock.Txs[1].StateRootAfter, oBlock.NewStateRoot)
+	assert.Equal(t, builder.State().StateRoot(), oBlock.NewStateRoot)
+
+	// empty block
+	oBlock, err = blockBuilder.BuildBlock(2, time.Now().UnixMilli(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, oBlock.OldStateRoot, oBlock.NewStateRoot)
+	assert.Equal(t, 2, len(oBlock.Txs))
+}
+
+func TestBuildInvalidBlock(t *testing.T) {
+	builder := newTestBuilder(t)
+	root := builder.State().StateRoot()
+	blockBuilder, err := NewBlockBuilder(builder, 3, testutil.GasAccount, []int64{0})
+	assert.NoError(t, err)
+
+	// the whole block is rolled back when a tx fails
+	_, err = blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          txtypes.TxTypeRegisterZns,
+			AccountIndex:    4,
+			AccountName:     "dave.legend",
+			AccountNameHash: testutil.NameHash(4),
+			PubKey:          hex.EncodeToString(privateKey(t, testutil.Alice).PublicKey.Bytes()),
+		},
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, root, builder.State().StateRoot())
+	assert.Empty(t, builder.PendingGas())
+	assert.Equal(t, int64(0), builder.State().GetAccount(testutil.Alice).Nonce)
+
+	// asset 1 is not a gas asset
+	_, err = blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 1, 0),
+	})
+	assert.True(t, errors.Is(err, executor.ErrInvalidGasAsset), err)
+
+	// expired
+	_, err = blockBuilder.BuildBlock(1, expiredAt+1, []txtypes.TxInfo{
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+	})
+	assert.True(t, errors.Is(err, executor.ErrTxExpired), err)
+
+	// too many txs
+	_, err = blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 1),
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 2),
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 3),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, root, builder.State().StateRoot())
+
+	_, err = NewBlockBuilder(builder, 3, testutil.GasAccount, []int64{0, 0})
+	assert.Error(t, err)
+	_, err = NewBlockBuilder(builder, 0, testutil.GasAccount, []int64{0})
+	assert.Error(t, err)
+}
+
+func TestBuildUncheckedBlock(t *testing.T) {
+	builder := newTestBuilder(t)
+	blockBuilder, err := NewBlockBuilder(builder, 2, testutil.GasAccount, []int64{0})
+	assert.NoError(t, err)
+
+	// the expired tx and the gas in asset 1 are built, the circuit rejects them
+	for _, txInfo := range []*txtypes.TransferTxInfo{
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 0, 0),
+		transferTx(t, testutil.Alice, testutil.Bob, 0, 1, 0),
+	} {
+		createdAt := time.Now().UnixMilli()
+		if txInfo.GasFeeAssetId == 0 {
+			createdAt = expiredAt + 1
+		}
+		_, err = blockBuilder.BuildBlock(1, createdAt, []txtypes.TxInfo{txInfo})
+		assert.Error(t, err)
+		oBlock, err := blockBuilder.BuildUncheckedBlock(1, createdAt, []txtypes.TxInfo{txInfo})
+		assert.NoError(t, err)
+		witness, err := circuit.SetBlockWitness(oBlock)
+		assert.NoError(t, err)
+		assert.Error(t, test.IsSolved(newBlockCircuit(2, []int64{0}), &witness, ecc.BN254.ScalarField()))
+		builder = newTestBuilder(t)
+		blockBuilder, err = NewBlockBuilder(builder, 2, testutil.GasAccount, []int64{0})
+		assert.NoError(t, err)
+	}
+}
+
+func newBlockCircuit(txsCount int, gasAssetIds []int64) *circuit.BlockConstraints {
+	blockConstraints := &circuit.BlockConstraints{
+		TxsCount:        txsCount,
+		Txs:             make([]circuit.TxConstraints, txsCount),
+		GasAssetIds:     gasAssetIds,
+		GasAccountIndex: testutil.GasAccount,
+		Gas:             circuit.GetZeroGasConstraints(gasAssetIds),
+	}
+	for i := 0; i < txsCount; i++ {
+		blockConstraints.Txs[i] = circuit.GetZeroTxConstraint()
+	}
+	return blockConstraints
+}
+
+func checkBlock(t *testing.T, oBlock *circuit.Block, gasAssetIds []int64) {
+	witness, err := circuit.SetBlockWitness(oBlock)
+	assert.NoError(t, err)
+	assert.NoError(t, test.IsSolved(newBlockCircuit(len(oBlock.Txs), gasAssetIds), &witness, ecc.BN254.ScalarField()))
+
+	pubData, onChainOpsCount, err := circuit.ComputeBlockPubData(oBlock)
+	assert.NoError(t, err)
+	commitment, err := circuit.ComputeCommitment(oBlock.BlockNumber, oBlock.CreatedAt, oBlock.OldStateRoot, oBlock.NewStateRoot, pubData, onChainOpsCount)
+	assert.NoError(t, err)
+	assert.Equal(t, oBlock.BlockCommitment, commitment)
+
+	witness.BlockCommitment = new(big.Int).Add(new(big.Int).SetBytes(oBlock.BlockCommitment), big.NewInt(1))
+	assert.Error(t, test.IsSolved(newBlockCircuit(len(oBlock.Txs), gasAssetIds), &witness, ecc.BN254.ScalarField()))
+}
+
+func TestBlockCommitment(t *testing.T) {
+	builder := newTestBuilder(t)
+	gasAssetIds := []int64{0, 1}
+	blockBuilder, err := NewBlockBuilder(builder, 6, testutil.GasAccount, gasAssetIds)
+	assert.NoError(t, err)
+
+	withdrawInfo, err := txtypes.ConstructWithdrawTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.WithdrawSegmentFormat{
+		FromAccountIndex:  testutil.Bob,
+		AssetId:           0,
+		AssetAmount:       "12345",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	collectionInfo, err := txtypes.ConstructCreateCollectionTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.CreateCollectionSegmentFormat{
+		AccountIndex:      testutil.Carol,
+		Name:              "collection",
+		Introduction:      "collection",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	mintInfo, err := txtypes.ConstructMintNftTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.MintNftSegmentFormat{
+		CreatorAccountIndex: testutil.Carol,
+		ToAccountIndex:      testutil.Bob,
+		ToAccountNameHash:   common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		NftContentHash:      common.Bytes2Hex(testutil.NameHash(100)),
+		NftCollectionId:     0,
+		CreatorTreasuryRate: 100,
+		GasAccountIndex:     testutil.GasAccount,
+		GasFeeAssetId:       0,
+		GasFeeAssetAmount:   "10",
+		ExpiredAt:           expiredAt,
+		Nonce:               1,
+	}))
+	assert.NoError(t, err)
+	mintInfo.NftIndex = 0
+	oBlock, err := blockBuilder.BuildBlock(1, time.Now().UnixMilli(), []txtypes.TxInfo{
+		&txtypes.DepositTxInfo{
+			TxType:          txtypes.TxTypeDeposit,
+			AccountNameHash: testutil.NameHash(testutil.Alice),
+			AssetId:         1,
+			AssetAmount:     big.NewInt(1000000),
+			AccountIndex:    testutil.Alice,
+		},
+		transferTx(t, testutil.Alice, testutil.Bob, 1, 1, 0),
+		withdrawInfo,
+		collectionInfo,
+		mintInfo,
+	})
+	assert.NoError(t, err)
+	checkBlock(t, oBlock, gasAssetIds)
+
+	transferNftInfo, err := txtypes.ConstructTransferNftTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.TransferNftSegmentFormat{
+		FromAccountIndex:  testutil.Bob,
+		ToAccountIndex:    testutil.Alice,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Alice)),
+		NftIndex:          0,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     1,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         expiredAt,
+		Nonce:             1,
+	}))
+	assert.NoError(t, err)
+	buyOffer, err := txtypes.ConstructOfferTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.OfferSegmentFormat{
+		Type:         txtypes.BuyOfferType,
+		OfferId:      3,
+		AccountIndex: testutil.Bob,
+		NftIndex:     0,
+		AssetId:      0,
+		AssetAmount:  "1000000",
+		ListedAt:     time.Now().UnixMilli(),
+		ExpiredAt:    expiredAt,
+		TreasuryRate: 200,
+	}))
+	assert.NoError(t, err)
+	sellOffer, err := txtypes.ConstructOfferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.OfferSegmentFormat{
+		Type:         txtypes.SellOfferType,
+		OfferId:      0,
+		AccountIndex: testutil.Alice,
+		NftIndex:     0,
+		AssetId:      0,
+		AssetAmount:  "1000000",
+		ListedAt:     time.Now().UnixMilli(),
+		ExpiredAt:    expiredAt,
+		TreasuryRate: 200,
+	}))
+	assert.NoError(t, err)
+	matchInfo, err := txtypes.ConstructAtomicMatchTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.AtomicMatchSegmentFormat{
+		AccountIndex:      testutil.Carol,
+		BuyOffer:          testutil.Segment(buyOffer),
+		SellOffer:         testutil.Segment(sellOffer),
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     1,
+		GasFeeAssetAmount: "0",
+		Nonce:             2,
+		ExpiredAt:         expiredAt,
+	}))
+	assert.NoError(t, err)
+	cancelInfo, err := txtypes.ConstructCancelOfferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.CancelOfferSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		OfferId:           1,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         expiredAt,
+		Nonce:             1,
+	}))
+	assert.NoError(t, err)
+	withdrawNftInfo, err := txtypes.ConstructWithdrawNftTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.WithdrawNftSegmentFormat{
+		AccountIndex:      testutil.Bob,
+		NftIndex:          0,
+		ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "10",
+		ExpiredAt:         expiredAt,
+		Nonce:             2,
+	}))
+	assert.NoError(t, err)
+	blockBuilder, err = NewBlockBuilder(builder, 9, testutil.GasAccount, gasAssetIds)
+	assert.NoError(t, err)
+	oBlock, err = blockBuilder.BuildBlock(2, time.Now().UnixMilli(), []txtypes.TxInfo{
+		&txtypes.RegisterZnsTxInfo{
+			TxType:          txtypes.TxTypeRegisterZns,
+			AccountIndex:    4,
+			AccountName:     "dave.legend",
+			AccountNameHash: testutil.NameHash(4),
+			PubKey:          hex.EncodeToString(privateKey(t, testutil.Alice).PublicKey.Bytes()),
+		},
+		transferNftInfo,
+		matchInfo,
+		cancelInfo,
+		withdrawNftInfo,
+		&txtypes.DepositNftTxInfo{
+			TxType:              txtypes.TxTypeDepositNft,
+			AccountNameHash:     testutil.NameHash(testutil.Bob),
+			CreatorAccountIndex: testutil.Carol,
+			CreatorTreasuryRate: 50,
+			NftL1Address:        "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+			NftL1TokenId:        big.NewInt(7),
+			NftContentHash:      testutil.NameHash(101),
+			CollectionId:        0,
+			NftIndex:            5,
+			AccountIndex:        testutil.Bob,
+		},
+		&txtypes.FullExitTxInfo{
+			TxType:          txtypes.TxTypeFullExit,
+			AccountNameHash: testutil.NameHash(testutil.Alice),
+			AssetId:         1,
+			AccountIndex:    testutil.Alice,
+		},
+		&txtypes.FullExitNftTxInfo{
+			TxType:                 txtypes.TxTypeFullExitNft,
+			NftIndex:               5,
+			AccountNameHash:        testutil.NameHash(testutil.Bob),
+			AccountIndex:           testutil.Bob,
+			CreatorAccountNameHash: testutil.NameHash(testutil.Carol),
+		},
+	})
+	assert.NoError(t, err)
+	checkBlock(t, oBlock, gasAssetIds)
+}
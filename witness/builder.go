@@ -0,0 +1,323 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package witness
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/merkleTree"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+/*
+	GasDelta: gas collected by a transaction, it is credited to the gas account
+	when the block is settled, not by the transaction itself
+*/
+type GasDelta struct {
+	AssetId      int64
+	BalanceDelta *big.Int
+}
+
+/*
+	Builder: turns txtypes transactions into fully populated circuit.Tx,
+	applying every transaction to the state it is built on
+*/
+type Builder struct {
+	state *state.State
+	// gas collected since the last settlement, asset id -> amount
+	pendingGas map[int64]*big.Int
+	// leaves touched since Checkpoint, nil if there is no open checkpoint
+	checkpoint    *state.Journal
+	checkpointGas map[int64]*big.Int
+}
+
+func NewBuilder(s *state.State) *Builder {
+	return &Builder{
+		state:      s,
+		pendingGas: make(map[int64]*big.Int),
+	}
+}
+
+func (b *Builder) State() *state.State {
+	return b.state
+}
+
+/*
+	PendingGas: gas collected by the transactions built since the last ResetPendingGas
+*/
+func (b *Builder) PendingGas() map[int64]*big.Int {
+	res := make(map[int64]*big.Int, len(b.pendingGas))
+	for assetId, amount := range b.pendingGas {
+		res[assetId] = new(big.Int).Set(amount)
+	}
+	return res
+}
+
+func (b *Builder) ResetPendingGas() {
+	b.pendingGas = make(map[int64]*big.Int)
+}
+
+/*
+	Checkpoint: start recording the transactions built from now on,
+	so that they can be undone together by Rollback
+*/
+func (b *Builder) Checkpoint() {
+	b.checkpoint = new(state.Journal)
+	b.checkpointGas = b.PendingGas()
+}
+
+/*
+	Rollback: undo every transaction built since the last Checkpoint,
+	including the gas they collected
+*/
+func (b *Builder) Rollback() error {
+	if b.checkpoint == nil {
+		return nil
+	}
+	err := b.checkpoint.Revert(b.state)
+	b.pendingGas = b.checkpointGas
+	b.Commit()
+	return err
+}
+
+/*
+	Commit: keep the transactions built since the last Checkpoint
+*/
+func (b *Builder) Commit() {
+	b.checkpoint = nil
+	b.checkpointGas = nil
+}
+
+/*
+	ConstructTx: check txInfo against the rules of e, as executor.CheckTx does, then build
+	its circuit witness and apply it to the state. e must execute on the state of the builder.
+	A *executor.TxError is wrapped in the error of a tx the circuit would reject.
+	The state is left unchanged if an error is returned.
+*/
+func (b *Builder) ConstructTx(e *executor.Executor, txInfo txtypes.TxInfo) (oTx *circuit.Tx, err error) {
+	if e.State() != b.state {
+		errInfo := "[ConstructTx] executor of another state"
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if err = e.CheckTx(txInfo); err != nil {
+		// keep the *executor.TxError so that callers can tell which rule failed
+		err = fmt.Errorf("[ConstructTx] invalid tx: %w", err)
+		log.Println(err.Error())
+		return nil, err
+	}
+	return b.constructTx(txInfo)
+}
+
+/*
+	ConstructUncheckedTx: ConstructTx without the rules of the executor, the witness of an
+	invalid tx is built and the circuit rejects it. Meant for tests of the circuit.
+*/
+func (b *Builder) ConstructUncheckedTx(txInfo txtypes.TxInfo) (oTx *circuit.Tx, err error) {
+	return b.constructTx(txInfo)
+}
+
+func (b *Builder) constructTx(txInfo txtypes.TxInfo) (oTx *circuit.Tx, err error) {
+	if err = txInfo.Validate(); err != nil {
+		errInfo := fmt.Sprintf("[ConstructTx] invalid tx: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	txType := txInfo.GetTxType()
+	oTx = &circuit.Tx{
+		TxType:    uint8(txType),
+		Signature: types.EmptySignature(),
+	}
+	if executor.IsLayer2Tx(txType) {
+		oTx.Nonce = txInfo.GetNonce()
+		oTx.ExpiredAt = txInfo.GetExpiredAt()
+	}
+	var plan *txPlan
+	switch info := txInfo.(type) {
+	case *txtypes.RegisterZnsTxInfo:
+		plan, err = b.registerZns(oTx, info)
+	case *txtypes.DepositTxInfo:
+		plan, err = b.deposit(oTx, info)
+	case *txtypes.DepositNftTxInfo:
+		plan, err = b.depositNft(oTx, info)
+	case *txtypes.TransferTxInfo:
+		plan, err = b.transfer(oTx, info)
+	case *txtypes.WithdrawTxInfo:
+		plan, err = b.withdraw(oTx, info)
+	case *txtypes.CreateCollectionTxInfo:
+		plan, err = b.createCollection(oTx, info)
+	case *txtypes.MintNftTxInfo:
+		plan, err = b.mintNft(oTx, info)
+	case *txtypes.TransferNftTxInfo:
+		plan, err = b.transferNft(oTx, info)
+	case *txtypes.AtomicMatchTxInfo:
+		plan, err = b.atomicMatch(oTx, info)
+	case *txtypes.CancelOfferTxInfo:
+		plan, err = b.cancelOffer(oTx, info)
+	case *txtypes.WithdrawNftTxInfo:
+		plan, err = b.withdrawNft(oTx, info)
+	case *txtypes.FullExitTxInfo:
+		plan, err = b.fullExit(oTx, info)
+	case *txtypes.FullExitNftTxInfo:
+		plan, err = b.fullExitNft(oTx, info)
+	default:
+		err = errors.New(fmt.Sprintf("unsupported tx type: %d", txType))
+	}
+	if err != nil {
+		errInfo := fmt.Sprintf("[ConstructTx] unable to construct tx: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	if err = b.execute(oTx, plan); err != nil {
+		errInfo := fmt.Sprintf("[ConstructTx] unable to execute tx: %s", err.Error())
+		log.Println(errInfo)
+		return nil, errors.New(errInfo)
+	}
+	for _, gas := range plan.gas {
+		if _, ok := b.pendingGas[gas.AssetId]; !ok {
+			b.pendingGas[gas.AssetId] = big.NewInt(0)
+		}
+		b.pendingGas[gas.AssetId].Add(b.pendingGas[gas.AssetId], gas.BalanceDelta)
+	}
+	return oTx, nil
+}
+
+/*
+	execute: walk the slots in the order the circuit verifies them. Every proof is
+	taken against the trees updated by the previous slots, which is how the circuit
+	chains the merkle proofs, so the same account or asset may sit in several slots.
+*/
+func (b *Builder) execute(oTx *circuit.Tx, plan *txPlan) (err error) {
+	s := b.state
+	oTx.AccountRootBefore = s.AccountRoot()
+	oTx.NftRootBefore = s.NftRoot()
+	oTx.StateRootBefore = s.StateRoot()
+	var undo state.Journal
+	defer func() {
+		if err == nil {
+			return
+		}
+		if revertErr := undo.Revert(s); revertErr != nil {
+			err = errors.New(fmt.Sprintf("%s, %s", err.Error(), revertErr.Error()))
+		}
+	}()
+
+	var proof *merkleTree.Proof
+	for i, slot := range plan.accounts {
+		proof, err = s.AccountProof(slot.accountIndex)
+		if err != nil {
+			return err
+		}
+		copyProof(oTx.MerkleProofsAccountBefore[i][:], proof)
+		account := s.GetAccount(slot.accountIndex)
+		undo.RecordAccount(account)
+		accountBefore := toCircuitAccount(account)
+		for j, assetId := range slot.assetIds {
+			proof, err = s.AssetProof(slot.accountIndex, assetId)
+			if err != nil {
+				return err
+			}
+			copyProof(oTx.MerkleProofsAccountAssetsBefore[i][j][:], proof)
+			asset := s.GetAsset(slot.accountIndex, assetId)
+			accountBefore.AssetsInfo[j] = toCircuitAsset(asset)
+			if slot.assetUpdates[j] == nil {
+				continue
+			}
+			undo.RecordAsset(slot.accountIndex, asset)
+			slot.assetUpdates[j](asset)
+			if err = s.SetAsset(slot.accountIndex, asset); err != nil {
+				return err
+			}
+		}
+		oTx.AccountsInfoBefore[i] = accountBefore
+		if slot.update == nil {
+			continue
+		}
+		account = s.GetAccount(slot.accountIndex)
+		slot.update(account)
+		if err = s.SetAccount(account); err != nil {
+			return err
+		}
+	}
+
+	proof, err = s.NftProof(plan.nftIndex)
+	if err != nil {
+		return err
+	}
+	copyProof(oTx.MerkleProofsNftBefore[:], proof)
+	nft := s.GetNft(plan.nftIndex)
+	oTx.NftBefore = toCircuitNft(nft)
+	if plan.nftUpdate != nil {
+		undo.RecordNft(nft)
+		plan.nftUpdate(nft)
+		if err = s.SetNft(nft); err != nil {
+			return err
+		}
+	}
+	oTx.StateRootAfter = s.StateRoot()
+	if b.checkpoint != nil {
+		b.checkpoint.Append(&undo)
+	}
+	return nil
+}
+
+func copyProof(dst [][]byte, proof *merkleTree.Proof) {
+	for i := range dst {
+		dst[i] = proof.ProofSet[i]
+	}
+}
+
+func toCircuitAccount(account *state.AccountState) *types.Account {
+	pk := *account.AccountPk
+	return &types.Account{
+		AccountIndex:    account.AccountIndex,
+		AccountNameHash: account.AccountNameHash,
+		AccountPk:       &pk,
+		Nonce:           account.Nonce,
+		CollectionNonce: account.CollectionNonce,
+		AssetRoot:       account.AssetRoot,
+	}
+}
+
+func toCircuitAsset(asset *state.AssetState) *types.AccountAsset {
+	return &types.AccountAsset{
+		AssetId:                  asset.AssetId,
+		Balance:                  new(big.Int).Set(asset.Balance),
+		OfferCanceledOrFinalized: new(big.Int).Set(asset.OfferCanceledOrFinalized),
+	}
+}
+
+func toCircuitNft(nft *state.NftState) *types.Nft {
+	return &types.Nft{
+		NftIndex:            nft.NftIndex,
+		NftContentHash:      nft.NftContentHash,
+		CreatorAccountIndex: nft.CreatorAccountIndex,
+		OwnerAccountIndex:   nft.OwnerAccountIndex,
+		NftL1Address:        new(big.Int).Set(nft.NftL1Address),
+		NftL1TokenId:        new(big.Int).Set(nft.NftL1TokenId),
+		CreatorTreasuryRate: nft.CreatorTreasuryRate,
+		CollectionId:        nft.CollectionId,
+	}
+}
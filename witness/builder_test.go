@@ -0,0 +1,424 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package witness
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/executor"
+	"github.com/bnb-chain/zkbnb-crypto/internal/testutil"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+var expiredAt = time.Now().Add(time.Hour).UnixMilli()
+
+func privateKey(t *testing.T, accountIndex int64) *txtypes.PrivateKey {
+	sk, err := testutil.PrivateKey(accountIndex)
+	assert.NoError(t, err)
+	return sk
+}
+
+/*
+	rules: the executor the txs of the tests are checked with, gas is paid in the assets 0 to 2
+*/
+func rules(builder *Builder) *executor.Executor {
+	return executor.NewExecutor(builder.State(), time.Now().UnixMilli(), testutil.GasAccount, []int64{0, 1, 2})
+}
+
+/*
+	checkTx: the witness must satisfy the transaction circuit
+*/
+func checkTx(t *testing.T, builder *Builder, txInfo txtypes.TxInfo) *circuit.Tx {
+	oldRoot := builder.State().StateRoot()
+	oTx, err := builder.ConstructTx(rules(builder), txInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, oldRoot, oTx.StateRootBefore)
+	assert.Equal(t, builder.State().StateRoot(), oTx.StateRootAfter)
+	witness, err := circuit.SetTxWitness(oTx)
+	assert.NoError(t, err)
+	assert.NoError(t, test.IsSolved(&circuit.TxConstraints{}, &witness, ecc.BN254.ScalarField()))
+	return oTx
+}
+
+/*
+	rejectTx: the builder rejects an invalid tx and leaves the state unchanged. Its witness,
+	built unchecked, must be rejected by the transaction circuit too. The state is rolled back.
+*/
+func rejectTx(t *testing.T, builder *Builder, txInfo txtypes.TxInfo) {
+	root := builder.State().StateRoot()
+	_, err := builder.ConstructTx(rules(builder), txInfo)
+	var txErr *executor.TxError
+	assert.ErrorAs(t, err, &txErr)
+	assert.Equal(t, root, builder.State().StateRoot())
+
+	builder.Checkpoint()
+	oTx, err := builder.ConstructUncheckedTx(txInfo)
+	assert.NoError(t, builder.Rollback())
+	assert.Equal(t, root, builder.State().StateRoot())
+	if !assert.NoError(t, err) {
+		return
+	}
+	witness, err := circuit.SetTxWitness(oTx)
+	assert.NoError(t, err)
+	assert.Error(t, test.IsSolved(&circuit.TxConstraints{}, &witness, ecc.BN254.ScalarField()))
+}
+
+func balance(builder *Builder, accountIndex, assetId int64) int64 {
+	return builder.State().GetAsset(accountIndex, assetId).Balance.Int64()
+}
+
+func newTestBuilder(t *testing.T) *Builder {
+	s, err := state.NewState()
+	assert.NoError(t, err)
+	builder := NewBuilder(s)
+	for i := int64(testutil.GasAccount); i <= testutil.Carol; i++ {
+		txInfo, err := testutil.RegisterTx(i)
+		assert.NoError(t, err)
+		checkTx(t, builder, txInfo)
+	}
+	for i := int64(testutil.Alice); i <= testutil.Carol; i++ {
+		checkTx(t, builder, testutil.DepositTx(i, 0, 1000000000))
+	}
+	return builder
+}
+
+func TestConstructTransferTxs(t *testing.T) {
+	builder := newTestBuilder(t)
+	checkTx(t, builder, &txtypes.DepositTxInfo{
+		TxType:          txtypes.TxTypeDeposit,
+		AccountNameHash: testutil.NameHash(testutil.Alice),
+		AssetId:         1,
+		AssetAmount:     big.NewInt(5000),
+		AccountIndex:    testutil.Alice,
+	})
+
+	// asset and gas share the same leaf
+	txInfo, err := txtypes.ConstructTransferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		AssetId:           0,
+		AssetAmount:       "100000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, txInfo)
+	assert.Equal(t, int64(1000000000-101000), balance(builder, testutil.Alice, 0))
+	assert.Equal(t, int64(1000100000), balance(builder, testutil.Bob, 0))
+
+	// transfer to self, the same account sits in both slots
+	txInfo, err = txtypes.ConstructTransferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Alice,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Alice)),
+		AssetId:           1,
+		AssetAmount:       "3000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             1,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, txInfo)
+	assert.Equal(t, int64(5000), balance(builder, testutil.Alice, 1))
+	assert.Equal(t, int64(2), builder.State().GetAccount(testutil.Alice).Nonce)
+
+	withdrawInfo, err := txtypes.ConstructWithdrawTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.WithdrawSegmentFormat{
+		FromAccountIndex:  testutil.Bob,
+		AssetId:           0,
+		AssetAmount:       "12345",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, withdrawInfo)
+
+	checkTx(t, builder, &txtypes.FullExitTxInfo{
+		TxType:          txtypes.TxTypeFullExit,
+		AccountNameHash: testutil.NameHash(testutil.Alice),
+		AssetId:         1,
+		AccountIndex:    testutil.Alice,
+	})
+	assert.Equal(t, int64(0), balance(builder, testutil.Alice, 1))
+
+	// gas is collected, not credited yet
+	assert.Equal(t, int64(0), balance(builder, testutil.GasAccount, 0))
+	assert.Equal(t, map[int64]*big.Int{0: big.NewInt(3000)}, builder.PendingGas())
+}
+
+func TestConstructNftTxs(t *testing.T) {
+	builder := newTestBuilder(t)
+	collectionInfo, err := txtypes.ConstructCreateCollectionTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.CreateCollectionSegmentFormat{
+		AccountIndex:      testutil.Carol,
+		Name:              "collection",
+		Introduction:      "collection",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, collectionInfo)
+	assert.Equal(t, int64(1), builder.State().GetAccount(testutil.Carol).CollectionNonce)
+
+	mintInfo, err := txtypes.ConstructMintNftTxInfo(privateKey(t, testutil.Carol), testutil.Segment(&txtypes.MintNftSegmentFormat{
+		CreatorAccountIndex: testutil.Carol,
+		ToAccountIndex:      testutil.Alice,
+		ToAccountNameHash:   common.Bytes2Hex(testutil.NameHash(testutil.Alice)),
+		NftContentHash:      common.Bytes2Hex(testutil.NameHash(100)),
+		NftCollectionId:     0,
+		CreatorTreasuryRate: 100,
+		GasAccountIndex:     testutil.GasAccount,
+		GasFeeAssetId:       0,
+		GasFeeAssetAmount:   "1000",
+		ExpiredAt:           expiredAt,
+		Nonce:               1,
+	}))
+	assert.NoError(t, err)
+	mintInfo.NftIndex = 0
+	checkTx(t, builder, mintInfo)
+
+	transferInfo, err := txtypes.ConstructTransferNftTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.TransferNftSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		NftIndex:          0,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, transferInfo)
+	assert.Equal(t, int64(testutil.Bob), builder.State().GetNft(0).OwnerAccountIndex)
+
+	// alice buys the nft from bob, the sell offer bits share the leaf of the asset
+	buyOffer, err := txtypes.ConstructOfferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.OfferSegmentFormat{
+		Type:         txtypes.BuyOfferType,
+		OfferId:      130,
+		AccountIndex: testutil.Alice,
+		NftIndex:     0,
+		AssetId:      0,
+		AssetAmount:  "1000000",
+		ListedAt:     time.Now().UnixMilli(),
+		ExpiredAt:    expiredAt,
+		TreasuryRate: 200,
+	}))
+	assert.NoError(t, err)
+	sellOffer, err := txtypes.ConstructOfferTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.OfferSegmentFormat{
+		Type:         txtypes.SellOfferType,
+		OfferId:      1,
+		AccountIndex: testutil.Bob,
+		NftIndex:     0,
+		AssetId:      0,
+		AssetAmount:  "1000000",
+		ListedAt:     time.Now().UnixMilli(),
+		ExpiredAt:    expiredAt,
+		TreasuryRate: 200,
+	}))
+	assert.NoError(t, err)
+	matchInfo, err := txtypes.ConstructAtomicMatchTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.AtomicMatchSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		BuyOffer:          testutil.Segment(buyOffer),
+		SellOffer:         testutil.Segment(sellOffer),
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		Nonce:             1,
+		ExpiredAt:         expiredAt,
+	}))
+	assert.NoError(t, err)
+	aliceBalance, bobBalance, carolBalance := balance(builder, testutil.Alice, 0), balance(builder, testutil.Bob, 0), balance(builder, testutil.Carol, 0)
+	checkTx(t, builder, matchInfo)
+	assert.Equal(t, int64(testutil.Alice), builder.State().GetNft(0).OwnerAccountIndex)
+	assert.Equal(t, aliceBalance-1000000-1000, balance(builder, testutil.Alice, 0))
+	assert.Equal(t, bobBalance+1000000-10000-20000, balance(builder, testutil.Bob, 0))
+	assert.Equal(t, carolBalance+10000, balance(builder, testutil.Carol, 0))
+	assert.Equal(t, int64(4), builder.State().GetAsset(testutil.Alice, 1).OfferCanceledOrFinalized.Int64())
+	assert.Equal(t, int64(2), builder.State().GetAsset(testutil.Bob, 0).OfferCanceledOrFinalized.Int64())
+
+	// the offer is finalized, it can not be matched twice
+	rejectTx(t, builder, matchInfo)
+
+	cancelInfo, err := txtypes.ConstructCancelOfferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.CancelOfferSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		OfferId:           131,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             2,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, cancelInfo)
+	assert.Equal(t, int64(12), builder.State().GetAsset(testutil.Alice, 1).OfferCanceledOrFinalized.Int64())
+
+	// the offer is canceled, it can not be canceled twice
+	cancelInfo, err = txtypes.ConstructCancelOfferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.CancelOfferSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		OfferId:           131,
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             3,
+	}))
+	assert.NoError(t, err)
+	rejectTx(t, builder, cancelInfo)
+
+	withdrawInfo, err := txtypes.ConstructWithdrawNftTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.WithdrawNftSegmentFormat{
+		AccountIndex:      testutil.Alice,
+		NftIndex:          0,
+		ToAddress:         "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             3,
+	}))
+	assert.NoError(t, err)
+	checkTx(t, builder, withdrawInfo)
+	assert.Equal(t, state.NilNftNodeHash, builder.State().GetNft(0).Hash())
+
+	checkTx(t, builder, &txtypes.DepositNftTxInfo{
+		TxType:              txtypes.TxTypeDepositNft,
+		AccountNameHash:     testutil.NameHash(testutil.Bob),
+		CreatorAccountIndex: testutil.Carol,
+		CreatorTreasuryRate: 50,
+		NftL1Address:        "0x5b38da6a701c568545dcfcb03fcb875f56beddc4",
+		NftL1TokenId:        big.NewInt(7),
+		NftContentHash:      testutil.NameHash(101),
+		CollectionId:        0,
+		NftIndex:            5,
+		AccountIndex:        testutil.Bob,
+	})
+	// the nft of another account is left to its owner
+	nftHash := builder.State().GetNft(5).Hash()
+	oTx := checkTx(t, builder, &txtypes.FullExitNftTxInfo{
+		TxType:                 txtypes.TxTypeFullExitNft,
+		NftIndex:               5,
+		AccountNameHash:        testutil.NameHash(testutil.Carol),
+		AccountIndex:           testutil.Carol,
+		CreatorAccountNameHash: testutil.NameHash(testutil.Carol),
+	})
+	assert.Equal(t, nftHash, builder.State().GetNft(5).Hash())
+	assert.Equal(t, int64(0), oTx.FullExitNftTxInfo.NftL1TokenId.Int64())
+	checkTx(t, builder, &txtypes.FullExitNftTxInfo{
+		TxType:                 txtypes.TxTypeFullExitNft,
+		NftIndex:               5,
+		AccountNameHash:        testutil.NameHash(testutil.Bob),
+		AccountIndex:           testutil.Bob,
+		CreatorAccountNameHash: testutil.NameHash(testutil.Carol),
+	})
+	assert.Equal(t, state.NilNftNodeHash, builder.State().GetNft(5).Hash())
+	assert.Equal(t, map[int64]*big.Int{0: big.NewInt(6000 + 20000)}, builder.PendingGas())
+}
+
+func TestConstructInvalidTx(t *testing.T) {
+	builder := newTestBuilder(t)
+	root := builder.State().StateRoot()
+	// the to account does not match its name hash
+	txInfo, err := txtypes.ConstructTransferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Carol)),
+		AssetId:           0,
+		AssetAmount:       "100000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	rejectTx(t, builder, txInfo)
+	assert.Empty(t, builder.PendingGas())
+
+	// insufficient balance, the balance wraps around in the field
+	txInfo, err = txtypes.ConstructTransferTxInfo(privateKey(t, testutil.Alice), testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		AssetId:           0,
+		AssetAmount:       "1000000000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	rejectTx(t, builder, txInfo)
+
+	// signed by someone else
+	txInfo, err = txtypes.ConstructTransferTxInfo(privateKey(t, testutil.Bob), testutil.Segment(&txtypes.TransferSegmentFormat{
+		FromAccountIndex:  testutil.Alice,
+		ToAccountIndex:    testutil.Bob,
+		ToAccountNameHash: common.Bytes2Hex(testutil.NameHash(testutil.Bob)),
+		AssetId:           0,
+		AssetAmount:       "1000",
+		GasAccountIndex:   testutil.GasAccount,
+		GasFeeAssetId:     0,
+		GasFeeAssetAmount: "1000",
+		ExpiredAt:         expiredAt,
+		Nonce:             0,
+	}))
+	assert.NoError(t, err)
+	rejectTx(t, builder, txInfo)
+
+	// registered twice
+	rejectTx(t, builder, &txtypes.RegisterZnsTxInfo{
+		TxType:          txtypes.TxTypeRegisterZns,
+		AccountIndex:    testutil.Alice,
+		AccountName:     "alice.legend",
+		AccountNameHash: testutil.NameHash(testutil.Alice),
+		PubKey:          hex.EncodeToString(privateKey(t, testutil.Alice).PublicKey.Bytes()),
+	})
+
+	// an account out of the tree has no witness
+	_, err = builder.ConstructUncheckedTx(&txtypes.DepositTxInfo{
+		TxType:          txtypes.TxTypeDeposit,
+		AccountIndex:    1 << circuit.AccountMerkleLevels,
+		AccountNameHash: testutil.NameHash(testutil.Alice),
+		AssetId:         0,
+		AssetAmount:     big.NewInt(1000),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, root, builder.State().StateRoot())
+}
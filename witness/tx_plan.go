@@ -0,0 +1,520 @@
+/*
+ * Copyright © 2022 ZkBNB Protocol
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package witness
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+
+	"github.com/bnb-chain/zkbnb-crypto/circuit"
+	"github.com/bnb-chain/zkbnb-crypto/circuit/types"
+	"github.com/bnb-chain/zkbnb-crypto/pubdata"
+	"github.com/bnb-chain/zkbnb-crypto/state"
+	"github.com/bnb-chain/zkbnb-crypto/util"
+	"github.com/bnb-chain/zkbnb-crypto/wasm/txtypes"
+)
+
+type (
+	assetUpdate   func(asset *state.AssetState)
+	accountUpdate func(account *state.AccountState)
+	nftUpdate     func(nft *state.NftState)
+)
+
+/*
+	accountSlot: the account the circuit expects at a slot of AccountsInfoBefore,
+	with the asset ids of its two asset slots and the updates of the transaction
+*/
+type accountSlot struct {
+	accountIndex int64
+	assetIds     [types.NbAccountAssetsPerAccount]int64
+	assetUpdates [types.NbAccountAssetsPerAccount]assetUpdate
+	update       accountUpdate
+}
+
+type txPlan struct {
+	accounts  [types.NbAccountsPerTx]accountSlot
+	nftIndex  int64
+	nftUpdate nftUpdate
+	gas       []*GasDelta
+}
+
+/*
+	newTxPlan: unused slots point to the last account, asset and nft, like the
+	empty transactions of the circuit
+*/
+func newTxPlan() *txPlan {
+	plan := &txPlan{nftIndex: circuit.LastNftIndex}
+	for i := range plan.accounts {
+		plan.accounts[i] = newAccountSlot(circuit.LastAccountIndex)
+	}
+	return plan
+}
+
+func newAccountSlot(accountIndex int64) accountSlot {
+	return accountSlot{
+		accountIndex: accountIndex,
+		assetIds:     [types.NbAccountAssetsPerAccount]int64{circuit.LastAccountAssetId, circuit.LastAccountAssetId},
+	}
+}
+
+/*
+	fieldValue: the circuit computes balances in the field, an insufficient balance of a tx built
+	unchecked wraps around like it does there and the range checks of the circuit reject it
+*/
+func fieldValue(v *big.Int) *big.Int {
+	return v.Mod(v, fr.Modulus())
+}
+
+/*
+	fieldBytes: b reduced in the field, which is what the circuit sees of it
+*/
+func fieldBytes(b []byte) []byte {
+	v := new(big.Int).SetBytes(b)
+	if v.Cmp(fr.Modulus()) < 0 {
+		return b
+	}
+	return fieldValue(v).FillBytes(make([]byte, fr.Bytes))
+}
+
+func subBalance(amount *big.Int) assetUpdate {
+	return func(asset *state.AssetState) {
+		asset.Balance = fieldValue(new(big.Int).Sub(asset.Balance, amount))
+	}
+}
+
+func addBalance(amount *big.Int) assetUpdate {
+	return func(asset *state.AssetState) {
+		asset.Balance = fieldValue(new(big.Int).Add(asset.Balance, amount))
+	}
+}
+
+/*
+	finalizeOffer: set the bit of the offer in OfferCanceledOrFinalized
+*/
+func finalizeOffer(offerId int64) assetUpdate {
+	return func(asset *state.AssetState) {
+		bit := int(offerId % types.OfferSizePerAsset)
+		asset.OfferCanceledOrFinalized = new(big.Int).SetBit(asset.OfferCanceledOrFinalized, bit, 1)
+	}
+}
+
+/*
+	rateShare: amount * rate / RateBase divided in the field like the circuit does,
+	it is the integer share when amount * rate is a multiple of RateBase
+*/
+func rateShare(amount *big.Int, rate int64) *big.Int {
+	share := new(big.Int).Mul(amount, big.NewInt(rate))
+	share.Mul(share, new(big.Int).ModInverse(big.NewInt(types.RateBase), fr.Modulus()))
+	return fieldValue(share)
+}
+
+/*
+	packableAmount: amount rounded down to what packs. The pubdata holds the packed amount while
+	the balances move by amount, so the circuit rejects an amount that does not pack.
+	An amount out of range is returned as is and fails to pack.
+*/
+func packableAmount(amount *big.Int) *big.Int {
+	if amount == nil {
+		return nil
+	}
+	if packable, err := util.CleanPackedAmount(amount); err == nil {
+		return packable
+	}
+	return amount
+}
+
+// packableFee: fee rounded down to what packs, like packableAmount
+func packableFee(fee *big.Int) *big.Int {
+	if fee == nil {
+		return nil
+	}
+	if packable, err := util.CleanPackedFee(fee); err == nil {
+		return packable
+	}
+	return fee
+}
+
+func offerAssetId(offerId int64) int64 {
+	return offerId / types.OfferSizePerAsset
+}
+
+func incNonce(account *state.AccountState) {
+	account.Nonce++
+}
+
+func gasPlan(plan *txPlan, slot int, gasFeeAssetId int64, gasFeeAssetAmount *big.Int) {
+	plan.accounts[slot].assetIds[0] = gasFeeAssetId
+	plan.accounts[slot].assetUpdates[0] = subBalance(gasFeeAssetAmount)
+	plan.gas = append(plan.gas, &GasDelta{AssetId: gasFeeAssetId, BalanceDelta: gasFeeAssetAmount})
+}
+
+func (b *Builder) registerZns(oTx *circuit.Tx, txInfo *txtypes.RegisterZnsTxInfo) (*txPlan, error) {
+	var err error
+	if oTx.RegisterZnsTxInfo, err = pubdata.ToRegisterZnsTx(txInfo); err != nil {
+		return nil, err
+	}
+	pk := oTx.RegisterZnsTxInfo.PubKey
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	plan.accounts[0].update = func(account *state.AccountState) {
+		account.AccountNameHash = txInfo.AccountNameHash
+		account.AccountPk = &eddsa.PublicKey{A: pk.A}
+	}
+	return plan, nil
+}
+
+func (b *Builder) deposit(oTx *circuit.Tx, txInfo *txtypes.DepositTxInfo) (*txPlan, error) {
+	var err error
+	if oTx.DepositTxInfo, err = pubdata.ToDepositTx(txInfo); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	plan.accounts[0].assetIds[0] = txInfo.AssetId
+	plan.accounts[0].assetUpdates[0] = addBalance(txInfo.AssetAmount)
+	return plan, nil
+}
+
+func (b *Builder) depositNft(oTx *circuit.Tx, txInfo *txtypes.DepositNftTxInfo) (*txPlan, error) {
+	var err error
+	if oTx.DepositNftTxInfo, err = pubdata.ToDepositNftTx(txInfo); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	plan.nftIndex = txInfo.NftIndex
+	plan.nftUpdate = func(nft *state.NftState) {
+		nft.CreatorAccountIndex = txInfo.CreatorAccountIndex
+		nft.OwnerAccountIndex = txInfo.AccountIndex
+		nft.NftContentHash = txInfo.NftContentHash
+		nft.NftL1Address = pubdata.AddressToBigInt(txInfo.NftL1Address)
+		nft.NftL1TokenId = txInfo.NftL1TokenId
+		nft.CreatorTreasuryRate = txInfo.CreatorTreasuryRate
+		nft.CollectionId = txInfo.CollectionId
+	}
+	return plan, nil
+}
+
+func (b *Builder) transfer(oTx *circuit.Tx, txInfo *txtypes.TransferTxInfo) (*txPlan, error) {
+	var err error
+	packed := *txInfo
+	packed.AssetAmount = packableAmount(txInfo.AssetAmount)
+	packed.GasFeeAssetAmount = packableFee(txInfo.GasFeeAssetAmount)
+	if oTx.TransferTxInfo, err = pubdata.ToTransferTx(&packed); err != nil {
+		return nil, err
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	// from account: asset in slot 0, gas in slot 1
+	plan.accounts[0] = newAccountSlot(txInfo.FromAccountIndex)
+	plan.accounts[0].assetIds = [types.NbAccountAssetsPerAccount]int64{txInfo.AssetId, txInfo.GasFeeAssetId}
+	plan.accounts[0].assetUpdates[0] = subBalance(txInfo.AssetAmount)
+	plan.accounts[0].assetUpdates[1] = subBalance(txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = incNonce
+	plan.gas = append(plan.gas, &GasDelta{AssetId: txInfo.GasFeeAssetId, BalanceDelta: txInfo.GasFeeAssetAmount})
+	// to account
+	plan.accounts[1] = newAccountSlot(txInfo.ToAccountIndex)
+	plan.accounts[1].assetIds[0] = txInfo.AssetId
+	plan.accounts[1].assetUpdates[0] = addBalance(txInfo.AssetAmount)
+	return plan, nil
+}
+
+func (b *Builder) withdraw(oTx *circuit.Tx, txInfo *txtypes.WithdrawTxInfo) (*txPlan, error) {
+	var err error
+	packed := *txInfo
+	packed.GasFeeAssetAmount = packableFee(txInfo.GasFeeAssetAmount)
+	if oTx.WithdrawTxInfo, err = pubdata.ToWithdrawTx(&packed); err != nil {
+		return nil, err
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	// from account: asset in slot 0, gas in slot 1
+	plan.accounts[0] = newAccountSlot(txInfo.FromAccountIndex)
+	plan.accounts[0].assetIds = [types.NbAccountAssetsPerAccount]int64{txInfo.AssetId, txInfo.GasFeeAssetId}
+	plan.accounts[0].assetUpdates[0] = subBalance(txInfo.AssetAmount)
+	plan.accounts[0].assetUpdates[1] = subBalance(txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = incNonce
+	plan.gas = append(plan.gas, &GasDelta{AssetId: txInfo.GasFeeAssetId, BalanceDelta: txInfo.GasFeeAssetAmount})
+	return plan, nil
+}
+
+func (b *Builder) createCollection(oTx *circuit.Tx, txInfo *txtypes.CreateCollectionTxInfo) (*txPlan, error) {
+	var err error
+	packed := *txInfo
+	packed.GasFeeAssetAmount = packableFee(txInfo.GasFeeAssetAmount)
+	if oTx.CreateCollectionTxInfo, err = pubdata.ToCreateCollectionTx(&packed); err != nil {
+		return nil, err
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	gasPlan(plan, 0, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = func(account *state.AccountState) {
+		account.CollectionNonce++
+		incNonce(account)
+	}
+	return plan, nil
+}
+
+func (b *Builder) mintNft(oTx *circuit.Tx, txInfo *txtypes.MintNftTxInfo) (*txPlan, error) {
+	var err error
+	packed := *txInfo
+	packed.GasFeeAssetAmount = packableFee(txInfo.GasFeeAssetAmount)
+	if oTx.MintNftTxInfo, err = pubdata.ToMintNftTx(&packed); err != nil {
+		return nil, err
+	}
+	nftContentHash := fieldBytes(oTx.MintNftTxInfo.NftContentHash)
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	// creator pays the gas
+	plan.accounts[0] = newAccountSlot(txInfo.CreatorAccountIndex)
+	gasPlan(plan, 0, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = incNonce
+	plan.accounts[1] = newAccountSlot(txInfo.ToAccountIndex)
+	plan.nftIndex = txInfo.NftIndex
+	plan.nftUpdate = func(nft *state.NftState) {
+		nft.CreatorAccountIndex = txInfo.CreatorAccountIndex
+		nft.OwnerAccountIndex = txInfo.ToAccountIndex
+		nft.NftContentHash = nftContentHash
+		nft.CreatorTreasuryRate = txInfo.CreatorTreasuryRate
+		nft.CollectionId = txInfo.NftCollectionId
+	}
+	return plan, nil
+}
+
+func (b *Builder) transferNft(oTx *circuit.Tx, txInfo *txtypes.TransferNftTxInfo) (*txPlan, error) {
+	var err error
+	packed := *txInfo
+	packed.GasFeeAssetAmount = packableFee(txInfo.GasFeeAssetAmount)
+	if oTx.TransferNftTxInfo, err = pubdata.ToTransferNftTx(&packed); err != nil {
+		return nil, err
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.FromAccountIndex)
+	gasPlan(plan, 0, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = incNonce
+	plan.accounts[1] = newAccountSlot(txInfo.ToAccountIndex)
+	plan.nftIndex = txInfo.NftIndex
+	plan.nftUpdate = func(nft *state.NftState) {
+		nft.OwnerAccountIndex = txInfo.ToAccountIndex
+	}
+	return plan, nil
+}
+
+/*
+	atomicMatch: submitter pays the gas in slot 0, buyer and seller keep the asset in
+	asset slot 0 and the offer bits in asset slot 1, creator receives the royalty
+*/
+func (b *Builder) atomicMatch(oTx *circuit.Tx, txInfo *txtypes.AtomicMatchTxInfo) (*txPlan, error) {
+	buyOffer, sellOffer := txInfo.BuyOffer, txInfo.SellOffer
+	nft := b.state.GetNft(sellOffer.NftIndex)
+	amount := buyOffer.AssetAmount
+	// the balances follow the shares of the circuit, the pubdata holds the amounts of the tx
+	// or the integer shares
+	creatorShare := rateShare(amount, nft.CreatorTreasuryRate)
+	treasuryShare := rateShare(amount, buyOffer.TreasuryRate)
+	sellerAmount := fieldValue(new(big.Int).Sub(amount, new(big.Int).Add(creatorShare, treasuryShare)))
+	creatorAmount, treasuryAmount := txInfo.CreatorAmount, txInfo.TreasuryAmount
+	if creatorAmount == nil {
+		creatorAmount = new(big.Int).Div(new(big.Int).Mul(amount, big.NewInt(nft.CreatorTreasuryRate)), big.NewInt(types.RateBase))
+	}
+	if treasuryAmount == nil {
+		treasuryAmount = new(big.Int).Div(new(big.Int).Mul(amount, big.NewInt(buyOffer.TreasuryRate)), big.NewInt(types.RateBase))
+	}
+	packedCreatorAmount, err := pubdata.PackAmount(packableAmount(creatorAmount))
+	if err != nil {
+		return nil, err
+	}
+	// the treasury is credited to the gas account from its packed value
+	packedTreasuryAmount, err := pubdata.PackAmount(packableAmount(treasuryAmount))
+	if err != nil {
+		return nil, err
+	}
+	packedFee, err := pubdata.PackFee(packableFee(txInfo.GasFeeAssetAmount))
+	if err != nil {
+		return nil, err
+	}
+	oTx.AtomicMatchTxInfo = &types.AtomicMatchTx{
+		AccountIndex:      txInfo.AccountIndex,
+		CreatorAmount:     packedCreatorAmount,
+		TreasuryAmount:    packedTreasuryAmount,
+		GasAccountIndex:   txInfo.GasAccountIndex,
+		GasFeeAssetId:     txInfo.GasFeeAssetId,
+		GasFeeAssetAmount: packedFee,
+	}
+	packedBuyOffer, packedSellOffer := *buyOffer, *sellOffer
+	packedBuyOffer.AssetAmount = packableAmount(buyOffer.AssetAmount)
+	packedSellOffer.AssetAmount = packableAmount(sellOffer.AssetAmount)
+	if oTx.AtomicMatchTxInfo.BuyOffer, err = pubdata.ToOfferTx(&packedBuyOffer); err != nil {
+		return nil, err
+	}
+	if oTx.AtomicMatchTxInfo.SellOffer, err = pubdata.ToOfferTx(&packedSellOffer); err != nil {
+		return nil, err
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	gasPlan(plan, 0, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = incNonce
+	plan.gas = append([]*GasDelta{{AssetId: buyOffer.AssetId, BalanceDelta: treasuryAmount}}, plan.gas...)
+	// buyer
+	plan.accounts[1] = newAccountSlot(buyOffer.AccountIndex)
+	plan.accounts[1].assetIds = [types.NbAccountAssetsPerAccount]int64{buyOffer.AssetId, offerAssetId(buyOffer.OfferId)}
+	plan.accounts[1].assetUpdates[0] = subBalance(amount)
+	plan.accounts[1].assetUpdates[1] = finalizeOffer(buyOffer.OfferId)
+	// seller
+	plan.accounts[2] = newAccountSlot(sellOffer.AccountIndex)
+	plan.accounts[2].assetIds = [types.NbAccountAssetsPerAccount]int64{sellOffer.AssetId, offerAssetId(sellOffer.OfferId)}
+	plan.accounts[2].assetUpdates[0] = addBalance(sellerAmount)
+	plan.accounts[2].assetUpdates[1] = finalizeOffer(sellOffer.OfferId)
+	// creator
+	plan.accounts[3] = newAccountSlot(nft.CreatorAccountIndex)
+	plan.accounts[3].assetIds[0] = sellOffer.AssetId
+	plan.accounts[3].assetUpdates[0] = addBalance(creatorShare)
+	plan.nftIndex = sellOffer.NftIndex
+	plan.nftUpdate = func(nft *state.NftState) {
+		nft.OwnerAccountIndex = buyOffer.AccountIndex
+	}
+	return plan, nil
+}
+
+func (b *Builder) cancelOffer(oTx *circuit.Tx, txInfo *txtypes.CancelOfferTxInfo) (*txPlan, error) {
+	var err error
+	packed := *txInfo
+	packed.GasFeeAssetAmount = packableFee(txInfo.GasFeeAssetAmount)
+	if oTx.CancelOfferTxInfo, err = pubdata.ToCancelOfferTx(&packed); err != nil {
+		return nil, err
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	// gas in asset slot 0, offer bits in asset slot 1
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	gasPlan(plan, 0, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount)
+	plan.accounts[0].assetIds[1] = offerAssetId(txInfo.OfferId)
+	plan.accounts[0].assetUpdates[1] = finalizeOffer(txInfo.OfferId)
+	plan.accounts[0].update = incNonce
+	return plan, nil
+}
+
+/*
+	withdrawNft: the nft and creator fields of the pubdata are taken from the state
+*/
+func (b *Builder) withdrawNft(oTx *circuit.Tx, txInfo *txtypes.WithdrawNftTxInfo) (*txPlan, error) {
+	packedFee, err := pubdata.PackFee(packableFee(txInfo.GasFeeAssetAmount))
+	if err != nil {
+		return nil, err
+	}
+	nft := b.state.GetNft(txInfo.NftIndex)
+	creator := b.state.GetAccount(nft.CreatorAccountIndex)
+	oTx.WithdrawNftTxInfo = &types.WithdrawNftTx{
+		AccountIndex:           txInfo.AccountIndex,
+		CreatorAccountIndex:    nft.CreatorAccountIndex,
+		CreatorAccountNameHash: creator.AccountNameHash,
+		CreatorTreasuryRate:    nft.CreatorTreasuryRate,
+		NftIndex:               txInfo.NftIndex,
+		NftContentHash:         nft.NftContentHash,
+		NftL1Address:           nft.NftL1Address.String(),
+		NftL1TokenId:           nft.NftL1TokenId,
+		ToAddress:              pubdata.AddressToBigInt(txInfo.ToAddress).String(),
+		GasAccountIndex:        txInfo.GasAccountIndex,
+		GasFeeAssetId:          txInfo.GasFeeAssetId,
+		GasFeeAssetAmount:      packedFee,
+		CollectionId:           nft.CollectionId,
+	}
+	if oTx.Signature, err = pubdata.ParseSignature(txInfo.Sig); err != nil {
+		return nil, err
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	gasPlan(plan, 0, txInfo.GasFeeAssetId, txInfo.GasFeeAssetAmount)
+	plan.accounts[0].update = incNonce
+	plan.accounts[1] = newAccountSlot(nft.CreatorAccountIndex)
+	plan.nftIndex = txInfo.NftIndex
+	plan.nftUpdate = func(nft *state.NftState) {
+		*nft = *state.EmptyNftState(nft.NftIndex)
+	}
+	return plan, nil
+}
+
+func (b *Builder) fullExit(oTx *circuit.Tx, txInfo *txtypes.FullExitTxInfo) (*txPlan, error) {
+	// the whole balance leaves the layer 2 unless the tx says otherwise
+	amount := b.state.GetAsset(txInfo.AccountIndex, txInfo.AssetId).Balance
+	if txInfo.AssetAmount != nil {
+		amount = txInfo.AssetAmount
+	}
+	oTx.FullExitTxInfo = &types.FullExitTx{
+		AccountIndex:    txInfo.AccountIndex,
+		AccountNameHash: txInfo.AccountNameHash,
+		AssetId:         txInfo.AssetId,
+		AssetAmount:     amount,
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	plan.accounts[0].assetIds[0] = txInfo.AssetId
+	plan.accounts[0].assetUpdates[0] = subBalance(amount)
+	return plan, nil
+}
+
+func (b *Builder) fullExitNft(oTx *circuit.Tx, txInfo *txtypes.FullExitNftTxInfo) (*txPlan, error) {
+	nft := b.state.GetNft(txInfo.NftIndex)
+	isOwner := nft.OwnerAccountIndex == txInfo.AccountIndex
+	oTx.FullExitNftTxInfo = &types.FullExitNftTx{
+		AccountIndex:           txInfo.AccountIndex,
+		AccountNameHash:        txInfo.AccountNameHash,
+		CreatorAccountIndex:    nft.CreatorAccountIndex,
+		CreatorAccountNameHash: txInfo.CreatorAccountNameHash,
+		CreatorTreasuryRate:    nft.CreatorTreasuryRate,
+		NftIndex:               txInfo.NftIndex,
+		CollectionId:           nft.CollectionId,
+		NftContentHash:         nft.NftContentHash,
+		NftL1Address:           nft.NftL1Address.String(),
+		NftL1TokenId:           nft.NftL1TokenId,
+	}
+	plan := newTxPlan()
+	plan.accounts[0] = newAccountSlot(txInfo.AccountIndex)
+	plan.nftIndex = txInfo.NftIndex
+	// the nft of another account is left to its owner and the pubdata holds no nft
+	if !isOwner {
+		empty := state.EmptyNftState(txInfo.NftIndex)
+		oTx.FullExitNftTxInfo.NftContentHash = empty.NftContentHash
+		oTx.FullExitNftTxInfo.NftL1Address = empty.NftL1Address.String()
+		oTx.FullExitNftTxInfo.NftL1TokenId = empty.NftL1TokenId
+		return plan, nil
+	}
+	plan.nftUpdate = func(nft *state.NftState) {
+		*nft = *state.EmptyNftState(nft.NftIndex)
+	}
+	return plan, nil
+}